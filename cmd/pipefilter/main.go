@@ -21,56 +21,16 @@ import (
 )
 const BUF_SIZE=10                       // Buffer size for reading from the pipeS
 
-const (
-	Success=iota                        // No errors
-	ForkError						    // Fork error
-	PipeError                           // Pipe error
-	PipeCreated                         // Pipe created successfully
-	PipeReadEndClosed                   // Read end of pipe closed
-	PipeWriteEndClosed                  // Write end of pipe closed
-	PipeReadError                       // Read error
-	PipeWriteError                      // Write error
-	GotEOF                              // EOF encountered
-	ExecError                           // Exec error
-	UnknownError                        // Unknown error
-)
-
-func StatusToString(status int) string {// Convert status code to string
-	switch status {                     // Check the status code
-	case ForkError:                     // Fork error
-		return "Fork error"             // Return the string
-	case PipeError:                     // Pipe error
-		return "Pipe error"             // Return the string
-	case Success:                       // No errors
-		return "Success"                // Return the string
-	case PipeCreated:                   // Pipe created successfully
-		return "Pipe created successfully"// Return the string
-	case PipeReadEndClosed:             // Read end of pipe closed
-		return "Read end of pipe closed"// Return the string
-	case PipeWriteEndClosed:            // Write end of pipe closed
-		return "Write end of pipe closed"// Return the string
-	case PipeReadError:                 // Read error
-		return "Read error"             // Return the string
-	case PipeWriteError:                // Write error
-		return "Write error"            // Return the string
-  case GotEOF:                          // EOF encountered
-		return "EOF encountered"        // Return the string
-	case UnknownError:                  // Unknown error
-		return "Unknown error"          // Return the string
-	default:                            // Unknown status code
-		return "Unknown status code"    // Return the string
-	}                                   // Done stringing the status code.
-}                                       // ------------ StatusToString --------- //
 
-func pipeToBrother(log logger.Log) (int){
+func pipeToBrother(log logger.Log) (pipe.Status){
  // ----------------------------------- //
  // Create a pipe for synchronization between parent and child processes (1)
  // ----------------------------------- //
- status:=Success                        // Set the status code 
+ status:=pipe.Success                   // Set the status code 
  pfp,err:=pipe.NewPipe()                // Create a new pipe
   if err!=nil{                          // Pipe creation error?
     log.Err("Error creating pipe: %v",err) // Yes, log the error
-	status:=PipeError                   // Set the status code
+	status:=pipe.PipeError              // Set the status code
 	return status                       // Return the status code
   }                                     // Done checking for pipe creation error.
   log.Inf("Pipe created successfully")  // Log the pipe creation
@@ -81,7 +41,7 @@ func pipeToBrother(log logger.Log) (int){
   pid,_,errno:=syscall.RawSyscall(syscall.SYS_FORK,0,0,0) // Fork the process
   if errno!=0{                          // Error forking to new process?
 	log.Err("Error forking process: %v",errno) // Yes, log the error
-	status:=ForkError                   // Set the status code
+	status:=pipe.ForkError              // Set the status code
 	return status                       // Return the status code
   }                                     // Done checking for fork error.
   switch pid{                           // Act according to the process ID.
@@ -95,20 +55,20 @@ func pipeToBrother(log logger.Log) (int){
 	wfp,err:=pfp.GetWriteEnd()          // Get the write end of the pipe
 	if err!=nil{                        // Error getting the write end of the pipe?
 	  log.Err("Error getting write end of pipe: %v",err) // Yes, log the error
-	  status:=PipeWriteEndClosed        // Set the status code
+	  status:=pipe.PipeWriteEndClosed   // Set the status code
 	  return status                     // Return the status code
 	}                                   // Done checking for write end of pipe error.
     if wfp.Fd()!=os.Stdout.Fd(){        // Is the write end of the pipe not stdout?
 	  _,err=pipe.Dup2File(wfp,int(os.Stdout.Fd())) // Yes, duplicate the write end of the pipe on stdout
 	  if err!=nil{                      // Error duplicating the write end of the pipe?
 		log.Err("Error duplicating write end of pipe: %v",err) // Yes, log the error
-		status:=PipeWriteEndClosed      // Set the status code
+		status:=pipe.PipeWriteEndClosed // Set the status code
 		return status                   // Return the status code
 	  }                                 // Done checking for write end of pipe duplication error.
 	  log.Err("Write end of pipe already bound to stdout") // Log the error
 	  if pfp.CloseWrite()!=nil{         // Error closing the write end of the pipe?
 		log.Err("Error closing write end of pipe: %v",err) // Yes, log the error
-		status:=PipeWriteEndClosed      // Set the status code
+		status:=pipe.PipeWriteEndClosed // Set the status code
 		return status                   // Return the status code
 	  }                                 // Done closing the write end of the pipe.
 	}                                   // Done checking for write end of pipe not stdout.
@@ -120,7 +80,7 @@ func pipeToBrother(log logger.Log) (int){
 	err=syscall.Exec("/bin/ls",args,os.Environ()) // Execute the ls command
 	if err!=nil{                        // Error executing the ls command?
 	  log.Err("Error in child with pid=%ld executing ls command: %v",os.Getpid(),err) // Yes, log the error
-	  status=ExecError                  // Set the status code
+	  status=pipe.ExecError             // Set the status code
 	  return status                     // Return the status code	
     }                                   // Done checking for ls command execution error.
   default:                              // Parent falls through to create next child
@@ -133,7 +93,7 @@ func pipeToBrother(log logger.Log) (int){
   pid,_,errno=syscall.RawSyscall(syscall.SYS_FORK,0,0,0) // Fork the process
   if errno!=0{                          // Error forking to new process?
 	log.Err("Error forking process: %v",errno) // Yes, log the error
-	status:=ForkError                   // Set the status code
+	status:=pipe.ForkError              // Set the status code
 	return status                       // Return the status code
   }                                     // Done checking for fork error.
   switch pid{                           // Act according to the process ID.
@@ -145,7 +105,7 @@ func pipeToBrother(log logger.Log) (int){
 	// -------------------------------- // 
 	if pfp.CloseWrite()!=nil{           // Error closing the write end of the pipe?
       log.Err("Error closing write end of pipe: %v",err) // Yes, log the error
-	  status:=PipeWriteEndClosed        // Set the status code
+	  status:=pipe.PipeWriteEndClosed   // Set the status code
 	  return status                     // Return the status code
 	}                                   // Done closing the write end of the pipe.
 	// -------------------------------- //
@@ -155,20 +115,20 @@ func pipeToBrother(log logger.Log) (int){
 	rfp,err:=pfp.GetReadEnd()           // Get the read end of the pipe
 	if err!=nil{                        // Error getting the read end of the pipe?
 	  log.Err("Error getting read end of pipe: %v",err) // Yes, log the error
-	  status:=PipeReadEndClosed         // Set the status code
+	  status:=pipe.PipeReadEndClosed    // Set the status code
 	  return status                     // Return the status code
 	}                                   // Done checking for read end of pipe error.
 	if rfp.Fd()!=os.Stdin.Fd(){         // Is the read end of the pipe not stdin?
 	  _,err=pipe.Dup2File(rfp,int(os.Stdin.Fd())) // Yes, duplicate the read end of the pipe on stdin
 	  if err!=nil{                      // Error duplicating the read end of the pipe?
 		log.Err("Error duplicating read end of pipe: %v",err) // Yes, log the error
-		status:=PipeReadEndClosed       // Set the status code
+		status:=pipe.PipeReadEndClosed  // Set the status code
 		return status                   // Return the status code
 	  }                                 // Done checking for read end of pipe duplication error.
 	  log.Err("Read end of pipe already bound to stdin") // Log the error
 	  if pfp.CloseRead()!=nil{          // Error closing the read end of the pipe?
 		log.Err("Error closing read end of pipe: %v",err) // Yes, log the error
-		status:=PipeReadEndClosed       // Set the status code
+		status:=pipe.PipeReadEndClosed  // Set the status code
 		return status                   // Return the status code
 	  }                                 // Done closing the read end of the pipe.       
     }                                   // Done checking for read end of pipe not stdin.
@@ -180,7 +140,7 @@ func pipeToBrother(log logger.Log) (int){
 	err=syscall.Exec("/usr/bin/wc",args,os.Environ()) // Execute the wc command
     if err!=nil{                        // Error executing the wc command?
 	  log.Err("Error in child with pid=%ld executing wc command: %v",os.Getpid(),err) // Yes, log the error
-	  status=ExecError                  // Set the status code
+	  status=pipe.ExecError             // Set the status code
 	  return status                     // Return the status code
 	}                                   // Done checking for wc command execution error.
   default:                              // Parent falls through to wait for children
@@ -191,14 +151,14 @@ func pipeToBrother(log logger.Log) (int){
   // ---------------------------------- //
   if pfp.Close()!=nil{                  // Error closing the read end of the pipe?
 	log.Err("Error closing read end of pipe: %v",err) // Yes, log the error
-	status:=PipeError                   // Set the status code
+	status:=pipe.PipeError              // Set the status code
 	return status                       // Return the status code
   }                                     // Done closing the read end of the pipe.
   log.Inf("Pipe closed successfully")   // Log the pipe closure
   _,err=syscall.Wait4(int(pid),nil,0,nil) // Wait for the child process to finish
   if err!=nil{                          // Error waiting for the child process?
     log.Err("Error waiting for child process: %v",err) // Yes, log the error
-	status:=UnknownError                // Set the status code
+	status:=pipe.UnknownError           // Set the status code
 	return status                       // Return the status code
   }                                     // Done checking for wait error.
   log.Inf("Child process finished successfully") // Log the child process finish
@@ -224,10 +184,10 @@ func main(){
   utils.SetLogger(log)				    // Set the logger object  
   log.Inf("Starting pipeToBrother")     // Log the start of the program
   status:=pipeToBrother(log)        // Call the pipeToBrother function
-  if status!=Success{   // Report the error
-    log.Err("Pipe to child process state returned error: %s",StatusToString(status))
+  if status!=pipe.Success{ // Report the error
+    log.Err("Pipe to child process state returned error: %s",pipe.StatusToString(status))
   } else{                               // The good ending.
-    log.Inf("Pipe to child process state returned: %s",StatusToString(status))
+    log.Inf("Pipe to child process state returned: %s",pipe.StatusToString(status))
   }                                     // Done logging.
   cancel()                              // Send context cancellation signal.
   log.Inf("Program exited.")            // Log goodbye.
@@ -32,54 +32,15 @@ import (
 )
 const BUF_SIZE=10                       // Buffer size for reading from the pipeS
 
-const (
-	Success=iota                        // No errors
-	ForkError						    // Fork error
-	PipeError                           // Pipe error
-	PipeCreated                         // Pipe created successfully
-	PipeReadEndClosed                   // Read end of pipe closed
-	PipeWriteEndClosed                  // Write end of pipe closed
-	PipeReadError                       // Read error
-	PipeWriteError                      // Write error
-	GotEOF                              // EOF encountered
-	UnknownError                        // Unknown error
-)
-
-func StatusToString(status int) string {// Convert status code to string
-	switch status {                     // Check the status code
-	case ForkError:                     // Fork error
-		return "Fork error"             // Return the string
-	case PipeError:                     // Pipe error
-		return "Pipe error"             // Return the string
-	case Success:                       // No errors
-		return "Success"                // Return the string
-	case PipeCreated:                   // Pipe created successfully
-		return "Pipe created successfully"// Return the string
-	case PipeReadEndClosed:             // Read end of pipe closed
-		return "Read end of pipe closed"// Return the string
-	case PipeWriteEndClosed:            // Write end of pipe closed
-		return "Write end of pipe closed"// Return the string
-	case PipeReadError:                 // Read error
-		return "Read error"             // Return the string
-	case PipeWriteError:                // Write error
-		return "Write error"            // Return the string
-  case GotEOF:                          // EOF encountered
-		return "EOF encountered"        // Return the string
-	case UnknownError:                  // Unknown error
-		return "Unknown error"          // Return the string
-	default:                            // Unknown status code
-		return "Unknown status code"    // Return the string
-	}                                   // Done stringing the status code.
-}                                       // ------------ StatusToString --------- //
-func pipeToChildSynch(stdout *os.File,buf []byte, log logger.Log) (int){
+func pipeToChildSynch(stdout *os.File,buf []byte, log logger.Log) (pipe.Status){
  // ----------------------------------- //
  // Create a pipe for synchronization between parent and child processes (1)
  // ----------------------------------- //
- status:=Success                        // Set the status code 
+ status:=pipe.Success                   // Set the status code 
  pfp,err:=pipe.NewPipe()                // Create a new pipe
   if err!=nil{                          // Pipe creation error?
     log.Err("Error creating pipe: %v",err) // Yes, log the error
-	status:=PipeError                   // Set the status code
+	status:=pipe.PipeError              // Set the status code
 	return status                       // Return the status code
   }                                     // Done checking for pipe creation error.
   log.Inf("Pipe created successfully")  // Log the pipe creation
@@ -92,7 +53,7 @@ func pipeToChildSynch(stdout *os.File,buf []byte, log logger.Log) (int){
     pid,_,errno:=syscall.RawSyscall(syscall.SYS_FORK,0,0,0) // Fork the process
 	if errno!=0{                        // Error forking to new process?
       log.Err("Error forking process: %v",errno) // Yes, log the error
-	  status:=ForkError                 // Set the status code
+	  status:=pipe.ForkError            // Set the status code
 	  return status                     // Return the status code
 	}                                   // Done checking for fork error.
 	switch pid{                         // Act according to process ID.
@@ -106,7 +67,7 @@ func pipeToChildSynch(stdout *os.File,buf []byte, log logger.Log) (int){
       sleepTime,err:=strconv.Atoi(os.Args[i])// Get the sleep time from the cmd line arg
 	  if err!=nil{                      // Error converting sleep time to int?
         log.Err("Error converting sleep time to int: %v",err) // Yes, log the error
-		status:=UnknownError            // Set the status code
+		status:=pipe.UnknownError       // Set the status code
 		return status                   // Return the status code
 	  }                                 // Done checking for sleep time conversion error.
 	  log.Inf("Child process %d sleeping for %d seconds",i,sleepTime) // Log the sleep time
@@ -136,13 +97,13 @@ func pipeToChildSynch(stdout *os.File,buf []byte, log logger.Log) (int){
   if err!=nil{                          // Error reading from the pipe?
 	if err==io.EOF||n==0{               // Yes but it was an EOF?
       log.Inf("EOF encountered")        // Yes, log the EOF
-	  status=GotEOF                    // Set the status code
+	  status=pipe.GotEOF               // Set the status code
 	}                                   // Done checking for EOF.
 	log.Err("Error reading from pipe: %v",err) // Yes, log the error
-	status:=PipeReadError               // Set the status code
+	status:=pipe.PipeReadError          // Set the status code
 	return status                       // Return the status code
   }                                     // Done checking for read error.
-  log.Inf("Completed successfully, status: %s",StatusToString(status))
+  log.Inf("Completed successfully, status: %s",pipe.StatusToString(status))
   return status                         // Return the status code
 }                                       // ------------ pipeToChildSynch --------- //
 
@@ -175,10 +136,10 @@ func main(){
   buf:=make([]byte,BUF_SIZE)            // Create a buffer for reading from the pipe
   utils.SetLogger(log)				    // Set the logger object
   status:=pipeToChildSynch(stdout,buf,log)// Call the pipeToChild function
-  if status!=Success&&status!=GotEOF{   // Report the error
-    log.Err("Pipe to child process state returned error: %s",StatusToString(status))
+  if status!=pipe.Success&&status!=pipe.GotEOF{ // Report the error
+    log.Err("Pipe to child process state returned error: %s",pipe.StatusToString(status))
   } else{                               // The good ending.
-    log.Inf("Pipe to child process state returned: %s",StatusToString(status))
+    log.Inf("Pipe to child process state returned: %s",pipe.StatusToString(status))
   }                                     // Done logging.
  cancel()                               // Send context cancellation signal.
  log.Inf("Program exited.")             // Log goodbye.
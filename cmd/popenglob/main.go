@@ -29,47 +29,9 @@ const(
 	PCMD_BUF_SIZ=len(POPEN_FMT)+PAT_SIZ
 )
 
-const (
-	Success=iota                        // No errors
-	ForkError						    // Fork error
-	PipeError                           // Pipe error
-	PipeCreated                         // Pipe created successfully
-	PipeReadEndClosed                   // Read end of pipe closed
-	PipeWriteEndClosed                  // Write end of pipe closed
-	PipeReadError                       // Read error
-	PipeWriteError                      // Write error
-	GotEOF                              // EOF encountered
-	UnknownError                        // Unknown error
-)
-func StatusToString(status int) string {// Convert status code to string
-	switch status {                     // Check the status code
-	case ForkError:                     // Fork error
-		return "Fork error"             // Return the string
-	case PipeError:                     // Pipe error
-		return "Pipe error"             // Return the string
-	case Success:                       // No errors
-		return "Success"                // Return the string
-	case PipeCreated:                   // Pipe created successfully
-		return "Pipe created successfully"// Return the string
-	case PipeReadEndClosed:             // Read end of pipe closed
-		return "Read end of pipe closed"// Return the string
-	case PipeWriteEndClosed:            // Write end of pipe closed
-		return "Write end of pipe closed"// Return the string
-	case PipeReadError:                 // Read error
-		return "Read error"             // Return the string
-	case PipeWriteError:                // Write error
-		return "Write error"            // Return the string
-  case GotEOF:                          // EOF encountered
-		return "EOF encountered"        // Return the string
-	case UnknownError:                  // Unknown error
-		return "Unknown error"          // Return the string
-	default:                            // Unknown status code
-		return "Unknown status code"    // Return the string
-	}                                   // Done stringing the status code.
-}                                       // ------------ StatusToString --------- //
-func pipeFromShell(pat []byte,log logger.Log) (int,error){
+func pipeFromShell(pat []byte,log logger.Log) (pipe.Status,error){
   // Read pattern and display result from globbing.
- status:=Success                        // Status flag.
+ status:=pipe.Success                   // Status flag.
  badpat:=false                          // Bad pattern flag.
  for{                                  // Loop until EOF.
 	writer := bufio.NewWriter(os.Stdout)
@@ -79,20 +41,20 @@ func pipeFromShell(pat []byte,log logger.Log) (int,error){
 	if err!=nil{                        // Check for errors.
 	  if err==io.EOF{                   // EOF encountered?
 		log.Inf("EOF encountered")      // Yes, print message.
-		status=GotEOF                   // Yes, set status to EOF.
+		status=pipe.GotEOF              // Yes, set status to EOF.
 		break                           // Yes exit loop.
 	  }                                 // Done checking for EOF.
 	  if len(line)==0{                  // Empty line?
         continue                        // Yes, continue to next iteration.
 	  }                                 // Done checking for empty line.
 	  log.Err("Error reading input: %v",err) // Print error message.
-      status=UnknownError               // Set status to unknown error.
+      status=pipe.UnknownError          // Set status to unknown error.
 	  return status,err                 // Return status and error.
 	}                                   // Done checking for read err.
 	line=line[:len(line)-1]             // Remove newline from line.
 	if len(line)>PAT_SIZ{
 	  log.Err("Pattern too long: %s",line) // Print error message.
-	  status=UnknownError               // Set status to unknown error.
+	  status=pipe.UnknownError          // Set status to unknown error.
 	  return status,fmt.Errorf("pattern too long")
 	}
 	copy(pat,line)                      // Copy line to pattern buffer.
@@ -131,20 +93,20 @@ func pipeFromShell(pat []byte,log logger.Log) (int,error){
 	fd,pid,err:=pipe.Popen(cmd,pipe.POPENREAD) // Execute command.
 	if err!=nil{                         // Check for errors.
 	  log.Err("Error executing command: %v",err) // Print error message.
-	  status=PipeError                  // Set status to pipe error.
+	  status=pipe.PipeError             // Set status to pipe error.
 	  return status,err                 // Return status and error.
 	}                                   // Done checking for errors.
 	f:=os.NewFile(uintptr(fd),"popen-r")
 	if f==nil{                          // Check for errors.
 	  log.Err("Error creating file from fd: %v",err) // Print error message.
-	  status=PipeError                  // Set status to pipe error.
+	  status=pipe.PipeError             // Set status to pipe error.
 	  return status,fmt.Errorf("error creating file from fd")
 	}                                   // Done checking for errors.
 	proc,err:=os.FindProcess(pid)       // Find process by pid.
 	if err!=nil{                        // Error finding proc?
       log.Err("Error finding process with pid %d: %v",pid,err) // Print error message.
 	 f.Close()                          // Close file. 
-	  status=UnknownError               // Set status to unknown error.
+	  status=pipe.UnknownError          // Set status to unknown error.
 	  return status,fmt.Errorf("error finding process with pid %d",pid)
 	}                                   // Done checking for errors.
 	// -------------------------------- //
@@ -162,7 +124,7 @@ func pipeFromShell(pat []byte,log logger.Log) (int,error){
 	  if err!=nil{                      // Check for errors.
 		if err==io.EOF{                 // EOF encountered?
 		  log.Inf("EOF encountered")    // Yes, print message.
-		  status=GotEOF                 // Yes, set status to EOF.
+		  status=pipe.GotEOF            // Yes, set status to EOF.
 		  return "",io.EOF              // Yes, return empty string and nil.
 		}                               // Done checking for EOF.
 	  return "",err                     // Return empty string and error.
@@ -179,20 +141,20 @@ func pipeFromShell(pat []byte,log logger.Log) (int,error){
 	if err!=nil{                        // Error reading from pipe?
       if err==io.EOF{                   // Was it EOF?
         log.Inf("EOF encountered")      // Yes, print message.
-		status=GotEOF                   // Yes, set status to EOF.
+		status=pipe.GotEOF              // Yes, set status to EOF.
 		break                           // Yes, exit loop.
 	  }                                 // Done checking for EOF.
 	  log.Err("Result: %s",line)        // Print error message.
-	  status=PipeReadError              // Set status to pipe read error.
+	  status=pipe.PipeReadError         // Set status to pipe read error.
 	  return status,err                 // Return status and error.
 	}                                   // Done checking for read err.
 	// -------------------------------- //
 	// Close pipe, fetch and display results.
 	// -------------------------------- //
-	  status,_=pipe.PClose(f,proc)    // Close pipe and wait for child process to exit.
-	  if status!=0{                       // Check for errors.
-	    log.Err("Error closing pipe: %v",status) // Print error message.
-	    status=PipeReadEndClosed 
+	  code,_:=pipe.PClose(f,proc)     // Close pipe and wait for child process to exit.
+	  if code!=0{                         // Check for errors.
+	    log.Err("Error closing pipe: %v",code) // Print error message.
+	    status=pipe.PipeReadEndClosed 
 	    return status,fmt.Errorf("error closing pipe")
 	  }                                   // Done checking for errors.
 	  if len(line)>0{                   // Is line empty?
@@ -224,10 +186,10 @@ func main() {
   utils.SetLogger(log)				    // Set the logger object  
   buf:=make([]byte,PAT_SIZ)             // Create a buffer for reading from the pipe
   status,_:=pipeFromShell(buf,log)      // Call the pipeFromShell function
-  if status!=Success&&status!=GotEOF{   // Report the error
-    log.Err("Pipe to child process state returned error: %s",StatusToString(status))
+  if status!=pipe.Success&&status!=pipe.GotEOF{ // Report the error
+    log.Err("Pipe to child process state returned error: %s",pipe.StatusToString(status))
   } else{                               // The good ending.
-    log.Inf("Pipe to child process state returned: %s",StatusToString(status))
+    log.Inf("Pipe to child process state returned: %s",pipe.StatusToString(status))
   }                                     // Done logging.
  cancel()                               // Send context cancellation signal.
  log.Inf("Program exited.")             // Log goodbye.
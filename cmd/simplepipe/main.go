@@ -31,55 +31,16 @@ import (
 )
 const BUF_SIZE=10                       // Buffer size for reading from the pipeS
 
-const (
-	Success=iota                        // No errors
-	ForkError						    // Fork error
-	PipeError                           // Pipe error
-	PipeCreated                         // Pipe created successfully
-	PipeReadEndClosed                   // Read end of pipe closed
-	PipeWriteEndClosed                  // Write end of pipe closed
-	PipeReadError                       // Read error
-	PipeWriteError                      // Write error
-	GotEOF                              // EOF encountered
-	UnknownError                        // Unknown error
-)
-
-func StatusToString(status int) string {// Convert status code to string
-	switch status {                     // Check the status code
-	case ForkError:                     // Fork error
-		return "Fork error"             // Return the string
-	case PipeError:                     // Pipe error
-		return "Pipe error"             // Return the string
-	case Success:                       // No errors
-		return "Success"                // Return the string
-	case PipeCreated:                   // Pipe created successfully
-		return "Pipe created successfully"// Return the string
-	case PipeReadEndClosed:             // Read end of pipe closed
-		return "Read end of pipe closed"// Return the string
-	case PipeWriteEndClosed:            // Write end of pipe closed
-		return "Write end of pipe closed"// Return the string
-	case PipeReadError:                 // Read error
-		return "Read error"             // Return the string
-	case PipeWriteError:                // Write error
-		return "Write error"            // Return the string
-  case GotEOF:                          // EOF encountered
-		return "EOF encountered"        // Return the string
-	case UnknownError:                  // Unknown error
-		return "Unknown error"          // Return the string
-	default:                            // Unknown status code
-		return "Unknown status code"    // Return the string
-	}                                   // Done stringing the status code.
-}                                       // ------------ StatusToString --------- //
 
-func pipeToChild(buf []byte, log logger.Log) (int){
-  status:=Success                       // Initialize status to Success
+func pipeToChild(buf []byte, log logger.Log) (pipe.Status){
+  status:=pipe.Success                  // Initialize status to Success
   // ---------------------------------- //
   // Attempt to create a new pipe (1).
   // ---------------------------------- // 
   p,err:=pipe.NewPipe()		            // Call the pipe wrapper to create a pipe
   if err!=nil{                          // Did we error initializing the pipe?
     log.Err("Error creating pipe: %v",err) // Yes, return nil object and error.
-	status=PipeError                    // Set status to PipeError
+	status=pipe.PipeError               // Set status to PipeError
 	return status                       // Yes, signal error.
   }                                     // Done with error creating pipe.
   log.Inf("Pipe created successfully.") // Pipe created successfully
@@ -90,7 +51,7 @@ func pipeToChild(buf []byte, log logger.Log) (int){
   pid,_,errno:=syscall.RawSyscall(syscall.SYS_FORK,0,0,0) // Fork the process
   if errno!=0{                          // Did we error forking the process?
 	log.Err("Error forking process: %v",errno) // Yes, return nil object and error.
-	status=ForkError                    // Set status to ForkError
+	status=pipe.ForkError               // Set status to ForkError
 	return status                       // Yes, signal error.                           
   }                                     // Done with error forking process.
   switch pid{                           // Act according to the pid.
@@ -102,7 +63,7 @@ func pipeToChild(buf []byte, log logger.Log) (int){
 	re,err:=p.GetReadEnd()              // Get the write end of the pipe
 	if err!=nil{                        // Did we error getting the write end of the pipe?
 		log.Err("Error getting write end of pipe: %v",err)
-		status=PipeReadEndClosed        // Set status to PipeReadEndClosed
+		status=pipe.PipeReadEndClosed   // Set status to PipeReadEndClosed
 		return status                   // Yes, signal error.
 	}                                   // Done checking for error getting write end of pipe.
 	p.CloseWrite()                      // Close the write end of the pipe
@@ -114,11 +75,11 @@ func pipeToChild(buf []byte, log logger.Log) (int){
 		if err!=nil{                    // Did we error reading from the pipe?
 		  if err==io.EOF||numRead==0{   // Yes, did we get EOF? (5)
             log.Inf("EOF encountered.") // Yes, log EOF
-			status=GotEOF               // Set status to GotEOF
+			status=pipe.GotEOF          // Set status to GotEOF
 			break                       // Break out of the loop
 		  }                             // Done checking for EOF.
 		  log.Err("Error reading from pipe: %v",err) // Yes, return nil object and error.
-		  status=PipeReadError     	    // Set status to PipeReadError
+		  status=pipe.PipeReadError     	 // Set status to PipeReadError
 		  return status                 // Yes, signal error.
 	  }                                 // Done checking for error reading from pipe.
 	  // ------------------------------ //
@@ -127,22 +88,22 @@ func pipeToChild(buf []byte, log logger.Log) (int){
 	  n,err:=os.Stdout.Write(buf[:numRead]) // Write to stdout
 	  if err!=nil{                      // Did we error writing to stdout?
 	    log.Err("Error writing to stdout: %v",err) // Yes, return nil object and error.
-		  status=PipeWriteEndClosed     // Set status to PipeWriteEndClosed
+		  status=pipe.PipeWriteEndClosed // Set status to PipeWriteEndClosed
 		  return status                 // Yes, signal error.
 	  }                                 // Done checking for error writing to stdout.
 	  if n!=numRead{                    // Did we write all the bytes?
 	    log.Err("We read %d bytes but wrote %d bytes",numRead,n) // Yes, return log it.
-		status=PipeWriteError           // ..and set status to PipeWriteError
+		status=pipe.PipeWriteError      // ..and set status to PipeWriteError
     return status                       // Return status.
 	  }                                 // Done checking for bytes written.
 	  _,_=os.Stdout.Write([]byte("\n")) // Write a newline to stdout (7)
       log.Inf("Wrote %d bytes to stdout",n) // Log the number of bytes written
       if p.Close()!=nil{                // Did we error closing the pipe?
 	    log.Err("Error closing pipe: %v",err) // Yes, return nil object and error.
-		status=PipeError                // Set status to PipeError
+		status=pipe.PipeError           // Set status to PipeError
 		return status                   // Yes, signal error.
 	  }                                 // Done checking for error closing pipe.
-	  if status==Success||status==GotEOF{// No errors?
+	  if status==pipe.Success||status==pipe.GotEOF{// No errors?
 		  break                         // Break out of the loop
 	  }                                 // Done checking for errors.
   }                                     // Done reading from the pipe.
@@ -151,25 +112,25 @@ func pipeToChild(buf []byte, log logger.Log) (int){
 	// -------------------------------- //
 	// We are the parent so we will be writing to the pipe. (8)
 	// -------------------------------- //
-      we,err:=p.GetWriteEnd()           // Get the write end of the pipe
+      _,err=p.GetWriteEnd()             // Check the write end of the pipe
 	     if err!=nil{                   // Did we error getting the write end of the pipe?
 	        log.Err("Error getting write end of pipe: %v",err) // Yes, log it.
-	        status=PipeReadError        // Report status
+	        status=pipe.PipeReadError   // Report status
           return status                 // and, signal error.
 	     }                              // Done checking for error getting read end of pipe.
        p.CloseRead()                    // Close the read end of the pipe
 	  // ------------------------------ //
 	  // Now we write data to the pipe (9).
 	  // ------------------------------ //
-	    n,err:=we.Write([]byte(os.Args[1])) // Write to the pipe
+	    n,err:=p.WriteString(os.Args[1]) // Write all of it to the pipe
 	    if err!=nil{                    // Did we error writing to the pipe?
 	      log.Err("Error writing to pipe: %v",err) // Yes, return log it.
-	      status=PipeWriteEndClosed     // Set status to PipeWriteEndClosed
+	      status=pipe.PipeWriteEndClosed // Set status to PipeWriteEndClosed
 	      return status                 // and, signal error.
 	    }                               // Done checking for error writing to pipe.
 	    if n!=len(os.Args[1]){          // Did we write all the bytes?
 	      log.Err("We read %d bytes but wrote %d bytes",len(os.Args[1]),n) // Yes, return log it..
-	      status=PipeWriteError         // Set status to PipeWriteError
+	      status=pipe.PipeWriteError    // Set status to PipeWriteError
 	      return status                 // Yes, signal error.
 	    }                               // Done checking for bytes written.
 	// -------------------------------- //
@@ -177,7 +138,7 @@ func pipeToChild(buf []byte, log logger.Log) (int){
 	// -------------------------------- //
 	    if p.CloseWrite()!=nil{         // Did we error closing the write end of the pipe?
 	      log.Err("Error closing write end of pipe: %v",err) // Yes, log it.
-	      status=PipeWriteEndClosed     // Set status to PipeWriteEndClosed
+	      status=pipe.PipeWriteEndClosed // Set status to PipeWriteEndClosed
 	      return status                 // Yes, signal error.
       }                                 // Done closing write fd
     // -------------------------------- //
@@ -186,11 +147,11 @@ func pipeToChild(buf []byte, log logger.Log) (int){
 	  _,err=syscall.Wait4(int(pid),nil,0,nil) // Wait for the child to terminate
 	  if err!=nil{                      // Did we error waiting for the child to terminate?
 	    log.Err("Error waiting for child: %v",err) // Yes, return nil object and error.
-	    status=UnknownError             // Set status to UnknownError
+	    status=pipe.UnknownError        // Set status to UnknownError
 	    return status                   // Yes, signal error.
 	  }								    // Done checking for error waiting for child to terminate.
     log.Inf("Child terminated.")        // Child terminated successfully
-	  if status==Success{               // No errors?
+	  if status==pipe.Success{          // No errors?
 	    log.Inf("PipeToChild completed successfully.") // it's a success.
 	    break                           // Break out of the loop
 	  }                                 // Done checking for child process.
@@ -221,10 +182,10 @@ func main() {
   buf:=make([]byte,BUF_SIZE)            // Create a buffer for reading from the pipe
   utils.SetLogger(log)				    // Set the logger object
   status:=pipeToChild(buf,log)          // Call the pipeToChild function
-  if status!=Success{                   // Report the error
-    log.Err("Pipe to child process state returned: %s",StatusToString(status))
+  if status!=pipe.Success{              // Report the error
+    log.Err("Pipe to child process state returned: %s",pipe.StatusToString(status))
   } else{                               // The good ending.
-    log.Inf("Pipe to child process state returned: %s",StatusToString(status))
+    log.Inf("Pipe to child process state returned: %s",pipe.StatusToString(status))
   }                                     // Done logging.
   cancel()                              // Send context cancellation signal.
   log.Inf("Program exited.")            // Log goodbye.
@@ -14,9 +14,11 @@
 // ***************************************************************************
 package configuration
 import (
+		"context"
+		"image/color"
 		"io"
+		"os"
 		"time"
-		"golang.org/x/sys/unix"
 	  "github.com/ljt/ProxyServer/internal/logger"
 
 )
@@ -65,8 +67,8 @@ type ParameterAPI interface{
 	GetValueByteByIndex(i uint,dest *byte) error
 
 	// Times and durations
-	GetValueTimespec(value string, dest *unix.Timespec) error
-	GetValueTimespecByIndex(i uint,dest *unix.Timespec) error
+	GetValueTimespec(value string, dest *Timespec) error
+	GetValueTimespecByIndex(i uint,dest *Timespec) error
 	GetValueDuration(value string, dest *time.Duration) error
 	GetValueDurationByIndex(i uint,dest *time.Duration) error
 
@@ -137,6 +139,8 @@ type ParameterAPI interface{
 	SetNext(p *Parameter)
 	Append(p *Parameter)
   GetQuote(i uint) (byte,error)
+	RequireArity(min, max int) error      // Validate the number of values.
+	CommentText() string                  // Comments above it, as text.
 	Print(w io.Writer) (int64,error)
 }
 type Parameter struct{
@@ -144,10 +148,15 @@ type Parameter struct{
 	n           uint                      // The number of values.
 	values      []string                  // The values of the parameter.
 	quotes      []byte                    // Quote character for each value, 0 if none.
-	value       string										// The value of the parameter.
 	comments    *Comment                  // The comments associated with this parameter.
 	next        *Parameter                // Where to save next parameter on the list.
 	isimported   bool                     // True if was imported from another file.
+	file        string                    // The file it was read from, if any.
+	line        int                       // The line it was read from, if any.
+	flag        bool                      // True if written bare, without '='.
+	ref         string                    // The file an @path value came from.
+	rawJSON     string                    // The value as read, if it looks like JSON.
+	readonly    bool                      // True if it belongs to a ReadOnly() view.
 }
 
 // ========================= // Section // =====================================
@@ -171,6 +180,7 @@ type SectionAPI interface{
 	Append2(p *Parameter) *Parameter
 	// Add a parameter to this section.
 	AppendParameter(name string, valuestr string, comments *Comment,imported bool) *Parameter
+	AppendLiteralParameter(name, value string, quote byte) (*Parameter, error)
 	// Add a section to this section.
 	AppendSection(name string, imported bool)
 
@@ -179,7 +189,12 @@ type SectionAPI interface{
 	FindFirstParameter() *Parameter       // Get pointer to first parameter
 	// Get a pointer to a Parameter.
 	FindParameter(name string, searchParents bool) *Parameter
+	RenameParameter(old, new string) error // Rename a parameter in place.
+	Equal(other *Section) bool             // Same parameters and values?
 	FindNextParameter() *Parameter        // Get pointer to next parameter
+	EffectiveParameters() []*Parameter    // Own and inherited parameters.
+	CommentText() string                  // Comments above the header, as text.
+	ParameterCommentText(name string) string // Comments above a parameter.
 	FindSection(name string) *Section      // Get pointer to a Section.
 	GetFirstSection() *Section            // Get a pointer to a first section.
 	GetNext() *Section                    // Get pointer to next section
@@ -188,6 +203,7 @@ type SectionAPI interface{
 	GetLast() *Parameter                  // Get pointer to last parameter.
 	GetSelectedParameterName() string     // Get the name of the selected parameter.
   GetSelectedParameter() *Parameter     // Get the selected parameter.
+  PeekSelectedParameter() *Parameter    // Same, never advances the selection.
 	GetNParents() uint                    // Get the number of parents.
 	GetParent(n uint) *Section            // Get the nth parent.
 	GetParentName(n uint) string          // Get the name of the nth parent.
@@ -210,7 +226,9 @@ type SectionAPI interface{
 	GetValueArray(name string) []string
 	// C-style strings
 	GetValue(name string, i uint) string     // Get parameter value for a section name.
+	Lookup(name string) (value string, found bool) // Value, and whether it exists.
 	GetValues(name string) string           // Get values for a parameter name.
+	GetValueQuote(name string, i uint) (byte, error) // How value i was quoted.
   // Boolean
 	GetValueBool(name string,i uint,tval string, fval string) (bool,error)// Get a boolean value for a parameter name.
 	// Byte values (character values)
@@ -218,8 +236,8 @@ type SectionAPI interface{
 	GetValueByteByIndex(name string,i uint,dest *byte) error
 
 	// Times and durations
-	GetValueTimespec(name string, dest *unix.Timespec) error
-	GetValueTimespecByIndex(name string,i uint,dest *unix.Timespec) error
+	GetValueTimespec(name string, dest *Timespec) error
+	GetValueTimespecByIndex(name string,i uint,dest *Timespec) error
 	GetValueDuration(name string, dest *time.Duration) error
 	GetValueDurationByIndex(name string,i uint,dest *time.Duration) error
 
@@ -267,7 +285,7 @@ type SectionAPI interface{
 	// Floating point values with precision
 	GetValuePrecisionFloat32(name,precision string,dest *float32) error
 	GetValuePrecisionFloat32ByIndex(name string,i uint,precision string,dest *float32) error
-	GetValuePrecisionFloat64(name string,value,precision string,dest *float64) error
+	GetValuePrecisionFloat64(name,precision string,dest *float64) error
 	GetValuePrecisionFloat64ByIndex(name string,i uint,precision string,dest *float64) error
 
 	// Complex numbers
@@ -282,9 +300,12 @@ type SectionAPI interface{
 	scanValueByIndex(name string,i int, format string, dest any) error
   ClearParameters() error                // Clear all parameters in this section.
 	SetParentNames(name string)            // First pass.
+	ParentNames() []string                 // Copy of the declared parent names.
 	SetParentSection(i uint, p *Section)   // Second pass.
 	MakeShallowCopyOf(src *Section)        // Shallow copy of a section.
+	Clone(newName string) *Section         // Deep copy under a new name.
 	Print(w io.Writer) (int64,error) 	
+	String() string                        // Print() to a string.
 }
 type Section struct{
   name        string                      // The name of the section.
@@ -299,6 +320,7 @@ type Section struct{
   current     *Parameter                 // The current parameter.
 	comments    *Comment                   // The comments associated with this section.
 	cfg         *Configuration             // The configuration object that owns this section.
+	owner       *Configuration             // The configuration whose list holds it, if any.
 	// ----------------------------------- //
 	// If copy==true, then this section is a copy of another Section object, only
 	// the name was allocated. This is used for Section references. All other
@@ -318,23 +340,67 @@ type ConfigurationAPI interface{
 	Reconfigure() error                    // Prepare to re-read the data file.
 	SetDirectory(dir string)               // Set the directory for the configuration file.
 	SetFilename(name string)               // Set the filename for the configuration file.
+	SetPathname(full string)               // Set directory and filename at once.
 	SetDefaultExtension(ext string)        // Set the default extension.
 	GetPathname() string                   // Get the pathname of the configuration file.
 	GetImportedPathname() string           // Get the imported pathname of the configuration file.
 	GetDirectory() string                  // Get the directory of the configuration file.
 	GetFilename() string                   // Get the filename of the configuration file.
 	SaveComments(flag bool)                // Enable or disable saving comments.	
+	AllowCommentLoss(allow bool)           // Let WriteFile drop unsaved comments.
 	IgnoreImports(flag bool)              // Enable skipping import for file editing.
+	SetCommentPrefixes(prefixes ...string) // Set what starts a line comment.
+	SetSectionDelimiters(open, close string) // What surrounds section names.
+	GetSectionDelimiters() (string, string) // Get what surrounds them.
+	AddHeaderComment(text string)          // Comment lines before any section.
+	AddFooterComment(text string)          // Comment lines after the last section.
+	SetMaxLineLength(n int)                // Longest line, continuations included.
+	GetMaxLineLength() int                 // Get the longest line we accept.
+	SetFloatSpecials(allow bool)           // Let float getters accept nan/inf.
+	GetFloatSpecials() bool                // True if they accept nan/inf.
+	SetIntBase(base int)                   // 10, or 0 to honour 0x/0o/0b/0.
+	GetIntBase() int                       // Base integer getters read in.
+	SetFloatFormat(fmtByte byte, prec int) // How the float setters write.
+	GetFloatFormat() (byte, int)           // Get how they write.
+	SetFileValueRefs(allow bool)           // Read name=@path values from files.
+	GetFileValueRefs() bool                // True if @path values are read.
+	SetEnvironSeparator(sep string)        // Joins values in Environ().
+	GetEnvironSeparator() string           // Get what joins them.
+	SetNameValidator(validate func(name string) error) // Vet parameter names.
+	OnChange(cb func(section, name, oldValue, newValue string)) // Told of value changes.
+	SetDuplicateSectionPolicy(policy DuplicatePolicy) // Repeated [section] headers.
+	SetTrailingCommaPolicy(policy TrailingCommaPolicy) // Values like a,b, .
+	SetValidateUTF8(validate bool)         // Fail reads with invalid UTF-8.
+	SanitizeUTF8() error                   // Replace invalid UTF-8 with U+FFFD.
+	SetWriteOrder(order WriteOrder)        // Order Print() and WriteFile() use.
+	ApplyDefaults(defaults *Configuration) // Fill only the missing parameters.
+	Snapshot() Snapshot                    // Deep copy for transactional edits.
+	Restore(snap Snapshot)                 // Revert to a Snapshot.
+	Inline() *Configuration                // Flat copy with nothing to resolve.
+	Verify() error                         // Check WriteFile output reads back Equal.
+	Environ(prefix string) []string        // KEY=value entries for a child.
+	Dump() []string                        // Sorted section.name=value lines.
+	Dependencies() []string                // Files read or imported by ReadFile.
+	Warnings() []Warning                   // Lines ReadFile kept as comments.
+	WithOverlay(                          // View that layers env/flags over file.
+	  lookup func(section, name string) (string, bool)) *Configuration
+	ReadOnly() *Configuration              // View that refuses Set* calls.
+	IsReadOnly() bool                      // True on a ReadOnly() view.
 	NewFile(filename string)               // Create a new file.
+	MarkWritable()                         // Let WriteFile write it.
 	ReadFile(                             // Read the file from disk.
 	  filename,section string,             // The name of the file to read.
 		importing bool) error                // True if importing.
+	ReadContext(ctx context.Context, r io.Reader, name string) error // Cancellable read.
+	ReadDir(dir string, pattern string) error // Merge a conf.d directory.
 	WriteFile(filename string) error        // Write the file to disk.
 	AppendSection(                        // Append a section to the file.
 	  section string,                      // Name of new section.
 		comments *Comment,                  // Comments to add.
 		imported bool) *Section             // True if imported.
 	FindSection(name string) *Section      // Find a section by name.
+	RenameSection(old, new string) error   // Rename a section in place.
+	AddSection(s *Section) error           // Append a Clone()d section.
 	FindFirstParameter() *Parameter       // Find first parameter in current section.
 	GetFirstSection() *Section            // Get first section in the list.
 	GetLastSection() *Section             // Get last section in the list.
@@ -350,6 +416,7 @@ type ConfigurationAPI interface{
 	detectSectionHeader(line string)(name,parents,fromfile string,err error)
 
   GetNextParameter() *Parameter         // Get next parameter in the list.
+  PeekParameter() *Parameter            // Selected parameter, without advancing.
 	GetParameter(name string, searchParents bool) *Parameter
 	GetSectionName() string                // Get the name of the current section.
 	GetNextParameterValues(vals [][]string,q []string) (name []string, nValues int, values [][]string,quotes []string,err error) // Get next parameter in the list.
@@ -357,6 +424,7 @@ type ConfigurationAPI interface{
   
 	SetValue(name,valuestr string,quote byte) error // Set a value of a section.
 	SetValueBySection(section,name string, i uint, value string) error
+	SetValues(section string, kv map[string]string) error // Set many at once.
 	SetValueInFormat(name string,val any,format string) error // Set a value in a given format.
   SetArrayValue(name,valuestr string,i uint,quote byte) error
 	SetArrayValueBySection(section,name string, i uint, value string) error
@@ -364,6 +432,13 @@ type ConfigurationAPI interface{
 	SetArrayValueInFormat(name string,idx uint,val any,format string) error
 	
 	GetValue(name string) string       // Get a string parameter from the selected section.
+	Lookup(name string) (value string, found bool) // Value, and whether it exists.
+	HasFlag(name string) bool              // Is name there without a value?
+	GetValueAny(names ...string) (value, matched string, found bool) // First name with a value.
+	GetValueSubConfig(name, pairSep, kvSep string) (*Configuration, error) // Parse a value as a config.
+	GetValueSet(name string) (map[string]struct{}, error) // The values, duplicates dropped.
+	ValueContains(name, item string) bool  // Is item one of the values?
+	Equal(other *Configuration) bool       // Same sections, parameters and values?
 	GetValues(name string) string      // Get source string for parameter.
 	GetValueByIndex(name string, i uint) string // Get a value by index for a parameter.
 	
@@ -372,15 +447,29 @@ type ConfigurationAPI interface{
 	SetValueByte(name string, value byte) error
 	GetValueByteByIndex(name string,i uint,dest *byte) error
 	SetValueByteByIndex(name string, i uint, value byte) error
+	GetValueByteSlice(name string, dest *[]byte) error
 
 	// Times and durations
-	GetValueTimespec(name string, dest *unix.Timespec) error
-	GetValueTimespecByIndex(name string,i uint,dest *unix.Timespec) error
+	GetValueTimespec(name string, dest *Timespec) error
+	GetValueTimespecByIndex(name string,i uint,dest *Timespec) error
 	GetValueDuration(name string, dest *time.Duration) error
 	GetValueDurationByIndex(name string,i uint,dest *time.Duration) error
 	// Time since epoch
 	GetValueTime(name string, dest *time.Time) error
 	GetValueTimeByIndex(name string, i uint,dest *time.Time) error
+	GetValueTimeLayout(name, layout string, dest *time.Time) error
+	GetValueTimeLayoutIn(name, layout string, loc *time.Location, dest *time.Time) error
+
+	// Colors
+	GetValueColor(name string, dest *color.RGBA) error
+	SetValueColor(name string, value color.RGBA) error
+
+	// JSON documents
+	GetValueJSON(name string, dest any) error
+
+	// File modes
+	GetValueFileMode(name string, dest *os.FileMode) error
+	SetValueFileMode(name string, mode os.FileMode) error
 
 	// Signed Integers
 	GetValueInt(name string, dest *int) error
@@ -453,7 +542,7 @@ type ConfigurationAPI interface{
 	SetValuePrecisionFloat32(name,precision string,value float32) error
 	GetValuePrecisionFloat32ByIndex(name string,i uint,precision string,dest *float32) error
 	SetValuePrecisionFloat32ByIndex(name string, i uint, precision string, value float32) error
-	GetValuePrecisionFloat64(name string,value,precision string,dest *float64) error
+	GetValuePrecisionFloat64(name,precision string,dest *float64) error
 	SetValuePrecisionFloat64(name string,precision string,value float64) error
 	GetValuePrecisionFloat64ByIndex(name string,i uint,precision string,dest *float64) error
 	SetValuePrecisionFloat64ByIndex(name string, i uint, precision string, value float64) error
@@ -474,14 +563,21 @@ type ConfigurationAPI interface{
 	
 	GetValueBySection(section string, parameter string) string // Get value of named section.
 	GetValueBySectionAndIndex(section, name string, i uint) string
+	IsImported(section, name string) (bool, error) // Did it come from an import?
 	GetValueBool(parameter string, value *bool) error // Get a boolean parameter from the selected section.
 	ScanValue(i int, fmt string, dest any) error
   GetNParameters(section string) uint   // Get number of parameters in a section.
 	GetNValues(name string) uint          // Get number of values for a parameter of this section.
-	GetSelectedSectionName() string       // Get the name of the selected section.
+	RequireArity(section, name string, min, max int) error // Validate # of values.
+	GetSelectedSectionName() string// Get the name of the selected section.
 	GetSelectedSectionParentName() string // Get the name of the selected section's parent.
 	GetFirstSectionName() string          // Get the name of the first section.
+	GetSectionNames() []string            // All section names, in file order.
   Print(w io.Writer) (int64,error)
+  WriteTo(w io.Writer) (int64,error)     // Stream the configuration (io.WriterTo).
+	String() string                        // Print() to a string, secrets redacted.
+	MarkSecret(section, name string)       // Redact a value in Print() and String().
+	PrintSecrets(show bool)                // Let Print() show secrets after all.
  // private methods.
  initialize()                           // Initialize the cfg object (noop for now).
  deleteAll()                            // Delete all data structures.
@@ -498,8 +594,103 @@ type Configuration struct{
 	first,last *Section                   // First and last sections in the list of sections.
 	current    *Section                   // The current section.
 	firstComment,lastComment   *Comment   // Place to put comments at end of the file.
-	saveComments bool                     // True if saving comments.
+	footer       *Comment                 // Comments written after the sections.
+	dropComments bool                     // True after SaveComments(false).
+	lostComments bool                     // True if reading dropped any comments.
+	allowLoss    bool                     // True after AllowCommentLoss(true).
 	ignoreImports bool                    // True if ignoring import statements.
 	canWrite     bool                     // Set to false if did not read whole file.
+	overlay      func(section, name string) (string, bool) // Env/flag overlay, nil if none.
+	base         *Configuration           // What a WithOverlay() view follows, nil if none.
+	deps         []string                 // Files read or imported by the last ReadFile.
+	warnings     []Warning                // Lines the last ReadFile did not understand.
+	commentPrefixes []string              // Line comment prefixes, {"#"} if empty.
+	sectOpen     string                   // Opens a section header, "[" if empty.
+	sectClose    string                   // Closes it, "]" if empty.
+	maxLineLen   int                      // Longest line we read, 0 for 32768.
+	depth        int                      // How deep we are in nested ReadFile calls.
+	readonly     bool                     // True on a ReadOnly() view.
+	floatSpecials bool                    // True if float getters accept nan/inf.
+	intPrefixes  bool                     // True if integer getters take 0x/0o/0b/0.
+	floatFmt     byte                     // strconv format for float setters, 0 for 'f'.
+	floatPrec    int                      // And its precision.
+	fileRefs     bool                     // True if @path values are read from files.
+	secrets      map[string]bool          // "section\x00name" of values to redact.
+	showSecrets  bool                     // True if Print() shows secrets anyway.
+	envSep       *string                  // Joins values in Environ(), nil for ",".
+	nameValidator func(name string) error // Vets parameter names, nil for any.
+	onChange     func(section, name, oldValue, newValue string) // Told of changes, nil for none.
+	dupSections  DuplicatePolicy          // What to do with repeated sections.
+	writeOrder   WriteOrder               // Order we write sections and parameters in.
+	trailingComma TrailingCommaPolicy     // What a value list ending in ',' means.
+	validateUTF8 bool                     // True if reads reject invalid UTF-8.
 	log          logger.Log               // The logger object.             
 }
+
+// DuplicatePolicy says what ReadFile does with a repeated section header.
+type DuplicatePolicy int
+const(
+  DuplicateSeparate  DuplicatePolicy=iota // Keep it as a separate Section.
+	DuplicateMergeInto                      // Merge it into the first one.
+	DuplicateError                          // Fail the read.
+)
+
+// TrailingCommaPolicy says what becomes of the empty value after the comma
+// that ends a value list like a,b, when it is read or set.
+type TrailingCommaPolicy int
+const(
+  DropEmpty TrailingCommaPolicy=iota      // Discard it, so a,b, has two values.
+	KeepEmpty                               // Keep it as a third, empty value.
+	TrailingCommaError                      // Fail the read or the set.
+)
+
+// WriteOrder says in what order Print and WriteFile write things out.
+type WriteOrder int
+const(
+  AsRead WriteOrder=iota                  // The order they were read or added in.
+	Sorted                                  // Alphabetical sections and parameters.
+)
+
+// Snapshot is an opaque deep copy of a Configuration's sections, parameters
+// and comments, taken by Configuration.Snapshot() and put back by Restore().
+type Snapshot struct{
+  first        *Section                 // Copy of the list of sections.
+	firstComment *Comment                 // Copy of the comments at end of file.
+	footer       *Comment                 // Copy of the footer comments.
+	current      string                   // Name of the selected section, if any.
+}
+// ConfigError is the error returned by the Get, Set and ReadFile paths. It
+// says where the failure was; use errors.As to get at the fields and
+// errors.Is to test Err against the sentinel errors.
+type ConfigError struct{
+  File         string                   // The file being read, or the value came from.
+	Line         int                      // The line in File, 0 if not known.
+	Section      string                   // The section, "" if none.
+	Param        string                   // The parameter, "" if none.
+	Op           string                   // "read", "get" or "set".
+	Err          error                    // What went wrong.
+}
+// Warning is a line ReadFile could not make sense of. Rather than failing the
+// read, it kept the line as a comment; Configuration.Warnings() lists them.
+type Warning struct{
+  File         string                   // The file the line is in.
+	Line         int                      // The line's number in File.
+	Text         string                   // The line itself.
+	Reason       string                   // Why it was not understood.
+}
+// ========================= // ConfigWriter // ==============================
+// A builder for generating a configuration file from scratch.
+// ============================================================================
+type ConfigWriterAPI interface{
+  Section(name string) *ConfigWriter     // Start a new section.
+	Set(name, value string) *ConfigWriter  // Add a parameter to the section.
+	Comment(text string) *ConfigWriter     // Comment what comes next.
+	Err() error                            // The first error, if any.
+	Build() *Configuration                 // The configuration built so far.
+	WriteFile(path string) error           // Write it to a file.
+}
+type ConfigWriter struct{
+  cfg          *Configuration           // The configuration being built.
+	comments     []string                 // Comments for what comes next.
+	err          error                    // The first thing that went wrong.
+}
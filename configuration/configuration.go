@@ -33,17 +33,22 @@ package configuration
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"image/color"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"strconv"
 	"time"
-
-	"golang.org/x/sys/unix"
+	"unicode"
+	"unicode/utf8"
 )
 const debug=true
 const uselog=true
@@ -51,7 +56,85 @@ var(
   errs       []error
 	comments   *Comment
 	Config     *Configuration
+	ErrParameterNotFound=errors.New("parameter not found")// Test with errors.Is().
+	ErrLineTooLong=errors.New("line exceeds maximum length")// Test with errors.Is().
+	ErrReadOnly=errors.New("configuration is read-only")// Test with errors.Is().
+	ErrFloatSpecial=errors.New("nan and inf are not allowed")// Test with errors.Is().
+	ErrIndexGap=errors.New("index leaves a gap in the values")// Test with errors.Is().
+	ErrEmptyValue=errors.New("cannot decode empty value")// Test with errors.Is().
+	ErrTrailingComma=errors.New("value list ends in a comma")// Test with errors.Is().
+	ErrInvalidUTF8=errors.New("value is not valid UTF-8")// Test with errors.Is().
+	ErrCommentLoss=errors.New("writing would lose the comments that were not saved")// Test with errors.Is().
+	ErrUnwritableValue=errors.New("value would not read back as one value")// Test with errors.Is().
 )
+// ---------------------------- // ConfigError // --------------------------- //
+//  Format the error as file:line: op [section] param: err, leaving out the   //
+// parts we don't know.                                                       //
+// -------------------------------------------------------------------------- //
+func (e *ConfigError) Error() string{
+  var parts []string                    // The parts of the message.
+	loc:=e.File                           // Where it happened.
+	if e.Line>0{                          // Do we know the line?
+	  loc+=":"+strconv.Itoa(e.Line)       // Yes, add it.
+	}                                     // Done checking for line.
+	if loc!=""{                           // Do we know where?
+	  parts=append(parts,loc)             // Yes, start with that.
+	}                                     // Done checking for location.
+	what:=e.Op                            // What we were doing.
+	if e.Section!=""{                     // Do we know the section?
+	  what+=" ["+e.Section+"]"            // Yes, add it.
+	}                                     // Done checking for section.
+	if e.Param!=""{                       // Do we know the parameter?
+	  what+=" "+e.Param                   // Yes, add it.
+	}                                     // Done checking for parameter.
+	if what=strings.TrimSpace(what);what!=""{// Anything to say?
+	  parts=append(parts,what)            // Yes, add it.
+	}                                     // Done checking for what.
+	if e.Err!=nil{                        // Do we know the cause?
+	  parts=append(parts,e.Err.Error())   // Yes, it comes last.
+	}                                     // Done checking for cause.
+	return strings.Join(parts,": ")       // Return the message.
+}                                       // ------------- Error -------------- //
+func (e *ConfigError) Unwrap() error { return e.Err }
+// ------------------------------ // String // ------------------------------ //
+//  Format the warning as file:line: reason: text.                            //
+// -------------------------------------------------------------------------- //
+func (w Warning) String() string{
+  return fmt.Sprintf("%s:%d: %s: %s",w.File,w.Line,w.Reason,w.Text)
+}                                       // ------------- String ------------- //
+// ---------------------------- // configError // --------------------------- //
+//  Wrap err in a ConfigError saying which parameter of this Section it is    //
+// about. The parameter's file and line are used when it was read from a file,//
+// else the Configuration's. A nil err stays nil, and an err that already is  //
+// a ConfigError is returned as is so we don't say the same thing twice.      //
+// -------------------------------------------------------------------------- //
+func (s *Section) configError(op, name string, err error) error{
+  var ce *ConfigError                   // To check if it is already wrapped.
+	if err==nil||errors.As(err,&ce){      // Anything to wrap?
+	  return err                          // No, return it as is.
+	}                                     // Done checking for error.
+	e:=&ConfigError{Section: s.name,Param: name,Op: op,Err: err}
+	if s.cfg!=nil{                        // Do we belong to a Configuration?
+	  e.File=s.cfg.GetPathname()          // Yes, default to its file.
+	}                                     // Done checking for Configuration.
+	if p:=s.FindParameter(name,true);p!=nil&&p.file!=""{// Do we know where it was read?
+	  e.File,e.Line=p.file,p.line         // Yes, point there.
+	}                                     // Done checking for parameter.
+	return e                              // Return the wrapped error.
+}                                       // ---------- configError ----------- //
+// ---------------------------- // configError // --------------------------- //
+//  Wrap err as Section.configError() does, for the current section.          //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) configError(op, name string, err error) error{
+  if cfg.current!=nil{                  // Do we have a current section?
+	  return cfg.current.configError(op,name,err)// Yes, it knows more.
+	}                                     // Done checking for current section.
+	var ce *ConfigError                   // To check if it is already wrapped.
+	if err==nil||errors.As(err,&ce){      // Anything to wrap?
+	  return err                          // No, return it as is.
+	}                                     // Done checking for error.
+	return &ConfigError{File: cfg.GetPathname(),Param: name,Op: op,Err: err}
+}                                       // ---------- configError ----------- //
 // =========================== // Helpers // ==================================
 func isTrue(p string) bool{
   p = strings.ToLower(strings.TrimSpace(p))
@@ -61,6 +144,10 @@ func isFalse(p string) bool{
   p = strings.ToLower(strings.TrimSpace(p))
 	return p == "false"
 }
+func plural(n int) string{
+  if n==1{ return "" }
+	return "s"
+}
 func isPointer(v any) bool { return reflect.ValueOf(v).Kind() == reflect.Ptr }
 func verbComaptible(verb byte,k reflect.Kind) bool{
   switch verb{
@@ -80,6 +167,121 @@ func verbComaptible(verb byte,k reflect.Kind) bool{
 		  return true            
 	}
 }
+// --------------------------- // parseComplex // --------------------------- //
+// Decode a complex number such as "3+4i" or "(3+4i)" with the given bit     //
+// size (64 or 128). fmt.Sscanf() with "%v" does not reliably parse Go        //
+// complex literals, so we use strconv.ParseComplex() instead.                //
+// -------------------------------------------------------------------------- //
+func parseComplex(value string,bits int) (complex128,error){
+  v:=strings.TrimSpace(value)           // Remove surrounding whitespace.
+	if v==""{                             // Anything to decode?
+	  return 0,fmt.Errorf("can't decode empty \"value\" to complex%d",bits)
+	}                                     // Done checking for empty value.
+	c,err:=strconv.ParseComplex(v,bits)   // Parse the complex number.
+	if err!=nil{                          // Could we parse it?
+	  return 0,fmt.Errorf("can't decode \"%s\" to complex%d: %w",v,bits,err)
+	}                                     // Done checking for parse error.
+	return c,nil                          // Return the complex number.
+}                                       // ---------- parseComplex ---------- //
+// ---------------------------- // parseFloat // ---------------------------- //
+// Decode a float with the given bit size (32 or 64) using strconv.ParseFloat.//
+// "nan", "inf", "+inf" and "-inf", in any case, are only accepted when       //
+// specials is true; otherwise they fail with ErrFloatSpecial.                //
+// -------------------------------------------------------------------------- //
+func parseFloat(value string,bits int,specials bool) (float64,error){
+  v:=strings.TrimSpace(value)           // Remove surrounding whitespace.
+	if v==""{                             // Anything to decode?
+	  return 0,fmt.Errorf("can't decode empty \"value\" to float%d",bits)
+	}                                     // Done checking for empty value.
+	f,err:=strconv.ParseFloat(v,bits)     // Parse the float.
+	if err!=nil{                          // Could we parse it?
+	  return 0,fmt.Errorf("can't decode \"%s\" to float%d: %w",v,bits,err)
+	}                                     // Done checking for parse error.
+	if !specials&&(math.IsNaN(f)||math.IsInf(f,0)){// A special we don't allow?
+	  return 0,fmt.Errorf("can't decode \"%s\" to float%d: %w",v,bits,ErrFloatSpecial)
+	}                                     // Done checking for specials.
+	return f,nil                          // Return the float.
+}                                       // ----------- parseFloat ----------- //
+// ---------------------------- // parseColor // ---------------------------- //
+// Decode a "#RGB", "#RRGGBB" or "#RRGGBBAA" hex color. The first form is     //
+// short for "#RRGGBB" with each digit doubled; without AA a color is opaque. //
+// -------------------------------------------------------------------------- //
+func parseColor(value string) (color.RGBA,error){
+  v:=strings.TrimSpace(value)           // Remove surrounding whitespace.
+	hex,ok:=strings.CutPrefix(v,"#")      // Does it start with '#'?
+	if !ok||(len(hex)!=3&&len(hex)!=6&&len(hex)!=8){// And have a known length?
+	  return color.RGBA{},fmt.Errorf("can't decode \"%s\" to a color: want #RGB, #RRGGBB or #RRGGBBAA",v)
+	}                                     // Done checking the form.
+	if len(hex)==3{                       // Is it the short form?
+	  hex=string([]byte{hex[0],hex[0],hex[1],hex[1],hex[2],hex[2]})// Yes, double each digit.
+	}                                     // Done expanding the short form.
+	if len(hex)==6{                       // No alpha?
+	  hex+="ff"                           // Then it is opaque.
+	}                                     // Done checking for alpha.
+	n,err:=strconv.ParseUint(hex,16,32)   // Decode the digits.
+	if err!=nil{                          // Are they all hex?
+	  return color.RGBA{},fmt.Errorf("can't decode \"%s\" to a color: bad hex digits",v)
+	}                                     // Done checking the digits.
+	return color.RGBA{R: uint8(n>>24),G: uint8(n>>16),B: uint8(n>>8),A: uint8(n)},nil
+}                                       // ----------- parseColor ----------- //
+// --------------------------- // formatColor // ---------------------------- //
+// Encode a color as "#rrggbb", or "#rrggbbaa" if it is not opaque.           //
+// -------------------------------------------------------------------------- //
+func formatColor(c color.RGBA) string{
+  if c.A==0xff{                         // Is it opaque?
+	  return fmt.Sprintf("#%02x%02x%02x",c.R,c.G,c.B)// Yes, leave alpha out.
+	}                                     // Done checking for alpha.
+	return fmt.Sprintf("#%02x%02x%02x%02x",c.R,c.G,c.B,c.A)// With alpha.
+}                                       // ----------- formatColor ---------- //
+// --------------------------- // roundPrecision // ------------------------- //
+//  Round f to precision digits after the decimal point, as "%.<precision>f"  //
+// would write it, for the GetValuePrecision getters. An empty precision      //
+// leaves f alone.                                                            //
+// -------------------------------------------------------------------------- //
+func roundPrecision(f float64,precision string) (float64,error){
+  if precision==""{                     // Any precision asked for?
+	  return f,nil                        // No, keep every digit.
+	}                                     // Done checking for precision.
+	n,err:=strconv.Atoi(strings.TrimSpace(precision))// How many digits?
+	if err!=nil||n<0{                     // Is it a number of digits?
+	  return 0,fmt.Errorf("invalid precision \"%s\"",precision)
+	}                                     // Done checking precision.
+	if math.IsNaN(f)||math.IsInf(f,0){    // Anything to round?
+	  return f,nil                        // No, specials stay as they are.
+	}                                     // Done checking for specials.
+	return strconv.ParseFloat(strconv.FormatFloat(f,'f',n,64),64)// Round it.
+}                                       // --------- roundPrecision --------- //
+// --------------------------- // checkFormat // ---------------------------- //
+// Make sure format has exactly one verb, and that it can format src, so that //
+// fmt.Sprintf() won't produce %!d(string=...) junk. %v formats anything.     //
+// -------------------------------------------------------------------------- //
+func checkFormat(format string,src any) error{
+  k:=reflect.ValueOf(src).Kind()        // The kind of value to format.
+	nverbs:=0                             // How many verbs we found.
+	for j:=0;j<len(format);j++{           // For each character in fmt string...
+	  if format[j]!='%'{                  // Is it the start of a verb?
+		  continue                          // No, keep looking.
+		}                                   // Done checking for '%'.
+		j++                                 // Skip the '%'.
+		for j<len(format)&&strings.ContainsRune("#0- +.0123456789*",rune(format[j])){
+		  j++                               // Skip flags, width and precision.
+		}                                   // Done skipping to the verb.
+		if j==len(format){                  // Did the format end before the verb?
+		  return fmt.Errorf("format %q has no verb after '%%'",format)
+		}                                   // Done checking for missing verb.
+		if format[j]=='%'{                  // Is it a literal "%%"?
+		  continue                          // Yes, that is not a verb.
+		}                                   // Done checking for literal '%'.
+		if format[j]!='v'&&!verbComaptible(format[j],k){// Can this verb format src?
+		  return fmt.Errorf("format %s is not compatible with type %s",format,k)
+		}                                   // Done checking for compatibility.
+		nverbs++                            // We found a verb.
+	}                                     // Done iterating through the format string.
+	if nverbs!=1{                         // Exactly one verb for our one value?
+	  return fmt.Errorf("format %q must have exactly one verb, it has %d",format,nverbs)
+	}                                     // Done checking number of verbs.
+	return nil                            // The format is good.
+}                                       // ---------- checkFormat ----------- //
 
 // =========================== // Comment // ==================================
 // A class to store comments and blank lines from a configuration file.
@@ -106,6 +308,22 @@ func CopyComment(c *Comment) *Comment{
 	}                                     // Done checking for our purpose.
 	return nil                            // Nothing to copy, return nil.
 }                                       // ------------ CopyComment --------- //
+// --------------------------- // copyComments // --------------------------- //
+// Copy a whole list of comments, not just its head.                          //
+// -------------------------------------------------------------------------- //
+func copyComments(c *Comment) *Comment{
+  var head,tail *Comment                // The head and tail of the copy.
+	for ;c!=nil;c=c.next{                 // For each comment in the list...
+	  q:=CopyComment(c)                   // Copy this comment.
+		if head==nil{                       // Is it the first one?
+		  head=q                            // Yes, it is the head of the list.
+		} else{                             // Else we have a list already.
+		  tail.SetNext(q)                   // Append it to the list.
+		}                                   // Done checking for first comment.
+		tail=q                              // Now we have a new tail.
+	}                                     // Done iterating comment list.
+	return head                           // Return the copy of the list.
+}                                       // ---------- copyComments ---------- //
 // ------------------------------------ //
 // Inline getters and setters for the Comment object.
 // ------------------------------------ //
@@ -129,6 +347,36 @@ func (c *Comment) Print(w io.Writer) error{
 	}                                     // Done checking for our purpose.
 	return nil                            // Nothing to print, return nil.
 }
+// --------------------------- // commentText // ---------------------------- //
+//  Turn a list of comment lines into documentation text: each line loses its //
+// comment prefix (any of prefixes, or "#" if there are none, repeated as in   //
+// "## Title") and surrounding whitespace, blank lines and import statements  //
+// are dropped, and what is left is joined with newlines.                     //
+// -------------------------------------------------------------------------- //
+func commentText(c *Comment,prefixes []string) string{
+  if len(prefixes)==0{                  // Were we given any prefixes?
+	  prefixes=[]string{"#"}              // No, use the default.
+	}                                     // Done checking for prefixes.
+	var lines []string                    // The documentation lines.
+	for ;c!=nil;c=c.next{                 // For each comment line...
+	  if c.imports{                       // Is it really an import statement?
+		  continue                          // Yes, that's not documentation.
+		}                                   // Done checking for import.
+		line:=strings.TrimSpace(c.value)    // Remove surrounding whitespace.
+		for _,pfx:=range prefixes{          // For each comment prefix...
+		  if strings.HasPrefix(line,pfx){   // Does the line start with it?
+			  for strings.HasPrefix(line,pfx){// Yes, remove all of them.
+				  line=line[len(pfx):]          // Remove one.
+				}                               // Done removing prefixes.
+				break                           // Only one kind of prefix per line.
+			}                                 // Done checking this prefix.
+		}                                   // Done iterating prefixes.
+		if line=strings.TrimSpace(line);line!=""{// Anything left?
+		  lines=append(lines,line)          // Yes, keep it.
+		}                                   // Done checking for blank line.
+	}                                     // Done iterating comments.
+	return strings.Join(lines,"\n")       // One line per comment line.
+}                                       // ---------- commentText ----------- //
 // ========================= // Parameter // ==================================
 // A class to store individual parameters and their values.
 // ============================================================================
@@ -150,18 +398,14 @@ func NewParameter(name, valuestr string,comments *Comment, imported bool) *Param
 }                                       // -------- NewParameter ------- //
 // ------------------------- // Copy constructor // ------------------------- //
 // Initialize the parameter data structures. The copy will not be "imported". //
+// It gets its own copy of p's whole list of comments, not only of the first  //
+// one, so adding to or changing the copy's comments leaves p's alone.        //
 // -------------------------------------------------------------------------- //
 
 func CopyParameter(p *Parameter) *Parameter{
-  if p.comments!=nil{                    // Any comments to copy?
-    comments=CopyComment(p.comments)     // Yes, copy the comments.
-  }
+  comments:=copyComments(p.comments)    // Copy the comments, if any.
   n:=p.n                                // Copy the number of values.
   name := p.name                        // Copy the name
-  var value string                      // Initialize an empty string
-  if p.value != "" {                    // Any value to copy?
-	  value = p.value                     // Copy the source string.
-  }                                     // Done copying the values.
  var values []string=nil                // Where to copy the values.
  var quotes []byte=nil                  // Where to copy the quotes.
  if n!=0{                               // Are there any values?
@@ -177,9 +421,13 @@ func CopyParameter(p *Parameter) *Parameter{
 		n:    n,                            // Copy the number of values.
 		comments: comments,                 // Copy the comments.
 		isimported: false,                  // Copy the imported flag.
-		value: value,                       // Copy the value.
 		values: values,                     // Copy the values.
 		quotes: quotes,                     // Copy the quotes.
+		file: p.file,                       // Copy where it was read from.
+		line: p.line,                       // Copy the line it was on.
+		flag: p.flag,                       // Copy whether it is a bare flag.
+		ref: p.ref,                         // Copy the file its value came from.
+		rawJSON: p.rawJSON,                 // Copy the JSON document, if any.
 	}                                     // Done copying the Parameter object.
 }                                       // -------- CopyParameter -------- //
 // ------------------------------------ //
@@ -191,10 +439,46 @@ func (p *Parameter) GetValueArray() []string{ return p.values }
 func (p *Parameter) GetValue(i uint) string{
   if i<uint(p.n){ return p.values[i]} else{ return "" } 
 }
-func (p *Parameter) GetValues() string { return p.value}
+// ---------------------------- // GetValues // ----------------------------- //
+//  Return the right-hand side of the parameter as Print() writes it: the     //
+// values joined by commas, each in its quotes, if any. It is rebuilt from    //
+// the values, so it follows every setter.                                    //
+// -------------------------------------------------------------------------- //
+func (p *Parameter) GetValues() string{
+  var sb strings.Builder                // Where to build the string.
+	var n int64                           // Bytes written, we don't need them.
+	p.writeValues(&sb,&n)                 // Write the values, a Builder never fails.
+	return sb.String()                    // Return what we wrote.
+}                                       // ----------- GetValues ------------ //
+// --------------------------- // writeValues // ---------------------------- //
+// Write the values, comma-separated and quoted as they were, to w, adding    //
+// the number of bytes written to *n.                                         //
+// -------------------------------------------------------------------------- //
+func (p *Parameter) writeValues(w io.Writer,n *int64) error{
+  for i,v:=range p.values{              // For each value...
+	  sep:=""                             // The first value needs no comma...
+	  if i>0{                             // ...but the others do.
+		  sep=","                           // Separate multivalued parameter.
+		}                                   // Done checking for first value.
+		q:=""                               // Assume it has no quotes.
+		if i<len(p.quotes)&&p.quotes[i]!=0{ // Do we know its quote?
+		  q=string(p.quotes[i])             // Yes, use it.
+		}                                   // Done getting the quote.
+		if err:=writeStrings(w,n,sep,q,v,q);err!=nil{// Write the value.
+		  return err                        // Could not write the value.
+		}                                   // Done writing the value.
+	}                                     // Done iterating values.
+	return nil                            // We are good if we got here.
+}                                       // ---------- writeValues ----------- //
 func (p *Parameter) GetName() string{ return p.name }
 func (p *Parameter) GetNext() *Parameter{ return p.next }
-func (p *Parameter) SetNext(p2 *Parameter){ if p!=nil{ p.next=p2 } }
+func (p *Parameter) SetNext(p2 *Parameter){ if p!=nil&&!p.readonly{ p.next=p2 } }
+// --------------------------- // CommentText // ---------------------------- //
+//  Return the comments above this Parameter as documentation text, without  //
+// their "#" prefixes. A Parameter does not know its Configuration, so custom //
+// prefixes are not stripped here; Section.ParameterCommentText() does that. //
+// -------------------------------------------------------------------------- //
+func (p *Parameter) CommentText() string{ return commentText(p.comments,nil) }
 
 // ------------------------ // GetValueByte() // ---------------------------- //
 // Get the value of a parameter as a byte (8-bit character)
@@ -232,7 +516,7 @@ func (p *Parameter) GetValueTime(value string, dest *time.Time) error{
 	return nil                            // Return nil if we got here.   
 }                                       // -------- GetValueTime ------------ //
 func (p *Parameter) GetValueTimeByIndex(i uint, dest *time.Time) error{
-  if i>=uint(len(p.values)){            // Is the index out of range?
+  if i>=p.GetNValues(){                 // Is the index out of range?
 	  return fmt.Errorf("index %d out of range", i)// Yes, panic.
 	}                                     // Done checking for out of range index.
 	q:=p.values[i]                        // Get the value at the index.
@@ -243,7 +527,7 @@ func (p *Parameter) GetValueTimeByIndex(i uint, dest *time.Time) error{
 	*dest=t                               // Set the destination time to the parsed time.
 	return nil                            // Return nil if we got here.   
 }                                       // ---- GetValueTimeByIndex -------- //
-func (p *Parameter) GetValueTimespec(value string, dest *unix.Timespec) error{
+func (p *Parameter) GetValueTimespec(value string, dest *Timespec) error{
   if len(value)==0{                     // Where we given a value to decode?
 	  return fmt.Errorf("can't decode empty \"value\" to unix.Timespec")// No, that's bad.
 	}                                     // Done checking for empty value.
@@ -251,12 +535,11 @@ func (p *Parameter) GetValueTimespec(value string, dest *unix.Timespec) error{
 	if err!=nil{                          // Any error parsing the time?
 	  return fmt.Errorf("can't decode \"%s\" to unix.Timespec: %v", value, err)
 	}                                     // Done checking for parse error.
-	unix.NsecToTimespec(t.UnixNano()) 	  // Convert the time to a unix.Timespec.
-	*dest=unix.NsecToTimespec(t.UnixNano()) // Set the destination time to the parsed time.
+	*dest=nsecToTimespec(t.UnixNano())     // Set the destination time to the parsed time.
 	return nil                            // Return nil if we got here.   
 }                                       // -------- GetValueTimespec -------- //
-func (p *Parameter) GetValueTimespecByIndex(i uint,dest *unix.Timespec)error{
-  if i>=uint(len(p.values)){            // Is the index out of range?
+func (p *Parameter) GetValueTimespecByIndex(i uint,dest *Timespec)error{
+  if i>=p.GetNValues(){                 // Is the index out of range?
 	  return fmt.Errorf("index %d out of range", i)// Yes, panic.
 	}                                     // Done checking for out of range index.
 	q:=p.values[i]                        // Get the value at the index.
@@ -264,8 +547,7 @@ func (p *Parameter) GetValueTimespecByIndex(i uint,dest *unix.Timespec)error{
 	if err!=nil{                          // Any error parsing the time?
 	  return fmt.Errorf("can't decode \"%s\" to time.Time: %v", q, err)
 	}                                     // Done checking for parse error.
-	unix.NsecToTimespec(t.UnixNano()) 	  // Convert the time to a unix.Timespec.
-	*dest=unix.NsecToTimespec(t.UnixNano()) // Set the destination time to the parsed time.
+	*dest=nsecToTimespec(t.UnixNano())     // Set the destination time to the parsed time.
 	return nil                            // Return nil if we got here.
 }                                       // ---- GetValueTimeByIndex --------- //
 func (p *Parameter) GetValueDuration(value string, dest *time.Duration) error{
@@ -280,7 +562,7 @@ func (p *Parameter) GetValueDuration(value string, dest *time.Duration) error{
 	return nil                            // Return nil if we got here.   
 }                                       // -------- GetValueDuration -------- //
 func (p *Parameter) GetValueDurationByIndex(i uint, value string,dest *time.Duration) error{
-  if i>=uint(len(p.values)){            // Is the index out of range?
+  if i>=p.GetNValues(){                 // Is the index out of range?
 	  return fmt.Errorf("index %d out of range", i)// Yes, panic.
 	}                                     // Done checking for out of range index.
 	q:=p.values[i]                        // Get the value at the index.
@@ -453,25 +735,41 @@ func (p *Parameter)	GetValueFloat32(value string, dest *float32) error{
   if len(value)==0{                     
 	  return fmt.Errorf("can't decode empty \"value\" to float32")
 	}                                     
-	return p.scanValue("%f",dest)        
+	f,err:=parseFloat(p.GetValues(),32,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 func (p *Parameter)	GetValueFloat32ByIndex(i uint,dest *float32) error{
   if int(i)>=len(p.values){              
 	  return fmt.Errorf("can't decode empty \"value\" to float32")
 	}                                     
-	return p.scanValueByIndex(int(i),"%f",dest)
+	f,err:=parseFloat(p.values[i],32,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 func (p *Parameter)	GetValueFloat64(value string,dest *float64) error{
   if len(value)==0{                     
 	  return fmt.Errorf("can't decode empty \"value\" to float64")
 	}                                     
-	return p.scanValue("%f",dest)        
+	f,err:=parseFloat(p.GetValues(),64,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 func (p *Parameter)	GetValueFloat64ByIndex(i uint,dest *float64) error{
   if int(i)>=len(p.values){              
 	  return fmt.Errorf("can't decode empty \"value\" to float64")
 	}                                     
-	return p.scanValueByIndex(int(i),"%f",dest)
+	f,err:=parseFloat(p.values[i],64,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 	// Scientific notation
 func (p *Parameter)	GetValueSI(value string,dest *string) error{
@@ -482,28 +780,56 @@ func (p *Parameter)	GetValueSI(value string,dest *string) error{
 }
 // ------------------- Floating point values with precision ----------------- //
 func (p *Parameter)	GetValuePrecisionFloat32(value,precision string,dest *float32) error{
-  if len(value)==0{              
+  if len(value)==0{                     
 	  return fmt.Errorf("can't decode empty \"value\" to float32")
-	}
-	return p.scanValue(fmt.Sprintf("%%.%sf", precision), dest)  
+	}                                     
+	f,err:=parseFloat(p.GetValues(),32,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 func (p *Parameter)	GetValuePrecisionFloat32ByIndex(i uint,precision string,dest *float32) error{
   if int(i)>=len(p.values){              
 	  return fmt.Errorf("can't decode empty \"value\" to float32")
-	}
-	return p.scanValueByIndex(int(i), fmt.Sprintf("%%.%sf", precision), dest)
+	}                                     
+	f,err:=parseFloat(p.values[i],32,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 func (p *Parameter)	GetValuePrecisionFloat64(value,precision string,dest *float64) error{
   if len(value)==0{                     
 	  return fmt.Errorf("can't decode empty \"value\" to float64")
 	}                                     
-	return p.scanValue(fmt.Sprintf("%%.%sf", precision), dest)  
+	f,err:=parseFloat(p.GetValues(),64,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 func (p *Parameter)	GetValuePrecisionFloat64ByIndex(i uint,precision string,dest *float64) error{
   if int(i)>=len(p.values){              
 	  return fmt.Errorf("can't decode empty \"value\" to float64")
 	}                                     
-	return p.scanValueByIndex(int(i), fmt.Sprintf("%%.%sf", precision), dest)
+	f,err:=parseFloat(p.values[i],64,false)// No Configuration, so no specials.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return err                            // Return error if any.
 }
 
 // ---------------------- Complex numbers ----------------------------------- //
@@ -511,26 +837,46 @@ func (p *Parameter)	GetValueComplex64(value string,dest *complex64) error{
   if len(value)==0{                     
 	  return fmt.Errorf("can't decode empty \"value\" to complex64")
 	}                                     
-	return p.scanValue("%v",dest)        
-}  
+	c,err:=parseComplex(p.GetValue(0),64) // Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return err                          // No, say why.
+	}                                     // Done checking for error.
+	*dest=complex64(c)                    // Store it.
+	return nil                            // Done.
+}
 
 func (p *Parameter)	GetValueComplex64ByIndex(i uint,dest *complex64) error{
   if int(i)>=len(p.values){              
-	  return fmt.Errorf("can't decode empty \"value\" to complex4")
+	  return fmt.Errorf("can't decode empty \"value\" to complex64")
 	}                                     
-	return p.scanValueByIndex(int(i),"%v",dest)
+	c,err:=parseComplex(p.values[i],64)   // Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return err                          // No, say why.
+	}                                     // Done checking for error.
+	*dest=complex64(c)                    // Store it.
+	return nil                            // Done.
 }
 func (p *Parameter)	GetValueComplex128(value string,dest *complex128) error{
   if len(value)==0{                     
 	  return fmt.Errorf("can't decode empty \"value\" to complex128")
 	}                                     
-	return p.scanValue("%v",dest)        
+	c,err:=parseComplex(p.GetValue(0),128)// Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return err                          // No, say why.
+	}                                     // Done checking for error.
+	*dest=c                               // Store it.
+	return nil                            // Done.
 }
 func (p *Parameter)	GetValueComplex128ByIndex(i uint,dest *complex128) error{
   if int(i)>=len(p.values){              
 	  return fmt.Errorf("can't decode empty \"value\" to complex128")
 	}                                     
-	return p.scanValueByIndex(int(i),"%v",dest)
+	c,err:=parseComplex(p.values[i],128)  // Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return err                          // No, say why.
+	}                                     // Done checking for error.
+	*dest=c                               // Store it.
+	return nil                            // Done.
 }
 
 // ----------------------- ScanValueOfIndex --------------------------------- //
@@ -563,8 +909,9 @@ func (p *Parameter)	GetValueComplex128ByIndex(i uint,dest *complex128) error{
 //   "%<width>s" - Pad string with spaces set to width.
 // -------------------------------------------------------------------------- //
 func (p *Parameter) scanValue(format string, dest any) error{
-  if len(p.value)==0{       // Within range?
-	return fmt.Errorf("The value of the parameter %s is %v",p.name,p.value)
+  raw:=p.GetValues()                    // The values, as written.
+  if len(raw)==0{                       // Anything to scan?
+	return fmt.Errorf("The value of the parameter %s is %v",p.name,raw)
   }                                     // Done checking for out of range.
   var verb byte                         // The format verb.
   for j:=1;j<len(format);j++{           // For each character in fmt string...
@@ -591,12 +938,12 @@ func (p *Parameter) scanValue(format string, dest any) error{
   }                                     // Done checking for nil or pointer.
   //raw:=p.GetValue(uint(i))                              
 	// Scan the value into the destination variable
-  _,err:=fmt.Sscanf(string(p.value),format,dest)
+  _,err:=fmt.Sscanf(raw,format,dest)
 	return err                            // Return error if any.  
 }
 // ----------------------- scanValueByIndex --------------------------------- //
 func (p *Parameter) scanValueByIndex(i int, format string, dest any) error{
-  if i < 0 || i >= len(p.values){       // Within range?
+  if i < 0 || i >= int(p.n){            // Within range of what GetValue() returns?
 	return fmt.Errorf("index %d out of range", i)
   }                                     // Done checking for out of range.
   var verb byte                         // The format verb.
@@ -660,12 +1007,33 @@ func (p *Parameter) GetValueBool(i uint,tval string, fval string) (result bool,
 	}                                     // Done checking for one value.
 	return result, err                    // Return the result and error.
 }                                       // ----------- GetValueBool --------- //
+// --------------------------- // RequireArity // --------------------------- //
+//  Check that this Parameter has at least min and at most max values. A      //
+// negative max means there is no upper bound. Use this to validate           //
+// structural expectations at load time, e.g. RequireArity(3,3) for an RGB    //
+// triple or RequireArity(1,-1) for "at least one value".                     //
+// -------------------------------------------------------------------------- //
+func (p *Parameter) RequireArity(min, max int) error{
+  n:=int(p.GetNValues())                // The number of values we have.
+	switch{                               // Act according to the count.
+	  case max>=0&&min==max&&n!=min:      // Need exactly min values?
+		  return fmt.Errorf("parameter %s has %d value%s, expected exactly %d",p.name,n,plural(n),min)
+		case n<min:                         // Too few values?
+		  return fmt.Errorf("parameter %s has %d value%s, expected at least %d",p.name,n,plural(n),min)
+		case max>=0&&n>max:                 // Too many values?
+		  return fmt.Errorf("parameter %s has %d value%s, expected at most %d",p.name,n,plural(n),max)
+	}                                     // Done checking the count.
+	return nil                            // The count is within range.
+}                                       // ---------- RequireArity ---------- //
 // --------------------------- // Append // --------------------------------- //
 // Place another parameter in the list after this one. This must only be called
 // for the last parameter in the list.
 // -------------------------------------------------------------------------- //
 func (p *Parameter) Append(p2 *Parameter){
-  p.next=p2                             // Set the next parameter to p2.
+  if p.readonly{                        // Is it in a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	p.next=p2                             // Set the next parameter to p2.
 }                                       // ------------ Append ------------- //
 // ----------------------------- // SetValue // ----------------------------- //
 //  Set the value(s) for this Parameter. Parameters can be arrays, indicated  //
@@ -689,23 +1057,28 @@ func (p *Parameter) Append(p2 *Parameter){
 //                                                                            //
 //  Multi-valued Parameters may use continuation lines. A line is continued   //
 // if it ends with a comma. Any whitespace at the beginning of the next line  //
-// is removed, and the line is appended to the first line. You can have as    //
-// many continuation lines as you want, as long as the combined length of all //
-// the appended lines (minus their beginning whitespace and line terminators) //
-// does not exceed 32768 bytes. This is all handled before this method is     //
-// called, so all we will see is a very long line.                            //
+// is removed, unless the line was continued inside a quoted value, and the   //
+// line is appended to the first line. You can have as many continuation    //
+// lines as you want, as long as the combined length of all the appended     //
+// lines (minus their beginning whitespace and line terminators) does not    //
+// exceed 32768 bytes, or what was set with SetMaxLineLength(). This is all  //
+// handled before this method is called, so all we will see is a very long   //
+// line.                                                                      //
 // -------------------------------------------------------------------------- //
 func (p *Parameter) SetValue(valuestr string, quote byte) error{
+  if p.readonly{                        // Is it in a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
   // ---------------------------------- //
 	// Clear any old values if they exists.
 	// ---------------------------------- //
+	p.ref=""                              // The value is no longer from a file.
+	p.rawJSON=""                          // Nor a JSON document as read.
 	if p.values!=nil{                     // Any old values?
 	  p.values=p.values[:0]               // Yes, clear slice for reuse.
 		p.quotes=p.quotes[:0]               // and clear quotes too.
 	}                                     // Done clearing old values.
-	if p.value!=""{                       // Any old value?
-	  p.value=p.value[:0]                 // Yes, clear slice for reuse.
-	}                                     // Done clearing old value.
+	p.n=0                                 // So we count only the new ones.
 	var curr string                       // Where to store the current value.
   inquote:=false                        // Are we in a quote?
   q:=rune(quote)                        // The quote character.
@@ -724,9 +1097,10 @@ func (p *Parameter) SetValue(valuestr string, quote byte) error{
 		}                                   // Done acting according to the byte.
 	}                                     // Done processing the value string.
 	// ---------------------------------- //
-	// Now process the last field, if any exists.
+	// Now process the last field, if any exists. Blanks after a final comma
+	// are not a value, just as nothing after it is not.
 	// ---------------------------------- //
-	if len(curr)>0{                       // Any value left?
+	if len(curr)>0&&(p.n==0||strings.TrimSpace(curr)!=""){// Any value left?
 	  field:=strings.TrimSpace(curr)      // Trim the current value.
 		p.values=append(p.values,field)     // Append the value.
 		p.quotes=append(p.quotes,quote)     // Append the quote.
@@ -740,6 +1114,11 @@ func (p *Parameter) SetValue(valuestr string, quote byte) error{
 // after this call.
 // -------------------------------------------------------------------------- //
 func (p *Parameter) SetValuePtr(value string,quote byte) error{
+  if p.readonly{                        // Is it in a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	p.ref=""                              // The value is no longer from a file.
+	p.rawJSON=""                          // Nor a JSON document as read.
   // Clear old values (keeping capacity) and append new ones.
 	p.values=append(p.values[:0],value)
 	p.quotes=append(p.quotes[:0],quote)
@@ -747,30 +1126,34 @@ func (p *Parameter) SetValuePtr(value string,quote byte) error{
 	return nil                            // Return no error if we got here.
 }                                       // ----------- SetValuePtr ---------- //
 // -------------------- // SetValuePtrOnIndex // ---------------------------- //
-// Set a value of a multi-valued Parameter. This method cannot change the number
-// of values in the multi-valued Parameter, so the value must have already
-// existed before the call. If the index points to a value beyond the currently
-// existing number of values this method will fail.
+// Set a value of a multi-valued Parameter. The index may be that of an
+// existing value, or one past the last value to append a new one. An index
+// further out would leave values in between that were never set, which would
+// be written out as empty values, so it fails with ErrIndexGap instead.
 // -------------------------------------------------------------------------- //
 func (p *Parameter) SetValuePtrOnIndex(i uint,value string,quote byte) error{
-  if int(i)<0{
-    return fmt.Errorf("index %d out of range", i)
-  }
-	// Grow the valies slice if needed.
-	if int(i)>=len(p.values){             // Is the index out of range?
-	  newlen:=int(i)+1                    // Yes, grow the slice.
-		tmp:=make([]string,newlen)          // Allocate a new slice.
-		copy(tmp,p.values)                  // Copy the old values.
-		p.values=tmp                        // Set the new values.
-	}                                     // Done checking for out of range.
-	p.values[i]=value                     // Set the value.
-	// Grow the quotes slice if needed.
-	if int(i)>=len(p.quotes){             // Is the index out of range?
-	  tmp:=make([]byte,int(i)+1)          // Yes, grow the slice.
-		copy(tmp,p.quotes)                  // Copy the old quotes.
-		p.quotes=tmp                        // Set the new quotes.  
-	}                                     // Done checking for out of range.
-	p.quotes[i]=quote                     // Set the quote.
+  if p.readonly{                        // Is it in a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	if i>p.n{                             // Would it leave a gap?
+	  return fmt.Errorf("%w: index %d, parameter %s has %d value%s",ErrIndexGap,i,p.name,p.n,plural(int(p.n)))
+	}                                     // Done checking for a gap.
+	p.ref=""                              // The value is no longer from a file.
+	p.rawJSON=""                          // Nor a JSON document as read.
+	if int(p.n)<len(p.values){            // Anything past the last value?
+	  p.values=p.values[:p.n]             // Yes, it is not a value.
+	}                                     // Done trimming values.
+	for len(p.quotes)<len(p.values){      // A value without a quote?
+	  p.quotes=append(p.quotes,0)         // Give it none.
+	}                                     // Done padding quotes.
+	p.quotes=p.quotes[:len(p.values)]     // One quote per value.
+	if i==p.n{                            // Appending a value?
+	  p.values=append(p.values,value)     // Yes, add it.
+		p.quotes=append(p.quotes,quote)     // And its quote.
+	} else{                               // Else it replaces a value.
+	  p.values[i]=value                   // Set the value.
+		p.quotes[i]=quote                   // Set the quote.
+	}                                     // Done checking for append.
 	p.n=uint(len(p.values))               // We have this many values.
 	return nil                            // Return no error if we got here.   
 }                                       // -------- SetValuePtrOnIndex ------ //
@@ -794,38 +1177,14 @@ func (p *Parameter)GetQuote(i uint) (byte,error){
 // ----------------------------- // Print // -------------------------------- //
 // Print the object to the stream output.
 // -------------------------------------------------------------------------- //
-func (p *Parameter) Print(w io.Writer) (int64,error){
-  var n int64                          // Number of bytes written.
-	for c:=p.comments;c!=nil;c=c.next{   // For each comment listed.
-	  if !c.IsImported() || c.IsImportStatement(){
-		  k,err:=w.Write([]byte(c.value+"\n"))// Buffer to the stream writter.
-			n+=int64(k)                       // Add the number of bytes written.
-			if err!=nil{                      // Any error?
-			  return n,err                    // Yes, return the error.
-			}                                 // Done printing the comment.
-		}                                   // Done checking for import statement.
-	}                                     // Done iterating comment list.
-	var sb strings.Builder                // Where to store the string.
-	sb.WriteString(p.name)                // Write the name to the string.
-	if len(p.values)>0{                   // Any values to print?
-	  sb.WriteString("=")                 // Yes, append the '=' sign.
-		for i,v:=range p.values{            // For each value...
-		  if i>0{                           // First value?
-			  sb.WriteByte(',')               // No, append a comma to multivalued parameter.
-			}                                 // Done checking for first value.
-			q:=p.quotes[i]                    // Get the quote for this value.
-			if q!=0{                          // Any quotes?
-			  sb.WriteByte(q)                 // Yes, append the quote.
-			}                                 // Done checking for quotes.
-			sb.WriteString(v)                 // Append the value.
-			if q!=0{                          // Any quotes?
-			  sb.WriteByte(q)                 // Yes, append the quote.
-			}                                 // Done checking for quotes.
-		}                                   // Done iterating values.
-	}                                     // Done checking for values.
-	sb.WriteByte('\n')                    // Append a newline to the string.
-	k,err:=w.Write([]byte(sb.String()))   // Write the string to the stream.
-	return n+int64(k),err                 // Return # of byte written/error if any.
+func (p *Parameter) Print(w io.Writer) (int64,error){ return p.print(w,false) }
+// ------------------------------ // print // ------------------------------- //
+// Write this Parameter to a stream, its values as "***" if redact is true.   //
+// -------------------------------------------------------------------------- //
+func (p *Parameter) print(w io.Writer,redact bool) (int64,error){
+  var n int64                           // Number of bytes written.
+	err:=p.writeTo(w,&n,redact)           // Stream the parameter.
+	return n,err                          // Return # of byte written/error if any.
 }                                       // ------------- Print ------------- //
 // ============================== // Section // ============================= //
 // A class to store an entire section of a configuration file.                //
@@ -861,8 +1220,38 @@ func (s *Section) GetDirectory() string { return s.cfg.GetDirectory() }
 func (s *Section) GetFilename() string { return s.cfg.GetFilename() }
 func (s *Section) GetName() string { return s.name }
 func (s *Section) GetComments() *Comment { return s.comments }
+// --------------------------- // CommentText // ---------------------------- //
+//  Return the comments above this Section's header as documentation text,    //
+// without the comment prefixes set with SetCommentPrefixes(), or "#".        //
+// -------------------------------------------------------------------------- //
+func (s *Section) CommentText() string{ return commentText(s.comments,s.commentPrefixes()) }
+// ---------------------- // ParameterCommentText // ------------------------ //
+//  Return the comments above a Parameter of this Section, or of a parent, as //
+// documentation text, like CommentText(). Empty if there is no such one.     //
+// -------------------------------------------------------------------------- //
+func (s *Section) ParameterCommentText(name string) string{
+  p:=s.FindParameter(name,true)         // Find the parameter.
+	if p==nil{                            // Did we find it?
+	  return ""                           // No, so no documentation.
+	}                                     // Done checking for parameter.
+	return commentText(p.comments,s.commentPrefixes())// Yes, with our prefixes.
+}                                       // ------ ParameterCommentText ------ //
+// ------------------------- // commentPrefixes // -------------------------- //
+// The comment prefixes of our Configuration, nil for the default.            //
+// -------------------------------------------------------------------------- //
+func (s *Section) commentPrefixes() []string{
+  if s.cfg==nil{                        // Do we belong to a Configuration?
+	  return nil                          // No, use the default.
+	}                                     // Done checking for Configuration.
+	return s.cfg.commentPrefixes          // Yes, use its prefixes.
+}                                       // -------- commentPrefixes --------- //
+// ---------------------------- // readOnly // ------------------------------ //
+//  Return true if this Section belongs to a ReadOnly() view, so the methods  //
+// that change it must refuse, as the view's own Set*() methods do.           //
+// -------------------------------------------------------------------------- //
+func (s *Section) readOnly() bool{ return s.cfg!=nil&&s.cfg.readonly }
 func (s *Section) GetNext() *Section { return s.next }
-func (s *Section) SetNext(p *Section){ if s!=nil{ s.next=p}}
+func (s *Section) SetNext(p *Section){ if s!=nil&&!s.readOnly(){ s.next=p}}
 func (s *Section) GetFirst() *Parameter { return s.first }
 func (s *Section) GetLast() *Parameter { return s.last }
 func (s *Section) GetFirstSection() *Section { return s.firstSection }
@@ -875,6 +1264,7 @@ func (s *Section) GetSelectedParameterName() string{
 	return ""
 }
 func (s *Section) GetSelectedParameter() *Parameter{ return s.current }
+func (s *Section) PeekSelectedParameter() *Parameter{ return s.current }
 func (s *Section) SelectFirstParameter(){ s.current=s.first }
 func (s *Section) SelectParameter(p *Parameter) { s.current=p }
 func (s *Section) SelectParameterByName(name string) error{
@@ -932,6 +1322,103 @@ func (s *Section) FindParameter(name string, searchParents bool) *Parameter{
 	}                                     // Done checking for parent sections.
 	return p                              // Return what we found.
 }                                       // ---------- FindParameter --------- //
+// ------------------------- // RenameParameter // -------------------------- //
+//  Rename a Parameter of this Section in place, keeping its values, comments //
+// and position. Like FindParameter(), names are matched without regard to    //
+// case, so renaming onto another Parameter's name is an error.               //
+// -------------------------------------------------------------------------- //
+func (s *Section) RenameParameter(old, new string) error{
+  if s.readOnly(){                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	new=strings.TrimSpace(new)            // Remove surrounding whitespace.
+	if new==""{                           // Did they give us a new name?
+	  return fmt.Errorf("new name for parameter %s cannot be empty", old)
+	}                                     // Done checking for empty name.
+	p:=s.FindParameter(old,false)         // Find the parameter to rename.
+	if p==nil{                            // Did we find it?
+	  return fmt.Errorf("parameter %s not found in section %s", old, s.name)
+	}                                     // Done checking for parameter.
+	if q:=s.FindParameter(new,false);q!=nil&&q!=p{// Would it collide?
+	  return fmt.Errorf("parameter %s already exists in section %s", new, s.name)
+	}                                     // Done checking for collision.
+	p.name=new                            // Rename the parameter.
+	return nil                            // Success.
+}                                       // -------- RenameParameter --------- //
+// ------------------------------ // Equal // ------------------------------- //
+//  Return true if both Sections have the same name, the same parents and the //
+// same parameters with the same values. Names are compared ignoring case,    //
+// as FindParameter() does; values are compared exactly. Comments, the order  //
+// of the parameters and the quotes around values make no difference, but a   //
+// name given more than once is compared in order: the first x with the first //
+// x, the second with the second, and both must have as many.                 //
+// -------------------------------------------------------------------------- //
+func (s *Section) Equal(other *Section) bool{
+  return s.difference(other)==""        // Equal if we find no difference.
+}                                       // ------------- Equal -------------- //
+// ---------------------------- // difference // ---------------------------- //
+//  Describe the first way other differs from this Section, as Equal() sees   //
+// it, or return "" if it does not.                                           //
+// -------------------------------------------------------------------------- //
+func (s *Section) difference(other *Section) string{
+  if s==nil||other==nil{                // Do we have two sections?
+	  switch{                             // Only equal if both are missing.
+		  case s!=nil:                      // Only ours?
+			  return fmt.Sprintf("section [%s] is missing",s.name)
+			case other!=nil:                  // Only the other one?
+			  return fmt.Sprintf("section [%s] was added",other.name)
+		}                                   // Done checking which is missing.
+		return ""                           // Neither, so no difference.
+	}                                     // Done checking for nil.
+	if !strings.EqualFold(s.name,other.name){// Same name?
+	  return fmt.Sprintf("section [%s] became [%s]",s.name,other.name)
+	}                                     // Done checking names.
+	if !strings.EqualFold(strings.Join(s.parentNames,","),strings.Join(other.parentNames,",")){
+	  return fmt.Sprintf("section [%s] inherits from \"%s\", not \"%s\"",s.name,
+		  strings.Join(other.parentNames,","),strings.Join(s.parentNames,","))
+	}                                     // Done checking parents.
+	seen:=map[string]int{}                // How many of each name we passed.
+	for p:=s.first;p!=nil;p=p.next{       // For each of our parameters...
+	  k:=strings.ToLower(p.name)          // Its name, as FindParameter() sees it.
+		q:=other.nthParameter(p.name,seen[k])// Its twin: as many came before it.
+		seen[k]++                           // One more of that name.
+		if q==nil{                          // Is it there?
+		  return fmt.Sprintf("parameter %s of [%s] is missing",p.name,s.name)
+		}                                   // Done checking the twin.
+		if p.GetNValues()!=q.GetNValues(){  // Same arity?
+		  return fmt.Sprintf("parameter %s of [%s] has %d value%s, not %d",p.name,s.name,
+			  q.GetNValues(),plural(int(q.GetNValues())),p.GetNValues())
+		}                                   // Done checking the arity.
+		for i:=uint(0);i<p.GetNValues();i++{// For each value...
+		  if unquote(p.GetValue(i))!=unquote(q.GetValue(i)){// Same, quotes aside?
+			  return fmt.Sprintf("value %d of %s in [%s] is \"%s\", not \"%s\"",i,p.name,s.name,q.GetValue(i),p.GetValue(i))
+			}                                 // Done checking the value.
+		}                                   // Done iterating values.
+	}                                     // Done iterating parameters.
+	for q:=other.first;q!=nil;q=q.next{   // Does the other one have more?
+	  k:=strings.ToLower(q.name)          // Its name, as we counted them.
+		if seen[k]--;seen[k]<0{             // More of that name than ours?
+		  return fmt.Sprintf("parameter %s of [%s] was added",q.name,s.name)
+		}                                   // Done checking the parameter.
+	}                                     // Done iterating the other's parameters.
+	return ""                             // No difference if we got here.
+}                                       // ----------- difference ----------- //
+// --------------------------- // nthParameter // --------------------------- //
+//  Return the Parameter of this Section named name, ignoring case, that has  //
+// n others of that name before it, or nil if there are not that many. The    //
+// parents are not searched.                                                  //
+// -------------------------------------------------------------------------- //
+func (s *Section) nthParameter(name string,n int) *Parameter{
+  for p:=s.first;p!=nil;p=p.next{       // For each of our parameters...
+	  if strings.EqualFold(p.name,name){  // Does it have that name?
+		  if n==0{                          // Yes, is it the one?
+			  return p                        // Yes, return it.
+			}                                 // Done checking the count.
+			n--                               // No, one less to go.
+		}                                   // Done checking the name.
+	}                                     // Done iterating parameters.
+	return nil                            // There are not that many.
+}                                       // ---------- nthParameter ---------- //
 // ------------------------ // FindNextParameter // ------------------------- //
 //  Find the next Parameter in this section. Return nullptr if we are already //
 // at the last Parameter, or if there are no Parameters.                      //
@@ -944,11 +1431,45 @@ func (s *Section) FindNextParameter() *Parameter{
 	}                                     // Done checking for current parameter.
 	return s.current                      // Return what we currently have.
 }                                       // --------- FindNextParameter ------ //
+// ----------------------- // EffectiveParameters // ----------------------- //
+//  Return every Parameter that applies to this Section: its own, then the    //
+// ones it inherits that it does not override. A name is only listed once,   //
+// from the same place FindParameter(name,true) would find it, so the order   //
+// is ours in file order, then each parent's in the order they were declared. //
+// The Parameters are the real ones, not copies.                              //
+// -------------------------------------------------------------------------- //
+func (s *Section) EffectiveParameters() []*Parameter{
+  var res []*Parameter                  // The parameters that apply here.
+	seen:=map[string]bool{}               // Names we have already listed.
+	visited:=map[*Section]bool{}          // Sections we have already walked.
+	var walk func(sect *Section)          // Walk one section and its parents.
+	walk=func(sect *Section){
+	  if sect==nil||visited[sect]{        // Unresolved parent, or been here?
+		  return                            // Yes, nothing more to add.
+		}                                   // Done checking the section.
+		visited[sect]=true                  // Don't walk it twice.
+		for p:=sect.first;p!=nil;p=p.next{  // For each of its parameters...
+		  key:=strings.ToLower(p.name)      // Names don't care about case.
+			if !seen[key]{                    // Overridden by something closer?
+			  seen[key]=true                  // No, it applies here.
+				res=append(res,p)               // Add it to the list.
+			}                                 // Done checking for override.
+		}                                   // Done iterating parameters.
+		for i:=0;i<int(sect.nParents);i++{  // Then for each of its parents...
+		  walk(sect.parents[i])             // ...add what they have.
+		}                                   // Done iterating parents.
+	}                                     // Done defining walk.
+	walk(s)                               // Start with ourselves.
+	return res                            // Return the effective parameters.
+}                                       // ------- EffectiveParameters ------ //
 // ------------------------- // ClearParameters // -------------------------- //
 // Erase all Parameter objects from this section.                             //
 // -------------------------------------------------------------------------- //
 func (s *Section) ClearParameters() error{
-  for p:=s.first;p!=nil;p=p.GetNext(){  // For each parameter in our list...
+  if s.readOnly(){                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	for p:=s.first;p!=nil;p=p.GetNext(){  // For each parameter in our list...
 	  s.first,s.last,s.current=nil,nil,nil// Clear the list.
 		s.nParameters=0                     // Reset our count to 0.
 	}                                     // Done iterating through the list.
@@ -969,11 +1490,18 @@ func (s *Section) GetParentName (n uint) string{
 	}                                     // Done checking for parents.
 }                                       // ----------- GetParentName --------- //
 func (s *Section) RemoveMissingParent (i uint){
+  if s.readOnly(){                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	s.removeMissingParent(i)              // Remove it.
+}                                       // ------ RemoveMissingParent ------ //
+// RemoveMissingParent() with no questions asked, for resolveParents().
+func (s *Section) removeMissingParent(i uint){
   for j:=i;j<s.nParents;j++{            // For each parent...
 	  s.parents[i]=s.parents[j]           // Move the next parent into this one.
 	}                                     // Done moving parents.
 	s.nParents--                          // Decrement the number of parents.                              
-}                                       // ------ RemoveMissingParent ------ //
+}                                       // ------ removeMissingParent ------ //
 // -------------------------- // SetParentNames // -------------------------- //
 // SetParentNames stores the literal parent names that appear after the ':'
 // in a section header.  This is normally only called by ReadFile() in the
@@ -989,6 +1517,16 @@ func (s *Section) RemoveMissingParent (i uint){
 //   s.parents     == make([]*Section, 2)   // filled in ResolveParents()
 // -------------------------------------------------------------------------- //
 func (s *Section) SetParentNames(list string){
+  if s.readOnly(){                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	s.setParentNames(list)                // Store them.
+}                                       // --------- SetParentNames --------- //
+// --------------------------- // setParentNames // ------------------------- //
+//  SetParentNames() with no questions asked, for a Section being built, even //
+// in a read-only view, as copySections() does.                               //
+// -------------------------------------------------------------------------- //
+func (s *Section) setParentNames(list string){
     // -------------------------------- //
 	  // If this method is called more than once, wipe the old data first.
 	  // -------------------------------- //
@@ -1019,10 +1557,20 @@ func (s *Section) SetParentNames(list string){
 	// calling resolveParent() in the ReadFile() method.
 	// ---------------------------------- //
 	s.parents=make([]*Section,s.nParents) // Allocate space for the parent sections.
-}                                       // --------- SetParentNames --------- //
+}                                       // --------- setParentNames --------- //
+// --------------------------- // ParentNames // ---------------------------- //
+//  Return the names of the parents this Section declared, in the order they  //
+// were declared. The slice is a copy, so changing it changes nothing here.   //
+// -------------------------------------------------------------------------- //
+func (s *Section) ParentNames() []string{
+  return append([]string(nil),s.parentNames...)// Copy of the declared names.
+}                                       // ---------- ParentNames ----------- //
 // This should only be called by ReadFile.
 func (s *Section) SetParentSection(i uint, p *Section){
-  s.parents[i]=p                        // Set the parent section.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	s.parents[i]=p                        // Set the parent section.
 }                                       // -------- SetParentSection -------- //
 // ------------------------------ // Append // ------------------------------ //
 //  Append a new Section to the list; return a pointer to that new Section.   //
@@ -1031,7 +1579,10 @@ func (s *Section) SetParentSection(i uint, p *Section){
 // Section at the end of the list.  *** old (see commented-out lines) ***     //
 // -------------------------------------------------------------------------- //
 func (s *Section) Append(name string, imported bool) *Section{
-  newsect:=NewSection(s.cfg,name,s.comments,imported)// A new Section object.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return nil                          // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	newsect:=NewSection(s.cfg,name,s.comments,imported)// A new Section object.
 	if s!=nil{                            // Any previous section to append to?
 	  s.next=newsect                      // Yes, append new section to the list.
 	}                                     // Done checking for previous section.
@@ -1043,7 +1594,10 @@ func (s *Section) Append(name string, imported bool) *Section{
 // deleting that copy.                                                        //
 // -------------------------------------------------------------------------- //
 func (s *Section) Append2(p *Parameter) *Parameter{
-  q:=CopyParameter(p)                   // Create a Parameter object copy.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return nil                          // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	q:=CopyParameter(p)                   // Create a Parameter object copy.
 	if s.first==nil{                      // Any parameter in the list?
 	  s.first=q                           // No, this is the first one.
 	} else{                               // Else we have parameters in the list.
@@ -1061,7 +1615,70 @@ func (s *Section) Append2(p *Parameter) *Parameter{
 // want to leak memory. But Go does garbage collection so it is not a problem.
 // -------------------------------------------------------------------------- //
 func (s *Section) AppendParameter(name, valuestr string, comments *Comment,imported bool) *Parameter{
-  p:=NewParameter(name,valuestr,comments,imported)// A new Parameter object.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return nil                          // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	p:=NewParameter(name,valuestr,comments,imported)// A new Parameter object.
+	return s.linkParameter(p)             // Link it at the end of the list.
+}                                       // --------- AppendParameter -------- //
+// ------------------------ // AppendLiteralParameter // -------------------- //
+//  Append a new Parameter holding exactly one value, written as is. Unlike   //
+// AppendParameter() the value is not parsed, so quotes in it are kept. This  //
+// is meant for building a configuration programmatically; quote is the quote //
+// character to write the value with, or 0 for none. If the value holds that  //
+// quote, the other one is used, as SetValueInFormat() does, or none if it    //
+// holds both. Reading a file splits a value at every comma, quoted or not,   //
+// so a value with a comma cannot be written and read back whole; nor can one //
+// with blanks at either end that holds both quotes. Such a value fails with  //
+// ErrUnwritableValue and nothing is appended.                                //
+// -------------------------------------------------------------------------- //
+func (s *Section) AppendLiteralParameter(name, value string, quote byte) (*Parameter,error){
+  if s.readOnly(){                      // Is this a read-only view?
+	  return nil,ErrReadOnly              // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	quote,err:=literalQuote(value,quote)  // Can it be written, and how?
+	if err!=nil{                          // Can it?
+	  return nil,s.configError("set",name,err)// No, say which parameter.
+	}                                     // Done checking the value.
+	return s.appendLiteral(name,value,quote),nil// Append it.
+}                                       // ----- AppendLiteralParameter ----- //
+// --------------------------- // appendLiteral // -------------------------- //
+//  Append a new Parameter holding exactly one value, with no questions asked, //
+// for a Section that is never written, as GetValueSubConfig() builds.        //
+// -------------------------------------------------------------------------- //
+func (s *Section) appendLiteral(name, value string, quote byte) *Parameter{
+  p:=NewParameter(name,"",nil,false)    // A new Parameter with no values.
+	_=p.SetValuePtr(value,quote)          // Store value as the only value.
+	return s.linkParameter(p)             // Link it at the end of the list.
+}                                       // --------- appendLiteral ---------- //
+// --------------------------- // literalQuote // --------------------------- //
+//  Return the quote to write a literal value with, given the one asked for,  //
+// or ErrUnwritableValue if no quote makes it read back as that one value.    //
+// -------------------------------------------------------------------------- //
+func literalQuote(value string,quote byte) (byte,error){
+  if strings.ContainsRune(value,','){   // Would reading split it?
+	  return 0,ErrUnwritableValue         // Yes, it can't be written whole.
+	}                                     // Done checking for commas.
+	if quote!=0&&strings.IndexByte(value,quote)<0{// Can the quote enclose it?
+	  return quote,nil                    // Yes, use it.
+	}                                     // Done checking the quote asked for.
+	if quote==0&&strings.TrimSpace(value)==value{// Is it fine unquoted?
+	  return 0,nil                        // Yes, write it as is.
+	}                                     // Done checking for no quotes.
+	switch{                               // Act according to the value.
+	  case !strings.ContainsRune(value,'"'):// No double quote in it?
+		  return '"',nil                    // Then double quotes will do.
+		case !strings.ContainsRune(value,'\''):// No single quote in it?
+		  return '\'',nil                   // Then single quotes will do.
+		case strings.TrimSpace(value)==value:// Both, but no blanks to keep?
+		  return 0,nil                      // Then it can go unquoted.
+	}                                     // Done acting according to the value.
+	return 0,ErrUnwritableValue           // Its blanks would be trimmed.
+}                                       // ---------- literalQuote ---------- //
+// --------------------------- // linkParameter // -------------------------- //
+// Place p at the end of this Section's list of Parameters.                   //
+// -------------------------------------------------------------------------- //
+func (s *Section) linkParameter(p *Parameter) *Parameter{
 	if s.first==nil{                      // Any parameter in the list?
 	  s.first=p                           // No this is the first one.
 	} else{                               // Else we have parameters in the list.
@@ -1070,12 +1687,22 @@ func (s *Section) AppendParameter(name, valuestr string, comments *Comment,impor
 	s.last=p                              // But now p is the new last one.
 	s.nParameters++                       // Always keep track of # of Parameters.
 	return p                              // Return the appended Parameter object.
-}                                       // --------- AppendParameter -------- //
+}                                       // ---------- linkParameter --------- //
 // ----------------------------- // AppendSection // ------------------------ //
 // Append a Section to this Section. This will be a copy of the Section stored
 // somewhere else but with different links.
 // -------------------------------------------------------------------------- //
 func (s *Section) AppendSection(name string, imported bool){
+  if s.readOnly(){                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	s.appendSection(name,imported)        // Append it.
+}                                       // --------- AppendSection ---------- //
+// --------------------------- // appendSection // -------------------------- //
+//  AppendSection() with no questions asked, for a Section being built, even  //
+// in a read-only view, as copySections() does.                               //
+// -------------------------------------------------------------------------- //
+func (s *Section) appendSection(name string, imported bool){
   newsect:=NewSection(s.cfg,name,s.comments,imported)// A new section object.
 	if s.firstSection==nil{               // Any section in the list?
 	  s.firstSection=newsect              // No, this is the first one.
@@ -1084,7 +1711,7 @@ func (s *Section) AppendSection(name string, imported bool){
 	}                                     // Done checking for previous section.
 	s.lastSection=newsect                 // But now newsect is the last one.
 	s.nSections++                         // Always keep track of # of sections.
-}                                       // --------- AppendSection ---------- //
+}                                       // --------- appendSection ---------- //
 // ------------------------------ // SetValue // ---------------------------- //
 //  Set the value of an existing Parameter. This is used by the application   //
 // to change parameters that can be modified. The application is not allowed  //
@@ -1096,29 +1723,49 @@ func (s *Section) AppendSection(name string, imported bool){
 // keep trying to go up the hierarchy until you find the parameter.           //
 // -------------------------------------------------------------------------- //
 func (s *Section) SetValue(name, value string, quote byte) error{
-  p:=s.FindParameter(name,false)        // Find the parameter in this section.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	p:=s.FindParameter(name,false)        // Find the parameter in this section.
 	if p!=nil{                            // Did we find it?
-	  return p.SetValue(value,quote)      // Yes, set the value.
+	  old:=p.GetValues()                  // Yes, remember what it was...
+		err:=s.cfg.splitValue(p,value,quote)// ...set the value...
+		s.cfg.changed(s.name,p.name,old,p.GetValues())// ...and say if it changed.
+		return s.configError("set",name,err)// Return error if any.
 	}                                     // Done checking if we found it.
-	return fmt.Errorf("parameter %s not found in section %s", name, s.name)// No, return error.
+	return s.configError("set",name,ErrParameterNotFound)// No, return error.
 }                                       // ----------- SetValue ------------ //
 func (s *Section) SetValuePtr(name,value string, quote byte) error{
-  p:=s.FindParameter(name,false)        // Find the parameter in this section.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	p:=s.FindParameter(name,false)        // Find the parameter in this section.
 	if p!=nil{                            // Did we find it?
-	  return p.SetValuePtr(value,quote)   // Yes, set the value.
+	  old:=p.GetValues()                  // Yes, remember what it was...
+		err:=p.SetValuePtr(value,quote)     // ...set the value...
+		s.cfg.changed(s.name,p.name,old,p.GetValues())// ...and say if it changed.
+		return s.configError("set",name,err)// Return error if any.
 	}                                     // Done checking if we found it.
-	return fmt.Errorf("parameter %s not found in section %s", name, s.name)// No, return error.
+	return s.configError("set",name,ErrParameterNotFound)// No, return error.
 }                                       // ----------- SetValuePtr --------- //
 func (s *Section) SetValuePtrOnIndex(name,value string, i uint, quote byte) error{
-  p:=s.FindParameter(name,false)          // Find the parameter in this section.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	p:=s.FindParameter(name,false)        // Find the parameter in this section.
 	if p!=nil{                            // Did we find it?
-	  return p.SetValuePtrOnIndex(i,value,quote)// Yes, set the value.
+	  old:=p.GetValues()                  // Yes, remember what it was...
+		err:=p.SetValuePtrOnIndex(i,value,quote)// ...set the value...
+		s.cfg.changed(s.name,p.name,old,p.GetValues())// ...and say if it changed.
+		return s.configError("set",name,err)// Return error if any.
 	}                                     // Done checking if we found it.
-	return fmt.Errorf("parameter %s not found in section %s", name, s.name)// No, return error.
+	return s.configError("set",name,ErrParameterNotFound)// No, return error.
 }                                       // --------- SetValuePtrOnIndex ----- //
 
 // ---------------------------- // GetNValues // ---------------------------- //
-// Return the number of values in the given Parameter of this Section.        //
+// Return the number of values in the given Parameter of this Section, or of  //
+// the one it inherits from a parent Section, which is the same Parameter     //
+// GetValue() reads from.                                                     //
 // Note: If the Parameter is not found, or if none is given and none is       //
 //       selected, then this method just returns zero. This is the same       //
 //       result as if the Parameter exists but has no values.                 //
@@ -1166,6 +1813,59 @@ func (s *Section) GetValue(name string, i uint) string{
 	}                                     // Done checking for parameter.
 	return ""                             // Otherwise return empty string.
 }                                       // ----------- GetValue ------------ //
+// ----------------------------- // Lookup // ------------------------------- //
+//  Like GetValue() for the first value, but like os.LookupEnv() it also says //
+// whether the parameter exists at all, so an explicitly empty parameter can  //
+// be told apart from a missing one. Parents are searched too.               //
+// -------------------------------------------------------------------------- //
+func (s *Section) Lookup(name string) (value string, found bool){
+  p:=s.FindParameter(name,true)         // Find the parameter in this section.
+	if p==nil{                            // Did we find it?
+	  return "",false                     // No, it genuinely does not exist.
+	}                                     // Done checking for parameter.
+	return p.GetValue(0),true             // Yes, return its value, empty or not.
+}                                       // ------------ Lookup -------------- //
+// --------------------------- // presentValue // --------------------------- //
+//  Get value i of a parameter for the typed getters, which need something to //
+// decode: a missing parameter is ErrParameterNotFound, a missing value i is  //
+// out of range and a parameter that is there but empty is ErrEmptyValue.     //
+// -------------------------------------------------------------------------- //
+func (s *Section) presentValue(name string, i uint) (string, error){
+  p:=s.FindParameter(name,true)         // Find the parameter in this section.
+	switch{                               // Act according to what we found.
+	  case p==nil:                        // Is it there at all?
+		  return "",ErrParameterNotFound    // No, say so.
+		case i>=p.n&&(i>0||p.n>0):          // A value the parameter lacks?
+		  return "",fmt.Errorf("index %d out of range", i)
+		case p.GetValue(i)=="":             // There, but empty?
+		  return "",ErrEmptyValue           // Yes, nothing to decode.
+	}                                     // Done acting on what we found.
+	return p.GetValue(i),nil              // Return the value.
+}                                       // ---------- presentValue ---------- //
+// -------------------------- // GetValueQuote // ---------------------------- //
+//  Get the quote character that surrounded value i of a parameter in the     //
+// source: '"', '\'' or 0 if the value was not quoted. A value read from a    //
+// file keeps its quotes, so it is the quote at both ends of the value; a     //
+// value set with a quote reports that quote. Unlike GetQuote(), it does not  //
+// guess a quote for values that had none.                                    //
+// -------------------------------------------------------------------------- //
+func (s *Section) GetValueQuote(name string, i uint) (byte, error){
+  p:=s.FindParameter(name,true)         // Find the parameter in this section.
+	if p==nil{                            // Did we find it?
+	  return 0,s.configError("get",name,ErrParameterNotFound)
+	}                                     // Done checking for parameter.
+	if i>=p.n{                            // Subscript out of range?
+	  return 0,s.configError("get",name,fmt.Errorf("index %d out of range, parameter %s has %d value%s",i,name,p.n,plural(int(p.n))))
+	}                                     // Done checking for out of range subscript.
+	if int(i)<len(p.quotes)&&p.quotes[i]!=0{// Was it set with a quote?
+	  return p.quotes[i],nil              // Yes, return it.
+	}                                     // Done checking for stored quote.
+	v:=p.values[i]                        // No, look at the value as read.
+	if len(v)>1&&(v[0]=='"'||v[0]=='\'')&&v[len(v)-1]==v[0]{// Is it quoted?
+	  return v[0],nil                     // Yes, return its quote.
+	}                                     // Done checking for quotes.
+	return 0,nil                          // It was not quoted.
+}                                       // --------- GetValueQuote ---------- //
 
 // --------------------------- // GetValue // ------------------------------- //
 // Get the value from a given Parameter pertaining to this section.
@@ -1173,94 +1873,92 @@ func (s *Section) GetValue(name string, i uint) string{
 
 // ----------------------- Byte values (character values) ------------------- //
 func (s *Section)	GetValueByte(name string, dest *byte) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{
-	  return fmt.Errorf("can't decode empty \"value\" to byte")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{
+	  return s.configError("get",name,err)
 	}
-	return s.scanValue(p,"%c",dest) 
+	return s.configError("get",name,s.scanValue(p,"%c",dest))
 }
 func (s *Section)	GetValueByteByIndex(name string,i uint,dest *byte) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
 	}
-	return s.scanValueByIndex(name,int(i),"%c",dest)
+	return s.configError("get",name,s.scanValueByIndex(name,int(i),"%c",dest))
 }
 
 // -------------------------- Times and durations -------------------------- //
-func (s *Section)	GetValueTimespec(name string, dest *unix.Timespec) error{
-  p:=s.GetValue(name,0)                 
-  if len(p)==0{                         // Where we given a value to decode?
-	  return fmt.Errorf("can't decode empty \"value\" to unix.Timespec")// No, that's bad.
+func (s *Section)	GetValueTimespec(name string, dest *Timespec) error{
+  p,err:=s.presentValue(name,0)
+  if err!=nil{                          // Where we given a value to decode?
+	  return s.configError("get",name,err)
 	}                                     // Done checking for empty value.
 	t,err:=time.Parse(time.RFC3339,p) // Parse the value as a time.
 	if err!=nil{                          // Any error parsing the time?
-	  return fmt.Errorf("can't decode \"%s\" to unix.Timespec: %v", p, err)
+	  return s.configError("get",name,fmt.Errorf("can't decode \"%s\" to unix.Timespec: %v", p, err))
 	}                                     // Done checking for parse error.
-	unix.NsecToTimespec(t.UnixNano()) 	  // Convert the time to a unix.Timespec.
-	*dest=unix.NsecToTimespec(t.UnixNano()) // Set the destination time to the parsed time.
+	*dest=nsecToTimespec(t.UnixNano())     // Set the destination time to the parsed time.
 	return nil                            // Return nil if we got here.
 }
-func (s *Section)	GetValueTimespecByIndex(name string,i uint,dest *unix.Timespec) error{
-  p:=s.GetValue(name,i)                 // Get the value for the given name.
-	if i>=uint(len(p)){                   // Is the index out of range?
-	  return fmt.Errorf("index %d out of range", i)// Yes, panic.
-	}                                     // Done checking for out of range index.
+func (s *Section)	GetValueTimespecByIndex(name string,i uint,dest *Timespec) error{
+  p,err:=s.presentValue(name,i)         // Get the value at the index.
+	if err!=nil{                          // Is it there, and not empty?
+	  return s.configError("get",name,err)// No, return error.
+	}                                     // Done checking for the value.
 	q:=p                                  // Get the value at the index.
 	t,err:=time.Parse(time.RFC3339,q)     // Parse the value as a time.
 	if err!=nil{                          // Any error parsing the time?
-	  return fmt.Errorf("can't decode \"%s\" to time.Time: %v", q, err)
+	  return s.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Time: %v", q, err))
 	}                                     // Done checking for parse error.
-	unix.NsecToTimespec(t.UnixNano()) 	  // Convert the time to a unix.Timespec.
-	*dest=unix.NsecToTimespec(t.UnixNano()) // Set the destination time to the parsed time.
+	*dest=nsecToTimespec(t.UnixNano())     // Set the destination time to the parsed time.
 	return nil                            // Return nil if we got here.
 }
 func (s *Section)	GetValueDuration(name string, dest *time.Duration) error{
-  p:=s.GetValue(name,0)                 // Get the value for the given name.
-	if len(p)==0{                         // Where we given a value to decode?
-	  return fmt.Errorf("can't decode empty \"value\" to time.Duration")// No, that's bad.
+  p,err:=s.presentValue(name,0)         // Get the value for the given name.
+	if err!=nil{                          // Where we given a value to decode?
+	  return s.configError("get",name,err)
 	}                                     // Done checking for empty value.
 	d,err:=time.ParseDuration(p)          // Parse the value as a duration.
 	if err!=nil{                          // Any error parsing the duration?
-	  return fmt.Errorf("can't decode \"%s\" to time.Duration: %v", name, err)
+	  return s.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Duration: %v", name, err))
 	}                                     // Done checking for parse error.
 	*dest=d                               // Set the destination duration to the parsed duration.
 	return nil                            // Return nil if we got here.  
 }
 func (s *Section)	GetValueDurationByIndex(name string,i uint,dest *time.Duration) error{
-  p:=s.GetValue(name,i)						      // Get the value for the given name.
-	if i>=uint(len(p)){                   // Is the index out of range?
-	  return fmt.Errorf("index %d out of range", i)// Yes, panic.
-	}                                     // Done checking for out of range index.
+  p,err:=s.presentValue(name,i)         // Get the value at the index.
+	if err!=nil{                          // Is it there, and not empty?
+	  return s.configError("get",name,err)// No, return error.
+	}                                     // Done checking for the value.
 	q:=p                                  // Get the value at the index.
 	d,err:=time.ParseDuration(q)          // Parse the value as a duration.
 	if err!=nil{                          // Any error parsing the duration?
-	  return fmt.Errorf("can't decode \"%s\" to time.Duration: %v", q, err)
+	  return s.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Duration: %v", q, err))
 	}                                     // Done checking for parse error.
 	*dest=d                               // Set the destination duration to the parsed duration.
 	return nil                            // Return nil if we got here. 
 }
 // Time since epoch
 func (s *Section)	GetValueTime(name string, dest *time.Time) error{
-  p:=s.GetValue(name,0)                 // Get the value for the given name.
-	if len(p)==0{                         // Where we given a value to decode?
-	  return fmt.Errorf("can't decode empty \"value\" to time.Time")// No, that's bad.
+  p,err:=s.presentValue(name,0)         // Get the value for the given name.
+	if err!=nil{                          // Where we given a value to decode?
+	  return s.configError("get",name,err)
 	}                                     // Done checking for empty value.
 	t,err:=time.Parse(time.RFC3339,p)     // Parse the value as a time.
 	if err!=nil{                          // Any error parsing the time?
-	  return fmt.Errorf("can't decode \"%s\" to time.Time: %v", name, err)
+	  return s.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Time: %v", name, err))
 	}                                     // Done checking for parse error.
 	*dest=t                               // Set the destination time to the parsed time.
 	return nil                            // Return nil if we got here.	
 	}
 func (s *Section)	GetValueTimeByIndex(name string, i uint,dest *time.Time) error{
-  p:=s.GetValue(name,i)                 // Get the value for the given name.
-	if i>=uint(len(p)){                   // Is the index out of range?
-	  return fmt.Errorf("index %d out of range", i)// Yes, panic.
-	}                                     // Done checking for out of range index.
+  p,err:=s.presentValue(name,i)         // Get the value at the index.
+	if err!=nil{                          // Is it there, and not empty?
+	  return s.configError("get",name,err)// No, return error.
+	}                                     // Done checking for the value.
 	q:=p                                  // Get the value at the index.
 	t,err:=time.Parse(time.RFC3339,q)     // Parse the value as a time.
 	if err!=nil{                          // Any error parsing the time?
-	  return fmt.Errorf("can't decode \"%s\" to time.Time: %v", q, err)
+	  return s.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Time: %v", q, err))
 	}                                     // Done checking for parse error.
 	sec,nsec:=t.UnixNano()/1e9,t.UnixNano()%1e9 // Get seconds and nanoseconds.
 	*dest=time.Unix(sec,nsec).In(t.Location())        
@@ -1269,265 +1967,350 @@ func (s *Section)	GetValueTimeByIndex(name string, i uint,dest *time.Time) error
 
 // -------------------------- Signed Integers ------------------------------- //
 func (s *Section)	GetValueInt(name string, dest *int) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to int")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest)     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueIntByIndex(name string,i uint,dest *int) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	} 
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt8(name string, dest *int8) error{
-	p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to int8")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt8ByIndex(name string,i uint,dest *int8) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt16(name string, dest *int16) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to int16")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt16ByIndex(name string,i uint,dest *int16) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	}                                  
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt32(name string, dest *int32) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to int32")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt32ByIndex(name string,i uint,dest *int32) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	}                                
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt64(name string, dest *int64) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to int64")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueInt64ByIndex(name string,i uint,dest *int64) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	}                                 
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 
 // ------------------- Unicode, binary and hex values ----------------------- //
 func (s *Section) GetValueRune(name string, dest *rune) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to rune")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%c",dest) 
+	return s.configError("get",name,s.scanValue(p,"%c",dest))
 }
 func (s *Section)	GetValueRuneByIndex(name string,i uint,dest *rune) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
 	}                                    
-	return s.scanValueByIndex(name,int(i),"%c",dest) 
+	return s.configError("get",name,s.scanValueByIndex(name,int(i),"%c",dest))
 }
 func (s *Section)	GetValueBinary(name string, dest *string) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to binary string")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%b",dest) 
+	return s.configError("get",name,s.scanValue(p,"%b",dest))
 }
 func (s *Section)	GetValueHex(name string, dest *string) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to hex string")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%x",dest) 
+	return s.configError("get",name,s.scanValue(p,"%x",dest))
 }
 func (s *Section)	GetValueOctal(name string, dest *string) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to octal string")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%o",dest) 
+	return s.configError("get",name,s.scanValue(p,"%o",dest))
 }
 
 // ----------------------- Unsigned integers -------------------------------- //
 func (s *Section)	  GetValueUint(name string, dest *uint) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to uint")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUintByIndex(name string,i uint,dest *uint) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint8(name string, dest *uint8) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to uint8")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint8ByIndex(name string,i uint,dest *uint8) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	}                                    
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint16(name string, dest *uint16) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to uint16")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint16ByIndex(name string,i uint, dest *uint16) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint32(name string, dest *uint32) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to uint32")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint32ByIndex(name string,i uint,dest *uint32) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	}                                 
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint64(name string, dest *uint64) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to uint64")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%d",dest) 
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 func (s *Section)	GetValueUint64ByIndex(name string,i uint,dest *uint64) error{
-  if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	}                                    
-	return s.scanValueByIndex(name,int(i),"%d",dest) 
+  p,err:=s.presentValue(name,i)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	return s.configError("get",name,decodeInteger(p,dest,s.cfg.GetIntBase()))
 }
 
 // ------------------------- Floating point values -------------------------- //
+// -------------------------- // floatSpecials // --------------------------- //
+// True if our Configuration lets the float getters accept nan and inf.       //
+// -------------------------------------------------------------------------- //
+func (s *Section) floatSpecials() bool{
+  return s.cfg!=nil&&s.cfg.GetFloatSpecials()// Sections of nothing don't.
+}                                       // --------- floatSpecials ---------- //
+// ------------------------- // getFloatByIndex // -------------------------- //
+// Decode value i of a parameter, found here or in a parent, as a float.      //
+// -------------------------------------------------------------------------- //
+func (s *Section) getFloatByIndex(name string,i uint,bits int) (float64,error){
+  p:=s.FindParameter(name,true)         // Find the parameter in this section.
+	if p==nil{                            // Did we find the parameter?
+	  return 0,fmt.Errorf("parameter %s not found in section %s", name, s.name)// No, return error.
+	}                                     // Done checking for parameter.
+	if i>=p.GetNValues(){                 // Within range?
+	  return 0,fmt.Errorf("index %d out of range", i)
+	}                                     // Done checking for out of range.
+	return parseFloat(p.GetValue(i),bits,s.floatSpecials())// Decode the value.
+}                                       // -------- getFloatByIndex --------- //
 func (s *Section)	GetValueFloat32(name string, dest *float32) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to float32")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%f",dest)     
+	f,err:=parseFloat(p,32,s.floatSpecials())// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
 }
 func (s *Section)	GetValueFloat32ByIndex(name string,i uint,dest *float32) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
 	}                                  
-	return s.scanValueByIndex(name,int(i),"%f",dest) 
+	f,err:=s.getFloatByIndex(name,i,32)   // Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
 }
 func (s *Section)	GetValueFloat64(name string,dest *float64) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to float64")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
 	}                                     
-	return s.scanValue(p,"%f",dest)     
+	f,err:=parseFloat(p,64,s.floatSpecials())// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
 }
 func (s *Section)	GetValueFloat64ByIndex(name string,i uint,dest *float64) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	} 
-	return s.scanValueByIndex(name,int(i),"%f",dest)
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
+	}                                  
+	f,err:=s.getFloatByIndex(name,i,64)   // Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
 }
 
 // Floating point values with precision
 func (s *Section)	GetValuePrecisionFloat32(name,precision string,dest *float32) error{
-  p:=s.GetValue(name,0)
-	if len(p)==0{
-	  return fmt.Errorf("can't decode empty \"value\" to float32 with precision %s", precision)
-	}
-	return s.scanValue(p,fmt.Sprintf("%%.%sf", precision),dest)
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	f,err:=parseFloat(p,32,s.floatSpecials())// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
 }
 func (s *Section)	GetValuePrecisionFloat32ByIndex(name string,i uint,precision string,dest *float32) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	} 
-	return s.scanValueByIndex(name,int(i),fmt.Sprintf("%%.%sf", precision),dest)
-}
-func (s *Section)	GetValuePrecisionFloat64(name string,value,precision string,dest *float64) error{
-  p:=s.GetValue(name,0)
-	if len(p)==0{
-	  return fmt.Errorf("can't decode empty \"value\" to float64 with precision %s", precision)
-	}
-	return s.scanValue(p,fmt.Sprintf("%%.%sf", precision),dest)
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
+	}                                  
+	f,err:=s.getFloatByIndex(name,i,32)   // Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
+}
+func (s *Section)	GetValuePrecisionFloat64(name,precision string,dest *float64) error{
+  p,err:=s.presentValue(name,0)
+	if err!=nil{                          
+	  return s.configError("get",name,err)
+	}                                     
+	f,err:=parseFloat(p,64,s.floatSpecials())// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
 }
 func (s *Section)	GetValuePrecisionFloat64ByIndex(name string,i uint,precision string,dest *float64) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	} 
-	return s.scanValueByIndex(name,int(i),fmt.Sprintf("%%.%sf", precision),dest)
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
+	}                                  
+	f,err:=s.getFloatByIndex(name,i,64)   // Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return s.configError("get",name,err) // Return error if any.
 }
 
 // --------------------------- Complex numbers ------------------------------ //
 func (s *Section)	GetValueComplex64(name string,dest *complex64) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to complex64")
-	}                                     
-	return s.scanValue(p,"%v",dest)     
+  c,err:=parseComplex(s.GetValue(name,0),64)// Decode it.
+	if err!=nil{                          // Did it decode?
+	  return s.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=complex64(c)                    // Store it.
+	return nil                            // Done.
 }
 func (s *Section)	GetValueComplex64ByIndex(name string,i uint,dest *complex64) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
 	}                                   
-	return s.scanValueByIndex(name,int(i),"%v",dest) 
+	c,err:=parseComplex(s.GetValue(name,i),64)// Decode it.
+	if err!=nil{                          // Did it decode?
+	  return s.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=complex64(c)                    // Store it.
+	return nil                            // Done.
 }
 func (s *Section)	GetValueComplex128(name string,dest *complex128) error{
-  p:=s.GetValue(name,0)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to complex128")
-	}                                     
-	return s.scanValue(p,"%v",dest)     
+  c,err:=parseComplex(s.GetValue(name,0),128)// Decode it.
+	if err!=nil{                          // Did it decode?
+	  return s.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=c                               // Store it.
+	return nil                            // Done.
 }
 func (s *Section)	GetValueComplex128ByIndex(name string,i uint,dest *complex128) error{
   if len(name)==0{
-	  return fmt.Errorf("name cannot be empty")
-	}                                    
-	return s.scanValueByIndex(name,int(i),"%v",dest) 
+	  return s.configError("get",name,fmt.Errorf("name cannot be empty"))
+	}                                   
+	c,err:=parseComplex(s.GetValue(name,i),128)// Decode it.
+	if err!=nil{                          // Did it decode?
+	  return s.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=c                               // Store it.
+	return nil                            // Done.
 }
 
 // --------------------------- Scientific notation -------------------------- //
 func (s *Section)	GetValueSI(name string,dest *string) error{
-  p:=s.GetValue(name,0)
-	if len(p)==0{
-	  return fmt.Errorf("can't decode empty \"value\" to scientific notation")
+  p,err:=s.presentValue(name,0)
+	if err!=nil{
+	  return s.configError("get",name,err)
 	}
-	return s.scanValue(p,"%e",dest)
+	return s.configError("get",name,s.scanValue(p,"%e",dest))
 }
 // ----------------------------- // GetValueBool // ------------------------- //
 //  Get the value of a bool parameter. If values are given for true and       //
@@ -1561,69 +2344,30 @@ func (s *Section) GetValueBool(name string,i uint,tval string, fval string) (res
 			}                                 // Done checking if can decode p.
 		}                                   // Done checking if caller gave values.
 	}                                     // Done checking for value.
-  return result,err                     // Return result and error if any.
+  return result,s.configError("get",name,err)// Return result and error if any.
 }                                       // ----------- GetValueBool --------- //
 // ------------------------------ // Print // ------------------------------- //
-// Write this Section object to a stream.                                     //
+// Write this Section object to a stream, with the values of the parameters   //
+// marked by MarkSecret() redacted unless PrintSecrets(true) was called.      //
 // -------------------------------------------------------------------------- //
 func (s *Section) Print(w io.Writer) (int64,error){
+  return s.print(w,s.cfg!=nil&&!s.cfg.showSecrets)// Redact unless asked not to.
+}                                       // ------------- Print ------------- //
+// ------------------------------ // String // ------------------------------ //
+// Return what Print() writes, so "%v" redacts secrets too.                   //
+// -------------------------------------------------------------------------- //
+func (s *Section) String() string{
+  var sb strings.Builder                // Where to print the section.
+	s.Print(&sb)                          // Print it, a Builder never fails.
+	return sb.String()                    // Return what we printed.
+}                                       // ------------- String ------------ //
+// ------------------------------ // print // ------------------------------- //
+// Write this Section object to a stream, redacting secrets if redact is true.//
+// -------------------------------------------------------------------------- //
+func (s *Section) print(w io.Writer,redact bool) (int64,error){
   var n int64                           // Number of bytes written.
-	for c:=s.comments;c!=nil;c=c.GetNext(){// For each comment listed.
-	  if !c.IsImported()||c.IsImportStatement(){// Is it an import statement?
-		  k,err:=w.Write([]byte(c.value+"\n"))// Buffer to the stream writter.
-			n+=int64(k)                       // Add the number of bytes written.
-			if err!=nil{                      // Any error?
-			  return n,err                    // Yes, return the error.
-			}                                 // Done printing the comment.
-		}                                   // Done checking for import statement.
-	}                                     // Done iterating comment list.
-	// ---------------------------------- //
-	// Now we need to print the section header.
-	// ---------------------------------- //
-	header:=s.name                        // The section name.
-	if s.nParents>0{                      // Any parents?
-	  header=fmt.Sprintf("%s:%s",s.name,strings.Join(s.parentNames,","))
-	}                                     // Yes, append the parent names.
-	/*header:=strings.Builder{}             // The section name.
-	header.WriteString("[")               // Start the section header.
-	header.WriteString(s.name)            // Write the section name.
-	if s.nParents>0{
-	  header.WriteString(":")						 // Append the colon.
-		for i,p:=range s.parentNames{
-		  if i>0{
-			  header.WriteByte(',')
-			}
-			header.WriteString(p)					   // Append the parent name.
-		}
-	}
-	header.WriteString("]\n")            // End the section header.*/
-	k,err:=fmt.Fprintf(w,"[%s]\n",header) // Print the section name.
-	//k,err:=io.WriteString(w,header.String()) // Print the section name.
-	n+=int64(k)                           // Add the number of bytes written.
-	if err!=nil{                          // Any error?
-	  return n,err                        // Yes, return the error.
-	}                                     // Done printing the section name.
-	// ---------------------------------- //
-	// Now we need to print the parameters and nested section references in order.
-	// ---------------------------------- //
-	for p:=s.first;p!=nil;p=p.GetNext(){  // For each parameter in our list...
-	  m,err:=p.Print(w)                   // Print the parameter to the stream.
-		n+=m                                // Add the number of bytes written.
-		if err!=nil{                        // Any error?
-		  return n,err                      // Yes, return the error.
-		}                                   // Done printing the parameter.
-	}                                     // Done iterating through the list.
-	// ---------------------------------- //
-	// Now we need to print the nested sections.
-	// ---------------------------------- //
-	for q:=s.firstSection;q!=nil;q=q.GetNext(){// For each nested section...
-	  m,err:=q.Print(w)                   // Print the section to the stream.
-		n+=m                                // Add the number of bytes written.
-		if err!=nil{                        // Any error?
-		  return n,err                      // Yes, return the error.
-		}                                   // Done printing the section.
-	}                                     // Done iterating through the nested sections.
-	return n,nil                          // Return the number of bytes written and no error.
+	err:=s.writeTo(w,&n,redact)           // Stream the section.
+	return n,err                          // Return # of bytes written/error if any.
 }                                       // ------------- Print ------------- //
 // ----------------------------- // ScanValue // ---------------------------- //
 // Scan the requested value into the destination variable using the specified
@@ -1668,7 +2412,7 @@ func (s *Section) scanValueByIndex(name string,i int, format string, dest any) e
 	if p==nil{                            // Did we find the parameter?
 	  return fmt.Errorf("parameter %s not found in section %s", name, s.name)// No, return error.
 	}                                     // Done checking for parameter.
-	if i < 0 || i >= int(s.GetNValues(name)){       // Within range?
+	if i < 0 || i >= int(p.GetNValues()){ // Within range of the one we found?
 	return fmt.Errorf("index %d out of range", i)
   }                                     // Done checking for out of range.
   var verb byte                         // The format verb.
@@ -1695,28 +2439,44 @@ func (s *Section) scanValueByIndex(name string,i int, format string, dest any) e
 	return errors.New("destination must be a non-nil pointer")// Yes, return an error.
   }                                     // Done checking for nil or pointer
 	// Scan the value into the destination variable
-  _,err:=fmt.Sscanf(string(s.GetValue(name,uint(i))),format,dest)
+  _,err:=fmt.Sscanf(p.GetValue(uint(i)),format,dest)// From that same parameter.
 	return err                            // Return error if any.
 }                                       // ------------ ScanValue ----------- //
 // -------------------------- // SetValueFormat // ------------------------- //
 // Set the value of a Parameter using a format string. It formats the value in
 // src according to the format string and set the value of the Parameter name,
-// at element i, to the formatted value.                                      //
+// at element i, to the formatted value. An i past the end of the values,     //
+// other than one past it to append a value, fails with ErrIndexGap.          //
 // -------------------------------------------------------------------------- //
 func (s *Section) SetValueInFormat(name string,i int,format string,src any) error{
-  if i<0{                               // Were we given a valid index?
-	  return fmt.Errorf("index %d must be non-negative", i)// No, return error.
+  if s.readOnly(){                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	if i<0{                               // Were we given a valid index?
+	  return s.configError("set",name,fmt.Errorf("index %d must be non-negative", i))// No, return error.
 	}                                     // Done checking for valid index.
+	if format==""{                        // Did they give us a format?
+	  format="%v"                         // No, just use the default format.
+	}                                     // Done checking for format.
+	if err:=checkFormat(format,src);err!=nil{// Would it format src properly?
+	  return s.configError("set",name,err)// No, don't store a malformed value.
+	}                                     // Done checking the format.
 	p:=s.FindParameter(name,false)        // Find the parameter in this section.
+	var n uint                            // How many values it has.
+	if p!=nil{                            // Does it have any?
+	  n=p.GetNValues()                    // Yes, count them.
+	}                                     // Done counting values.
+	if uint(i)>n{                         // Would it leave a gap?
+	  return s.configError("set",name,fmt.Errorf("%w: index %d, parameter %s has %d value%s",ErrIndexGap,i,name,n,plural(int(n))))
+	}                                     // Done checking for a gap.
 	if p==nil{                            // Did we find the parameter?
+	  if err:=s.cfg.checkName(name);err!=nil{// Does the application accept the name?
+		  return s.configError("set",name,fmt.Errorf("invalid parameter name \"%s\" in section %s: %w", name, s.name, err))
+		}                                   // Done checking the name.
 	  p=s.AppendParameter(name,"",nil,false)// No, append a new parameter.
 	}                                     // Done checking for parameter.
-	var valstr string                     // The formatted value to set.
-	if format==""{                        // Did they give us a format?
-	  valstr=fmt.Sprintf("%v",src)        // No, just use the default format.
-	} else{                               // Else they gave us a format.
-	  valstr=fmt.Sprintf(format,src)      // Use the format to format the value.
-	}                                     // Done checking for format.
+	old:=p.GetValues()                    // What it was, to tell OnChange().
+	valstr:=fmt.Sprintf(format,src)       // Use the format to format the value.
 	if i>=int(p.GetNValues()){            // Is the index out of range?
 	  newlen:=i+1                         // Yes, we need to grow the values slice.
 		tmpval:=make([]string,newlen)       // Make a new slice of strings.
@@ -1736,10 +2496,21 @@ func (s *Section) SetValueInFormat(name string,i int,format string,src any) erro
 		  p.quotes[i]=0                     // So set the quote to nothing.
 	}                                     // Done acting according to the value.
 	p.n=uint(len(p.values))               // We now have this many values.
+	s.cfg.changed(s.name,p.name,old,p.GetValues())// Say if it changed.
 	return nil														// We are good if we got here.
 }                                       // --------- SetValueInFormat ------- //
 
 func (s *Section) MakeShallowCopyOf(src *Section){
+  if s.readOnly(){                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	s.shallowCopyOf(src)                  // Share what src has.
+}                                       // --------- MakeShallowCopy -------- //
+// --------------------------- // shallowCopyOf // -------------------------- //
+//  Share the parents, parameters and references of src, for a Section that  //
+// is being built, even in a read-only view, as Clone() does.                 //
+// -------------------------------------------------------------------------- //
+func (s *Section) shallowCopyOf(src *Section){
   s.parentNames=src.parentNames         // The names of the parents if any.
 	s.parents=src.parents                 // Array of parent sections if any.
   s.nParents=src.nParents               // Number of parents of this section.
@@ -1752,7 +2523,36 @@ func (s *Section) MakeShallowCopyOf(src *Section){
 	s.current=src.current                 // The currently selected Parameter.
 	s.comments=src.comments               // The comments for this section.
 	s.copy=true                           // Set the copy flag.
-}                                       // --------- MakeShallowCopy -------- //
+}                                       // ---------- shallowCopyOf --------- //
+// ------------------------------ // Clone // ------------------------------- //
+//  Return a deep copy of this Section named newName, to use as a template:   //
+// its parameters and comments are copies, not shared, so changing one does   //
+// not change the other. The clone inherits from the same parents, and its    //
+// section references refer to the same sections, as MakeShallowCopyOf()      //
+// does. It belongs to no Configuration's list of sections until it is given  //
+// to AddSection().                                                           //
+// -------------------------------------------------------------------------- //
+func (s *Section) Clone(newName string) *Section{
+  ns:=NewSection(s.cfg,strings.TrimSpace(newName),copyComments(s.comments),false)
+	ns.parentNames=append([]string(nil),s.parentNames...)// Its own parent names...
+	ns.parents=append([]*Section(nil),s.parents...)// ...for the same parents.
+	ns.nParents=s.nParents                // As many as we have.
+	for ref:=s.firstSection;ref!=nil;ref=ref.GetNext(){// For each reference...
+	  nr:=NewSection(s.cfg,ref.name,nil,ref.isimported)// ...make another...
+		nr.shallowCopyOf(ref)               // ...to the same section.
+		if ns.firstSection==nil{            // Is it the first one?
+		  ns.firstSection=nr                // Yes, start the list.
+		} else{                             // Else we have a list already.
+		  ns.lastSection.next=nr            // Append it to the list.
+		}                                   // Done checking for first reference.
+		ns.lastSection=nr                   // Now we have a new last one.
+		ns.nSections++                      // Count it.
+	}                                     // Done copying references.
+	for p:=s.first;p!=nil;p=p.GetNext(){  // For each parameter...
+	  ns.linkParameter(CopyParameter(p))  // ...append a copy.
+	}                                     // Done copying parameters.
+	return ns                             // Return the clone.
+}                                       // -------------- Clone ------------- //
 // =========================== // Configuration // ========================== //
 // A class to store the entire configuration file.                            //
 // ========================================================================== //
@@ -1760,6 +2560,7 @@ func (s *Section) MakeShallowCopyOf(src *Section){
 // Initialize the Configuration, Section, and Parameter data structures.      //
 // -------------------------------------------------------------------------- //
 func NewConfiguration(ext string) (cfg *Configuration){
+  cfg=&Configuration{}                  // Allocate the configuration.
   cfg.SetDefaultExtension(ext)          // Set the default extension.
 	cfg.initialize()                      // Initialize the configuration.
 	return cfg                            // Return the configuration object.
@@ -1775,6 +2576,13 @@ func (cfg *Configuration) initialize(){
 // Must be called at the beginning of any derived Reconfigure() methods.      //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) Reconfigure() error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  defer cfg.follow()                  // Yes, catch up when it is done...
+	  return cfg.base.Reconfigure()       // ...by the original.
+	}                                     // Done checking for a view.
   cfg.deleteAll()                         // Delete all data structures.
 	cfg.initialize()                        // Initialize the configuration.
 	return nil                            // Always successful.
@@ -1789,31 +2597,48 @@ func (cfg *Configuration) deleteAll(){
 	for p:=cfg.firstComment;p!=nil;p=p.GetNext(){// For each comment in our list...
 	  cfg.firstComment,cfg.lastComment=nil,nil // Clear the list.
 	}                                     // Done clearing comment list.
+	cfg.footer=nil                        // And the footer.
 }                                       // ----------- deleteAll ------------ //
 
 // Helpers:
 func (cfg *Configuration) SetFilename(fname string) { cfg.path=fname }
 func (cfg *Configuration) SetDirectory(dir string) { cfg.path=filepath.Join(dir,filepath.Base(cfg.path))}
 func (cfg *Configuration) SetDefaultExtension(ext string) { cfg.ext=ext}
+// ---------------------------- // SetPathname // --------------------------- //
+//  Set the full path of the configuration file in one go. This is the        //
+// preferred way to set where WriteFile() writes: GetDirectory() and          //
+// GetFilename() are derived from it, and unlike SetDirectory() it does not   //
+// depend on a file name having been set first.                               //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetPathname(full string){
+  if full==""{                          // Were we given a path?
+	  cfg.path=""                         // No, forget the one we had.
+		return                              // Nothing else to do.
+	}                                     // Done checking for empty path.
+	cfg.path=filepath.Clean(full)         // Store it, tidied up.
+}                                       // ---------- SetPathname ----------- //
 func (cfg *Configuration) GetPathname() string { return cfg.path }
 func (cfg *Configuration) GetImportedPathname() string { return cfg.importpath } 
 func (cfg *Configuration) GetDirectory() string { return filepath.Dir(cfg.path) }
 func (cfg *Configuration) GetFilename() string { return filepath.Base(cfg.path) }
-func (cfg *Configuration) GetFirstSection() *Section { return cfg.first }
-func (cfg *Configuration) GetLastSection() *Section { return cfg.last }
-func (cfg *Configuration) GetFirst() *Section { return cfg.first }
-func (cfg *Configuration) GetLast() *Section { return cfg.last }
-func (cfg *Configuration) GetSelectedSection() *Section { return cfg.current }
+func (cfg *Configuration) GetFirstSection() *Section { cfg.follow(); return cfg.first }
+func (cfg *Configuration) GetLastSection() *Section { cfg.follow(); return cfg.last }
+func (cfg *Configuration) GetFirst() *Section { cfg.follow(); return cfg.first }
+func (cfg *Configuration) GetLast() *Section { cfg.follow(); return cfg.last }
+func (cfg *Configuration) GetSelectedSection() *Section { cfg.follow(); return cfg.current }
 func (cfg *Configuration) GetSectionName() string{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current==nil{ return "" } else { return cfg.current.GetName() }
 }
 func (cfg *Configuration) GetSection(section string) *Section{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current==nil { return nil } else { return cfg.current.FindSection(section) }
 }
 // ---------------------- // GetSelectedSectionName // ---------------------- //
 // Return a string with the name of the currently selected section.           //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetSelectedSectionName() string{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Is a section selected?
 	  return cfg.current.GetName()        // Yes, return its name.
 	}                                     // Done checking for selected section.
@@ -1824,6 +2649,7 @@ func (cfg *Configuration) GetSelectedSectionName() string{
 // section.                                                                   //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetSelectedSectionParentName() string{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Is a section selected?
 	  return cfg.current.GetParentName(0) // Yes, return its parent's name.
 	}                                     // Done checking for selected section.
@@ -1833,20 +2659,979 @@ func (cfg *Configuration) GetSelectedSectionParentName() string{
 // Return a string with the name of the first section in the list.            //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetFirstSectionName() string{
+  cfg.follow()                          // Catch up with the original.
   if cfg.first!=nil{                    // Are there any sections?
 	  return cfg.first.GetName()          // Yes, return the first section name.
 	}                                     // Done checking for first section.
 	return ""                             // No, return empty string.
 }                                       // ------ GetFirstSectionName ------- //
+// ------------------------- // GetSectionNames // -------------------------- //
+// Return the names of all of the sections, in the order they are in the file.//
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetSectionNames() []string{
+  var names []string                    // Where to put the names.
+	cfg.follow()                          // Catch up with the original.
+	for s:=cfg.first;s!=nil;s=s.GetNext(){// For each section...
+	  names=append(names,s.GetName())     // Add its name.
+	}                                     // Done iterating sections.
+	return names                          // Return the names.
+}                                       // -------- GetSectionNames --------- //
 // --------------------------- // SaveComments // --------------------------- //
-// Set or clear the flag that says we are saving comments.                    //
+// Set or clear the flag that says we are saving comments. Comments are saved //
+// by default. With SaveComments(false) ReadFile() does not keep comments,    //
+// blank lines or lines it can't make sense of, so large files take less      //
+// memory. If the file had any, WriteFile() then fails with ErrCommentLoss    //
+// rather than write it back without them, unless AllowCommentLoss(true).     //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) SaveComments(flag bool){
-  cfg.saveComments=flag                 // Save comments if true.
+  cfg.dropComments=!flag                // Drop comments if not saving them.
 }                                       // ----------- SaveComments --------- //
+// ------------------------- // AllowCommentLoss // ------------------------- //
+//  Let WriteFile() write a file that was read with SaveComments(false) even  //
+// though it had comments, which are then gone for good.                      //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) AllowCommentLoss(allow bool){
+  cfg.allowLoss=allow                   // Remember the policy.
+}                                       // -------- AllowCommentLoss -------- //
 func (cfg *Configuration) IgnoreImports(flag bool){
   cfg.ignoreImports=flag                // Ignore imports if true.
 }                                       // ----------- IgnoreImports -------- //
+// ------------------------ // SetCommentPrefixes // ------------------------ //
+//  Set the prefixes that start a line comment, "#" by default. Deployments  //
+// that put '#' in unquoted values can use e.g. ";" instead. Block comments  //
+// (/* ... */) are always recognized, whatever the prefixes are.             //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetCommentPrefixes(prefixes ...string){
+  cfg.commentPrefixes=nil               // Forget the old prefixes.
+	for _,p:=range prefixes{              // For each prefix we were given...
+	  if p=strings.TrimSpace(p);p!=""{    // Is it a real prefix?
+		  cfg.commentPrefixes=append(cfg.commentPrefixes,p)// Yes, keep it.
+		}                                   // Done checking for empty prefix.
+	}                                     // Done iterating prefixes.
+}                                       // ------- SetCommentPrefixes ------- //
+// -------------------------- // commentPrefix // --------------------------- //
+// The prefix used when we write comments of our own: the first one set.      //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) commentPrefix() string{
+  if len(cfg.commentPrefixes)==0{       // Were any prefixes set?
+	  return "#"                          // No, use the default.
+	}                                     // Done checking for prefixes.
+	return cfg.commentPrefixes[0]         // Return the first prefix.
+}                                       // --------- commentPrefix ---------- //
+// ----------------------- // SetSectionDelimiters // ----------------------- //
+//  Set what surrounds a section name in a header, "[" and "]" by default,    //
+// so that a file can be shared with tooling that expects e.g. <name>. Both   //
+// the parser and Print(), WriteTo() and WriteFile() use them, so a file we   //
+// write reads back the same; names holding the closing delimiter are quoted. //
+// An empty delimiter, or an opening one that would be read as a comment,     //
+// restores the defaults for both. Set the comment prefixes first.            //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetSectionDelimiters(open, close string){
+  open,close=strings.TrimSpace(open),strings.TrimSpace(close)// No blanks around them.
+	if open==""||close==""||cfg.isCommentLine(open){// Were both given, and usable?
+	  open,close="",""                    // No, use the defaults.
+	}                                     // Done checking for empty delimiters.
+	cfg.sectOpen,cfg.sectClose=open,close // Remember the delimiters.
+}                                       // ------ SetSectionDelimiters ------ //
+// ----------------------- // GetSectionDelimiters // ----------------------- //
+// Return what opens and what closes a section header.                        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetSectionDelimiters() (string,string){
+  if cfg==nil||cfg.sectOpen==""{        // Were any delimiters set?
+	  return "[","]"                      // No, use the defaults.
+	}                                     // Done checking for delimiters.
+	return cfg.sectOpen,cfg.sectClose     // Return the delimiters.
+}                                       // ------ GetSectionDelimiters ------ //
+// --------------------------- // makeComment // ---------------------------- //
+// Build a Comment holding text, prefixed with our comment prefix, so that    //
+// Print() writes it back as a comment the parser will recognize.             //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) makeComment(text string) *Comment{
+  return NewComment(cfg.commentPrefix()+" "+text,false)// Make the comment.
+}                                       // ---------- makeComment ----------- //
+// ------------------------- // AddHeaderComment // ------------------------- //
+//  Put text at the front of the file-level comments, so Print() and          //
+// WriteFile() write it before any section: a license or a "generated by"     //
+// banner. Each line of text that is not a comment already is prefixed with   //
+// our comment prefix. A later call's lines go above an earlier call's.       //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) AddHeaderComment(text string){
+  if cfg.readonly{                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  cfg.base.AddHeaderComment(text)     // Yes, the original holds them.
+		cfg.follow()                        // Catch up with it.
+		return                              // Done.
+	}                                     // Done checking for a view.
+	head,tail:=cfg.commentLines(text)     // Make the comments.
+	if head==nil{                         // Anything to add?
+	  return                              // No, nothing to do.
+	}                                     // Done checking for comments.
+	tail.SetNext(cfg.firstComment)        // Put them before the ones we have.
+	if cfg.lastComment==nil{              // Did we have any?
+	  cfg.lastComment=tail                // No, these end the list too.
+	}                                     // Done checking for comments.
+	cfg.firstComment=head                 // Now they come first.
+}                                       // -------- AddHeaderComment -------- //
+// ------------------------- // AddFooterComment // ------------------------- //
+//  Add text to the comments Print() and WriteFile() write after the last     //
+// section, prefixed like AddHeaderComment(). A later call's lines go below   //
+// an earlier call's. Reading a file forgets them, as a comment at the end of //
+// a file is read as a file-level comment, which is written first.            //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) AddFooterComment(text string){
+  if cfg.readonly{                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  cfg.base.AddFooterComment(text)     // Yes, the original holds them.
+		cfg.follow()                        // Catch up with it.
+		return                              // Done.
+	}                                     // Done checking for a view.
+	head,_:=cfg.commentLines(text)        // Make the comments.
+	if cfg.footer==nil{                   // Do we have a footer?
+	  cfg.footer=head                     // No, this is it.
+		return                              // Done.
+	}                                     // Done checking for footer.
+	last:=cfg.footer                      // Find the end of the footer.
+	for last.next!=nil{                   // Not at the end yet?
+	  last=last.next                      // Keep walking.
+	}                                     // Done finding the end.
+	last.SetNext(head)                    // Append the new comments.
+}                                       // -------- AddFooterComment -------- //
+// --------------------------- // commentLines // --------------------------- //
+// Make a list of Comments from the lines of text, prefixing each line that   //
+// isCommentLine() does not recognize. Blank lines are left out, as they are  //
+// when a file is read.                                                       //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) commentLines(text string) (head,tail *Comment){
+  for _,line:=range strings.Split(text,"\n"){// For each line of text...
+	  line=strings.TrimSpace(line)        // Ignore surrounding blanks.
+		if line==""{                        // Is it a blank line?
+		  continue                          // Yes, leave it out.
+		}                                   // Done checking for blank line.
+		q:=NewComment(line,false)           // Assume it is a comment already.
+		if !cfg.isCommentLine(line){        // Is it?
+		  q=cfg.makeComment(line)           // No, so make it one.
+		}                                   // Done checking for comment.
+		if head==nil{                       // Is it the first one?
+		  head=q                            // Yes, it is the head of the list.
+		} else{                             // Else we have a list already.
+		  tail.SetNext(q)                   // Append it to the list.
+		}                                   // Done checking for first comment.
+		tail=q                              // Now we have a new tail.
+	}                                     // Done iterating lines.
+	return head,tail                      // Return the list.
+}                                       // ---------- commentLines ---------- //
+// --------------------------- // isCommentLine // -------------------------- //
+// True if the line starts with one of our comment prefixes.                 //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) isCommentLine(line string) bool{
+  if len(cfg.commentPrefixes)==0{       // Were any prefixes set?
+	  return strings.HasPrefix(line,"#")  // No, use the default.
+	}                                     // Done checking for prefixes.
+	for _,p:=range cfg.commentPrefixes{   // For each prefix...
+	  if strings.HasPrefix(line,p){       // Does the line start with it?
+		  return true                       // Yes, it is a comment.
+		}                                   // Done checking this prefix.
+	}                                     // Done iterating prefixes.
+	return false                          // Not a comment.
+}                                       // --------- isCommentLine ---------- //
+// -------------------------- // isSectionHeader // ------------------------- //
+// True if the line starts with our opening section delimiter.                //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) isSectionHeader(line string) bool{
+  open,_:=cfg.GetSectionDelimiters()    // What opens a section header.
+	return strings.HasPrefix(line,open)   // Does the line start with it?
+}                                       // -------- isSectionHeader --------- //
+// ------------------------ // SetMaxLineLength // ------------------------- //
+//  Set the maximum length of a line, once its continuation lines have been   //
+// appended to it. ReadFile() fails with ErrLineTooLong, and the number of    //
+// the offending line, rather than grow a line without bound. n<=0 restores  //
+// the default of 32768 bytes.                                               //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetMaxLineLength(n int){
+  cfg.maxLineLen=n                      // Remember the maximum.
+}                                       // -------- SetMaxLineLength -------- //
+func (cfg *Configuration) GetMaxLineLength() int{
+  if cfg.maxLineLen<=0{                 // Was a maximum set?
+	  return 32768                        // No, use the default.
+	}                                     // Done checking for maximum.
+	return cfg.maxLineLen                 // Return the maximum.
+}                                       // -------- GetMaxLineLength -------- //
+// ------------------------ // SetFloatSpecials // ------------------------- //
+//  Choose whether the float getters accept "nan", "inf" and "-inf". They are //
+// rejected with ErrFloatSpecial by default, since a NaN or infinite setting  //
+// is usually a typo; scientific configurations may want them.                //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetFloatSpecials(allow bool){
+  cfg.floatSpecials=allow               // Remember the policy.
+}                                       // -------- SetFloatSpecials -------- //
+// ------------------------ // GetFloatSpecials // ------------------------- //
+//  Return true if the float getters accept "nan", "inf" and "-inf".          //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetFloatSpecials() bool{ return cfg.floatSpecials }
+// ---------------------------- // SetIntBase // ---------------------------- //
+//  Choose how the integer getters read a value. With base 10, the default,   //
+// 010 is ten and 0x1F is an error. With base 0 a prefix picks the base, as   //
+// in C and Go: 0x1F is hex, 0o17 and 010 are octal, 0b101 is binary. Any     //
+// other base restores the default.                                           //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetIntBase(base int){
+  cfg.intPrefixes=base==0               // Remember the policy.
+}                                       // ----------- SetIntBase ----------- //
+// ---------------------------- // GetIntBase // ---------------------------- //
+//  Return the base the integer getters read values in, 0 or 10.              //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetIntBase() int{
+  if cfg!=nil&&cfg.intPrefixes{         // Do prefixes pick the base?
+	  return 0                            // Yes, let them.
+	}                                     // Done checking the policy.
+	return 10                             // No, decimal it is.
+}                                       // ----------- GetIntBase ----------- //
+// ------------------------- // SetFloatFormat // -------------------------- //
+//  Choose how SetValueFloat32(), SetValueFloat64() and the rest of the float //
+// setters write a value, as strconv.FormatFloat() does with fmtByte and      //
+// prec: 'g' with -1 writes 1e-09 where the default 'f' with -1 writes        //
+// 0.000000001, and 'e' with 3 writes 1.000e-09. A fmtByte FormatFloat does   //
+// not know, or 0, restores the default of 'f' with -1.                       //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetFloatFormat(fmtByte byte, prec int){
+  if !strings.ContainsRune("bfeEgGxX",rune(fmtByte)){// Is it a format we know?
+	  fmtByte,prec=0,-1                   // No, use the default.
+	}                                     // Done checking the format.
+	cfg.floatFmt,cfg.floatPrec=fmtByte,prec// Remember the format.
+}                                       // --------- SetFloatFormat --------- //
+// ------------------------- // GetFloatFormat // -------------------------- //
+//  Return the format and precision the float setters write with.            //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetFloatFormat() (byte, int){
+  if cfg.floatFmt==0{                   // Was a format set?
+	  return 'f',-1                       // No, use the default.
+	}                                     // Done checking for format.
+	return cfg.floatFmt,cfg.floatPrec     // Return the format.
+}                                       // --------- GetFloatFormat --------- //
+// --------------------------- // formatFloat // ---------------------------- //
+// Format a float the way the float setters write it, for a float of bits.    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) formatFloat(v float64, bits int) string{
+  f,prec:=cfg.GetFloatFormat()          // How we write floats.
+	return strconv.FormatFloat(v,f,prec,bits)// Write this one.
+}                                       // ----------- formatFloat ---------- //
+// ------------------------- // formatPrecision // -------------------------- //
+// Format a float for the SetValuePrecision setters: precision digits after   //
+// the decimal point, as "%.<precision>f" would write it. An empty precision  //
+// writes it the way the other float setters do.                              //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) formatPrecision(v float64,bits int,precision string) (string,error){
+  if precision==""{                     // Any precision asked for?
+	  return cfg.formatFloat(v,bits),nil  // No, write it as usual.
+	}                                     // Done checking for precision.
+	n,err:=strconv.Atoi(strings.TrimSpace(precision))// How many digits?
+	if err!=nil||n<0{                     // Is it a number of digits?
+	  return "",fmt.Errorf("invalid precision \"%s\"",precision)
+	}                                     // Done checking precision.
+	return strconv.FormatFloat(v,'f',n,bits),nil// Write that many digits.
+}                                       // --------- formatPrecision -------- //
+// ------------------------ // SetFileValueRefs // ------------------------- //
+//  Choose whether ReadFile() reads a value written as @path, as in           //
+// password=@/run/secrets/db_pass, from the file it names: the value becomes  //
+// the file's contents with surrounding whitespace trimmed, as one value, and //
+// a file that can't be read fails the read. The path is taken as written,   //
+// like an import's, so a relative one is relative to the working directory. //
+// Print() and WriteFile() write the @path back, never the contents. A quoted //
+// "@..." value is left alone. It is off by default. The files so read are   //
+// not listed by Dependencies().                                              //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetFileValueRefs(allow bool){
+  cfg.fileRefs=allow                    // Remember the policy.
+}                                       // -------- SetFileValueRefs -------- //
+// ------------------------ // GetFileValueRefs // ------------------------- //
+//  Return true if ReadFile() reads @path values from files.                  //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetFileValueRefs() bool{ return cfg.fileRefs }
+// ---------------------------- // MarkSecret // ---------------------------- //
+//  Mark a parameter of a section as a secret, such as a password, so that    //
+// Print(), WriteTo() and String(), and so "%v", write its value as "***".    //
+// WriteFile() still writes the real value. The section and parameter need   //
+// not exist yet: a secret may be marked before the file is read.             //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) MarkSecret(section, name string){
+  secrets:=map[string]bool{}            // Copy, as newLike() shares the map.
+	for k:=range cfg.secrets{             // For each secret we have...
+	  secrets[k]=true                     // ...keep it.
+	}                                     // Done copying the secrets.
+	secrets[secretKey(section,name)]=true // Add this one.
+	cfg.secrets=secrets                   // And use the copy.
+}                                       // ----------- MarkSecret ----------- //
+// --------------------------- // PrintSecrets // --------------------------- //
+//  Choose whether Print(), WriteTo() and String() write the real values of   //
+// the parameters marked by MarkSecret(). They don't by default.              //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) PrintSecrets(show bool){
+  cfg.showSecrets=show                  // Remember the policy.
+}                                       // ---------- PrintSecrets ---------- //
+// ----------------------------- // isSecret // ----------------------------- //
+// Return true if MarkSecret() marked the parameter of the section.           //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) isSecret(section, name string) bool{
+  if cfg==nil{                          // Do we belong to a Configuration?
+	  return false                        // No, nothing is secret.
+	}                                     // Done checking for Configuration.
+	return cfg.secrets[secretKey(section,name)]// Is it marked?
+}                                       // ------------ isSecret ------------ //
+// ---------------------------- // secretKey // ---------------------------- //
+// Names don't care about case, and a NUL can't appear in either of them.     //
+// -------------------------------------------------------------------------- //
+func secretKey(section, name string) string{
+  return strings.ToLower(section)+"\x00"+strings.ToLower(name)
+}                                       // ------------ secretKey ----------- //
+// ----------------------- // SetEnvironSeparator // ------------------------ //
+//  Set what Environ() puts between the values of a multi-valued parameter.   //
+// The default is ",", as the values were written; ":" suits PATH-like lists.  //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetEnvironSeparator(sep string){
+  cfg.envSep=&sep                       // Remember the separator.
+}                                       // ------- SetEnvironSeparator ------ //
+func (cfg *Configuration) GetEnvironSeparator() string{
+  if cfg.envSep==nil{                   // Was a separator set?
+	  return ","                          // No, use the default.
+	}                                     // Done checking for separator.
+	return *cfg.envSep                    // Return the separator.
+}                                       // ------- GetEnvironSeparator ------ //
+// ------------------------- // SetNameValidator // ------------------------- //
+//  Install a function that vets every parameter name as the file is read,    //
+// and as SetValues() and Section.SetValueInFormat() create parameters. When  //
+// it returns an error, ReadFile() fails with that error and the line number, //
+// and nothing is created. A nil function, the default, accepts any name.     //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetNameValidator(validate func(name string) error){
+  cfg.nameValidator=validate            // Remember the validator.
+}                                       // -------- SetNameValidator -------- //
+// ---------------------------- // checkName // ----------------------------- //
+// Run the name validator, if there is one, on a parameter name.              //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) checkName(name string) error{
+  if cfg==nil||cfg.nameValidator==nil{  // Do we have a validator?
+	  return nil                          // No, every name is fine.
+	}                                     // Done checking for validator.
+	return cfg.nameValidator(name)        // Ask the validator.
+}                                       // ----------- checkName ------------ //
+// ----------------------------- // OnChange // ----------------------------- //
+//  Install a function to call whenever a Set call, at the Configuration or   //
+// the Section level, changes the value of a parameter, to invalidate what an //
+// application derived from it. It is called synchronously once the change    //
+// is made, with the values as GetValues() writes them; setting a parameter   //
+// to the value it has does not call it. Reading a file, Restore() and        //
+// ApplyDefaults() do not call it either. A nil function, the default, turns  //
+// it off.                                                                    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) OnChange(cb func(section, name, oldValue, newValue string)){
+  cfg.onChange=cb                       // Remember the callback.
+}                                       // ------------ OnChange ------------ //
+// ----------------------------- // changed // ------------------------------ //
+// Call the OnChange() callback, if there is one and the value did change.    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) changed(section, name, oldValue, newValue string){
+  if cfg==nil||cfg.onChange==nil||oldValue==newValue{// Anything to say?
+	  return                              // No, stay quiet.
+	}                                     // Done checking for change.
+	cfg.onChange(section,name,oldValue,newValue)// Tell the application.
+}                                       // ------------- changed ------------ //
+// -------------------- // SetDuplicateSectionPolicy // --------------------- //
+//  Choose what ReadFile() does when a file repeats a section header:         //
+//   DuplicateSeparate  appends a second Section of the same name. This is    //
+//                      the default, and what we always did; FindSection()    //
+//                      only ever returns the first one, so the parameters    //
+//                      of the second are only reachable by walking the list. //
+//   DuplicateMergeInto adds the parameters and comments of the repeat to the //
+//                      first Section. The repeat may only declare the same   //
+//                      parents as the first. A parameter set in both keeps   //
+//                      the first value for lookups, as within one Section.   //
+//   DuplicateError     makes ReadFile() fail with the line number.           //
+//  Sections pulled in from imported files are not checked.                   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetDuplicateSectionPolicy(policy DuplicatePolicy){
+  cfg.dupSections=policy                // Remember the policy.
+}                                       // --- SetDuplicateSectionPolicy ---- //
+// ---------------------- // SetTrailingCommaPolicy // ---------------------- //
+//  Choose what a value list that ends in a comma, like hosts=a,b, means      //
+// when ReadFile() reads it or a Set call splits it:                          //
+//   DropEmpty          discards the empty value after the last comma, so     //
+//                      hosts has two values. This is the default.            //
+//   KeepEmpty          keeps it, so hosts has three values, the last "".     //
+//   TrailingCommaError makes ReadFile() fail with the line number, and the   //
+//                      Set call fail with ErrTrailingComma.                  //
+//  A comma inside quotes does not count, as in hosts=a,"b,".                 //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetTrailingCommaPolicy(policy TrailingCommaPolicy){
+  cfg.trailingComma=policy              // Remember the policy.
+}                                       // ----- SetTrailingCommaPolicy ----- //
+// ---------------------------- // splitValue // ---------------------------- //
+//  Set the values of p from a comma-separated list, as Parameter.SetValue()  //
+// does, and then apply the trailing comma policy to the list.                //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) splitValue(p *Parameter,value string,quote byte) error{
+  trailing:=cfg!=nil&&endsInComma(value,quote)// Does it end in a comma?
+	if trailing&&cfg.trailingComma==TrailingCommaError{// Is that an error?
+	  return ErrTrailingComma             // Yes, leave the values alone.
+	}                                     // Done checking for an error.
+	if err:=p.SetValue(value,quote);err!=nil{// Split the list.
+	  return err                          // Could not, return the error.
+	}                                     // Done splitting the list.
+	if trailing&&cfg.trailingComma==KeepEmpty{// Keep the empty value?
+	  return p.SetValuePtrOnIndex(p.n,"",0)// Yes, put it back at the end.
+	}                                     // Done checking for keeping it.
+	return nil                            // The list is set.
+}                                       // ----------- splitValue ----------- //
+// ------------------------- // SetValidateUTF8 // -------------------------- //
+//  Choose whether ReadFile() fails, with the line number and ErrInvalidUTF8, //
+// on a parameter that is not valid UTF-8, so that a file from an untrusted   //
+// source can't smuggle bytes that corrupt logs or JSON made from it later.   //
+// Reads are permissive by default; SanitizeUTF8() cleans up after them.      //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetValidateUTF8(validate bool){
+  cfg.validateUTF8=validate             // Remember the policy.
+}                                       // -------- SetValidateUTF8 --------- //
+// --------------------------- // SanitizeUTF8 // --------------------------- //
+//  Replace every invalid UTF-8 sequence in the values of every section with  //
+// U+FFFD, the Unicode replacement character. Splitting a list on commas      //
+// already does this, so it is the values set whole that may need it: those   //
+// set with SetValuePtr(), read from @path files or kept as JSON documents.   //
+// OnChange() is told of the parameters that change.                          //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SanitizeUTF8() error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
+	for s:=cfg.first;s!=nil;s=s.GetNext(){// For each section...
+	  if s.copy{                          // Is it a [Ref] copy?
+		  continue                          // Yes, its section does it.
+		}                                   // Done checking for a copy.
+	  for p:=s.first;p!=nil;p=p.GetNext(){// For each of its parameters...
+		  old:=p.GetValues()                // What it was, to tell OnChange().
+			if utf8.ValidString(old)&&utf8.ValidString(p.rawJSON){// Anything to fix?
+			  continue                        // No, leave it alone.
+			}                                 // Done checking the values.
+			for i,v:=range p.values{          // For each value...
+			  p.values[i]=strings.ToValidUTF8(v,"\uFFFD")// ...replace what is invalid.
+			}                                 // Done iterating values.
+			p.rawJSON=strings.ToValidUTF8(p.rawJSON,"\uFFFD")// And in the JSON too.
+			cfg.changed(s.name,p.name,old,p.GetValues())// Say it changed.
+		}                                   // Done iterating parameters.
+	}                                     // Done iterating sections.
+	return nil                            // Every value is valid now.
+}                                       // ---------- SanitizeUTF8 ---------- //
+// --------------------------- // SetWriteOrder // -------------------------- //
+//  Choose the order Print(), WriteTo() and WriteFile() write in:             //
+//   AsRead writes sections and parameters in the order they were read or     //
+//          added. This is the default.                                      //
+//   Sorted writes sections, nested ones included, and the parameters of each //
+//          section alphabetically, ignoring case, so that generated files    //
+//          diff cleanly. Comments stay with the section or parameter they    //
+//          belong to; the comments at the end of the file stay at the top.   //
+//  Only the output changes: the lists themselves keep their order.           //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetWriteOrder(order WriteOrder){
+  cfg.writeOrder=order                  // Remember the order.
+}                                       // --------- SetWriteOrder ---------- //
+// ---------------------------- // writeSorted // --------------------------- //
+// Return true if this Configuration writes things out alphabetically.        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) writeSorted() bool{
+  return cfg!=nil&&cfg.writeOrder==Sorted// Do we sort the output?
+}                                       // ----------- writeSorted ---------- //
+// ---------------------------- // sectionOrder // -------------------------- //
+// Return the list of sections starting at first in the order to write them.  //
+// -------------------------------------------------------------------------- //
+func sectionOrder(first *Section,sorted bool) []*Section{
+  var list []*Section                   // The sections to write.
+	for s:=first;s!=nil;s=s.GetNext(){    // For each section in the list...
+	  list=append(list,s)                 // ...take it as it comes.
+	}                                     // Done collecting sections.
+	if sorted{                            // Do we write them sorted?
+	  sort.SliceStable(list,func(i,j int) bool{// Yes, sort them by name.
+		  return strings.ToLower(list[i].name)<strings.ToLower(list[j].name)
+		})                                  // Done sorting the sections.
+	}                                     // Done checking for sorting.
+	return list                           // Return the sections in order.
+}                                       // ---------- sectionOrder ---------- //
+// --------------------------- // parameterOrder // ------------------------- //
+// Return this Section's parameters in the order to write them.               //
+// -------------------------------------------------------------------------- //
+func (s *Section) parameterOrder() []*Parameter{
+  var list []*Parameter                 // The parameters to write.
+	for p:=s.first;p!=nil;p=p.GetNext(){  // For each parameter in the list...
+	  list=append(list,p)                 // ...take it as it comes.
+	}                                     // Done collecting parameters.
+	if s.cfg.writeSorted(){               // Do we write them sorted?
+	  sort.SliceStable(list,func(i,j int) bool{// Yes, sort them by name.
+		  return strings.ToLower(list[i].name)<strings.ToLower(list[j].name)
+		})                                  // Done sorting the parameters.
+	}                                     // Done checking for sorting.
+	return list                           // Return the parameters in order.
+}                                       // --------- parameterOrder --------- //
+// --------------------------- // ApplyDefaults // -------------------------- //
+//  Fill in whatever this Configuration is missing from a configuration of    //
+// defaults. Every Section and Parameter in defaults is added only if this    //
+// Configuration does not already have it, including through inheritance      //
+// from parent Sections. Values the user set are never overwritten, and the   //
+// comments from defaults are only copied for the entries we add.            //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) ApplyDefaults(defaults *Configuration){
+  if defaults==nil||cfg.readonly{       // Any defaults, and may we change?
+	  return                              // No, nothing to do.
+	}                                     // Done checking for defaults.
+	for ds:=defaults.first;ds!=nil;ds=ds.GetNext(){// For each default section...
+	  if cfg.FindSection(ds.GetName())==nil{// Do we have this section?
+		  s:=cfg.AppendSection(ds.GetName(),copyComments(ds.comments),false)
+			if ds.nParents>0{                 // Does it have parents?
+			  s.SetParentNames(strings.Join(ds.parentNames,","))// Yes, copy their names.
+			}                                 // Done checking for parents.
+		}                                   // Done checking for section.
+	}                                     // Done adding missing sections.
+	cfg.resolveParents()                  // So inheritance sees the new sections.
+	for ds:=defaults.first;ds!=nil;ds=ds.GetNext(){// For each default section...
+	  s:=cfg.FindSection(ds.GetName())    // Get our section of the same name.
+		for dp:=ds.first;dp!=nil;dp=dp.GetNext(){// For each default parameter...
+		  if s.FindParameter(dp.GetName(),true)==nil{// Do we have it anywhere?
+			  s.Append2(dp)                   // No, append a copy of the default.
+			}                                 // Done checking for parameter.
+		}                                   // Done iterating default parameters.
+	}                                     // Done iterating default sections.
+}                                       // --------- ApplyDefaults ---------- //
+// ---------------------------- // Snapshot // ----------------------------- //
+//  Take a deep copy of the sections, parameters and comments, so a batch of  //
+// edits can be rolled back with Restore() if it fails validation.           //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Snapshot() Snapshot{
+  var snap Snapshot                     // The snapshot we are taking.
+	cfg.follow()                          // Catch up with the original.
+	snap.first,_=cfg.copySections(cfg.first)// Copy the sections.
+	snap.firstComment=copyComments(cfg.firstComment)// Copy the trailing comments.
+	snap.footer=copyComments(cfg.footer)  // And the footer.
+	if cfg.current!=nil{                  // Is a section selected?
+	  snap.current=cfg.current.GetName()  // Yes, remember which.
+	}                                     // Done checking for current section.
+	return snap                           // Return the snapshot.
+}                                       // ----------- Snapshot ------------- //
+// ----------------------------- // Restore // ------------------------------ //
+//  Revert to the state saved in a Snapshot. The Snapshot is copied again, so //
+// it can be restored more than once. It does nothing on a ReadOnly() view.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Restore(snap Snapshot){
+  if cfg.readonly{                      // Is this a read-only view?
+	  return                              // Yes, leave it alone.
+	}                                     // Done checking for read-only view.
+	if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  cfg.base.Restore(snap)              // Yes, the original holds the sections.
+		cfg.follow()                        // Catch up with it.
+		cfg.current=cfg.FindSection(snap.current)// And select what was selected.
+		return                              // Done.
+	}                                     // Done checking for a view.
+	cfg.restore(snap)                     // Put back the snapshot.
+}                                       // ------------ Restore ------------- //
+// ----------------------------- // restore // ------------------------------ //
+//  Put a copy of a Snapshot in place of our sections and comments.           //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) restore(snap Snapshot){
+  cfg.first,cfg.last=cfg.copySections(snap.first)// Put back the sections.
+	cfg.firstComment=copyComments(snap.firstComment)// And the trailing comments.
+	cfg.footer=copyComments(snap.footer)  // And the footer.
+	cfg.lastComment=cfg.firstComment      // Find the last of the comments.
+	for cfg.lastComment!=nil&&cfg.lastComment.next!=nil{// Not at the end yet?
+	  cfg.lastComment=cfg.lastComment.next// Keep walking.
+	}                                     // Done finding the last comment.
+	cfg.resolveParents()                  // Link the sections to their parents.
+	cfg.resolveSectionRefs()              // And to the sections they reference.
+	cfg.current=nil                       // Assume no section was selected.
+	if snap.current!=""{                  // Was a section selected?
+	  cfg.current=cfg.FindSection(snap.current)// Yes, select it again.
+	}                                     // Done checking for current section.
+	if cfg.readonly{                      // Is it a read-only view?
+	  cfg.freeze()                        // Yes, its parameters are too.
+	}                                     // Done checking for read-only view.
+}                                       // ------------ restore ------------- //
+// ------------------------------ // freeze // ------------------------------ //
+//  Mark every Parameter of a read-only view as such, so its setters refuse   //
+// too; a Parameter does not know its Section or Configuration.               //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) freeze(){
+  for s:=cfg.first;s!=nil;s=s.GetNext(){// For each section...
+	  for p:=s.first;p!=nil;p=p.GetNext(){// ...and each of its parameters...
+		  p.readonly=true                   // ...refuse changes.
+		}                                   // Done iterating parameters.
+	}                                     // Done iterating sections.
+}                                       // ------------- freeze ------------- //
+// -------------------------- // copySections // ---------------------------- //
+//  Deep copy a list of sections into this Configuration. Parents and section //
+// references are copied by name, to be resolved once the list is in place.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) copySections(first *Section) (head,tail *Section){
+  for s:=first;s!=nil;s=s.GetNext(){    // For each section in the list...
+	  ns:=NewSection(cfg,s.name,copyComments(s.comments),s.isimported)
+		ns.owner=cfg                        // It goes on our list.
+		names:=make([]string,0,s.nParents)  // The names of its parents.
+		for i:=uint(0);i<s.nParents;i++{    // For each parent...
+		  if i<uint(len(s.parents))&&s.parents[i]!=nil{// Is it resolved?
+			  names=append(names,s.parents[i].GetName())// Yes, use its name.
+			} else{                           // Else it is not resolved yet.
+			  names=append(names,s.parentNames[i])// So use the name we read.
+			}                                 // Done checking for resolved parent.
+		}                                   // Done collecting parent names.
+		ns.setParentNames(strings.Join(names,","))// Copy the parent names.
+		for ref:=s.firstSection;ref!=nil;ref=ref.GetNext(){// For each reference...
+		  ns.appendSection(ref.GetName(),ref.isimported)// Copy it by name.
+		}                                   // Done copying references.
+		for p:=s.first;p!=nil;p=p.GetNext(){// For each parameter...
+		  ns.linkParameter(CopyParameter(p)).isimported=p.isimported// Copy it, keeping its origin.
+		}                                   // Done copying parameters.
+		if head==nil{                       // Is it the first section?
+		  head=ns                           // Yes, it is the head of the list.
+		} else{                             // Else we have a list already.
+		  tail.next=ns                      // Append it to the list.
+		}                                   // Done checking for first section.
+		tail=ns                             // Now we have a new tail.
+	}                                     // Done iterating sections.
+	return head,tail                      // Return the copy of the list.
+}                                       // ---------- copySections ---------- //
+// ------------------------------ // Inline // ------------------------------ //
+//  Return a new Configuration holding what this one means, with nothing left //
+// to resolve: every section gets the parameters it inherits written into it  //
+// and no parents, section references and [Ref] copies become plain sections, //
+// and what came in through "read" and "import" is part of the file. Comments //
+// that were read from other files are left out. WriteFile() on the result,   //
+// which is writable, gives a config that stands on its own.                  //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Inline() *Configuration{
+  cfg.follow()                          // Catch up with the original.
+	out:=cfg.newLike()                    // The flat configuration.
+	out.path=cfg.path                     // Same file name to start with.
+	out.canWrite=true                     // It may be written...
+	out.lostComments=cfg.lostComments     // ...unless we dropped comments.
+	for s:=cfg.first;s!=nil;s=s.GetNext(){// For each section...
+	  ns:=NewSection(out,s.name,inlineComments(s.comments),false)
+		for _,p:=range s.EffectiveParameters(){// For each parameter that applies...
+		  q:=ns.Append2(p)                  // Copy it here.
+			q.comments=inlineComments(p.comments)// With only our own comments.
+		}                                   // Done copying parameters.
+		out.linkSection(ns)                 // Append it to the list.
+	}                                     // Done iterating sections.
+	out.firstComment=inlineComments(cfg.firstComment)// The comments at the end.
+	out.footer=inlineComments(cfg.footer) // And the footer.
+	out.lastComment=out.firstComment      // Find the last of them.
+	for out.lastComment!=nil&&out.lastComment.next!=nil{// Not at the end yet?
+	  out.lastComment=out.lastComment.next// Keep walking.
+	}                                     // Done finding the last comment.
+	if cfg.current!=nil{                  // Is a section selected?
+	  out.current=out.FindSection(cfg.current.GetName())// Yes, select it there too.
+	}                                     // Done checking for current section.
+	return out                            // Return the flat configuration.
+}                                       // ------------- Inline ------------- //
+// ------------------------------ // Environ // ----------------------------- //
+//  Flatten the configuration into KEY=value entries for a child's            //
+// environment, as PopenEnv() or exec.Cmd.Env take them. The key is          //
+// PREFIX_SECTION_NAME, uppercased with anything but letters and digits made  //
+// an underscore; an empty prefix or section name is left out along with its  //
+// underscore. Each section lists the parameters it inherits too, the overlay //
+// wins as it does for GetValue(), and the values of a multi-valued parameter //
+// are joined with GetEnvironSeparator(). SetWriteOrder(Sorted) sorts the    //
+// entries as it sorts what Print() writes.                                   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Environ(prefix string) []string{
+  var env []string                      // The entries we make.
+	cfg.follow()                          // Catch up with the original.
+	for _,s:=range sectionOrder(cfg.first,cfg.writeSorted()){// For each section...
+	  params:=s.EffectiveParameters()     // The parameters that apply here.
+		if cfg.writeSorted(){               // Do we write them sorted?
+		  sort.SliceStable(params,func(i,j int) bool{// Yes, sort them by name.
+			  return strings.ToLower(params[i].name)<strings.ToLower(params[j].name)
+			})                                // Done sorting the parameters.
+		}                                   // Done checking for sorting.
+		for _,p:=range params{              // For each parameter...
+		  v,ok:=cfg.lookupOverlay(s.name,p.name)// Does the overlay have it?
+			if !ok{                           // No, use what we read.
+			  v=strings.Join(p.values[:p.n],cfg.GetEnvironSeparator())
+			}                                 // Done getting the value.
+			env=append(env,environKey(prefix,s.name,p.name)+"="+v)
+		}                                   // Done iterating parameters.
+	}                                     // Done iterating sections.
+	return env                            // Return the environment.
+}                                       // ------------- Environ ------------ //
+// ------------------------------- // Dump // ------------------------------- //
+//  List the effective configuration as section.name=value lines, sorted so   //
+// that the dumps of two running instances can be compared with diff. As for  //
+// Environ(), each section lists the parameters it inherits too and the       //
+// overlay wins. A multi-valued parameter gets a section.name[i]=value line   //
+// per value, in index order; one with no values a section.name= line. The    //
+// values of secrets are "***" unless PrintSecrets(true) was called.          //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Dump() []string{
+  type entry struct{                    // The lines of one parameter.
+	  key   string                        // section.name, to sort by.
+		lines []string                      // Its lines in index order.
+	}                                     // Done defining the entry type.
+	var entries []entry                   // The parameters we list.
+	cfg.follow()                          // Catch up with the original.
+	for s:=cfg.first;s!=nil;s=s.GetNext(){// For each section...
+	  for _,p:=range s.EffectiveParameters(){// ...and what applies in it...
+		  key:=p.name                       // The key is the name...
+			if s.name!=""{                    // ...in its section, if it has one.
+			  key=s.name+"."+p.name           // It does, so say which.
+			}                                 // Done making the key.
+			values:=p.values[:p.n]            // What we read.
+			if v,ok:=cfg.lookupOverlay(s.name,p.name);ok{// Does the overlay have it?
+			  values=cfg.splitCSVList(v)      // Yes, split it like the file's.
+			}                                 // Done getting the values.
+			if cfg.dumpSecret(s,p)&&!cfg.showSecrets&&len(values)>0{// Hide it?
+			  values=[]string{"***"}          // Yes, one line says it is there.
+			}                                 // Done checking for secrets.
+			e:=entry{key:key}                 // The lines of this parameter.
+			switch len(values){               // How many values does it have?
+			  case 0:                         // None?
+				  e.lines=[]string{key+"="}     // Say it is there, but empty.
+				case 1:                         // Just one?
+				  e.lines=[]string{key+"="+values[0]}// No index needed.
+				default:                        // Many?
+				  for i,v:=range values{        // For each value...
+					  e.lines=append(e.lines,fmt.Sprintf("%s[%d]=%s",key,i,v))// ...say which.
+					}                             // Done iterating values.
+			}                                 // Done acting on the number of values.
+			entries=append(entries,e)         // Keep the entry.
+		}                                   // Done iterating parameters.
+	}                                     // Done iterating sections.
+	sort.SliceStable(entries,func(i,j int) bool{// Sort by key; indexes stay numeric.
+	  return entries[i].key<entries[j].key// Which comes first?
+	})                                    // Done sorting the entries.
+	var dump []string                     // The lines we return.
+	for _,e:=range entries{               // For each parameter...
+	  dump=append(dump,e.lines...)        // ...add its lines.
+	}                                     // Done collecting the lines.
+	return dump                           // Return the listing.
+}                                       // -------------- Dump -------------- //
+// ---------------------------- // dumpSecret // ---------------------------- //
+// Return true if p is a secret of s, or of the section s inherits it from.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) dumpSecret(s *Section,p *Parameter) bool{
+  if cfg.isSecret(s.name,p.name){       // Is it marked here?
+	  return true                         // Yes, it is a secret.
+	}                                     // Done checking this section.
+	for q:=cfg.first;q!=nil;q=q.GetNext(){// For each section...
+	  if q.FindParameter(p.name,false)==p&&cfg.isSecret(q.name,p.name){// Its secret?
+		  return true                       // Yes, it is a secret.
+		}                                   // Done checking the section.
+	}                                     // Done iterating sections.
+	return false                          // Not a secret anywhere.
+}                                       // ----------- dumpSecret ----------- //
+// ---------------------------- // environKey // ---------------------------- //
+// Join the non-empty parts with "_", uppercased, others made underscores.    //
+// -------------------------------------------------------------------------- //
+func environKey(parts ...string) string{
+  var keep []string                     // The parts we use.
+	for _,part:=range parts{              // For each part of the key...
+	  if part!=""{                        // Anything to use?
+		  keep=append(keep,part)            // Yes, keep it.
+		}                                   // Done checking for empty part.
+	}                                     // Done collecting parts.
+	return strings.Map(func(r rune) rune{ // Make it safe for the environment.
+	  switch{                             // What is this character?
+		case r>='a'&&r<='z':                // Lower case letter?
+		  return r-'a'+'A'                  // Yes, upper case it.
+		case r>='A'&&r<='Z',r>='0'&&r<='9': // Upper case letter or digit?
+		  return r                          // Yes, keep it.
+		}                                   // Done checking the character.
+		return '_'                          // Anything else is an underscore.
+	},strings.Join(keep,"_"))             // Done mapping the key.
+}                                       // ----------- environKey ----------- //
+// ------------------------------ // newLike // ----------------------------- //
+// Return an empty Configuration with the same settings as this one.          //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) newLike() *Configuration{
+  return &Configuration{                // A new configuration...
+		ext: cfg.ext,                       // Same default extension.
+		dropComments: cfg.dropComments,     // Same comment policy...
+		allowLoss: cfg.allowLoss,           // ...whether we may lose them...
+		commentPrefixes: cfg.commentPrefixes,// ...and comment prefixes.
+		sectOpen: cfg.sectOpen,             // Same section header...
+		sectClose: cfg.sectClose,           // ...delimiters.
+		ignoreImports: cfg.ignoreImports,   // Same import policy.
+		maxLineLen: cfg.maxLineLen,         // Same longest line.
+		floatSpecials: cfg.floatSpecials,   // Same float policy.
+		intPrefixes: cfg.intPrefixes,       // Same integer base.
+		floatFmt: cfg.floatFmt,             // Same float format...
+		floatPrec: cfg.floatPrec,           // ...and precision.
+		fileRefs: cfg.fileRefs,             // Same @path policy.
+		secrets: cfg.secrets,               // Same secrets...
+		showSecrets: cfg.showSecrets,       // ...and whether to show them.
+		envSep: cfg.envSep,                 // Same Environ() separator.
+		nameValidator: cfg.nameValidator,   // Same name policy.
+		dupSections: cfg.dupSections,       // Same duplicate policy.
+		trailingComma: cfg.trailingComma,   // Same trailing comma policy.
+		validateUTF8: cfg.validateUTF8,     // Same UTF-8 policy.
+		writeOrder: cfg.writeOrder,         // Same output order.
+		log: cfg.log,                       // Same logger.
+	}                                     // Done creating the configuration.
+}                                       // ------------- newLike ------------ //
+// --------------------------- // inlineComments // ------------------------- //
+// Copy the comments that Print() would write, minus import statements.       //
+// -------------------------------------------------------------------------- //
+func inlineComments(c *Comment) *Comment{
+  var head,tail *Comment                // The head and tail of the copy.
+	for ;c!=nil;c=c.next{                 // For each comment in the list...
+	  if c.IsImported()||c.IsImportStatement(){// Is it from another file?
+		  continue                          // Yes, leave it out.
+		}                                   // Done checking for imported comment.
+		q:=CopyComment(c)                   // Copy this comment.
+		if head==nil{                       // Is it the first one?
+		  head=q                            // Yes, it is the head of the list.
+		} else{                             // Else we have a list already.
+		  tail.SetNext(q)                   // Append it to the list.
+		}                                   // Done checking for first comment.
+		tail=q                              // Now we have a new tail.
+	}                                     // Done iterating comment list.
+	return head                           // Return the copy of the list.
+}                                       // --------- inlineComments --------- //
+// -------------------------- // Dependencies // ---------------------------- //
+//  Return the absolute paths of the files pulled in by "read", "import" and  //
+// "inherits" statements during the last ReadFile(), in the order they were   //
+// encountered and without duplicates. Build systems can use this to know    //
+// when a configuration must be reloaded. Files named by @path values, see    //
+// SetFileValueRefs(), are left out: they hold secrets, not configuration,    //
+// and their paths need not be handed around with the rest.                   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Dependencies() []string{
+  cfg.follow()                          // Catch up with the original.
+  return append([]string(nil),cfg.deps...)// Return a copy of the list.
+}                                       // ---------- Dependencies ---------- //
+// ---------------------------- // Warnings // ------------------------------ //
+//  Return the lines the last ReadFile() kept as comments because it could    //
+// not make sense of them, like a parameter missing its '=' sign or a broken  //
+// section header, in the order they were read, imported files included. The //
+// read still succeeds; these are likely typos worth reporting to the user.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Warnings() []Warning{
+  cfg.follow()                          // Catch up with the original.
+  return append([]Warning(nil),cfg.warnings...)// Return a copy of the list.
+}                                       // ------------ Warnings ------------ //
+// ------------------------- // addDependency // ---------------------------- //
+// Record a file referenced while reading, once, as an absolute path.         //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) addDependency(path string){
+  if abs,err:=filepath.Abs(path);err==nil{// Can we make it absolute?
+	  path=abs                            // Yes, use the absolute path.
+	}                                     // Done making path absolute.
+	for _,d:=range cfg.deps{              // For each dependency we have...
+	  if d==path{                         // Do we have this one already?
+		  return                            // Yes, nothing to do.
+		}                                   // Done checking for duplicate.
+	}                                     // Done iterating dependencies.
+	cfg.deps=append(cfg.deps,path)        // Remember this dependency.
+}                                       // --------- addDependency ---------- //
+// ---------------------------- // WithOverlay // ---------------------------- //
+//  Return a view of this Configuration whose GetValue() calls consult the    //
+// lookup function first and fall back to the values read from the file.     //
+// This lets command-line flags and environment variables override the file   //
+// at lookup time without mutating the parsed tree. An overlay value is a     //
+// list like the file's, so ports=8080,8443 gives index 0 and 1 as usual.     //
+// The view has its own overlay, selected section and settings, but shares    //
+// the original's sections and parameters: a later SetValue() or ReadFile()   //
+// on the original shows through the view, and changes made through the view //
+// are made to the original. WriteFile() on the view only writes the          //
+// file-backed values, never the overlay's.                                   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) WithOverlay(lookup func(section, name string) (string, bool)) *Configuration{
+  view:=*cfg                            // Same settings as the original...
+	view.base=cfg                         // ...and the same sections.
+	view.overlay=lookup                   // But the view gets its own overlay.
+	return &view                          // Return the view.
+}                                       // ----------- WithOverlay ---------- //
+// ------------------------------ // follow // ------------------------------ //
+//  Catch up with the Configuration a WithOverlay() view was made from: take  //
+// its sections, comments and what its last read found, and select our       //
+// section again by name if it has read or restored a new list since. It does //
+// nothing on a Configuration that is not such a view.                        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) follow(){
+  b:=cfg.base                           // The Configuration we are a view of.
+	if b==nil{                            // Are we a view?
+	  return                              // No, our sections are our own.
+	}                                     // Done checking for a view.
+	b.follow()                            // It may be a view itself.
+	if cfg.first!=b.first&&cfg.current!=nil{// Is its list a new one?
+	  cfg.current=b.FindSection(cfg.current.GetName())// Yes, select ours in it.
+	}                                     // Done checking for a new list.
+	cfg.first,cfg.last=b.first,b.last     // Its sections...
+	cfg.firstComment,cfg.lastComment=b.firstComment,b.lastComment// ...comments...
+	cfg.footer=b.footer                   // ...and footer.
+	cfg.deps,cfg.warnings=b.deps,b.warnings// What its last read found...
+	cfg.canWrite,cfg.lostComments=b.canWrite,b.lostComments// ...and left us.
+}                                       // ------------- follow ------------- //
+// ----------------------------- // ReadOnly // ----------------------------- //
+//  Return a view of this Configuration that a component can read but not     //
+// change. The view holds its own copy of the sections and parameters, taken  //
+// when ReadOnly() is called, so it does not see later changes made through   //
+// the original, and nothing done to the view or to the Sections it hands out //
+// can reach the original. ReadFile(), Reconfigure(), ClearParameters(),      //
+// RenameSection() and every Set*() call that returns an error fail with      //
+// ErrReadOnly on the view, AppendSection() returns nil, and ApplyDefaults()  //
+// and Restore() do nothing. The same goes for the Sections and Parameters    //
+// the view hands out: their setters, RenameParameter(), ClearParameters()    //
+// and AppendLiteralParameter() fail with ErrReadOnly, the Append*() methods  //
+// that return a pointer return nil, and the rest leave them alone. Clone()   //
+// still works, and CopyParameter() gives a Parameter that may be changed.    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) ReadOnly() *Configuration{
+  view:=*cfg                            // Same settings as the original...
+	view.restore(cfg.Snapshot())          // ...but a copy of its sections.
+	view.base=nil                         // Which do not follow the original.
+	view.readonly=true                    // And the view refuses changes.
+	view.freeze()                         // So do its parameters.
+	return &view                          // Return the view.
+}                                       // ------------ ReadOnly ------------ //
+// --------------------------- // IsReadOnly // ----------------------------- //
+//  Return true if this Configuration is a view returned by ReadOnly().       //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) IsReadOnly() bool { return cfg.readonly }
+// --------------------------- // lookupOverlay // -------------------------- //
+// Ask the overlay function, if any, for the value of a parameter.            //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) lookupOverlay(section, name string) (string,bool){
+  if cfg.overlay==nil{                  // Do we have an overlay?
+	  return "",false                     // No, nothing to look up.
+	}                                     // Done checking for overlay.
+	return cfg.overlay(section,name)      // Ask the overlay for the value.
+}                                       // ---------- lookupOverlay --------- //
+// --------------------------- // FindConfigFile // ------------------------- //
+//  Locate an application's configuration file. The candidates, in order, are //
+// the explicit path (if not empty), $XDG_CONFIG_HOME/<app>/<app>.cfg,        //
+// $HOME/.config/<app>/<app>.cfg and /etc/<app>/<app>.cfg. Unset environment  //
+// variables are skipped. The first candidate that exists and is not a        //
+// directory is returned; otherwise the error, which wraps os.ErrNotExist,    //
+// lists every location searched.                                             //
+// -------------------------------------------------------------------------- //
+func FindConfigFile(appName, explicit string) (string, error){
+  var cands []string                    // The locations to search.
+	if explicit!=""{                      // Were we given an explicit path?
+	  cands=append(cands,explicit)        // Yes, it comes first.
+	}                                     // Done checking for explicit path.
+	file:=appName+".cfg"                  // The file name we look for.
+	if xdg:=os.Getenv("XDG_CONFIG_HOME");xdg!=""{// Is XDG_CONFIG_HOME set?
+	  cands=append(cands,filepath.Join(xdg,appName,file))
+	}                                     // Done checking XDG_CONFIG_HOME.
+	if home:=os.Getenv("HOME");home!=""{  // Is HOME set?
+	  cands=append(cands,filepath.Join(home,".config",appName,file))
+	}                                     // Done checking HOME.
+	cands=append(cands,filepath.Join("/etc",appName,file))// The system location.
+	for _,c:=range cands{                 // For each candidate...
+	  if fi,err:=os.Stat(c);err==nil&&!fi.IsDir(){// Does it exist?
+		  return c,nil                      // Yes, this is the one.
+		}                                   // Done checking candidate.
+	}                                     // Done searching candidates.
+	return "",fmt.Errorf("configuration file for %s not found, searched %s: %w",
+	  appName,strings.Join(cands,", "),os.ErrNotExist)
+}                                       // --------- FindConfigFile --------- //
 // ------------------------------ // ReadFile // ---------------------------- //
 // Read a configuration file into the internal data structures.               //
 //                                                                            //
@@ -1869,14 +3654,132 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 	section string,                       // Section to read if importing       //
 	importing bool)error{                 // True if importing.                 //
                                         // ------------ ReadFile ------------ //
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  defer cfg.follow()                  // Yes, catch up when it is done...
+	  return cfg.base.ReadFile(filename,section,importing)// ...by the original.
+	}                                     // Done checking for a view.
+	return cfg.readFile(context.Background(),filename,section,importing)
+}                                       // ------------ ReadFile ------------ //
+// ------------------------------ // ReadDir // ------------------------------ //
+//  Read a conf.d style directory: the files in dir matching pattern, "*.cfg"  //
+// if empty, are read in lexical order and merged into this Configuration. A  //
+// section found in several files gets the parameters of all of them, and a   //
+// parameter set in several files keeps the value from the last one. With the //
+// DuplicateError policy a section may only appear in one of the files. The   //
+// result is not one file, so WriteFile() refuses it until MarkWritable().    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) ReadDir(dir string, pattern string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  defer cfg.follow()                  // Yes, catch up when it is done...
+	  return cfg.base.ReadDir(dir,pattern)// ...by the original.
+	}                                     // Done checking for a view.
+	if pattern==""{                       // Were we given a pattern?
+	  pattern="*.cfg"                     // No, use the usual one.
+	}                                     // Done checking for pattern.
+	files,err:=filepath.Glob(filepath.Join(dir,pattern))// Find the fragments.
+	if err!=nil{                          // Was the pattern good?
+	  return &ConfigError{File: dir,Op: "read",Err: err}// No, return error.
+	}                                     // Done checking for bad pattern.
+	sort.Strings(files)                   // Read them in lexical order.
+	cfg.deps=nil                          // Forget the last read's dependencies.
+	cfg.warnings=nil                      // And its warnings.
+	for _,file:=range files{              // For each fragment...
+	  if fi,err:=os.Stat(file);err!=nil||!fi.Mode().IsRegular(){// Is it a file?
+		  continue                          // No, skip it.
+		}                                   // Done checking for regular file.
+	  frag:=cfg.newLike()                 // Read it on its own first...
+		if err:=frag.ReadFile(file,"",false);err!=nil{// ...so it can't clash.
+		  return err                        // Could not read it.
+		}                                   // Done reading the fragment.
+		cfg.warnings=append(cfg.warnings,frag.warnings...)// Keep its warnings.
+		if err:=cfg.mergeFragment(frag,file);err!=nil{// Merge it into ours.
+		  return err                        // Could not merge it.
+		}                                   // Done merging the fragment.
+		cfg.addDependency(file)             // We depend on it...
+		for _,d:=range frag.deps{           // ...and on what it read.
+		  cfg.addDependency(d)              // Remember each of them.
+		}                                   // Done adding dependencies.
+	}                                     // Done iterating fragments.
+	cfg.resolveParents()                  // Link the sections to their parents.
+	cfg.resolveSectionRefs()              // And to the sections they reference.
+	cfg.canWrite=false                    // It is not one file any more.
+	return nil                            // All fragments merged.
+}                                       // ------------- ReadDir ------------ //
+// --------------------------- // mergeFragment // -------------------------- //
+//  Merge the sections of a fragment read by ReadDir() into this one, letting //
+// the fragment's parameters replace ours.                                    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) mergeFragment(frag *Configuration,file string) error{
+  for fs:=frag.first;fs!=nil;fs=fs.GetNext(){// For each section of the fragment...
+	  s:=cfg.FindSection(fs.name)         // Do we have it already?
+		if s!=nil&&cfg.dupSections==DuplicateError{// Yes, is that allowed?
+		  return &ConfigError{File: file,Section: fs.name,Op: "read",Err: errors.New("section is also in an earlier file")}
+		}                                   // Done checking the policy.
+		if s==nil{                          // Is it a new section?
+		  s=cfg.AppendSection(fs.name,copyComments(fs.comments),false)// Yes, add it.
+		}                                   // Done checking for new section.
+		if len(fs.parentNames)>0{           // Does the fragment give it parents?
+		  s.SetParentNames(strings.Join(fs.parentNames,","))// Yes, they win.
+		}                                   // Done checking for parents.
+		for p:=fs.first;p!=nil;p=p.GetNext(){// For each of its parameters...
+		  old:=s.FindParameter(p.name,false)// Do we have it already?
+			if old==nil{                      // Is it new?
+			  s.Append2(p)                    // Yes, add a copy.
+				continue                        // On to the next one.
+			}                                 // Done checking for new parameter.
+			old.values=append([]string(nil),p.values...)// Replace the values...
+			old.quotes=append([]byte(nil),p.quotes...)// ...and their quotes.
+			old.n=p.n                         // And everything else
+			old.file,old.line=p.file,p.line   // that says what it is.
+		}                                   // Done iterating parameters.
+	}                                     // Done iterating sections.
+	if c:=copyComments(frag.firstComment);c!=nil{// Any comments at its end?
+	  if cfg.firstComment==nil{           // Yes, do we have any?
+		  cfg.firstComment=c                // No, these are the first.
+		} else{                             // Else add them after ours.
+		  cfg.lastComment.SetNext(c)        // Append the new comments.
+		}                                   // Done checking for first comment.
+		for cfg.lastComment=c;cfg.lastComment.next!=nil;{// Find the last one.
+		  cfg.lastComment=cfg.lastComment.next// Keep walking.
+		}                                   // Done finding the last comment.
+	}                                     // Done merging comments.
+	return nil                            // Merged.
+}                                       // ---------- mergeFragment --------- //
+// ----------------------------- // readFile // ----------------------------- //
+//  Open a file and parse it with readFrom(), keeping track of how deeply     //
+// nested we are in read, import and inherits statements.                     //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) readFile(ctx context.Context,filename,section string,importing bool) error{
+  if cfg.depth==0{                      // Is this the outermost ReadFile?
+	  cfg.deps=nil                        // Yes, forget the last file's dependencies.
+		cfg.warnings=nil                    // And its warnings.
+	}                                     // Done checking for outermost call.
+	cfg.depth++                           // We are one file deeper.
+	defer func(){ cfg.depth-- }()         // And back out when done.
   f,err:=os.Open(filename)              // Open the file for reading. 
   if err!=nil{                          // Error opening the file?
-    return fmt.Errorf("error opening file %s: %w", filename, err)// Yes, return error.
+    return &ConfigError{File: filename,Op: "read",Err: err}// Yes, return error.
   }                                     // Done checking for error opening file.
 	defer f.Close()                       // Close the file when done.
+	return cfg.readFrom(ctx,f,filename,section,importing)// Parse it.
+}                                       // ------------ readFile ------------ //
+// ----------------------------- // readFrom // ----------------------------- //
+//  Parse a configuration from r into the internal data structures, as       //
+// described for ReadFile(). filename is only used to name r in errors and    //
+// as our pathname. Between lines, it gives up with ctx.Err() once ctx is     //
+// cancelled.                                                                 //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) readFrom(ctx context.Context,r io.Reader,filename,section string,importing bool) error{
 	cfg.path=filename                     // Store the last opened file path.
-	const linelen=32*1024                 // Maximum line length is 32KiB.
-	reader:=bufio.NewReaderSize(f,linelen)// Buffered reader to read the file.
+	const linelen=32*1024                 // Buffer size is 32KiB.
+	reader:=bufio.NewReaderSize(r,linelen)// Buffered reader to read the file.
+	maxlen:=cfg.GetMaxLineLength()        // Longest line, continuations included.
 	var(                                  // Our local variables list to hold state info.
 	  lineno     int                      // The current line number.
 		inBlock bool                        // True if we are inside a block comment.
@@ -1914,6 +3817,12 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 	// to the target object.
 	// ---------------------------------- //
 	appendComment:=func(raw string){      // Append a new comment to the list.
+	  if cfg.dropComments{                // Are we saving comments?
+		  if !importing&&strings.TrimSpace(raw)!=""{// No, is it one we'd write back?
+			  cfg.lostComments=true           // Yes, WriteFile() must not lose it.
+			}                                 // Done checking for a lost comment.
+		  return                            // No, so don't even build it.
+		}                                   // Done checking for saving comments.
 	  c:=NewComment(raw,importing)        // Create a new Comment object.
 		if c==nil{                          // Could we create a new Comment?
 		  return                            // No, so just return.
@@ -1926,16 +3835,31 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 		cTail=c                             // Set the tail to the new comment.
 	}                                     // Done defining the appendComment function.
 	// ---------------------------------- //
+	// Ad-hoc function to say where in the file we failed.
+	// ---------------------------------- //
+	fail:=func(sect,param string,err error) error{
+	  return &ConfigError{File: filename,Line: lineno,Section: sect,Param: param,Op: "read",Err: err}
+	}                                     // Done defining the fail function.
+	// ---------------------------------- //
+	// Ad-hoc function to note a line we keep as a comment but did not expect.
+	// ---------------------------------- //
+	warn:=func(line string,start int,reason string){
+	  cfg.warnings=append(cfg.warnings,Warning{File: filename,Line: start,Text: line,Reason: reason})
+	}                                     // Done defining the warn function.
+	// ---------------------------------- //
 	// Now we will begin processing the file line by line.
 	// ---------------------------------- //
 	for{                                  // While we have a sequence of bytes to read...
-	  n,err:=reader.ReadBytes('\n')       // Read a line from the file.
+	  if err:=ctx.Err();err!=nil{         // Were we cancelled?
+		  return err                        // Yes, give up.
+		}                                   // Done checking for cancellation.
+	  n,err:=readLine(reader,maxlen)      // Read a line from the file.
 		eof:=errors.Is(err,io.EOF)          // Is it the end of the file?
+		lineno++                            // Increment the line number.
 		if err!=nil&&!eof{                  // An error and not EOF?  
-		  return fmt.Errorf("error reading file %s at line %d: %w", filename, lineno, err)// Yes, return error.
+		  return fail("","",err)            // Yes, return error.
 		}                                   // Done checking for error reading file.
-		lineno++                            // Increment the line number.
-		n=bytes.TrimRight(n,"\r\n")         // Remove trailing newlines/carriage returns.
+		start:=lineno                       // Where a continued line starts.
 		// Handle block comments (comments that start with /* and end with */).
 		if bytes.HasPrefix(n,[]byte("/*")){ // Are we entering a block comment?
 		  inBlock=true                      // Yes, so set the flag.
@@ -1956,8 +3880,15 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 		// -------------------------------- //
 		for bytes.HasSuffix(n,[]byte{'\\'})&&!eof{// While we have a continuation line...
 		  n=n[:len(n)-1]                    // Remove backslash from end of the line.
-			next,_:=reader.ReadBytes('\n')    // Read the next line from the file.
-			next=bytes.TrimLeft(next," \t")   // Remove leading whitespace from the next line.
+			next,nerr:=readLine(reader,maxlen-len(n))// Read the next line from the file.
+			eof=errors.Is(nerr,io.EOF)        // Was that the last line of the file?
+			if nerr!=nil&&!eof{               // An error and not EOF?
+			  lineno++                        // The failing line is the next one.
+			  return fail("","",nerr)         // Return error.
+			}                                 // Done checking for error reading file.
+			if !endsInQuote(n){               // Is the value still inside quotes?
+			  next=bytes.TrimLeft(next," \t") // No, remove leading whitespace from the next line.
+			}                                 // Yes, the whitespace is part of the value.
 			n=append(n,next...)               // Append the next line to the current line.
 			lineno++                          // Increment the line number.
 		}                                   // Done checking for continuation lines.
@@ -1979,18 +3910,19 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 		// -------------------------------- //
 		switch{                             // Act according to the line content.
 		  // Comments
-			case strings.HasPrefix(line,"#"): // Is it a comment line?
+			case cfg.isCommentLine(line):     // Is it a comment line?
 			  appendComment(line)             // Yes, so append it to the comment list.
 			// Read "file.cfg"
 			case strings.HasPrefix(line,"read \""):// Is it a read statement?
 			  flushComments(cfg)              // Yes, flush comment to Configuration object.
 				fname:=strings.TrimSpace(line[5:])// Get the filename from the line.
 				if len(fname)<2||fname[0]!='"'||fname[len(fname)-1]!='"'{
-				  return fmt.Errorf("invalid read statement at line %d: %s", lineno, line)// No, return error.
+				  return fail("","",fmt.Errorf("invalid read statement: %s", line))// No, return error.
 				}                               // Done checking for malformed read statement.
 				target:=fname[1:len(fname)-1]   // Remove the quotes from the filename.
-				if err:=cfg.ReadFile(target,"",false);err!=nil{
-				  return fmt.Errorf("error reading file %s at line %d: %w", target, lineno, err)// No, return error.
+				cfg.addDependency(target)       // Remember we depend on it.
+				if err:=cfg.readFile(ctx,target,"",false);err!=nil{
+				  return fail("","",err)        // No, return error.
 				}                               // Done reading the file.
 			// Import "file.cfg"
 			case strings.HasPrefix(line,"import "):// Are we importing a whole file?!
@@ -2000,33 +3932,62 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 			  flushComments(cfg)              // Yes, flush comment to Configuration object.
 				fname:=strings.TrimSpace(line[len("import "):])// Get the filename from the line.
 				if len(fname)<2||fname[0]!='"'||fname[len(fname)-1]!='"'{
-				  return fmt.Errorf("invalid import statement at line %d: %s", lineno, line)// No, return error.
+				  return fail("","",fmt.Errorf("invalid import statement: %s", line))// No, return error.
 				}                               // Done checking for malformed import statement.
 				target:=fname[1:len(fname)-1]   // Remove the quotes from the filename.
-				if err:=cfg.ReadFile(target,"",true);err!=nil{// Read the imported file.
-				  return fmt.Errorf("error reading imported file %s at line %d: %w", target, lineno, err)// No, return error.
+				cfg.addDependency(target)       // Remember we depend on it.
+				if err:=cfg.readFile(ctx,target,"",true);err!=nil{// Read the imported file.
+				  return fail("","",err)        // No, return error.
 				}                               // Done reading the imported file.
 			// Section Headers
-			case line[0]=='[':                // Is it a section header?
-			  flushComments(cfg)              // Yes flush section commments to Configuration object.
+			case cfg.isSectionHeader(line):   // Is it a section header?
 				if importingSect{               // Are we importing a section?
+				  flushComments(cfg)            // Yes, flush comments to Configuration object.
 				  return nil                    // We are done with the imported section.
 				}                               // Done checking if importing section.
 				sectName,parents,fromfile,err:=cfg.detectSectionHeader((line))// Detect the section header.
 				if err!=nil{                    // Could we detect the section header?
-				  appendComment(line)           // No, so treat the section hdr as a comment.
+				  warn(line,start,"invalid section header")// No, say so...
+				  appendComment(line)           // ...and treat the section hdr as a comment.
 					break                         // Skip the rest of the line.
 				}                               // Done detecting section header.
 				if section!=""&&sectName!=section{// Are we looking for a specific section?
+				  flushComments(cfg)            // Yes, its comments aren't this one's.
 				  break                         // Break, we are still looking for it.
 				}                               // Done checking for section name.
 				searching=false                 // We are no longer searching for a section.
-				currSect=cfg.AppendSection(sectName,cHead,importing)// Append a new Section object.
-				currSect.SetParentNames(parents)// Set the parent names for the section.
-				flushComments(currSect)         // Flush the comments to the section.
+				merged:=false                   // True if we add to an earlier section.
+				if dup:=cfg.FindSection(sectName);dup!=nil&&!importing{// Seen this header before?
+				  switch cfg.dupSections{       // Yes, what do we do about it?
+					  case DuplicateError:        // Refuse the file?
+						  return fail(sectName,"",errors.New("duplicate section"))
+						case DuplicateMergeInto:    // Add to the first one?
+						  if parents!=""&&!strings.EqualFold(parents,strings.Join(dup.parentNames,",")){
+							  return fail(sectName,"",errors.New("duplicate section has different parents"))
+							}                         // Done checking the parents.
+							if dup.comments==nil{     // Does the first one have comments?
+							  dup.comments=cHead      // No, so take these.
+							} else{                   // Else add these after its own.
+							  c:=dup.comments         // Start at its first comment...
+								for c.next!=nil{        // ...and find...
+								  c=c.next              // ...its last one.
+								}                       // Found the last one.
+								c.next=cHead            // Then append ours.
+							}                         // Done merging comments.
+							cHead,cTail=nil,nil       // The comments are used up.
+							currSect=dup              // Parameters go into the first one.
+							merged=true               // So don't append a new one.
+					}                             // Done acting according to the policy.
+				}                               // Done checking for duplicate section.
+				if !merged{                     // Did we merge into an earlier section?
+				  currSect=cfg.AppendSection(sectName,cHead,importing)// Append a new Section object.
+				  currSect.SetParentNames(parents)// Set the parent names for the section.
+				  flushComments(currSect)       // Flush the comments to the section.
+				}                               // Done checking for merged section.
 				if fromfile!=""{                // Is there a file to import from?
-				  if err:=cfg.ReadFile(fromfile,sectName,true);err!=nil{// Read from imported file.
-					  return fmt.Errorf("error reading imported file %s at line %d: %w", fromfile, lineno, err)// No, return error.
+				  cfg.addDependency(fromfile)   // Remember we depend on it.
+				  if err:=cfg.readFile(ctx,fromfile,sectName,true);err!=nil{// Read from imported file.
+					  return fail(sectName,"",err)// No, return error.
 					}                             // Done reading imported file.
 				}                               // Done checking for imported file.
 			// Parameters
@@ -2037,20 +3998,26 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 				}                               // Done checking for searching section.
 				name,values,err:=cfg.detectParameter(line)// Detect the parameter.
 				if err!=nil{                    // Could we detect the parameter?
-				  appendComment(string(n))      // No, so treat the line as a comment.
+				  warn(line,start,"not a comment, section header or parameter")// No, say so...
+				  appendComment(string(n))      // ...and treat the line as a comment.
 					break                         // Skip the rest of the line.
 				}                               // Done detecting parameter.
+				if err:=cfg.checkName(name);err!=nil{// Does the application accept the name?
+				  return fail(currSect.name,name,fmt.Errorf("invalid parameter name: %w", err))
+				}                               // Done checking the name.
+				if cfg.validateUTF8&&!utf8.ValidString(line){// Must it be valid UTF-8?
+				  return fail(currSect.name,name,ErrInvalidUTF8)// Yes, and it is not.
+				}                               // Done checking for UTF-8.
 				// ---------------------------- //
 				// If the line is of the form Ref=[SectionName], we don't want a 
 				// parameter called Ref, but rather a shallow-copy of [sectionName].
 				// So we check for opening and closing brackets of the value of Ref and
-				// build the copy.
+				// build the copy. Ref=[1] is a reference too; only a value that can't
+				// be one, like Ref=["a"] or Ref=[], may be a JSON array.
 				// ---------------------------- //
 				if len(values.arr)==1{          // Do we have a single value?
-				  v:=strings.TrimSpace(values.arr[0])// Yes, get the value.
-					if strings.HasPrefix(v,"[")&&strings.HasSuffix(v,"]"){// Is it a section reference?
-					  target:=strings.TrimSpace(v[1:len(v)-1])// Yes, get the section name.
-						ref:=cfg.AppendSection(name,cHead,importing)// make [Ref]
+				  if target,ok:=sectionRef(values.raw);ok{// Yes, is it a section reference?
+						ref:=cfg.AppendSection(name,cHead,importing)// Yes, make [Ref]
 						flushComments(ref)          // Flush the comments to the section.
 						if tgt:=cfg.FindSection(target);tgt!=nil{// Did we find the section?
 				  // -------------------------- //
@@ -2068,7 +4035,32 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 						break                       // Skip the rest of the line.
 				  }                             // Done checking for section reference.
 				}																// Done checking for single value.
+				trailing:=values.trailingComma()// Does the list end in a comma?
+				if trailing{                    // Yes, what does that mean?
+				  if cfg.trailingComma==TrailingCommaError{// An error?
+					  return fail(currSect.name,name,ErrTrailingComma)// Yes, say where.
+					}                             // Done checking for an error.
+					values.arr=values.arr[:len(values.arr)-1]// Drop the empty value...
+					values.quotes=values.quotes[:len(values.quotes)-1]// ...and its quote.
+				}                               // Done checking for a trailing comma.
 				p:=currSect.AppendParameter(name,values.raw,cHead,importing)// Append a new Parameter object.
+				p.file,p.line=filename,start    // Remember where we read it.
+				p.flag=values.flag              // Remember if it had no '='.
+				if trailing&&cfg.trailingComma==KeepEmpty{// Keep the empty value?
+				  _=p.SetValuePtrOnIndex(p.n,"",0)// Yes, put it back at the end.
+				}                               // Done checking for keeping it.
+				if cfg.fileRefs&&p.n==1&&len(p.quotes)>0&&p.quotes[0]==0&&len(p.values[0])>1&&p.values[0][0]=='@'{
+				  ref:=p.values[0][1:]          // Yes, the file the value is in.
+					b,err:=os.ReadFile(ref)       // Read the value from it.
+					if err!=nil{                  // Could we?
+					  return fail(currSect.name,name,err)// No, return error.
+					}                             // Done reading the value.
+					p.SetValuePtr(strings.TrimSpace(string(b)),0)// That is our value.
+				  p.ref=ref                     // But we write the reference back.
+				}                               // Done checking for a file reference.
+				if looksLikeJSON(values.raw){   // Is the value a JSON document?
+				  p.rawJSON=values.raw          // Yes, keep it whole for GetValueJSON().
+				}                               // Done checking for a JSON document.
 				flushComments(p)                // Flush the comments to the parameter.
 		}                                   // Done acting according to the line content.
 		if eof{                             // Are we at the end of the file?
@@ -2078,17 +4070,84 @@ func (cfg *Configuration) ReadFile(     // Nil error if successful.           //
 	flushComments(cfg)                    // Flush any remaining comments to the Configuration object.
 	cfg.resolveParents()                  // Resolve the parent sections for all sections.
 	cfg.resolveSectionRefs()              // Resolve the section references for all sections.
+	if cfg.depth==1&&!importing{          // Did we just read a whole file ourselves?
+	  cfg.canWrite=true                   // Yes, so it may be written back.
+	}                                     // Done checking for a whole file.
 	return nil                            // Return nil error if successful.
-}                                       // ------------ ReadFile ------------ //
+}                                       // ------------ readFrom ------------ //
+// --------------------------- // ReadContext // ---------------------------- //
+//  Parse a configuration from r, which name names in errors, like ReadFile() //
+// does for a file. Once ctx is cancelled, parsing stops with ctx.Err() at    //
+// the next line or the next read from r, whichever comes first. A read that  //
+// is already blocked is not interrupted: to give up on a source that stalls, //
+// close it or give it a deadline, as net.Conn's SetReadDeadline() does. The  //
+// configuration is parsed into a scratch copy that replaces ours only on     //
+// success, so on any error, cancellation included, this Configuration is     //
+// left untouched.                                                            //
+// read, import and inherits statements in r are read from files as usual.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) ReadContext(ctx context.Context, r io.Reader, name string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  defer cfg.follow()                  // Yes, catch up when it is done...
+	  return cfg.base.ReadContext(ctx,r,name)// ...by the original.
+	}                                     // Done checking for a view.
+	tmp:=*cfg                             // Keep our settings...
+	tmp.first,tmp.last,tmp.current=nil,nil,nil// ...but none of our sections...
+	tmp.firstComment,tmp.lastComment=nil,nil// ...or comments...
+	tmp.footer=nil                        // ...or footer...
+	tmp.deps,tmp.canWrite=nil,false       // ...or what we read last time.
+	tmp.warnings=nil                      // ...or what we did not understand.
+	tmp.lostComments=false                // ...or the comments we dropped.
+	tmp.depth=1                           // We are the outermost read.
+	if err:=tmp.readFrom(ctx,ctxReader{ctx,r},name,"",false);err!=nil{// Parse it.
+	  return err                          // Failed, we are untouched.
+	}                                     // Done checking for error.
+	tmp.depth=0                           // Done reading.
+	*cfg=tmp                              // Take what we read.
+	for s:=cfg.first;s!=nil;s=s.GetNext(){// For each section we read...
+	  s.cfg,s.owner=cfg,cfg               // ...it belongs to us, not to tmp...
+		for ss:=s.firstSection;ss!=nil;ss=ss.GetNext(){// ...and so do...
+		  ss.cfg=cfg                        // ...its section references.
+		}                                   // Done iterating references.
+	}                                     // Done iterating sections.
+	return nil                            // Success.
+}                                       // ----------- ReadContext ---------- //
+// ---------------------------- // ctxReader // ----------------------------- //
+//  An io.Reader that fails with ctx.Err() once ctx is cancelled, so that     //
+// ReadContext() stops at its next read, not only at the end of a line.       //
+// -------------------------------------------------------------------------- //
+type ctxReader struct{
+  ctx context.Context                   // Stop reading once it is done.
+	r   io.Reader                         // Where we read from.
+}
+func (cr ctxReader) Read(b []byte) (int,error){
+  if err:=cr.ctx.Err();err!=nil{        // Were we cancelled?
+	  return 0,err                        // Yes, read no more.
+	}                                     // Done checking for cancellation.
+	return cr.r.Read(b)                   // No, read on.
+}                                       // -------------- Read -------------- //
 // ----------------------------- // SplitCSVList // ------------------------- //
 // Split a comma-separated list of values into a slice of strings.            //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) splitCSVList(list string) []string{
+  res,_:=splitCSVQuoted(list)           // Split it, we don't need the quotes.
+	return res                            // Return the result slice of strings.
+}                                       // ----------- SplitCSVList --------- //
+// ---------------------------- // splitCSVQuoted // ------------------------ //
+//  Split a comma-separated list like splitCSVList(), and also return the     //
+// quote character each value was enclosed in, 0 if it was not quoted.       //
+// -------------------------------------------------------------------------- //
+func splitCSVQuoted(list string) ([]string,[]byte){
   var(                                  // Local variables for the function.
 	  res  []string                       // The result slice of strings.
+		quotes []byte                       // The quote used by each value.
 		curr strings.Builder						    // A string builder to build the values.
 		inquotes bool                       // Are we inside quotes?
 		quote rune	                        // Where to store a quote character if any.
+		fieldq byte                         // The quote used by the current value.
 	)                                     // Done declaring local variables.
 	for _,v:=range list{                  // For each character in the list....
 	  switch {                            // Act according to the character.
@@ -2097,36 +4156,49 @@ func (cfg *Configuration) splitCSVList(list string) []string{
 				  inquotes=false                // Yes, then that must be the closing one.
 				} else if !inquotes{            // Else we are about to enter a quote
 				  inquotes,quote=true,v         // So say that.
+					if fieldq==0{                 // Is it the value's first quote?
+					  fieldq=byte(v)              // Yes, remember it.
+					}                             // Done checking for first quote.
 				}                               // Done checking for quotes.
 			case v==','&&!inquotes:           // Is it a comma and not inside quotes?
 			  res=append(res,strings.TrimSpace(curr.String()))// Yes, add the current value to the result.
+				quotes=append(quotes,fieldq)    // And how it was quoted.
 		    curr.Reset()                    // Reset the string builder.
+				fieldq=0                        // The next value is not quoted yet.
 			default:                          // Any other character.
 			  curr.WriteRune(v)               // Just add it to the current value.
 		}                                   // Done acting according to these characters.
 	}                                     // Done iterating through the list.
 	res=append(res,strings.TrimSpace(curr.String()))// Add the last value to the result.
-	return res                            // Return the result slice of strings.
-}                                       // ----------- SplitCSVList --------- //
+	quotes=append(quotes,fieldq)          // And how it was quoted.
+	return res,quotes                     // Return the values and their quotes.
+}                                       // --------- splitCSVQuoted --------- //
 
 // ----------------------- // detectSectionHeader // ------------------------ //
 // Detect if the current line is a section header, and if it is... Then, we   //
-// check if it has parents, or if it is an imported section.                  //
+// check if it has parents, or if it is an imported section. The name and the //
+// parents may be quoted, as in ["weird:name"], so that a colon or bracket in //
+// them is taken literally.                                                   //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) detectSectionHeader(line string) (name,parents,fromfile string,err error){
-  if !strings.HasPrefix(line,"["){      // Is it a section header?
+  open,close:=cfg.GetSectionDelimiters()// What surrounds the section name.
+  if !strings.HasPrefix(line,open){     // Is it a section header?
 	  return "","","",fmt.Errorf("line \"%s\" is not a section header",line)// No
 	}                                     // Done checking if not section header.
-	end:=strings.IndexByte(line,']')      // Find the end of the section header.
+	end:=indexUnquotedString(line[len(open):],close)// Find the end of the section header.
 	if end==-1{                           // No Closing bracket?
 	  return "","","",fmt.Errorf("line \"%s\" is not a valid section header",line)// No
 	}                                     // Done checking for closing bracket.
-	field:=strings.TrimSpace(line[1:end]) // Get the field name.
-	if colon:=strings.IndexByte(field,':');colon!=-1{// Is there a colon in the field?
-	  name=strings.TrimSpace(field[:colon])// Yes get string up to the colon.
-		parents=strings.TrimSpace(field[colon+1:])// Get the parents after the colon.
+	field:=strings.TrimSpace(line[len(open):len(open)+end])// Get the field name.
+	end+=len(open)+len(close)-1           // Where the closing delimiter ends.
+	if colon:=indexUnquoted(field,':');colon!=-1{// Is there a colon in the field?
+	  name=unquote(strings.TrimSpace(field[:colon]))// Yes get string up to the colon.
+		if ps:=strings.TrimSpace(field[colon+1:]);ps!=""{// Any parents after the colon?
+		  list,_:=splitCSVQuoted(ps)        // Yes, split them, dropping quotes.
+		  parents=strings.Join(list,",")    // Get the parents after the colon.
+		}                                   // Done checking for parents.
 	} else{                               // Else there is no colon...
-	  name=field                          // So there is no hierarchical relationship.
+	  name=unquote(field)                 // So there is no hierarchical relationship.
 	}                                     // Done checking for colon.
 	rest:=strings.TrimSpace(line[end+1:]) // Get the rest of the line after the closing bracket.
 	if rest==""{                          // Nothing after the closing bracket.
@@ -2145,22 +4217,191 @@ func (cfg *Configuration) detectSectionHeader(line string) (name,parents,fromfil
 }                                       // ----- detectSectionHeader -------- //
 // -------------------------- // detectParameter // ------------------------- //
 // Detect if the current line is a parameter, and if it is... Then, we check  //
-// if it has a value, or if it is a reference to another section.             //
+// if it has a value, or if it is a reference to another section. A line     //
+// that is a single bare token, like "verbose", is a flag: a parameter with  //
+// no values. Anything else without an '=' sign is not a parameter.          //
 // -------------------------------------------------------------------------- //
 type paramVals struct{
   raw string														// The raw value of the parameter.
 	arr []string                          // The array of values for the parameter.
+	quotes []byte                         // The quote around each value, 0 if none.
+	flag bool                             // True if the line had no '=' sign.
+}
+// Return true if the values end in an empty, unquoted value after a comma.
+func (v paramVals) trailingComma() bool{
+  n:=len(v.arr)                         // How many values we split.
+	return n>1&&v.arr[n-1]==""&&v.quotes[n-1]==0// Is the last one phantom?
 }
 func (cfg *Configuration) detectParameter(line string) (name string,vals paramVals, err error){
-  eq:=strings.IndexByte(line,'=')       // Find the equals sign in the line.
+  eq:=indexUnquoted(line,'=')           // Find the first unquoted equals sign.
+	if eq<0&&indexUnquoted(line,' ')<0&&indexUnquoted(line,'\t')<0{// A bare token?
+	  if name=unquote(line);name!=""{     // Yes, is there a name in it?
+		  vals.flag=true                    // Yes, it is a flag.
+		  return name,vals,nil              // Return the name and no values.
+		}                                   // Done checking for a name.
+	}                                     // Done checking for a flag.
 	if eq<=0{                             // Do we have an equals sign?
 	  return "",vals,fmt.Errorf("line \"%s\" is not a valid parameter",line)// No, return error.
 	}                                     // Done checking for equals sign.
-	name=strings.TrimSpace(line[:eq])     // Get everything before the equals sign.
+	name=unquote(strings.TrimSpace(line[:eq]))// Get everything before the equals sign.
+	if name==""{                          // Was there anything before the '='?
+	  return "",vals,fmt.Errorf("line \"%s\" is not a valid parameter",line)// No, return error.
+	}                                     // Done checking for empty name.
   vals.raw=strings.TrimSpace(line[eq+1:])// Get everything after the equals sign.
-	vals.arr=cfg.splitCSVList(vals.raw)   // Split the values by commas.
+	vals.arr,vals.quotes=splitCSVQuoted(vals.raw)// Split the values by commas.
 	return name,vals,nil                  // Return the name and values.
 }                                       // -------- detectParameter --------- //
+// ---------------------------- // quoteName // ----------------------------- //
+// Quote a parameter name that holds an '=' or a blank, so it reads back as   //
+// the same name rather than being split at its first unquoted '='.           //
+// -------------------------------------------------------------------------- //
+func quoteName(name string) string{
+  if !strings.ContainsAny(name,"= \t"){// Anything that needs quoting?
+	  return name                         // No, write it as it is.
+	}                                     // Done checking for quoting.
+	if strings.ContainsRune(name,'"'){    // Does it hold a double quote?
+	  return "'"+name+"'"                 // Yes, use single quotes then.
+	}                                     // Done checking for double quotes.
+	return "\""+name+"\""                 // Quote it with double quotes.
+}                                       // ----------- quoteName ----------- //
+// --------------------------- // indexUnquoted // -------------------------- //
+//  Return the index of the first c in line that is not inside single or     //
+// double quotes, or -1 if there is none. Everything after that index,        //
+// including any further c characters, is left for the caller to handle.      //
+// -------------------------------------------------------------------------- //
+func indexUnquoted(line string,c byte) int{
+  var quote byte                        // The quote we are inside of, 0 if none.
+	for i:=0;i<len(line);i++{             // For each byte in the line...
+	  b:=line[i]                          // Get the i'th byte.
+		switch{                             // Act according to the byte.
+		  case quote!=0:                    // Are we inside quotes?
+			  if b==quote{                    // Yes, is this the closing quote?
+				  quote=0                       // Yes, we are out of the quotes.
+				}                               // Done checking for closing quote.
+			case b=='"'||b=='\'':             // Is it an opening quote?
+			  quote=b                         // Yes, remember which one.
+			case b==c:                        // Is it what we are looking for?
+			  return i                        // Yes, return its index.
+		}                                   // Done acting according to the byte.
+	}                                     // Done iterating through the line.
+	return -1                             // Not found.
+}                                       // ---------- indexUnquoted --------- //
+// ------------------------ // indexUnquotedString // ----------------------- //
+// Like indexUnquoted(), but look for the string sub instead of one byte.     //
+// -------------------------------------------------------------------------- //
+func indexUnquotedString(line,sub string) int{
+  var quote byte                        // The quote we are inside of, 0 if none.
+	for i:=0;i<len(line);i++{             // For each byte in the line...
+	  b:=line[i]                          // Get the i'th byte.
+		switch{                             // Act according to the byte.
+		  case quote!=0:                    // Are we inside quotes?
+			  if b==quote{                    // Yes, is this the closing quote?
+				  quote=0                       // Yes, we are out of the quotes.
+				}                               // Done checking for closing quote.
+			case b=='"'||b=='\'':             // Is it an opening quote?
+			  quote=b                         // Yes, remember which one.
+			case strings.HasPrefix(line[i:],sub):// Is it what we are looking for?
+			  return i                        // Yes, return its index.
+		}                                   // Done acting according to the byte.
+	}                                     // Done iterating through the line.
+	return -1                             // Not found.
+}                                       // ------- indexUnquotedString ------ //
+// ---------------------------- // endsInComma // --------------------------- //
+//  Return true if the value list ends in a comma that would split it, blanks //
+// after it aside. As in Parameter.SetValue(), only the quote character       //
+// quotes, and 0 means there are no quotes at all.                            //
+// -------------------------------------------------------------------------- //
+func endsInComma(value string,quote byte) bool{
+  inquote:=false                        // Are we in a quote?
+	last:=rune(0)                         // The last unquoted non-blank, if any.
+	for _,b:=range value{                 // For each character in the value...
+	  switch{                             // Act according to the character.
+		  case quote!=0&&b==rune(quote):    // Is it a quote?
+			  inquote=!inquote                // Yes, toggle the inquote flag.
+				last=b                          // It is not a comma.
+			case !inquote&&!unicode.IsSpace(b):// Something outside quotes?
+			  last=b                          // Yes, remember it.
+		}                                   // Done acting according to the character.
+	}                                     // Done iterating through the value.
+	return last==','                      // Did it end in a comma?
+}                                       // ---------- endsInComma ----------- //
+// ---------------------------- // endsInQuote // -------------------------- //
+//  Return true if line ends inside a quoted value, i.e. it opened a ' or "   //
+// quote that it never closed. Inside one quote the other kind is literal. A  //
+// quote opens only where a value starts: at the start of the line or after   //
+// '=' or ',', blanks aside. Elsewhere, as in don't, it is part of the value. //
+// -------------------------------------------------------------------------- //
+func endsInQuote(line []byte) bool{
+  var q byte                            // The open quote, 0 if none.
+	start:=true                           // Are we where a value starts?
+	for _,c:=range line{                  // For each byte in the line...
+	  switch{                             // Act according to the byte.
+		  case q!=0:                        // Inside a quote?
+			  if c==q{                        // Yes, closing it?
+				  q=0                           // Yes, we are outside again.
+				}                               // Done checking for closing quote.
+			case start&&(c=='"'||c=='\''):    // Opening a quote?
+			  q,start=c,false                 // Yes, remember which one.
+			case c=='='||c==',':              // A new value starts after it.
+			  start=true                      // So a quote may open next.
+			case c!=' '&&c!='\t':             // Part of an unquoted value?
+			  start=false                     // Yes, a quote here is literal.
+		}                                   // Done checking the byte.
+	}                                     // Done iterating bytes.
+	return q!=0                           // Still inside a quote?
+}                                       // ---------- endsInQuote ----------- //
+// ----------------------------- // readLine // ----------------------------- //
+//  Read one line from the reader without its line terminator. A line ends   //
+// at a lone '\n', a lone '\r' (old Mac style) or a "\r\n" pair, so files  //
+// written on any platform split the same way. The last line of a file need  //
+// not be terminated: it is returned along with io.EOF. A line longer than   //
+// max bytes, not counting its terminator, is an ErrLineTooLong error, so a   //
+// runaway file can't exhaust memory. An empty line is fine even if max<=0.   //
+// -------------------------------------------------------------------------- //
+func readLine(reader *bufio.Reader,max int) ([]byte,error){
+  var line []byte                       // The line we are reading.
+	for{                                  // Until we reach a line terminator...
+	  b,err:=reader.ReadByte()            // Read the next byte.
+		if err!=nil{                        // Could we read it?
+		  return line,err                   // No, return what we have and why.
+		}                                   // Done checking for read error.
+		switch b{                           // Act according to the byte.
+		  case '\n':                        // A Unix line terminator?
+			  return line,nil                 // Yes, we have a line.
+			case '\r':                        // A Mac or the start of a DOS terminator?
+			  if next,err:=reader.Peek(1);err==nil&&next[0]=='\n'{// DOS "\r\n"?
+				  reader.ReadByte()             // Yes, consume the '\n' too.
+				}                               // Done checking for DOS terminator.
+				return line,nil                 // We have a line.
+		}                                   // Done acting according to the byte.
+	  if len(line)>=max{                  // Is the line getting too long?
+		  return nil,ErrLineTooLong         // Yes, give up on it.
+		}                                   // Done checking line length.
+		line=append(line,b)                 // Not a terminator, keep it.
+	}                                     // Done reading the line.
+}                                       // ------------ readLine ------------ //
+// ------------------------- // quoteSectionName // ------------------------- //
+// Quote a section name for a header if it holds something that would not    //
+// read back as the same name, like a colon, a bracket or a comma.            //
+// -------------------------------------------------------------------------- //
+func quoteSectionName(name,close string) string{
+  if !strings.ContainsAny(name,":[],\"'")&&!strings.Contains(name,close)&&name==strings.TrimSpace(name){// Safe?
+	  return name                         // Yes, leave it alone.
+	}                                     // Done checking for special characters.
+	if strings.ContainsRune(name,'"'){    // Would double quotes clash?
+	  return "'"+name+"'"                 // Yes, use single quotes.
+	}                                     // Done checking for double quotes.
+	return "\""+name+"\""                 // Quote it.
+}                                       // -------- quoteSectionName -------- //
+// ------------------------------ // unquote // ----------------------------- //
+// Remove one pair of matching single or double quotes surrounding s, if any. //
+// -------------------------------------------------------------------------- //
+func unquote(s string) string{
+  if len(s)>=2&&(s[0]=='"'||s[0]=='\'')&&s[len(s)-1]==s[0]{// Quoted?
+	  return s[1:len(s)-1]                // Yes, return what is inside the quotes.
+	}                                     // Done checking for quotes.
+	return s                              // Not quoted, return it as is.
+}                                       // ------------- unquote ------------ //
 // -------------------------- // resolveParents // -------------------------- //
 // Resolve parent sections for all sections in the configuration.
 // -------------------------------------------------------------------------- //
@@ -2171,10 +4412,10 @@ func (cfg *Configuration) resolveParents(){
 		  name:=s.GetParentName(i)          // Get THIS parent name.
 			parent:=cfg.FindSection(name)     // Find the parent section by name.
 			if parent!=nil{                   // Did we find the parent section?
-			  s.SetParentSection(i,parent)    // Yes, then set the parent section.
+			  s.parents[i]=parent             // Yes, then set the parent section.
 				i++                             // Increment the parent index.
 			} else{                           // Else we could not find the parent section.
-			  s.RemoveMissingParent(i)        // So remove the missing parent.
+			  s.removeMissingParent(i)        // So remove the missing parent.
 			}                                 // Done checking if we found the parent section.
 		}                                   // Done iterating through parents.
 	}                                     // Done iterating through sections.
@@ -2187,38 +4428,165 @@ func (cfg *Configuration) resolveSectionRefs(){
 	  for ref:=s.firstSection;ref!=nil;ref=ref.GetNext(){// For each section reference...
 		  target:=cfg.FindSection(ref.GetName())// Find the target section.
 			if target!=nil{                   // Did we find the target section?
-			  ref.MakeShallowCopyOf(target)   // Yes, so make a shallow copy of it.
+			  ref.shallowCopyOf(target)       // Yes, so make a shallow copy of it.
 			}                                 // Done checking if we found the target section.
 		}                                   // Done iterating through section references.
 	}                                     // Done iterating through sections.
 }                                       // ------- resolveSectionRefs ------- //
 // --------------------------- // Print // ---------------------------------- //
-// Print the configuration to a buffered writer.
+// Print the configuration to a buffered writer. The values of the parameters
+// marked by MarkSecret() are written as "***" unless PrintSecrets(true) was
+// called; WriteFile() always writes them.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) Print(w io.Writer) (int64,error){
+  return cfg.print(w,!cfg.showSecrets)  // Redact unless asked not to.
+}                                       // ----------- Print ---------------- //
+// ----------------------------- // String // ------------------------------- //
+//  Return what Print() writes, secrets redacted, so an accidental            //
+// log.Inf("config: %v",cfg) does not leak a password.                        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) String() string{
+  var sb strings.Builder                // Where to print the configuration.
+	cfg.Print(&sb)                        // Print it, a Builder never fails.
+	return sb.String()                    // Return what we printed.
+}                                       // ------------- String ------------- //
+// ------------------------------ // print // ------------------------------- //
+// Print the configuration, redacting secrets if redact is true.              //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) print(w io.Writer,redact bool) (int64,error){
   var n int64                           // The number of bytes written.
-	// ---------------------------------- //
-	// Print the file-level comments to the buffered writer.
-	// ---------------------------------- //
-	for c:=cfg.firstComment;c!=nil;c=c.GetNext(){// For each comment in the list...
+	err:=cfg.writeTo(w,&n,redact)         // Stream the configuration.
+	return n,err                          // Return # of bytes written/error if any.
+}                                       // ----------- Print ---------------- //
+// ----------------------------- // WriteTo // ------------------------------ //
+//  Stream the configuration to w without building intermediate strings, so   //
+// large configurations can be copied to a network connection or any other    //
+// io.Writer with backpressure. This makes Configuration an io.WriterTo. It   //
+// is Print() by another name, secrets redacted alike.                        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) WriteTo(w io.Writer) (int64,error){
+  return cfg.print(w,!cfg.showSecrets)  // Redact unless asked not to.
+}                                       // ------------- WriteTo ------------ //
+// ----------------------------- // writeTo // ------------------------------ //
+// Stream the configuration to w, adding the number of bytes written to *n,   //
+// and redacting secrets if redact is true. Print(), WriteTo() and            //
+// WriteFile() all come down to this and to the writeTo of Section and        //
+// Parameter, so there is one serializer to keep up to date.                  //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) writeTo(w io.Writer,n *int64,redact bool) error{
+  cfg.follow()                          // Catch up with the original.
+  for c:=cfg.firstComment;c!=nil;c=c.GetNext(){// For each comment in the list...
 	  if !c.IsImported()||c.IsImportStatement(){// Is it an import statement?
-		  if _,err:=w.Write([]byte(c.value+"\n"));err!=nil{// Try to write the comment.
-			  return n,err                    // Return error if failed to write.
+		  if err:=writeStrings(w,n,c.value,"\n");err!=nil{// Try to write the comment.
+			  return err                      // Return error if failed to write.
 			}                                 // Done writing comment.
 		}                                   // Done checking if comment is import statement.
 	}                                     // Done iterating through comments.
-	// ---------------------------------- //
-	// Write the sections in order.
-	// ---------------------------------- //
-	for s:=cfg.first;s!=nil;s=s.GetNext(){// Starting from the first section...
-	  m,err:=s.Print(w)                   // Print the section to the buffered writer.
-		n+=m                                // Add the number of bytes written.
-		if err!=nil{                        // Error printing the section?
-		  return n,err                      // Yes, return error.
-		}                                   // Done checking for error printing section.
+	for _,s:=range sectionOrder(cfg.first,cfg.writeSorted()){// For each section in the configuration...
+	  if err:=s.writeTo(w,n,redact);err!=nil{// Stream the section.
+		  return err                        // Return error if failed to write.
+		}                                   // Done checking for error.
 	}                                     // Done iterating through sections.
-	return n,nil                          // Return # of bytes written and nil error.
-}                                       // ----------- Print ---------------- //
+	for c:=cfg.footer;c!=nil;c=c.GetNext(){// For each footer comment...
+	  if err:=writeStrings(w,n,c.value,"\n");err!=nil{// Try to write the comment.
+		  return err                        // Return error if failed to write.
+		}                                   // Done writing comment.
+	}                                     // Done iterating through footer.
+	return nil                            // We are good if we got here.
+}                                       // ------------- writeTo ------------ //
+// ----------------------------- // writeTo // ------------------------------ //
+// Stream a Section, its Parameters and nested Sections to w, adding the      //
+// number of bytes written to *n, and redacting secrets if redact is true.    //
+// -------------------------------------------------------------------------- //
+func (s *Section) writeTo(w io.Writer,n *int64,redact bool) error{
+  for c:=s.comments;c!=nil;c=c.GetNext(){// For each comment listed.
+	  if !c.IsImported()||c.IsImportStatement(){// Is it an import statement?
+		  if err:=writeStrings(w,n,c.value,"\n");err!=nil{
+			  return err                      // Could not write the comment.
+			}                                 // Done writing the comment.
+		}                                   // Done checking for import statement.
+	}                                     // Done iterating comment list.
+	open,close:=s.cfg.GetSectionDelimiters()// What surrounds the section name.
+	if err:=writeStrings(w,n,open,quoteSectionName(s.name,close));err!=nil{// Start the section header.
+	  return err                          // Could not write the header.
+	}                                     // Done writing the section name.
+	if s.nParents>0{                      // Any parents?
+	  for i,name:=range s.parentNames{    // Yes, for each parent name...
+		  sep:=","                          // Parents are separated by commas...
+			if i==0{                          // ...except the first one...
+			  sep=":"                         // ...which follows a colon.
+			}                                 // Done picking the separator.
+			if err:=writeStrings(w,n,sep,quoteSectionName(name,close));err!=nil{
+			  return err                      // Could not write the parent name.
+			}                                 // Done writing the parent name.
+		}                                   // Done iterating parent names.
+	}                                     // Done checking for parents.
+	if err:=writeStrings(w,n,close,"\n");err!=nil{// End the section header.
+	  return err                          // Could not write the header.
+	}                                     // Done writing the section header.
+	for _,p:=range s.parameterOrder(){    // For each parameter in our list...
+	  if err:=p.writeTo(w,n,redact&&s.cfg.isSecret(s.name,p.name));err!=nil{
+		  return err                        // Could not write the parameter.
+		}                                   // Done writing the parameter.
+	}                                     // Done iterating through the list.
+	for _,q:=range sectionOrder(s.firstSection,s.cfg.writeSorted()){// For each nested section...
+	  if err:=q.writeTo(w,n,redact);err!=nil{// Stream the nested section.
+		  return err                        // Could not write the section.
+		}                                   // Done writing the section.
+	}                                     // Done iterating nested sections.
+	return nil                            // We are good if we got here.
+}                                       // ------------- writeTo ------------ //
+// ----------------------------- // writeTo // ------------------------------ //
+// Stream a Parameter to w, value by value, adding the number of bytes        //
+// written to *n. If redact is true its values are written as "***".          //
+// -------------------------------------------------------------------------- //
+func (p *Parameter) writeTo(w io.Writer,n *int64,redact bool) error{
+  for c:=p.comments;c!=nil;c=c.next{    // For each comment listed.
+	  if !c.IsImported()||c.IsImportStatement(){
+		  if err:=writeStrings(w,n,c.value,"\n");err!=nil{
+			  return err                      // Could not write the comment.
+			}                                 // Done writing the comment.
+		}                                   // Done checking for import statement.
+	}                                     // Done iterating comment list.
+	if err:=writeStrings(w,n,quoteName(p.name));err!=nil{// Write the name.
+	  return err                          // Could not write the name.
+	}                                     // Done writing the name.
+	if redact&&(p.ref!=""||len(p.values)>0){// Is it a secret with a value?
+	  return writeStrings(w,n,"=***\n")   // Yes, hide the value.
+	}                                     // Done checking for a secret.
+	if p.ref!=""{                         // Was the value read from a file?
+	  return writeStrings(w,n,"=@",p.ref,"\n")// Yes, write the reference instead.
+	}                                     // Done checking for a file reference.
+	if p.rawJSON!=""{                     // Was it a JSON document?
+	  return writeStrings(w,n,"=",p.rawJSON,"\n")// Yes, write it as it was read.
+	}                                     // Done checking for a JSON document.
+	if len(p.values)==0&&p.flag{          // A bare flag?
+	  return writeStrings(w,n,"\n")       // Yes, it has no '=' sign.
+	}                                     // Done checking for a bare flag.
+	if err:=writeStrings(w,n,"=");err!=nil{// Write the '=' sign.
+	  return err                          // Could not write it.
+	}                                     // Done writing the '=' sign.
+	if err:=p.writeValues(w,n);err!=nil{  // Write the values.
+	  return err                          // Could not write them.
+	}                                     // Done writing the values.
+	return writeStrings(w,n,"\n")         // Terminate the line.
+}                                       // ------------- writeTo ------------ //
+// --------------------------- // writeStrings // --------------------------- //
+// Write each of the strings to w in order, adding the bytes written to *n.   //
+// -------------------------------------------------------------------------- //
+func writeStrings(w io.Writer,n *int64,strs ...string) error{
+  for _,str:=range strs{                // For each string...
+	  if str==""{                         // Anything to write?
+		  continue                          // No, skip it.
+		}                                   // Done checking for empty string.
+	  k,err:=io.WriteString(w,str)        // Write it to the stream.
+		*n+=int64(k)                        // Add the number of bytes written.
+		if err!=nil{                        // Any error?
+		  return err                        // Yes, return the error.
+		}                                   // Done checking for error.
+	}                                     // Done iterating strings.
+	return nil                            // We are good if we got here.
+}                                       // ---------- writeStrings ---------- //
 // ------------------------------ // NewFile // ----------------------------- //
 // Allow writing a new file, and optionally give filename.                    //
 // Note: If the comments from the original file were not saved, the new file  //
@@ -2227,51 +4595,106 @@ func (cfg *Configuration) Print(w io.Writer) (int64,error){
 // string fileName              File to write to.                             //
 func (cfg *Configuration) NewFile(filename string) error{
   cfg.SetFilename(filename)             // Set the filename to write to.
-	cfg.canWrite=true                     // Set the flag that we can write to the file.
+	cfg.MarkWritable()                    // Set the flag that we can write to the file.
 	return nil                            // Always successful, return nil error.
 }                                       // ------------- NewFile ------------ //
+// --------------------------- // MarkWritable // ---------------------------- //
+//  Allow WriteFile() to write this Configuration. NewFile() and a ReadFile() //
+// that read a whole file do this for you; a Configuration that was built by //
+// hand, or that only imported a section, needs it before it can be written. //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) MarkWritable(){
+  if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  cfg.base.MarkWritable()             // Yes, so may the original.
+	}                                     // Done checking for a view.
+  cfg.canWrite=true                     // We may write it now.
+}                                       // ---------- MarkWritable ---------- //
 // ----------------------------- // WriteFile // ---------------------------- //
-// Write a configuration file from the internal data structures.              //
+// Write a configuration file from the internal data structures. It fails     //
+// unless NewFile(), ReadFile() of a whole file or MarkWritable() came first, //
+// and with ErrCommentLoss if ReadFile() dropped comments the file had, after //
+// SaveComments(false), unless AllowCommentLoss(true) was called.             //
 // ________type/name___________ _________________description_________________ //
 // string fileName              File to write to.                             //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) WriteFile(filename string) error{
+  cfg.follow()                          // Catch up with the original.
   if !cfg.canWrite{                     // Can we write to the file?
 	  return fmt.Errorf("configuration is not writable")// No, return error.
 	}                                     // Done checking if we can write.
+	if cfg.lostComments&&!cfg.allowLoss{  // Would we lose comments?
+	  return ErrCommentLoss               // Yes, and we may not.
+	}                                     // Done checking for lost comments.
 	if filename!=""{                      // Did they give us a filename?
 	  cfg.SetFilename(filename)           // Yes, so set the filename.
 	} else if cfg.GetPathname()==""{      // We have no pathname stored and no filename given?
 	  return fmt.Errorf("no filename given and no pathname set")// No, return error.
 	}                                     // Done checking for filename.
-	f,err:=os.Create(cfg.GetPathname())   // Create the file to write to.
+	// ---------------------------------- //
+	// Configuration files often hold secrets, so new files are only readable
+	// by their owner, and an existing file keeps its permissions and, if we
+	// are root, its owner.
+	// ---------------------------------- //
+	perm:=os.FileMode(0600)               // New files are private.
+	uid,gid:=-1,-1                        // Assume we don't know the owner.
+	if fi,err:=os.Stat(cfg.GetPathname());err==nil{// Does the file exist?
+	  perm=fi.Mode().Perm()               // Yes, keep its permissions.
+		if u,g,ok:=fileOwner(fi);ok{        // Do we know its owner?
+		  uid,gid=u,g                       // Yes, remember it.
+		}                                   // Done checking for owner.
+	}                                     // Done checking for existing file.
+	f,err:=os.OpenFile(cfg.GetPathname(),os.O_WRONLY|os.O_CREATE|os.O_TRUNC,perm)
 	if err!=nil{                          // Error creating the file?
 	  return err                          // Yes, return error.
 	}                                     // Done checking for error creating file.
 	defer f.Close()                       // Close the file when done.
+	if err:=f.Chmod(perm);err!=nil{       // Make sure umask didn't change them.
+	  return err                          // Could not set the permissions.
+	}                                     // Done setting permissions.
+	if uid!=-1&&os.Geteuid()==0{          // Are we root and know the owner?
+	  f.Chown(uid,gid)                    // Yes, try to keep the owner.
+	}                                     // Done checking for owner.
 	buf:=bufio.NewWriter(f)               // Our buffered writer.
-	if _,err:=cfg.Print(buf);err!=nil{    // Try to write the configuration to the file.
+	if _,err:=cfg.print(buf,false);err!=nil{// Write it, secrets and all, to the file.
 	  return err                          // Return error if any.
 	}                            // Done checking for error writing configuration.
   return buf.Flush()                    // Flush the buffered writer to the file.
 }                                       // ----------- WriteFile ------------ //
 // -------------------------- // AppendSection // --------------------------- //
-// Create a new Section and select it as the default section.                 //
+// Create a new Section and select it as the default section. Returns nil on  //
+// a ReadOnly() view.                                                         //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) AppendSection(name string,comments *Comment,importing bool) *Section{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return nil                          // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
   p:=NewSection(cfg,name,comments,importing)// Make a new Section.
+	return cfg.linkSection(p)             // Append it to our list.
+}                                       // ----------- AppendSection -------- //
+// --------------------------- // linkSection // ---------------------------- //
+// Place s at the end of this Configuration's list of Sections, and record    //
+// that it is ours, so AddSection() can tell it is on a list.                 //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) linkSection(s *Section) *Section{
+  if cfg.base!=nil{                     // Is this a WithOverlay() view?
+	  cfg.base.linkSection(s)             // Yes, the original holds the sections.
+		cfg.follow()                        // Catch up with it.
+		return s                            // Return the appended Section object.
+	}                                     // Done checking for a view.
 	if cfg.first==nil{                    // Is our section list empty?
-	  cfg.first=p                         // Yes, so make this the first section.
+	  cfg.first=s                         // Yes, so make this the first section.
 	} else{                               // Else we already have sections.
-	  cfg.last.SetNext(p)                 // So append this section to the end of the list.
+	  cfg.last.SetNext(s)                 // So append this section to the end of the list.
 	}                                     // Done appending section to the list.
-	cfg.last=p                            // Now we have a new last section.
-	return p                              // Return the new Section object.
-}                                       // ----------- AppendSection -------- //
+	cfg.last=s                            // Now we have a new last section.
+	s.owner=cfg                           // It is on our list now.
+	return s                              // Return the appended Section object.
+}                                       // ----------- linkSection ---------- //
 // ------------------------- // FindSection // ------------------------------ //
 // Look for a Section by name.                                                //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) FindSection(name string) *Section{
+  cfg.follow()                          // Catch up with the original.
   for s:=cfg.first;s!=nil;s=s.GetNext(){// For each section in the configuration...
 	  if strings.EqualFold(s.GetName(),name){// Is it the section we are looking for?
 		  return s                          // Yes, we found the section, return it.
@@ -2279,6 +4702,151 @@ func (cfg *Configuration) FindSection(name string) *Section{
 	}                                     // Done iterating through sections.
 	return nil                            // No match found, return nil.
 }                                       // ------------ FindSection --------- //
+// -------------------------- // RenameSection // ---------------------------- //
+//  Rename a Section in place, keeping its parameters, comments and position. //
+// Sections that inherit from it are updated to use the new name. [Ref]      //
+// copies share the Section's parameters, so they follow it automatically.    //
+// Section names are matched without regard to case, so renaming onto the     //
+// name of another Section is an error, but changing the case is not.         //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) RenameSection(old, new string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  new=strings.TrimSpace(new)            // Remove surrounding whitespace.
+	if new==""{                           // Did they give us a new name?
+	  return fmt.Errorf("new name for section \"%s\" cannot be empty", old)
+	}                                     // Done checking for empty name.
+	s:=cfg.FindSection(old)               // Find the section to rename.
+	if s==nil{                            // Did we find it?
+	  return fmt.Errorf("section \"%s\" not found", old)// No, return error.
+	}                                     // Done checking for section.
+	if q:=cfg.FindSection(new);q!=nil&&q!=s{// Would it collide with another?
+	  return fmt.Errorf("section \"%s\" already exists", new)// Yes, return error.
+	}                                     // Done checking for collision.
+	for c:=cfg.first;c!=nil;c=c.GetNext(){// For each section...
+	  for i,name:=range c.parentNames{    // For each of its parent names...
+		  if strings.EqualFold(name,s.name){// Does it inherit from us?
+			  c.parentNames[i]=new            // Yes, follow the new name.
+			}                                 // Done checking parent name.
+		}                                   // Done iterating parent names.
+	}                                     // Done iterating sections.
+	s.name=new                            // Rename the section.
+	return nil                            // Success.
+}                                       // --------- RenameSection ---------- //
+// --------------------------- // AddSection // ----------------------------- //
+//  Append a Section made by Section.Clone() to this Configuration, e.g. to   //
+// copy [defaults] to [instance-1] and then change a few of its values. Like  //
+// RenameSection(), it fails if a Section of the same name is already there.  //
+// A Section that is already on a list, this Configuration's or another's,    //
+// can't be added: Clone() it instead.                                        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) AddSection(s *Section) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	if s==nil||s.name==""{                // Do we have a named section?
+	  return fmt.Errorf("section to add must have a name")// No, return error.
+	}                                     // Done checking the section.
+	if cfg.FindSection(s.name)!=nil{      // Would it collide with another?
+	  return fmt.Errorf("section \"%s\" already exists", s.name)// Yes, return error.
+	}                                     // Done checking for collision.
+	if s.owner!=nil&&s.owner!=cfg{        // Is it another Configuration's?
+	  return fmt.Errorf("section \"%s\" belongs to another configuration", s.name)
+	}                                     // Done checking the owner.
+	if s.owner!=nil||s.next!=nil{         // Is it on a list already?
+	  return fmt.Errorf("section \"%s\" is already on a list", s.name)
+	}                                     // Done checking for a list.
+	s.cfg=cfg                             // It belongs to us now...
+	for ss:=s.firstSection;ss!=nil;ss=ss.GetNext(){// ...and so do...
+	  ss.cfg=cfg                          // ...its section references.
+	}                                     // Done iterating references.
+	cfg.linkSection(s)                    // Append it to our list.
+	return nil                            // Success.
+}                                       // ----------- AddSection ----------- //
+// ------------------------------ // Equal // ------------------------------- //
+//  Return true if both Configurations hold the same Sections and Parameters  //
+// with the same values, as Section.Equal() compares them. Comments, blank    //
+// lines, the order of the Sections and quoting make no difference, so a file //
+// that was only reformatted is still Equal to the original. Sections of the  //
+// same name are compared in order, like Parameters of the same name.         //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Equal(other *Configuration) bool{
+  return cfg.difference(other)==""      // Equal if we find no difference.
+}                                       // ------------- Equal -------------- //
+// ---------------------------- // difference // ---------------------------- //
+//  Describe the first way other differs from this Configuration, as Equal()  //
+// sees it, or return "" if it does not.                                      //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) difference(other *Configuration) string{
+  if cfg==nil||other==nil{              // Do we have two configurations?
+	  if cfg==other{                      // Only equal if both are missing.
+		  return ""                         // They are.
+		}                                   // Done checking for both missing.
+		return "configuration is missing"   // Only one of them is.
+	}                                     // Done checking for nil.
+	seen:=map[string]int{}                // How many of each name we passed.
+	cfg.follow()                          // Catch up with the originals, if...
+	other.follow()                        // ...either is a WithOverlay() view.
+	for s:=cfg.first;s!=nil;s=s.GetNext(){// For each of our sections...
+	  k:=strings.ToLower(s.name)          // Its name, as FindSection() sees it.
+		t:=other.nthSection(s.name,seen[k]) // Its twin: as many came before it.
+		seen[k]++                           // One more of that name.
+		if d:=s.difference(t);d!=""{        // Does its twin match?
+		  return d                          // No, say how they differ.
+		}                                   // Done checking the twin.
+	}                                     // Done iterating sections.
+	for s:=other.first;s!=nil;s=s.GetNext(){// Does the other one have more?
+	  k:=strings.ToLower(s.name)          // Its name, as we counted them.
+		if seen[k]--;seen[k]<0{             // More of that name than ours?
+		  return fmt.Sprintf("section [%s] was added",s.name)
+		}                                   // Done checking the section.
+	}                                     // Done iterating the other's sections.
+	return ""                             // No difference if we got here.
+}                                       // ----------- difference ----------- //
+// ---------------------------- // nthSection // ---------------------------- //
+//  Return the Section named name, ignoring case, that has n others of that   //
+// name before it, or nil if there are not that many.                         //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) nthSection(name string,n int) *Section{
+  cfg.follow()                          // Catch up with the original.
+  for s:=cfg.first;s!=nil;s=s.GetNext(){// For each of our sections...
+	  if strings.EqualFold(s.name,name){  // Does it have that name?
+		  if n==0{                          // Yes, is it the one?
+			  return s                        // Yes, return it.
+			}                                 // Done checking the count.
+			n--                               // No, one less to go.
+		}                                   // Done checking the name.
+	}                                     // Done iterating sections.
+	return nil                            // There are not that many.
+}                                       // ----------- nthSection ----------- //
+// ------------------------------ // Verify // ------------------------------ //
+//  Check that what WriteFile() would write reads back as an Equal            //
+// Configuration, so a generated config with a quoting or escaping bug is     //
+// caught before it ships. It prints to memory, secrets and all, reads that   //
+// back with the same settings, imports ignored as what they brought in was   //
+// printed, and returns an error naming the first section or parameter that   //
+// did not survive, or nil.                                                   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Verify() error{
+  var buf bytes.Buffer                  // Where we print.
+	if _,err:=cfg.print(&buf,false);err!=nil{// Print it as WriteFile() would.
+	  return err                          // Could not print it.
+	}                                     // Done printing.
+	tmp:=cfg.newLike()                    // A configuration like us...
+	tmp.ignoreImports=true                // ...that has all it needs in buf.
+	name:=cfg.GetPathname()               // Name the text after our file...
+	if name==""{                          // ...if we have one...
+	  name="<verify>"                     // ...or say what it is.
+	}                                     // Done naming the text.
+	if err:=tmp.ReadContext(context.Background(),&buf,name);err!=nil{// Read it back.
+	  return fmt.Errorf("configuration does not round-trip: %w",err)
+	}                                     // Done reading it back.
+	if d:=cfg.difference(tmp);d!=""{      // Did it come back the same?
+	  return fmt.Errorf("configuration does not round-trip: %s",d)
+	}                                     // Done comparing.
+	return nil                            // It survived.
+}                                       // ------------- Verify ------------- //
 // ------------------------- // SelectSection // ---------------------------- //
 // Set default section for Get & Set Parameter calls without section names.   //
 // -------------------------------------------------------------------------- //
@@ -2294,6 +4862,10 @@ func (cfg *Configuration) SelectSection(name string) error{
 // Erase all parameters in the current section.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) ClearParameters(section string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.first!=nil && cfg.SelectSection(section)==nil{// Could we select the section?
 	  cfg.current.ClearParameters()       // Yes, so clear the parameters in the section.
 		return nil                          // Return nil error if successful.
@@ -2302,9 +4874,11 @@ func (cfg *Configuration) ClearParameters(section string) error{
 }                                       // --------- ClearParameters -------- //
 // -------------------- // GetNextParameterValues // ------------------------ //
 // Get next parameter from the default section.
-// If values=nil, the user is just getting the name of the next parameter.
+// The results are always freshly allocated, so vals and q may be nil; they
+// are only kept so existing callers still compile, and are never written to.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetNextParameterValues(vals [][]string,q []string) (name []string, nValues int, values [][]string,quotes []string,err error){
+  cfg.follow()                          // Catch up with the original.
   s:=cfg.current                        // Get the current section.
 	if s!=nil{                            // Is there a current section?
 	  p:=s.GetSelectedParameter()         // Get the selected parameter.
@@ -2314,13 +4888,9 @@ func (cfg *Configuration) GetNextParameterValues(vals [][]string,q []string) (na
 			values=make([][]string,1)         // Allocate space for the values.
 			name[0]=p.GetName()               // Yes, get it's name.
 			nValues=int(p.GetNValues())       // Get the number of values.
-			if vals!=nil{                     // Did they give us any values?
-			  values[0]=p.GetValueArray()     // Yes, so get the values.
-			}                                 // Done checking for values.
-			if q!=nil{                        // Did they give us any quotes?
-			  quotes=make([]string,1)         // Yes, so allocate space for the quotes.
-				quotes[0]=string(p.quotes)      // Yes, so get the quotes.
-			}                                 // Done checking for quotes.
+			values[0]=append([]string(nil),p.GetValueArray()...)// Copy the values.
+			quotes=make([]string,1)           // Allocate space for the quotes.
+			quotes[0]=string(p.quotes)        // Get the quotes.
 			s.SelectParameter(p.GetNext())    // Select the next parameter in the section.
       return name,nValues,values,quotes,nil// Return what we found.
 		} else{                             // Else no more in this section.
@@ -2329,11 +4899,13 @@ func (cfg *Configuration) GetNextParameterValues(vals [][]string,q []string) (na
 	}                                     // Done checking for current section.
 	return nil,0,nil,nil,fmt.Errorf("no current section selected") // No current section, return error.
 }                                       // ----- GetNextParameterValues ----- //
-// -------------------- // GetNextParameterValues // ------------------------ //
-// Get next parameter from the default section.
-// If values=nil, the user is just getting the name of the next parameter.
+// -------------------- // GetNextParameterValues2 // ----------------------- //
+// Get next parameter and its first value from the default section.
+// The results are always freshly allocated, so vals may be nil; it is only
+// kept so existing callers still compile, and is never written to.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetNextParameterValues2(vals [][]string) (name []string, values []string,err error){
+  cfg.follow()                          // Catch up with the original.
   s:=cfg.current                        // Get the current section.
 	if s!=nil{                            // Is there a current section?
 	  p:=s.GetSelectedParameter()         // Get the selected parameter.
@@ -2342,9 +4914,7 @@ func (cfg *Configuration) GetNextParameterValues2(vals [][]string) (name []strin
 			name=make([]string,1)             // Allocate space for the name.
 			values=make([]string,1)         // Allocate space for the values.
 		  name[0]=p.GetName()               // Yes, get it's name.
-			if vals!=nil{                     // Did they give us any values?
-			  values[0]=p.GetValue(0)         // Yes, so get the value.
-			}                                 // Done checking for values.
+			values[0]=p.GetValue(0)           // Get the first value.
 			s.SelectParameter(p.GetNext())    // Select the next parameter in the section.
 			return name,values,nil            // Return what we found.
 		} else{                             // Else no more in this section.
@@ -2357,6 +4927,7 @@ func (cfg *Configuration) GetNextParameterValues2(vals [][]string) (name []strin
 // Get next parameter from the default section.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetNextParameter() (*Parameter){
+  cfg.follow()                          // Catch up with the original.
   s:=cfg.current                        // Get the current section.
 	if s!=nil{                            // Do we have a section?
 	  p:=s.GetSelectedParameter()         // Get the selected parameter.
@@ -2367,7 +4938,19 @@ func (cfg *Configuration) GetNextParameter() (*Parameter){
 	}                                     // Done checking for current section.
 	return nil                            // No current section, return nil.                    
 }                                       // -------- GetNextParameter -------- //
+// ------------------------- // PeekParameter // ---------------------------- //
+// Get the selected parameter of the default section, like GetNextParameter() //
+// but without advancing to the next one, so it can be used to look ahead.    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) PeekParameter() *Parameter{
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current!=nil{                  // Do we have a section?
+	  return cfg.current.PeekSelectedParameter()// Yes, return its selection.
+	}                                     // Done checking for current section.
+	return nil                            // No current section, return nil.
+}                                       // --------- PeekParameter ---------- //
 func (cfg *Configuration) SelectParameter(name string) error{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
 	  return cfg.current.SelectParameterByName(name)// Yes, so select the parameter by name.
 	}                                     // Done checking for current section.
@@ -2377,12 +4960,169 @@ func (cfg *Configuration) SelectParameter(name string) error{
 // Get a parameter value from the currently-selected section.                 //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetValue(name string) string{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
+	  if v,ok:=cfg.lookupOverlay(cfg.current.GetName(),name);ok{// Overlay has it?
+		  return v                          // Yes, the overlay wins.
+		}                                   // Done checking the overlay.
 	  return cfg.current.GetValue(name,uint(0))// Get the value of the parameter.	
 	}                                     // Done checking for current section.
 	return ""                             // No current section, return empty string.
 }                                       // ------------- GetValue ----------- //
+// ----------------------------- // Lookup // ------------------------------- //
+//  Get a parameter value from the currently-selected section, and whether it //
+// exists, in the manner of os.LookupEnv(). The overlay, if any, is consulted //
+// first.                                                                     //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) Lookup(name string) (value string, found bool){
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current==nil{                  // Do we have a current section?
+	  return "",false                     // No, so nothing can be found.
+	}                                     // Done checking for current section.
+	if v,ok:=cfg.lookupOverlay(cfg.current.GetName(),name);ok{// Overlay has it?
+	  return v,true                       // Yes, the overlay wins.
+	}                                     // Done checking the overlay.
+	return cfg.current.Lookup(name)       // Look in the current section.
+}                                       // ------------ Lookup -------------- //
+// --------------------------- // presentValue // --------------------------- //
+//  Like Section.presentValue() for the currently-selected section, after    //
+// consulting the overlay, whose value is split into a list like the file's.  //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) presentValue(name string, i uint) (string, error){
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current==nil{                  // Do we have a current section?
+	  return "",fmt.Errorf("no current section selected")
+	}                                     // Done checking for current section.
+	if v,ok:=cfg.lookupOverlay(cfg.current.GetName(),name);ok{// Overlay has it?
+	  vals:=cfg.splitCSVList(v)           // Yes, split it like the file's.
+		switch{                             // Act according to what we found.
+		  case i>=uint(len(vals))&&(i>0||len(vals)>0):// A value it lacks?
+			  return "",fmt.Errorf("index %d out of range", i)
+			case len(vals)==0||vals[i]=="":   // There, but empty?
+			  return "",ErrEmptyValue         // Yes, nothing to decode.
+		}                                   // Done acting on what we found.
+	  return vals[i],nil                  // The overlay wins.
+	}                                     // Done checking the overlay.
+	return cfg.current.presentValue(name,i)// Look in the current section.
+}                                       // ---------- presentValue ---------- //
+// ------------------------------ // HasFlag // ----------------------------- //
+//  Return true if the currently-selected section, or one it inherits from,   //
+// has the parameter with no value at all, as a bare "verbose" line makes.   //
+// An empty value, as in "verbose=", is not a flag. The overlay only holds    //
+// values, so it is not consulted.                                            //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) HasFlag(name string) bool{
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current==nil{                  // Do we have a current section?
+	  return false                        // No, so nothing can be found.
+	}                                     // Done checking for current section.
+	p:=cfg.current.FindParameter(name,true)// Find the parameter.
+	return p!=nil&&p.flag&&p.n==0         // There, bare and without values?
+}                                       // ------------ HasFlag ------------- //
+// ---------------------------- // GetValueAny // --------------------------- //
+//  Get the first non-empty value among several names for the same parameter, //
+// from the currently-selected section, and which name it was found under.    //
+// List the current name first and the old ones after it, so a renamed key    //
+// keeps working in files written before the rename.                          //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueAny(names ...string) (value string, matched string, found bool){
+  for _,name:=range names{              // For each candidate name...
+	  if v,ok:=cfg.Lookup(name);ok&&v!=""{// Is it there, with a value?
+		  return v,name,true                // Yes, this is the one.
+		}                                   // Done checking this name.
+	}                                     // Done iterating names.
+	return "","",false                    // None of them has a value.
+}                                       // ---------- GetValueAny ----------- //
+// -------------------------- // GetValueSubConfig // ----------------------- //
+//  Parse the value of a parameter of the currently-selected section, like    //
+// options="host=x;port=5432", as a configuration of its own: the result has  //
+// one selected section, named after the parameter, so the usual getters can //
+// read the embedded keys. pairSep separates the pairs and kvSep a key from   //
+// its value; they default to ";" and "=". Values are kept whole, without     //
+// splitting on commas, and lose one pair of surrounding quotes if they have  //
+// them.                                                                      //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueSubConfig(name, pairSep, kvSep string) (*Configuration, error){
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current==nil{                  // Do we have a current section?
+	  return nil,fmt.Errorf("no current section selected")
+	}                                     // Done checking for current section.
+	if pairSep==""{                       // Were we given a pair separator?
+	  pairSep=";"                         // No, use the usual one.
+	}                                     // Done checking pair separator.
+	if kvSep==""{                         // Were we given a key separator?
+	  kvSep="="                           // No, use the usual one.
+	}                                     // Done checking key separator.
+	raw,ok:=cfg.lookupOverlay(cfg.current.GetName(),name)// Overlay has it?
+	if !ok{                               // No, so look in the file.
+	  p:=cfg.current.FindParameter(name,true)// Find the parameter.
+		if p==nil{                          // Did we find it?
+		  return nil,cfg.configError("get",name,ErrParameterNotFound)
+		}                                   // Done checking for parameter.
+		raw=strings.Join(p.values[:p.n],",")// Put back commas split on read.
+		raw=unquote(raw)                    // A quoted value is taken whole.
+	}                                     // Done getting the raw value.
+	sub:=cfg.newLike()                    // The embedded configuration.
+	sub.path=cfg.path                     // Errors point at our file.
+	s:=sub.AppendSection(name,nil,false)  // Its only section.
+	sub.current=s                         // Which is selected.
+	for _,pair:=range strings.Split(raw,pairSep){// For each pair...
+	  if pair=strings.TrimSpace(pair);pair==""{// Anything there?
+		  continue                          // No, skip empty pairs.
+		}                                   // Done checking for empty pair.
+		k,v,found:=strings.Cut(pair,kvSep)  // Split the key from the value.
+		k=strings.TrimSpace(k)              // The key without spaces.
+		if !found||k==""{                   // Is it a key and a value?
+		  return nil,cfg.configError("get",name,fmt.Errorf("\"%s\" is not a %s pair",pair,"key"+kvSep+"value"))
+		}                                   // Done checking the pair.
+		s.appendLiteral(k,unquote(strings.TrimSpace(v)),0)// Add it.
+	}                                     // Done iterating pairs.
+	return sub,nil                        // Return the embedded configuration.
+}                                       // ------- GetValueSubConfig -------- //
+// --------------------------- // GetValueSet // ---------------------------- //
+//  Get the values of a parameter of the currently-selected section as a set, //
+// for parameters that list flags or features, like features=a,b,a,c: each    //
+// value is there once and empty values are left out. An overlay value is     //
+// split on commas, as it would have been in the file.                        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueSet(name string) (map[string]struct{}, error){
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current==nil{                  // Do we have a current section?
+	  return nil,fmt.Errorf("no current section selected")
+	}                                     // Done checking for current section.
+	var values []string                   // The values, duplicates and all.
+	if v,ok:=cfg.lookupOverlay(cfg.current.GetName(),name);ok{// Overlay has it?
+	  values=cfg.splitCSVList(v)          // Yes, split it like the file's.
+	} else{                               // Else look in the file.
+	  p:=cfg.current.FindParameter(name,true)// Find the parameter.
+		if p==nil{                          // Did we find it?
+		  return nil,cfg.configError("get",name,ErrParameterNotFound)
+		}                                   // Done checking for parameter.
+		values=p.values[:p.n]               // Its values.
+	}                                     // Done getting the values.
+	set:=make(map[string]struct{},len(values))// The set we return.
+	for _,v:=range values{                // For each value...
+	  if v=strings.TrimSpace(v);v!=""{    // Is there anything there?
+		  set[v]=struct{}{}                 // Yes, add it once.
+		}                                   // Done checking for empty value.
+	}                                     // Done iterating values.
+	return set,nil                        // Return the set.
+}                                       // ---------- GetValueSet ----------- //
+// -------------------------- // ValueContains // --------------------------- //
+//  Return true if item is one of the values of a parameter of the currently- //
+// selected section, as GetValueSet() reads them. A parameter that is not     //
+// there contains nothing.                                                    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) ValueContains(name, item string) bool{
+  set,err:=cfg.GetValueSet(name)        // Get the values.
+	if err!=nil{                          // Could we?
+	  return false                        // No, so it is not there.
+	}                                     // Done checking for error.
+	_,ok:=set[item]                       // Is it in the set?
+	return ok                             // Say so.
+}                                       // --------- ValueContains ---------- //
 func (cfg *Configuration) GetValues(name string) string{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
     return cfg.current.GetValues(name)  // Yes, return the value of this parameter.	
 	}                                     // Done checking for current section.
@@ -2392,6 +5132,7 @@ func (cfg *Configuration) GetValues(name string) string{
 // Get a parameter value from the currently-selected section.                 //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetValueByIndex(name string, i uint) string{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
 	  return cfg.current.GetValue(name,uint(i))// Get the value of the parameter.	
 	}                                     // Done checking for current section.
@@ -2401,6 +5142,9 @@ func (cfg *Configuration) GetValueByIndex(name string, i uint) string{
 // Get a parameter from a particular Section without selecting that Section.  //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetValueBySection(section, name string) string{
+  if v,ok:=cfg.lookupOverlay(section,name);ok{// Does the overlay have it?
+	  return v                            // Yes, the overlay wins.
+	}                                     // Done checking the overlay.
   s:=cfg.FindSection(section)           // Find the section by name.
 	if s!=nil{                            // Did we find the section?
 	  return s.GetValue(name,uint(0))     // Yes, return the value of this parameter.	
@@ -2417,6 +5161,23 @@ func (cfg *Configuration) GetValueBySectionAndIndex(section, name string, i uint
 	}                                     // Done checking for current section.
 	return ""                             // No current section, return empty string.
 }                                       // --- GetValueBySectionAndIndex ---- //
+// ----------------------------- // IsImported // --------------------------- //
+//  Return true if a parameter of a Section came from an imported file, and   //
+// false if it was defined locally, so tools can tell local overrides from    //
+// defaults pulled in with import statements. A parameter the Section only    //
+// inherits is reported as its parent has it. The overlay is not consulted.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) IsImported(section, name string) (bool, error){
+  s:=cfg.FindSection(section)           // Find the section by name.
+	if s==nil{                            // Did we find the section?
+	  return false,fmt.Errorf("section \"%s\" not found", section)// No, return error.
+	}                                     // Done checking for section.
+	p:=s.FindParameter(name,true)         // Find the parameter, inherited too.
+	if p==nil{                            // Did we find it?
+	  return false,s.configError("get",name,ErrParameterNotFound)
+	}                                     // Done checking for parameter.
+	return p.IsImported(),nil             // Return where it came from.
+}                                       // ----------- IsImported ----------- //
 func (cfg *Configuration) GetValueBool(name string,i uint,tval string, fval string) (result bool, err error){
   
 	p:=cfg.GetValueByIndex(name,i)        // Get the parameter value.
@@ -2441,12 +5202,28 @@ func (cfg *Configuration) GetValueBool(name string,i uint,tval string, fval stri
 			}                                 // Done checking if can decode p.
 		}                                   // Done checking if caller gave values.
 	}                                     // Done checking for value.
-  return result,err                     // Return result and error if any.
+  return result,cfg.configError("get",name,err)// Return result and error if any.
 }                                       // ----------- GetValueBool --------- //
+// --------------------------- // RequireArity // --------------------------- //
+//  Check that the named Parameter of the given Section (searching its        //
+// parents) has between min and max values. A negative max means unbounded.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) RequireArity(section, name string, min, max int) error{
+  s:=cfg.FindSection(section)           // Find the section by name.
+	if s==nil{                            // Did we find the section?
+	  return fmt.Errorf("section \"%s\" not found", section) // No, return error.
+	}                                     // Done checking for section.
+	p:=s.FindParameter(name,true)         // Find the parameter in the section.
+	if p==nil{                            // Did we find the parameter?
+	  return fmt.Errorf("parameter %s not found in section %s", name, section)
+	}                                     // Done checking for parameter.
+	return p.RequireArity(min,max)        // Check the number of values.
+}                                       // ---------- RequireArity ---------- //
 // --------------------------- // GetNValues // ----------------------------- //
 // Return the number of values in the given Parameter of the selected Section.//
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetNValues(name string) uint{
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
 	  return cfg.current.GetNValues(name) // Yes, return the number of values.
 	}                                     // Done checking for current section.
@@ -2457,6 +5234,7 @@ func (cfg *Configuration) GetNValues(name string) uint{
 // selected Section. The currently-selected Section will not be changed.      //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) GetNParameters(section string) uint{
+  cfg.follow()                          // Catch up with the original.
   s:=cfg.current                        // Get the current section as default.
 	if section!=""{                       // Were we given a section?
 	  s=cfg.FindSection(section)          // Yes, so find the section by name.
@@ -2475,11 +5253,15 @@ func (cfg *Configuration) GetNParameters(section string) uint{
 // destination variable using the format string.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) scanValue(name string,i int, format string, dest any) error{
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current==nil{                  // Do we have a current section?
+	  return fmt.Errorf("no current section selected")
+	}                                     // Done checking for current section.
   p:=cfg.current.FindParameter(name,true)// Find the parameter in this section.
 	if p==nil{                            // Did we find the parameter?
 	  return fmt.Errorf("parameter %s not found in section %s", name, cfg.current.name)// No, return error.
 	}                                     // Done checking for parameter.
-	if i < 0 || i >= int(cfg.current.GetNValues(name)){       // Within range?
+	if i < 0 || i >= int(p.GetNValues()){ // Within range of the one we found?
 	return fmt.Errorf("index %d out of range", i)
   }                                     // Done checking for out of range.                                // Done checking for out of range.
   var verb byte                         // The format verb.
@@ -2506,7 +5288,7 @@ func (cfg *Configuration) scanValue(name string,i int, format string, dest any)
 	return errors.New("destination must be a non-nil pointer")// Yes, return an error.
   }                                     // Done checking for nil or pointer
 	// Scan the value into the destination variable
-  _,err:=fmt.Sscanf(string(cfg.GetValueByIndex(name,uint(i))),format,dest)
+  _,err:=fmt.Sscanf(p.GetValue(uint(i)),format,dest)// From that same parameter.
 	return err                            // Return error if any.
 }                                       // ------------ ScanValue ----------- //
 
@@ -2514,10 +5296,14 @@ func (cfg *Configuration) scanValue(name string,i int, format string, dest any)
 // Set a parameter value in the currently-selected section.                   //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) SetValue(name,valuestr string,quote byte) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
-	  return cfg.current.SetValue(name,valuestr,quote)// Yes, set the value of the parameter.
+	  return cfg.configError("set",name,cfg.current.SetValue(name,valuestr,quote))// Yes, set the value of the parameter.
 	}                                     // Done checking for current section.
-	return fmt.Errorf("no current section selected") // No current section, return error.
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))// No current section, return error.
 }                                       // ------------- SetValue ----------- //
 
 // ------------------------ // SetValueBySection // ------------------------- //
@@ -2525,36 +5311,100 @@ func (cfg *Configuration) SetValue(name,valuestr string,quote byte) error{
 // Section.                                                                   //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) SetValueBySection(section,name string, i uint, value string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
   s:=cfg.FindSection(section)           // Find the section by name.
 	if s!=nil{                            // Did we find the section?
 	  return s.SetValuePtr(name,value,0)  // Yes, set the value of the parameter.
 	}                                     // Done checking if found section.
 	return fmt.Errorf("section \"%s\" not found", section) // No, return error.   
 }                                       // ------- SetValueBySection -------- //
+// ---------------------------- // SetValues // ----------------------------- //
+//  Set many parameters of one Section in a single pass. The Section is found,//
+// or appended if missing, only once; an empty section means the currently-   //
+// selected one, and the selection is not changed. Parameters that exist are  //
+// set, the rest are appended in name order so the file comes out the same   //
+// every time. A bad key does not stop the others from being set: all of the  //
+// failures come back together in one errors.Join() error.                    //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetValues(section string, kv map[string]string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
+	s:=cfg.current                        // Default to the current section.
+	if section!=""{                       // Were we given a section?
+	  s=cfg.FindSection(section)          // Yes, find it by name.
+		if s==nil{                          // Does it exist?
+		  s=cfg.AppendSection(section,nil,false)// No, so create it.
+		}                                   // Done checking for section.
+	}                                     // Done checking for section name.
+	if s==nil{                            // Do we have a section?
+	  return fmt.Errorf("no current section selected")
+	}                                     // Done checking for section.
+	names:=make([]string,0,len(kv))       // The parameter names...
+	for name:=range kv{                   // ...taken from the map...
+	  names=append(names,name)            // ...one by one...
+	}                                     // ...
+	sort.Strings(names)                   // ...in a predictable order.
+	var failed []error                    // What went wrong, if anything.
+	for _,name:=range names{              // For each parameter to set...
+	  if strings.TrimSpace(name)==""||strings.ContainsAny(name,"=\r\n")||cfg.isCommentLine(name){
+		  failed=append(failed,fmt.Errorf("invalid parameter name \"%s\" in section %s",name,s.name))
+			continue                          // Skip it, but carry on with the rest.
+		}                                   // Done checking the name.
+		if err:=cfg.checkName(name);err!=nil{// Does the application accept it?
+		  failed=append(failed,fmt.Errorf("invalid parameter name \"%s\" in section %s: %w",name,s.name,err))
+			continue                          // No, skip it too.
+		}                                   // Done checking with the validator.
+		p:=s.FindParameter(name,false)      // Do we have it already?
+		if p==nil{                          // No, so...
+		  p=s.AppendParameter(name,"",nil,false)// ...append a new parameter.
+		}                                   // Done checking for parameter.
+		old:=p.GetValues()                  // What it was, to tell OnChange().
+		if err:=cfg.splitValue(p,kv[name],0);err!=nil{// Set its value.
+		  failed=append(failed,fmt.Errorf("parameter %s in section %s: %w",name,s.name,err))
+		}                                   // Done checking for error.
+		cfg.changed(s.name,p.name,old,p.GetValues())// Say if it changed.
+	}                                     // Done iterating parameters.
+	return errors.Join(failed...)         // Nil if everything was set.
+}                                       // ----------- SetValues ------------ //
 // --------------------------- // SetValueInFormat // ----------------------- //
 // Set a parameter value in the currently-selected section, using the format
 // specified by the format string.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) SetValueInFormat(name string,val any,format string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current==nil{                  // Do we have a current section?
-	  return fmt.Errorf("no current sectioon selected") // No current section, return error.
+	  return cfg.configError("set",name,fmt.Errorf("no current sectioon selected"))// No current section, return error.
 	}                                     // Done checking for current section.
-	return cfg.current.SetValueInFormat(name,0,format,val)// Set the parameter's value.
+	return cfg.configError("set",name,cfg.current.SetValueInFormat(name,0,format,val))// Set the parameter's value.
 }                                       // --------- SetValueInFormat ------- //
 // ------------------------- // SetArrayValue // ---------------------------- //
 // Set a parameter value in the currently-selected section.                   //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) SetArrayValue(name,valuestr string,i uint,quote byte) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
-	  return cfg.current.SetValuePtrOnIndex(name,valuestr,i,quote)// Yes, set the value of the parameter.
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,valuestr,i,quote))// Yes, set the value of the parameter.
 	}                                     // Done checking for current section.
-	return fmt.Errorf("no current section selected") // No current section, return error.
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))// No current section, return error.
 }                                       // ----------- SetArrayValue -------- //
 // ---------------------- // SetArrayValueBySection // ---------------------- //
 // Set a value for a parameter in a particular Section without selecting that //
 // Section.                                                                   //
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) SetArrayValueBySection(section,name string, i uint, value string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
   s:=cfg.FindSection(section)           // Find the section by name.
 	if s!=nil{                            // Did we find the section?
 	  return s.SetValuePtrOnIndex(name,value,i,0) // Yes, set the value of the parameter.
@@ -2566,622 +5416,1262 @@ func (cfg *Configuration) SetArrayValueBySection(section,name string, i uint, va
 // specified by the format string, and the index of the multi-value parameter.
 // -------------------------------------------------------------------------- //
 func (cfg *Configuration) SetArrayValueInFormat(name string,idx uint,val any,format string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  // Do we have a current section?
-	  return cfg.current.SetValueInFormat(name,int(idx),format,val)// Set the parameter's value.
+	  return cfg.configError("set",name,cfg.current.SetValueInFormat(name,int(idx),format,val))// Set the parameter's value.
 	}                                     // Done checking for current section.
-  return fmt.Errorf("no current section selected") // No current section, return error.
+  return cfg.configError("set",name,fmt.Errorf("no current section selected"))// No current section, return error.
 }                                       // ----- SetArrayValueInFormat ------ //
 
 // ---------------- Byte values (character values) -------------------------- //
 func (cfg *Configuration) GetValueByte(name string, dest *byte) error{
-  p:=cfg.GetValue(name)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name) 
+  _,err:=cfg.presentValue(name,0)
+	if err!=nil{
+	  return cfg.configError("get",name,err)
 	}
-	return cfg.scanValue(name,0,"%c",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,0,"%c",dest))
 }
 func (cfg *Configuration)	SetValueByte(name string, value byte) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,string(value),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,string(value),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueByteByIndex(name string,i uint,dest *byte) error{
-  p:=cfg.GetValueByIndex(name,i)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name) 
+  _,err:=cfg.presentValue(name,i)
+	if err!=nil{
+	  return cfg.configError("get",name,err)
 	}
-	return cfg.scanValue(name,int(i),"%c",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,int(i),"%c",dest))
 }
 func (cfg *Configuration)	SetValueByteByIndex(name string, i uint, value byte) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,string(value),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,string(value),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 
+// ------------------------ // GetValueByteSlice // ------------------------- //
+//  Get every value of a multi-valued parameter in the currently-selected     //
+// Section as a byte sequence, e.g. magic=0xDE,0xAD,0xBE,0xEF. Each element  //
+// may be decimal, 0x-hex or 0-octal, and must fit in a byte.                //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueByteSlice(name string, dest *[]byte) error{
+  var p *Parameter                      // The parameter, resolved only once.
+	cfg.follow()                          // Catch up with the original.
+	if cfg.current!=nil{                  // Do we have a current section?
+	  p=cfg.current.FindParameter(name,true)// Yes, find it here or in a parent.
+	}                                     // Done checking for current section.
+	if p==nil||p.GetNValues()==0{         // Does it exist and have any values?
+	  return cfg.configError("get",name,ErrParameterNotFound)// No, return error.
+	}                                     // Done checking for values.
+	n:=p.GetNValues()                     // How many values does it have?
+	b:=make([]byte,n)                     // Room for all the bytes.
+	for i:=uint(0);i<n;i++{               // For each value...
+	  v:=strings.TrimSpace(p.GetValue(i)) // Get this value.
+		u,err:=strconv.ParseUint(v,0,8)     // Decode it as a byte.
+		if err!=nil{                        // Could we decode it?
+		  return cfg.configError("get",name,fmt.Errorf("can't decode element %d \"%s\" of %s to byte: %v",i,v,name,err))
+		}                                   // Done checking for decode error.
+		b[i]=byte(u)                        // Store the byte.
+	}                                     // Done iterating values.
+	*dest=b                               // Give the caller the bytes.
+	return nil                            // Success.
+}                                       // ------- GetValueByteSlice -------- //
+// ----------------------------- // GetList // ------------------------------ //
+//  Decode every value of a multi-valued parameter, e.g. ports=80,443,8080,   //
+// into a []T. T may be string, bool, any int or uint type, float32, float64  //
+// or time.Duration. An empty section means the currently-selected one, and   //
+// the overlay and parents are searched as GetValue() does. Integers are read //
+// in the base SetIntBase() chose, decimal unless it was 0. The error names   //
+// the first value that does not decode.                                      //
+// -------------------------------------------------------------------------- //
+func GetList[T any](cfg *Configuration, section, name string) ([]T, error){
+  cfg.follow()                          // Catch up with the original.
+	s:=cfg.current                        // Default to the current section.
+	if section!=""{                       // Were we given a section?
+	  s=cfg.FindSection(section)          // Yes, find it by name.
+		if s==nil{                          // Does it exist?
+		  return nil,fmt.Errorf("section \"%s\" not found", section)
+		}                                   // Done checking for section.
+	}                                     // Done checking for section name.
+	if s==nil{                            // Do we have a section?
+	  return nil,fmt.Errorf("no current section selected")
+	}                                     // Done checking for section.
+	var values []string                   // The values to decode.
+	if v,ok:=cfg.lookupOverlay(s.GetName(),name);ok{// Overlay has it?
+	  values=cfg.splitCSVList(v)          // Yes, split it like the file's.
+	} else{                               // Else look in the file.
+	  p:=s.FindParameter(name,true)       // Find the parameter, maybe in a parent.
+		if p==nil{                          // Did we find it?
+		  return nil,s.configError("get",name,ErrParameterNotFound)
+		}                                   // Done checking for parameter.
+		values=p.values[:p.n]               // Its values.
+	}                                     // Done getting the values.
+	res:=make([]T,len(values))            // One element per value.
+	for i:=range res{                     // For each value...
+	  v:=strings.TrimSpace(values[i])     // Get it.
+		if err:=decodeElement(v,&res[i],cfg.floatSpecials,cfg.GetIntBase());err!=nil{// Decode it.
+		  return nil,s.configError("get",name,fmt.Errorf("can't decode element %d \"%s\": %w",i,v,err))
+		}                                   // Done checking for decode error.
+	}                                     // Done iterating values.
+	return res,nil                        // Return the decoded list.
+}                                       // ------------- GetList ------------ //
+// -------------------------- // decodeInteger // --------------------------- //
+// Decode v into dest, which must point to an int or uint type, in base 10,   //
+// or with base 0 taking 0x, 0o, 0b and a leading 0 as strconv.ParseInt()     //
+// does. dest is left alone if v does not decode.                             //
+// -------------------------------------------------------------------------- //
+func decodeInteger(v string, dest any, base int) error{
+  var n int64                           // A signed value.
+	var u uint64                          // An unsigned value.
+	var err error                         // Any error decoding it.
+	switch d:=dest.(type){                // Act according to the type of dest.
+	  case *int:   if n,err=strconv.ParseInt(v,base,0);err==nil{ *d=int(n) }
+		case *int8:  if n,err=strconv.ParseInt(v,base,8);err==nil{ *d=int8(n) }
+		case *int16: if n,err=strconv.ParseInt(v,base,16);err==nil{ *d=int16(n) }
+		case *int32: if n,err=strconv.ParseInt(v,base,32);err==nil{ *d=int32(n) }
+		case *int64: if n,err=strconv.ParseInt(v,base,64);err==nil{ *d=n }
+		case *uint:  if u,err=strconv.ParseUint(v,base,0);err==nil{ *d=uint(u) }
+		case *uint8: if u,err=strconv.ParseUint(v,base,8);err==nil{ *d=uint8(u) }
+		case *uint16:if u,err=strconv.ParseUint(v,base,16);err==nil{ *d=uint16(u) }
+		case *uint32:if u,err=strconv.ParseUint(v,base,32);err==nil{ *d=uint32(u) }
+		case *uint64:if u,err=strconv.ParseUint(v,base,64);err==nil{ *d=u }
+		default:                            // Anything else?
+		  return fmt.Errorf("unsupported integer type %T", dest)// We can't do it.
+	}                                     // Done acting according to the type.
+	if err!=nil{                          // Did it decode?
+	  return fmt.Errorf("can't decode \"%s\" to %s: %w", v, reflect.TypeOf(dest).Elem(), err)
+	}                                     // Done checking for error.
+	return nil                            // We are good if we got here.
+}                                       // --------- decodeInteger ---------- //
+// -------------------------- // decodeElement // --------------------------- //
+// Decode one value into dest, which must point to one of the types GetList() //
+// supports. Integers are read in base, as decodeInteger() does.              //
+// -------------------------------------------------------------------------- //
+func decodeElement(v string, dest any, specials bool, base int) error{
+  var err error                         // Any error decoding the value.
+	switch d:=dest.(type){                // Act according to the type of dest.
+	  case *string:                       // A string needs no decoding.
+		  *d=v                              // Just copy it.
+		case *bool:                         // A boolean?
+		  switch{                           // Yes, which one is it?
+			  case isTrue(v): *d=true         // It is true.
+				case isFalse(v): *d=false       // It is false.
+				default: err=fmt.Errorf("value %s is not a boolean", v)
+			}                                 // Done checking the boolean.
+		case *time.Duration:                // A duration, before int64 which it is too.
+		  *d,err=time.ParseDuration(v)      // Parse it as a duration.
+		case *int,*int8,*int16,*int32,*int64,*uint,*uint8,*uint16,*uint32,*uint64:
+		  err=decodeInteger(v,dest,base)    // An integer, in the base we were given.
+		case *float32:                      // A single precision float?
+		  var f float64                     // Yes, parse it in double...
+		  f,err=parseFloat(v,32,specials)   // ...with 32-bit rounding.
+			*d=float32(f)                     // Store it.
+		case *float64:                      // A double precision float?
+		  *d,err=parseFloat(v,64,specials)  // Yes, parse it.
+		default:                            // Anything else?
+		  err=fmt.Errorf("unsupported element type %T", dest)// We can't do it.
+	}                                     // Done acting according to the type.
+	return err                            // Return error if any.
+}                                       // --------- decodeElement ---------- //
  // ---------------------- Times and durations ------------------------------ //
-func (cfg *Configuration)	GetValueTimespec(name string, dest *unix.Timespec) error{
-  p:=cfg.GetValue(name)                 
-  if len(p)==0{                         
-	  return fmt.Errorf("can't decode empty \"value\" to unix.Timespec")
+func (cfg *Configuration)	GetValueTimespec(name string, dest *Timespec) error{
+  p,err:=cfg.presentValue(name,0)
+  if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
 	t,err:=time.Parse(time.RFC3339,p) 
 	if err!=nil{                          
-	  return fmt.Errorf("can't decode \"%s\" to unix.Timespec: %v", p, err)
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to unix.Timespec: %v", p, err))
 	}                                     
-	unix.NsecToTimespec(t.UnixNano()) 	  
-	*dest=unix.NsecToTimespec(t.UnixNano()) 
+	*dest=nsecToTimespec(t.UnixNano()) 
 	return nil                            
 }
-func (cfg *Configuration)	GetValueTimespecByIndex(name string,i uint,dest *unix.Timespec) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+func (cfg *Configuration)	GetValueTimespecByIndex(name string,i uint,dest *Timespec) error{
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
 	t,err:=time.Parse(time.RFC3339,p) 
 	if err!=nil{                          
-	  return fmt.Errorf("can't decode \"%s\" to unix.Timespec: %v", p, err)
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to unix.Timespec: %v", p, err))
 	}                                     
-	unix.NsecToTimespec(t.UnixNano()) 	  
-	*dest=unix.NsecToTimespec(t.UnixNano()) 
+	*dest=nsecToTimespec(t.UnixNano()) 
 	return nil                            
 }
 func (cfg *Configuration)	GetValueDuration(name string, dest *time.Duration) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
 	d,err:=time.ParseDuration(p)          
 	if err!=nil{                          
-	  return fmt.Errorf("can't decode \"%s\" to time.Duration: %v", p, err)
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Duration: %v", p, err))
 	}                                     
 	*dest=d                               
 	return nil                            
 }
 func (cfg *Configuration)	GetValueDurationByIndex(name string,i uint,dest *time.Duration) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
 	d,err:=time.ParseDuration(p)          
 	if err!=nil{                          
-	  return fmt.Errorf("can't decode \"%s\" to time.Duration: %v", p, err)
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Duration: %v", p, err))
 	}                                     
 	*dest=d                               
 	return nil                            
 }
 
-// Time since epoch
+// Time since epoch, written as RFC3339. GetValueTimeLayout() reads others.
 func (cfg *Configuration)	GetValueTime(name string, dest *time.Time) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
 	t,err:=time.Parse(time.RFC3339,p) 
 	if err!=nil{                          
-	  return fmt.Errorf("can't decode \"%s\" to time.Time: %v", p, err)
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Time: %v", p, err))
 	}                                     
 	*dest=t                               
 	return nil                            
 }
 func (cfg *Configuration)	GetValueTimeByIndex(name string, i uint,dest *time.Time) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
 	t,err:=time.Parse(time.RFC3339,p) 
 	if err!=nil{                          
-	  return fmt.Errorf("can't decode \"%s\" to time.Time: %v", p, err)
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Time: %v", p, err))
 	}                                     
 	*dest=t                               
 	return nil                            
 }
+// ------------------------ // GetValueTimeLayout // ------------------------ //
+//  Decode a time written in another layout than the RFC3339 GetValueTime()   //
+// insists on, such as "2006-01-02 15:04:05" or the date-only "2006-01-02",   //
+// with time.Parse(). A time whose layout has no zone is taken as UTC; use    //
+// GetValueTimeLayoutIn() to read it in another location.                     //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueTimeLayout(name, layout string, dest *time.Time) error{
+  return cfg.GetValueTimeLayoutIn(name,layout,time.UTC,dest)// Zoneless is UTC.
+}                                       // ------- GetValueTimeLayout ------- //
+// ----------------------- // GetValueTimeLayoutIn // ----------------------- //
+//  Like GetValueTimeLayout(), but a time without a zone is in loc, as with   //
+// time.ParseInLocation(). A zone in the value still wins. A nil loc is UTC.  //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueTimeLayoutIn(name, layout string, loc *time.Location, dest *time.Time) error{
+  p,err:=cfg.presentValue(name,0)       // Get the value.
+	if err!=nil{                          // Is it there, and not empty?
+	  return cfg.configError("get",name,err)// No, return error.
+	}                                     // Done checking for the value.
+	if loc==nil{                          // Were we given a location?
+	  loc=time.UTC                        // No, use UTC.
+	}                                     // Done checking for location.
+	t,err:=time.ParseInLocation(layout,p,loc)// Parse it in the layout.
+	if err!=nil{                          // Could we?
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to time.Time with layout \"%s\": %v", p, layout, err))
+	}                                     // Done checking for parse error.
+	*dest=t                               // Store the time.
+	return nil                            // We are good if we got here.
+}                                       // ------ GetValueTimeLayoutIn ------ //
+// --------------------------- // GetValueColor // -------------------------- //
+//  Get a parameter of the currently-selected section as a color, written as  //
+// "#RGB", "#RRGGBB" or "#RRGGBBAA" in hex, like fg=#ff8800. A color with no  //
+// alpha is opaque.                                                           //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueColor(name string, dest *color.RGBA) error{
+  p,err:=cfg.presentValue(name,0)       // Get the value.
+	if err!=nil{                          // Is it there, and not empty?
+	  return cfg.configError("get",name,err)// No, return error.
+	}                                     // Done checking for the value.
+	c,err:=parseColor(p)                  // Decode it.
+	if err!=nil{                          // Could we?
+	  return cfg.configError("get",name,err)// No, return error.
+	}                                     // Done checking for error.
+	*dest=c                               // Store the color.
+	return nil                            // We are good if we got here.
+}                                       // --------- GetValueColor ---------- //
+// --------------------------- // SetValueColor // -------------------------- //
+//  Set a parameter of the currently-selected section to a color, as          //
+// "#rrggbb", or "#rrggbbaa" if it is not opaque, for GetValueColor().        //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetValueColor(name string, value color.RGBA) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
+	if cfg.current==nil{                  // Do we have a current section?
+	  return cfg.configError("set",name,fmt.Errorf("no current section selected"))
+	}                                     // Done checking for current section.
+	return cfg.configError("set",name,cfg.current.SetValue(name,formatColor(value),0))
+}                                       // --------- SetValueColor ---------- //
+// --------------------------- // GetValueJSON // --------------------------- //
+//  Decode a parameter of the currently-selected section, holding a JSON      //
+// document like routes=[{"path":"/a"}], into dest, as json.Unmarshal() does. //
+// A value read from a file that is a valid JSON object or array is kept      //
+// as it was written, since splitting it on commas and quotes, as GetValue()  //
+// sees it, would mangle it; Print() and WriteFile() write it back the same   //
+// way. Any other value, and one set since, is decoded as GetValues() writes  //
+// it. A document that is not valid JSON fails with the parameter name.       //
+// A bare [name], even [1], is read as a section reference, not a document.   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueJSON(name string, dest any) error{
+  cfg.follow()                          // Catch up with the original.
+  if cfg.current==nil{                  // Do we have a current section?
+	  return cfg.configError("get",name,fmt.Errorf("no current section selected"))
+	}                                     // Done checking for current section.
+	doc,ok:=cfg.lookupOverlay(cfg.current.GetName(),name)// Does the overlay have it?
+	if !ok{                               // No, look in the file.
+	  p:=cfg.current.FindParameter(name,true)// Find the parameter.
+		if p==nil{                          // Did we find it?
+		  return cfg.configError("get",name,ErrParameterNotFound)
+		}                                   // Done checking for parameter.
+		doc=p.rawJSON                       // The document as read...
+		if doc==""{                         // ...if it was one.
+		  doc=p.GetValues()                 // It wasn't, use what we have.
+		}                                   // Done getting the document.
+	}                                     // Done getting the value.
+	if err:=json.Unmarshal([]byte(doc),dest);err!=nil{// Could we decode it?
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" as JSON: %w",doc,err))
+	}                                     // Done checking for decode error.
+	return nil                            // We are good if we got here.
+}                                       // ---------- GetValueJSON ---------- //
+// -------------------------- // looksLikeJSON // --------------------------- //
+//  Return true if raw is a valid JSON object or array, which we keep whole,  //
+// and not a list that merely starts with '{' or '['. The reader asks only    //
+// once sectionRef() has turned the value down, so [1] stays a reference.     //
+// -------------------------------------------------------------------------- //
+func looksLikeJSON(raw string) bool{
+  if raw==""||(raw[0]!='{'&&raw[0]!='['){// Object or array?
+	  return false                        // No, it is not a document.
+	}                                     // Done checking the start.
+	return json.Valid([]byte(raw))        // Is it valid JSON?
+}                                       // --------- looksLikeJSON ---------- //
+// --------------------------- // sectionRef // ----------------------------- //
+//  Return the section name in a value like [name], and true if the value is  //
+// a section reference. The name may be anything but empty or holding quotes, //
+// braces or brackets, so [1] and [true] are references while ["a"], [] and   //
+// [{"a":1}] are left to looksLikeJSON().                                     //
+// -------------------------------------------------------------------------- //
+func sectionRef(value string) (string,bool){
+  v:=strings.TrimSpace(value)           // Remove surrounding whitespace.
+	if len(v)<2||v[0]!='['||v[len(v)-1]!=']'{// Is it in brackets?
+	  return "",false                     // No, it is just a value.
+	}                                     // Done checking for brackets.
+	target:=strings.TrimSpace(v[1:len(v)-1])// Get the section name.
+	if target==""||strings.ContainsAny(target,"\"'{}[]"){// Could it name a section?
+	  return "",false                     // No, it may be JSON.
+	}                                     // Done checking the name.
+	return target,true                    // It is a section reference.
+}                                       // ----------- sectionRef ----------- //
+// ------------------------- // GetValueFileMode // ------------------------- //
+//  Get a parameter of the currently-selected section as file permissions,    //
+// written in octal with or without a leading 0, like mode=0640 or mode=640.  //
+// The setuid, setgid and sticky bits, as in 04755, become os.ModeSetuid,     //
+// os.ModeSetgid and os.ModeSticky. Anything past 07777 is an error.          //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) GetValueFileMode(name string, dest *os.FileMode) error{
+  p,err:=cfg.presentValue(name,0)       // Get the value.
+	if err!=nil{                          // Is it there, and not empty?
+	  return cfg.configError("get",name,err)// No, return error.
+	}                                     // Done checking for the value.
+	u,err:=strconv.ParseUint(strings.TrimSpace(p),8,32)// Decode it in octal.
+	if err!=nil{                          // Could we?
+	  return cfg.configError("get",name,fmt.Errorf("can't decode \"%s\" to os.FileMode: %v", p, err))
+	}                                     // Done checking for parse error.
+	if u>07777{                           // Is it a permission at all?
+	  return cfg.configError("get",name,fmt.Errorf("file mode \"%s\" is out of range 0-07777", p))
+	}                                     // Done checking the range.
+	mode:=os.FileMode(u&0777)             // The permission bits.
+	if u&04000!=0{                        // Setuid?
+	  mode|=os.ModeSetuid                 // Yes, Go keeps it elsewhere.
+	}                                     // Done checking for setuid.
+	if u&02000!=0{                        // Setgid?
+	  mode|=os.ModeSetgid                 // Yes, Go keeps it elsewhere.
+	}                                     // Done checking for setgid.
+	if u&01000!=0{                        // Sticky?
+	  mode|=os.ModeSticky                 // Yes, Go keeps it elsewhere.
+	}                                     // Done checking for sticky.
+	*dest=mode                            // Store the mode.
+	return nil                            // We are good if we got here.
+}                                       // -------- GetValueFileMode -------- //
+// ------------------------- // SetValueFileMode // ------------------------- //
+//  Set a parameter of the currently-selected section to file permissions in  //
+// octal, as "0NNN", or "0NNNN" with setuid, setgid or sticky bits, for       //
+// GetValueFileMode(). The file type bits of mode, like os.ModeDir, are not   //
+// written.                                                                   //
+// -------------------------------------------------------------------------- //
+func (cfg *Configuration) SetValueFileMode(name string, mode os.FileMode) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
+	if cfg.current==nil{                  // Do we have a current section?
+	  return cfg.configError("set",name,fmt.Errorf("no current section selected"))
+	}                                     // Done checking for current section.
+	u:=uint32(mode.Perm())                // The permission bits.
+	if mode&os.ModeSetuid!=0{             // Setuid?
+	  u|=04000                            // Yes, in octal it is here.
+	}                                     // Done checking for setuid.
+	if mode&os.ModeSetgid!=0{             // Setgid?
+	  u|=02000                            // Yes, in octal it is here.
+	}                                     // Done checking for setgid.
+	if mode&os.ModeSticky!=0{             // Sticky?
+	  u|=01000                            // Yes, in octal it is here.
+	}                                     // Done checking for sticky.
+	return cfg.configError("set",name,cfg.current.SetValue(name,fmt.Sprintf("0%03o",u),0))
+}                                       // -------- SetValueFileMode -------- //
 
 // --------------------------- Signed Integers ------------------------------ //
 func (cfg *Configuration)	GetValueInt(name string, dest *int) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt(name string, value int) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.Itoa(value),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.Itoa(value),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueIntByIndex(name string,i uint,dest *int) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueIntByIndex(name string, i uint, value int) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(value),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(value),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt8(name string, dest *int8) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt8(name string, value int8) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.Itoa(int(value)),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.Itoa(int(value)),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt8ByIndex(name string,i uint,dest *int8) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt8ByIndex(name string, i uint, value int8) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(int(value)),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(int(value)),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt16(name string, dest *int16) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt16(name string, value int16) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.Itoa(int(value)),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.Itoa(int(value)),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt16ByIndex(name string,i uint,dest *int16) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt16ByIndex(name string, i uint, value int16) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(int(value)),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(int(value)),i,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt32(name string, dest *int32) error{
-  p:=cfg.GetValue(name)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name) 
-	}
-	return cfg.scanValue(name,0,"%d",dest)
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
+	}                                     
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt32(name string, value int32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{
-	  return cfg.current.SetValue(name,strconv.Itoa(int(value)),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.Itoa(int(value)),0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt32ByIndex(name string,i uint,dest *int32) error{
-  p:=cfg.GetValueByIndex(name,i)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name) 
-	}
-	return cfg.scanValue(name,int(i),"%d",dest)
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
+	}                                     
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt32ByIndex(name string, i uint, value int32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(int(value)),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.Itoa(int(value)),i,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt64(name string, dest *int64) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt64(name string, value int64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatInt(value,10),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatInt(value,10),0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueInt64ByIndex(name string,i uint,dest *int64) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueInt64ByIndex(name string, i uint, value int64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatInt(value,10),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatInt(value,10),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 
 // --------------------- Unicode, binary and hex values --------------------- //
 func (cfg *Configuration)	GetValueRune(name string, dest *rune) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  _,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%c",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,0,"%c",dest))
 }
 func (cfg *Configuration)	SetValueRune(name string, value rune) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,string(value),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,string(value),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueRuneByIndex(name string,i uint,dest *rune) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  _,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%c",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,int(i),"%c",dest))
 }
 func (cfg *Configuration)	SetValueRuneByIndex(name string, i uint, value rune) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,string(value),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,string(value),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueBinary(name string, dest *string) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  _,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%b",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,0,"%b",dest))
 }
 func (cfg *Configuration)	SetValueBinary(name string, value string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,value,0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,value,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueHex(name string, dest *string) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  _,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%x",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,0,"%x",dest))
 }
 func (cfg *Configuration)	SetValueHex(name string, value string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,value,0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,value,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueOctal(name string, dest *string) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  _,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%o",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,0,"%o",dest))
 }
 func (cfg *Configuration)	SetValueOctal(name string, value string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,value,0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,value,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 
 // ------------------------- Unsigned integers ------------------------------ //
 func (cfg *Configuration)  GetValueUint(name string, dest *uint) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint(name string, value uint) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUintByIndex(name string,i uint,dest *uint) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUintByIndex(name string, i uint, value uint) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint8(name string, dest *uint8) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration) SetValueUint8(name string, value uint8) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint8ByIndex(name string,i uint,dest *uint8) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint8ByIndex(name string, i uint, value uint8) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint16(name string, dest *uint16) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint16(name string, value uint16) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint16ByIndex(name string,i uint, dest *uint16) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint16ByIndex(name string, i uint, value uint16) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint32(name string, dest *uint32) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint32(name string, value uint32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatUint(uint64(value),10),0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint32ByIndex(name string,i uint,dest *uint32) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%u",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint32ByIndex(name string, i uint, value uint32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(uint64(value),10),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint64(name string, dest *uint64) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint64(name string, value uint64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatUint(value,10),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatUint(value,10),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueUint64ByIndex(name string,i uint,dest *uint64) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%d",dest)
+	return cfg.configError("get",name,decodeInteger(p,dest,cfg.GetIntBase()))
 }
 func (cfg *Configuration)	SetValueUint64ByIndex(name string, i uint, value uint64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(value,10),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatUint(value,10),i,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 
 // ------------------------ Floating point values --------------------------- //
 func (cfg *Configuration)	GetValueFloat32(name string, dest *float32) error{
-  p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%f",dest)
+	f,err:=parseFloat(p,32,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValueFloat32(name string, value float32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatFloat(float64(value),'f',-1,32),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,cfg.formatFloat(float64(value),32),0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueFloat32ByIndex(name string,i uint,dest *float32) error{
-  p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%f",dest)
+	f,err:=parseFloat(p,32,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValueFloat32ByIndex(name string, i uint, value float32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatFloat(float64(value),'f',-1,32),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,cfg.formatFloat(float64(value),32),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueFloat64(name string,dest *float64) error{
-	p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%f",dest)
+	f,err:=parseFloat(p,64,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValueFloat64(name string, value float64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatFloat(value,'f',-1,64),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,cfg.formatFloat(value,64),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueFloat64ByIndex(name string,i uint,dest *float64) error{
-  p:=cfg.GetValueByIndex(name,i)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name) 
-	}
-	return cfg.scanValue(name,int(i),"%f",dest)
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
+	}                                     
+	f,err:=parseFloat(p,64,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValueFloat64ByIndex(name string, i uint, value float64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+  cfg.follow()                          // Catch up with the original.
   if cfg.current!=nil{
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatFloat(value,'f',-1,64),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,cfg.formatFloat(value,64),i,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 
 // Floating point values with precision
 func (cfg *Configuration)	GetValuePrecisionFloat32(name,precision string,dest *float32) error{
-	p:=cfg.GetValue(name)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name)
-	}
-	return cfg.scanValue(name,0,"%"+precision+"f",dest)
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
+	}                                     
+	f,err:=parseFloat(p,32,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValuePrecisionFloat32(name,precision string,value float32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{
-	  return cfg.current.SetValue(name,strconv.FormatFloat(float64(value),'f',-1,32),0)
+	  v,err:=cfg.formatPrecision(float64(value),32,precision)// Write it as asked.
+	  if err!=nil{                        // Could we?
+	    return cfg.configError("set",name,err)// No, say why.
+	  }                                   // Done checking for error.
+	  return cfg.configError("set",name,cfg.current.SetValue(name,v,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValuePrecisionFloat32ByIndex(name string,i uint,precision string,dest *float32) error{
-	p:=cfg.GetValueByIndex(name,i)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name)
-	}
-	return cfg.scanValue(name,int(i),"%"+precision+"f",dest)
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
+	}                                     
+	f,err:=parseFloat(p,32,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=float32(f)                    // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValuePrecisionFloat32ByIndex(name string, i uint, precision string, value float32) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatFloat(float64(value),'f',-1,32),i,0)
+	  v,err:=cfg.formatPrecision(float64(value),32,precision)// Write it as asked.
+	  if err!=nil{                        // Could we?
+	    return cfg.configError("set",name,err)// No, say why.
+	  }                                   // Done checking for error.
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,v,i,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
-func (cfg *Configuration)	GetValuePrecisionFloat64(name string,value,precision string,dest *float64) error{
-	p:=cfg.GetValue(name)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name)
-	}
-	return cfg.scanValue(name,0,"%"+precision+"f",dest)
+func (cfg *Configuration)	GetValuePrecisionFloat64(name,precision string,dest *float64) error{
+  p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
+	}                                     
+	f,err:=parseFloat(p,64,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValuePrecisionFloat64(name string,precision string,value float64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{
-	  return cfg.current.SetValue(name,strconv.FormatFloat(value,'f',-1,64),0)
+	  v,err:=cfg.formatPrecision(value,64,precision)// Write it as asked.
+	  if err!=nil{                        // Could we?
+	    return cfg.configError("set",name,err)// No, say why.
+	  }                                   // Done checking for error.
+	  return cfg.configError("set",name,cfg.current.SetValue(name,v,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValuePrecisionFloat64ByIndex(name string,i uint,precision string,dest *float64) error{
-	p:=cfg.GetValueByIndex(name,i)
-	if len(p)==0{
-	  return fmt.Errorf("parameter %s not found", name)
-	}
-	return cfg.scanValue(name,int(i),"%"+precision+"f",dest)
+  p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
+	}                                     
+	f,err:=parseFloat(p,64,cfg.floatSpecials)// Decode it as a float.
+	if err==nil{                          // Did it decode?
+	  f,err=roundPrecision(f,precision)   // Yes, round it as asked.
+	}                                     // Done checking for error.
+	if err==nil{                          // Did it decode?
+	  *dest=f                             // Yes, store it.
+	}                                     // Done checking for error.
+	return cfg.configError("get",name,err)// Return error if any.
 }
 func (cfg *Configuration)	SetValuePrecisionFloat64ByIndex(name string, i uint, precision string, value float64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatFloat(value,'f',-1,64),i,0)
+	  v,err:=cfg.formatPrecision(value,64,precision)// Write it as asked.
+	  if err!=nil{                        // Could we?
+	    return cfg.configError("set",name,err)// No, say why.
+	  }                                   // Done checking for error.
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,v,i,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 
 // ----------------------------- Complex numbers ---------------------------- //
 func (cfg *Configuration)	GetValueComplex64(name string,dest *complex64) error{
-	p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+	p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%v",dest)
+	c,err:=parseComplex(p,64)             // Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return cfg.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=complex64(c)                    // Store it.
+	return nil                            // Done.
 }
 func (cfg *Configuration)	SetValueComplex64(name string, value complex64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatComplex(complex128(value),'v',-1,64),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatComplex(complex128(value),'v',-1,64),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueComplex64ByIndex(name string,i uint,dest *complex64) error{
-	p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+	p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%v",dest)
+	c,err:=parseComplex(p,64)             // Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return cfg.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=complex64(c)                    // Store it.
+	return nil                            // Done.
 }
 func (cfg *Configuration)	SetValueComplex64ByIndex(name string, i uint, value complex64) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatComplex(complex128(value),'v',-1,64),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatComplex(complex128(value),'v',-1,64),i,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueComplex128(name string,dest *complex128) error{
-	p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+	p,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%v",dest)
+	c,err:=parseComplex(p,128)            // Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return cfg.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=c                               // Store it.
+	return nil                            // Done.
 }
 func (cfg *Configuration)	SetValueComplex128(name string, value complex128) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,strconv.FormatComplex(value,'v',-1,64),0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,strconv.FormatComplex(value,'v',-1,64),0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 func (cfg *Configuration)	GetValueComplex128ByIndex(name string,i uint,dest *complex128) error{
-	p:=cfg.GetValueByIndex(name,i)        
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+	p,err:=cfg.presentValue(name,i)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,int(i),"%v",dest)
+	c,err:=parseComplex(p,128)            // Decode it as a complex number.
+	if err!=nil{                          // Did it decode?
+	  return cfg.configError("get",name,err)// No, say why.
+	}                                     // Done checking for error.
+	*dest=c                               // Store it.
+	return nil                            // Done.
 }
 func (cfg *Configuration)	SetValueComplex128ByIndex(name string, i uint, value complex128) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{                  
-	  return cfg.current.SetValuePtrOnIndex(name,strconv.FormatComplex(value,'v',-1,64),i,0)
+	  return cfg.configError("set",name,cfg.current.SetValuePtrOnIndex(name,strconv.FormatComplex(value,'v',-1,64),i,0))
 	}
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
 
 	// Scientific notation
 func (cfg *Configuration)	GetValueSI(name string,dest *string) error{
-	p:=cfg.GetValue(name)                 
-	if len(p)==0{                         
-	  return fmt.Errorf("parameter %s not found", name) 
+	_,err:=cfg.presentValue(name,0)
+	if err!=nil{                          
+	  return cfg.configError("get",name,err)
 	}                                     
-	return cfg.scanValue(name,0,"%s",dest)
+	return cfg.configError("get",name,cfg.scanValue(name,0,"%s",dest))
 }
 func (cfg *Configuration)	SetValueSI(name string, value string) error{
+  if cfg.readonly{                      // Is this a read-only view?
+	  return ErrReadOnly                  // Yes, refuse to change it.
+	}                                     // Done checking for read-only view.
+	cfg.follow()                          // Catch up with the original.
 	if cfg.current!=nil{                  
-	  return cfg.current.SetValue(name,value,0)
+	  return cfg.configError("set",name,cfg.current.SetValue(name,value,0))
 	}                                     
-	return fmt.Errorf("no current section selected")
+	return cfg.configError("set",name,fmt.Errorf("no current section selected"))
 }
+// =========================== // ConfigWriter // =========================== //
+// A fluent builder for tools that generate configuration files.              //
+// ========================================================================== //
+// ----------------------------- // NewWriter // ---------------------------- //
+//  Start building a configuration from scratch:                              //
+//   err:=NewWriter().Comment("generated").Section("db").Set("host","x").     //
+//     WriteFile("app.cfg")                                                   //
+// The first error sticks: later calls do nothing and Err(), or WriteFile(),  //
+// return it.                                                                 //
+// -------------------------------------------------------------------------- //
+func NewWriter() *ConfigWriter{
+  return &ConfigWriter{cfg: &Configuration{canWrite: true}}// Ready to write.
+}                                       // ------------ NewWriter ----------- //
+// ------------------------------ // Section // ----------------------------- //
+//  Start a new section; the Set() calls that follow add to it. Naming a      //
+// section that was already started goes back to it.                          //
+// -------------------------------------------------------------------------- //
+func (w *ConfigWriter) Section(name string) *ConfigWriter{
+  if w.err!=nil{                        // Did something go wrong already?
+	  return w                            // Yes, do nothing more.
+	}                                     // Done checking for error.
+	if strings.TrimSpace(name)==""{       // Do we have a name?
+	  w.err=fmt.Errorf("section name cannot be empty")
+		return w                            // No, remember why.
+	}                                     // Done checking the name.
+	if s:=w.cfg.FindSection(name);s!=nil{// Did we start it already?
+	  w.cfg.current=s                     // Yes, go back to it.
+		return w                            // Its comments are for what's next.
+	}                                     // Done checking for section.
+	w.cfg.current=w.cfg.AppendSection(name,w.takeComments(),false)
+	return w                              // Ready for its parameters.
+}                                       // ------------- Section ------------ //
+// -------------------------------- // Set // ------------------------------- //
+//  Add a parameter to the current section, exactly as if "name=value" had    //
+// been read from a file: commas separate values and quotes keep them         //
+// together. Setting the same name twice in a section is an error.            //
+// -------------------------------------------------------------------------- //
+func (w *ConfigWriter) Set(name, value string) *ConfigWriter{
+  if w.err!=nil{                        // Did something go wrong already?
+	  return w                            // Yes, do nothing more.
+	}                                     // Done checking for error.
+	s:=w.cfg.current                      // The section we are adding to.
+	switch{                               // Can we add it?
+	  case s==nil:                        // Is there a section?
+		  w.err=w.cfg.configError("set",name,fmt.Errorf("no section started"))
+		case strings.TrimSpace(name)=="":   // Is there a name?
+		  w.err=w.cfg.configError("set",name,fmt.Errorf("name cannot be empty"))
+		case s.FindParameter(name,false)!=nil:// Is it there already?
+		  w.err=w.cfg.configError("set",name,fmt.Errorf("parameter already set"))
+		default:                            // Else we can add it.
+		  s.AppendParameter(name,value,w.takeComments(),false)
+	}                                     // Done adding the parameter.
+	return w                              // Ready for more.
+}                                       // --------------- Set -------------- //
+// ------------------------------ // Comment // ----------------------------- //
+//  Comment the next section or parameter, as AddHeaderComment() prefixes     //
+// them. Comments after the last of them end the file.                        //
+// -------------------------------------------------------------------------- //
+func (w *ConfigWriter) Comment(text string) *ConfigWriter{
+  if w.err==nil{                        // Is all well so far?
+	  w.comments=append(w.comments,text)  // Yes, keep it for what's next.
+	}                                     // Done checking for error.
+	return w                              // Ready for more.
+}                                       // ------------- Comment ------------ //
+// -------------------------------- // Err // ------------------------------- //
+// Return the first error Section() or Set() ran into, nil if none.           //
+// -------------------------------------------------------------------------- //
+func (w *ConfigWriter) Err() error{
+  return w.err                          // Whatever went wrong, if anything.
+}                                       // --------------- Err -------------- //
+// ------------------------------- // Build // ------------------------------ //
+//  Return the configuration built so far, writable, with its last section    //
+// selected. It is the writer's own, so later calls keep changing it.         //
+// -------------------------------------------------------------------------- //
+func (w *ConfigWriter) Build() *Configuration{
+  if len(w.comments)>0{                 // Any comments left over?
+	  w.cfg.AddFooterComment(strings.Join(w.comments,"\n"))// They end the file.
+		w.comments=nil                      // They are used now.
+	}                                     // Done checking for comments.
+	return w.cfg                          // Return the configuration.
+}                                       // -------------- Build ------------- //
+// ----------------------------- // WriteFile // ---------------------------- //
+// Write the configuration built so far to path, unless something went wrong  //
+// building it.                                                               //
+// -------------------------------------------------------------------------- //
+func (w *ConfigWriter) WriteFile(path string) error{
+  if w.err!=nil{                        // Did something go wrong?
+	  return w.err                        // Yes, write nothing.
+	}                                     // Done checking for error.
+	return w.Build().WriteFile(path)      // Write it.
+}                                       // ------------ WriteFile ----------- //
+// --------------------------- // takeComments // --------------------------- //
+// Turn the comments kept for what comes next into a list, and forget them.   //
+// -------------------------------------------------------------------------- //
+func (w *ConfigWriter) takeComments() *Comment{
+  head,_:=w.cfg.commentLines(strings.Join(w.comments,"\n"))// Make the list.
+	w.comments=nil                        // They are used now.
+	return head                           // Return the list, nil if none.
+}                                       // ---------- takeComments ---------- //
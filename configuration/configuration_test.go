@@ -0,0 +1,3466 @@
+package configuration
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parse reads text as a configuration file named t.cfg.
+func parse(t *testing.T, text string) *Configuration {
+	t.Helper()
+	cfg := NewConfiguration("cfg")
+	if err := cfg.ReadContext(context.Background(), strings.NewReader(text), "t.cfg"); err != nil {
+		t.Fatalf("ReadContext: %v", err)
+	}
+	return cfg
+}
+
+// selected parses text and selects section name.
+func selected(t *testing.T, text, name string) *Configuration {
+	t.Helper()
+	cfg := parse(t, text)
+	if err := cfg.SelectSection(name); err != nil {
+		t.Fatalf("SelectSection(%q): %v", name, err)
+	}
+	return cfg
+}
+
+// writeFile writes text to the file name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, text string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFloatFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		fmt  byte
+		prec int
+		v    float64
+		want string
+	}{
+		{"default", 0, 0, 1e-9, "0.000000001"},
+		{"f", 'f', -1, 1e-9, "0.000000001"},
+		{"g", 'g', -1, 1e-9, "1e-09"},
+		{"e3", 'e', 3, 1e-9, "1.000e-09"},
+		{"unknown", 'q', 5, 0.5, "0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nx=0\n", "s")
+			if tt.fmt != 0 {
+				cfg.SetFloatFormat(tt.fmt, tt.prec)
+			}
+			if err := cfg.SetValueFloat64("x", tt.v); err != nil {
+				t.Fatalf("SetValueFloat64: %v", err)
+			}
+			if got := cfg.GetValue("x"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetValuePrecision(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision string
+		set       func(cfg *Configuration, precision string) error
+		want      string
+		wantErr   bool
+	}{
+		{"f32 2", "2", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat32("x", p, 3.14159) }, "3.14", false},
+		{"f64 3", "3", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat64("x", p, 2.0/3) }, "0.667", false},
+		{"f64 0", "0", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat64("x", p, 2.5) }, "2", false},
+		{"f64 empty", "", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat64("x", p, 0.25) }, "0.25", false},
+		{"f32 index", "1", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat32ByIndex("x", 0, p, 1.25) }, "1.2", false},
+		{"f64 index", "4", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat64ByIndex("x", 0, p, 1) }, "1.0000", false},
+		{"bad", "two", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat64("x", p, 1) }, "0", true},
+		{"negative", "-1", func(c *Configuration, p string) error { return c.SetValuePrecisionFloat32("x", p, 1) }, "0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nx=0\n", "s")
+			err := tt.set(cfg, tt.precision)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := cfg.GetValue("x"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetValueQuote(t *testing.T) {
+	const text = "[s]\ndq=\"a b\"\nsq='c'\nplain=d\nlist=\"x\",y\n"
+	tests := []struct {
+		name    string
+		param   string
+		i       uint
+		want    byte
+		wantErr error
+	}{
+		{"double", "dq", 0, '"', nil},
+		{"single", "sq", 0, '\'', nil},
+		{"unquoted", "plain", 0, 0, nil},
+		{"list first", "list", 0, '"', nil},
+		{"list second", "list", 1, 0, nil},
+		{"missing", "nope", 0, 0, ErrParameterNotFound},
+	}
+	s := parse(t, text).FindSection("s")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.GetValueQuote(tt.param, tt.i)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetValueQuote: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if _, err := s.GetValueQuote("plain", 1); err == nil {
+		t.Error("index past the values: no error")
+	}
+}
+
+func TestContinuationInQuotes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"quoted keeps blanks", "[s]\nx=\"a \\\n   b\"\n", "\"a    b\""},
+		{"unquoted trims", "[s]\nx=a \\\n   b\n", "a b"},
+		{"apostrophe is literal", "[s]\nmsg=don't \\\n    stop\n", "don't stop"},
+		{"quote after comma", "[s]\nx=a,'b \\\n  c'\n", "'b   c'"},
+		{"closed quote trims", "[s]\nx=\"a\" \\\n  b\n", "\"a\" b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parse(t, tt.text).FindSection("s")
+			p := s.FindParameter(tt.text[4:strings.IndexByte(tt.text, '=')], false)
+			if p == nil {
+				t.Fatal("parameter not found")
+			}
+			if got := p.GetValue(p.GetNValues() - 1); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLineLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		max     int
+		want    string
+		wantErr error
+	}{
+		{"exactly max", "abcd\n", 4, "abcd", nil},
+		{"exactly max crlf", "abcd\r\n", 4, "abcd", nil},
+		{"exactly max at eof", "abcd", 4, "abcd", io.EOF},
+		{"one past max", "abcde\n", 4, "", ErrLineTooLong},
+		{"empty with max 0", "\n", 0, "", nil},
+		{"empty with max -3", "\n", -3, "", nil},
+		{"text with max 0", "a\n", 0, "", ErrLineTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readLine(bufio.NewReader(strings.NewReader(tt.in)), tt.max)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxLineLengthContinuation(t *testing.T) {
+	tests := []struct {
+		name    string
+		max     int
+		text    string
+		wantErr bool
+	}{
+		{"fits", 8, "[s]\nx=abcd\n", false},
+		{"line of exactly max", 6, "[s]\nx=abcd\n", false},
+		{"continuation up to max", 6, "[s]\nx=ab\\\ncd\n", false},
+		{"empty continuation", 7, "[s]\nx=abcd\\\n\n", false},
+		{"continuation past max", 6, "[s]\nx=ab\\\ncde\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetMaxLineLength(tt.max)
+			err := cfg.ReadContext(context.Background(), strings.NewReader(tt.text), "t.cfg")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTimeByIndex(t *testing.T) {
+	const text = "[s]\nat=2024-01-02T03:04:05Z,2025-06-07T08:09:10Z\nfor=1s,2m\n"
+	s := parse(t, text).FindSection("s")
+	tests := []struct {
+		name    string
+		get     func() (string, error)
+		want    string
+		wantErr bool
+	}{
+		{"time 1", func() (string, error) {
+			var v time.Time
+			err := s.GetValueTimeByIndex("at", 1, &v)
+			return v.Format(time.RFC3339), err
+		}, "2025-06-07T08:09:10Z", false},
+		{"timespec 1", func() (string, error) {
+			var v Timespec
+			err := s.GetValueTimespecByIndex("at", 1, &v)
+			return time.Unix(v.Unix()).UTC().Format(time.RFC3339), err
+		}, "2025-06-07T08:09:10Z", false},
+		{"duration 1", func() (string, error) {
+			var v time.Duration
+			err := s.GetValueDurationByIndex("for", 1, &v)
+			return v.String(), err
+		}, "2m0s", false},
+		{"time 2", func() (string, error) {
+			var v time.Time
+			return "", s.GetValueTimeByIndex("at", 2, &v)
+		}, "", true},
+		{"duration 2", func() (string, error) {
+			var v time.Duration
+			return "", s.GetValueDurationByIndex("for", 2, &v)
+		}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.get()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParameterSetValueCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   uint
+	}{
+		{"one", []string{"a"}, 1},
+		{"three then one", []string{"a,b,c", "d"}, 1},
+		{"one then two", []string{"a", "b,c"}, 2},
+		{"two then none", []string{"a,b", ""}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParameter("x", "", nil, false)
+			for _, v := range tt.values {
+				if err := p.SetValue(v, 0); err != nil {
+					t.Fatalf("SetValue(%q): %v", v, err)
+				}
+			}
+			if got := p.GetNValues(); got != tt.want {
+				t.Errorf("GetNValues() = %d, want %d", got, tt.want)
+			}
+			var d time.Duration
+			if err := p.GetValueDurationByIndex(tt.want, "", &d); err == nil || !strings.Contains(err.Error(), "out of range") {
+				t.Errorf("value %d past the count: err = %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestAddSection(t *testing.T) {
+	const text = "[a]\nx=1\n[b]\ny=2\n"
+	tests := []struct {
+		name    string
+		section func(cfg, other *Configuration) *Section
+		wantErr bool
+	}{
+		{"clone", func(cfg, other *Configuration) *Section { return cfg.FindSection("a").Clone("c") }, false},
+		{"clone of other", func(cfg, other *Configuration) *Section { return other.FindSection("a").Clone("c") }, false},
+		{"nil", func(cfg, other *Configuration) *Section { return nil }, true},
+		{"duplicate name", func(cfg, other *Configuration) *Section { return cfg.FindSection("a").Clone("B") }, true},
+		{"other head", func(cfg, other *Configuration) *Section { return other.FindSection("a") }, true},
+		{"other tail", func(cfg, other *Configuration) *Section {
+			s := other.FindSection("b")
+			s.name = "c"
+			return s
+		}, true},
+		{"our tail renamed", func(cfg, other *Configuration) *Section {
+			s := cfg.FindSection("b")
+			s.name = "c"
+			return s
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, other := parse(t, text), parse(t, text)
+			err := cfg.AddSection(tt.section(cfg, other))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			n := 0
+			for s := other.first; s != nil; s = s.GetNext() {
+				n++
+			}
+			if n != 2 {
+				t.Errorf("other configuration has %d sections, want 2", n)
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	cfg := parse(t, "# about a\n[a]\nx=1,2\ny=3\n")
+	c := cfg.FindSection("a").Clone("b")
+	if err := cfg.AddSection(c); err != nil {
+		t.Fatalf("AddSection: %v", err)
+	}
+	if err := c.SetValue("x", "9", 0); err != nil {
+		t.Fatalf("SetValue: %v", err)
+	}
+	tests := []struct {
+		section, param string
+		want           string
+	}{
+		{"a", "x", "1"},
+		{"a", "y", "3"},
+		{"b", "x", "9"},
+		{"b", "y", "3"},
+	}
+	for _, tt := range tests {
+		if got := cfg.FindSection(tt.section).GetValue(tt.param, 0); got != tt.want {
+			t.Errorf("[%s] %s = %q, want %q", tt.section, tt.param, got, tt.want)
+		}
+	}
+}
+
+// slowReader hands out its text a few bytes at a time, and calls cancel once
+// it has handed out after bytes.
+type slowReader struct {
+	text   string
+	after  int
+	cancel context.CancelFunc
+	read   int
+}
+
+func (r *slowReader) Read(b []byte) (int, error) {
+	if r.read >= len(r.text) {
+		return 0, io.EOF
+	}
+	n := copy(b[:min(len(b), 3)], r.text[r.read:])
+	r.read += n
+	if r.read >= r.after {
+		r.cancel()
+	}
+	return n, nil
+}
+
+func TestReadContextCancel(t *testing.T) {
+	long := "[new]\nx=" + strings.Repeat("v", 1000) + "\ny=1\n"
+	tests := []struct {
+		name  string
+		after int
+	}{
+		{"at once", 0},
+		{"in the header", 3},
+		{"mid line", 100},
+		{"near the end", len(long) - 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, "[old]\nz=2\n")
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := &slowReader{text: long, after: tt.after, cancel: cancel}
+			err := cfg.ReadContext(ctx, r, "slow.cfg")
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("err = %v, want %v", err, context.Canceled)
+			}
+			if r.read >= len(long) {
+				t.Errorf("read all %d bytes after the cancel", r.read)
+			}
+			if cfg.FindSection("new") != nil || cfg.FindSection("old") == nil {
+				t.Error("a cancelled read changed the configuration")
+			}
+		})
+	}
+}
+
+func TestGetValueJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"array", `["a","b,c"]`, []string{"a", "b,c"}, false},
+		{"spaced", `[ "a" , "b" ]`, []string{"a", "b"}, false},
+		{"malformed", `["a",`, nil, true},
+		{"not json", `[a, b]`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nv="+tt.value+"\n", "s")
+			var got []string
+			err := cfg.GetValueJSON("v", &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeJSON(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want bool
+	}{
+		{`{"a":1}`, true},
+		{`[1,2]`, true},
+		{`[]`, true},
+		{`[a, b]`, false},
+		{`{not json`, false},
+		{`"a"`, false},
+		{`1`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeJSON(tt.raw); got != tt.want {
+			t.Errorf("looksLikeJSON(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestMalformedJSONPrint(t *testing.T) {
+	// A value that only starts like JSON is split like any other list, not
+	// kept whole as a document.
+	cfg := parse(t, "[s]\nv=[a, b\n")
+	p := cfg.FindSection("s").FindParameter("v", false)
+	if p.rawJSON != "" {
+		t.Errorf("rawJSON = %q, want none", p.rawJSON)
+	}
+	if n := p.GetNValues(); n != 2 {
+		t.Errorf("%d values, want 2", n)
+	}
+}
+
+func TestSectionRefNotJSON(t *testing.T) {
+	// [name] stays a section reference even when it would also be valid
+	// JSON; only a value that can't name a section is kept as a document.
+	cfg := parse(t, "[1]\nk=v\n[s]\nref=[1]\nlater=[t]\nj=[\"a\"]\nnone=[]\n[t]\n")
+	s := cfg.FindSection("s")
+	for _, name := range []string{"ref", "later"} {
+		if s.FindParameter(name, false) != nil {
+			t.Errorf("%s was read as a parameter, want a section reference", name)
+		}
+		if cfg.FindSection(name) == nil {
+			t.Errorf("no section %s", name)
+		}
+	}
+	for _, name := range []string{"j", "none"} {
+		p := s.FindParameter(name, false)
+		if p == nil || p.rawJSON == "" {
+			t.Errorf("%s was not kept as a JSON document", name)
+		}
+	}
+}
+
+func TestCommentPrefixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		text     string
+		want     string
+		header   string
+	}{
+		{"default", nil, "# c\n[s]\nurl=a#b\n", "a#b", "# made\n"},
+		{"semicolon", []string{";"}, "; c\n[s]\nurl=http://h/p#frag\n", "http://h/p#frag", "; made\n"},
+		{"two", []string{"//", ";"}, "// c\n; d\n[s]\nurl=x#y\n", "x#y", "// made\n"},
+		{"block", []string{";"}, "/* c\n# not a value\n*/\n[s]\nurl=#1\n", "#1", "; made\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetCommentPrefixes(tt.prefixes...)
+			if err := cfg.ReadContext(context.Background(), strings.NewReader(tt.text), "t.cfg"); err != nil {
+				t.Fatalf("ReadContext: %v", err)
+			}
+			if got := cfg.FindSection("s").GetValue("url", 0); got != tt.want {
+				t.Errorf("url = %q, want %q", got, tt.want)
+			}
+			cfg.AddHeaderComment("made")
+			var sb strings.Builder
+			if _, err := cfg.Print(&sb); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if !strings.HasPrefix(sb.String(), tt.header) {
+				t.Errorf("Print wrote %q, want it to start with %q", sb.String(), tt.header)
+			}
+		})
+	}
+}
+
+func TestCopyParameterComments(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "[s]\nx=1\n", nil},
+		{"one", "[s]\n# a\nx=1\n", []string{"# a"}},
+		{"two", "[s]\n# a\n# b\nx=1\n", []string{"# a", "# b"}},
+	}
+	comments := func(c *Comment) (lines []string) {
+		for ; c != nil; c = c.GetNext() {
+			lines = append(lines, c.GetValue())
+		}
+		return lines
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parse(t, tt.text).FindSection("s").FindParameter("x", false)
+			q := CopyParameter(p)
+			if got := comments(q.comments); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("copy has comments %q, want %q", got, tt.want)
+			}
+			if q.comments == nil {
+				return
+			}
+			if q.comments == p.comments {
+				t.Fatal("the copy shares its first comment with the original")
+			}
+			last := q.comments
+			for last.GetNext() != nil {
+				last = last.GetNext()
+			}
+			last.SetNext(NewComment("# added", false))
+			if got := comments(p.comments); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("changing the copy changed the original's comments to %q", got)
+			}
+		})
+	}
+}
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"same", "[s]\nx=1\n", "[s]\nx=1\n", true},
+		{"comments", "# a\n[s]\n# b\nx=1\n", "[s]\n\n# c\nx=1\n", true},
+		{"order", "[s]\nx=1\ny=2\n[t]\n", "[t]\n[s]\ny=2\nx=1\n", true},
+		{"quotes", "[s]\nx=\"1\",2\n", "[s]\nx='1',\"2\"\n", true},
+		{"value", "[s]\nx=1\n", "[s]\nx=2\n", false},
+		{"duplicate params equal", "[s]\nx=1\nx=2\n", "[s]\nx=1\nx=2\n", true},
+		{"duplicate params differ", "[s]\nx=1\nx=2\n", "[s]\nx=1\nx=3\n", false},
+		{"duplicate params swapped", "[s]\nx=1\nx=2\n", "[s]\nx=2\nx=1\n", false},
+		{"extra duplicate param", "[s]\nx=1\n", "[s]\nx=1\nx=1\n", false},
+		{"missing duplicate param", "[s]\nx=1\nx=1\n", "[s]\nx=1\n", false},
+		{"duplicate sections equal", "[s]\nx=1\n[s]\nx=2\n", "[s]\nx=1\n[s]\nx=2\n", true},
+		{"duplicate sections differ", "[s]\nx=1\n[s]\nx=2\n", "[s]\nx=1\n[s]\nx=3\n", false},
+		{"extra duplicate section", "[s]\nx=1\n", "[s]\nx=1\n[s]\nx=1\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := parse(t, tt.a), parse(t, tt.b)
+			if got := a.Equal(b); got != tt.want {
+				t.Errorf("a.Equal(b) = %v, want %v", got, tt.want)
+			}
+			if got := b.Equal(a); got != tt.want {
+				t.Errorf("b.Equal(a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithOverlay(t *testing.T) {
+	const text = "[db]\nhost=file-host\nport=5432\n"
+	overlay := func(section, name string) (string, bool) {
+		if section == "db" && name == "host" {
+			return "env-host", true
+		}
+		return "", false
+	}
+	cfg := selected(t, text, "db")
+	view := cfg.WithOverlay(overlay)
+	if err := view.SelectSection("db"); err != nil {
+		t.Fatalf("SelectSection: %v", err)
+	}
+	tests := []struct {
+		name string
+		cfg  *Configuration
+		want string
+	}{
+		{"host", view, "env-host"},
+		{"port", view, "5432"},
+		{"host", cfg, "file-host"},
+		{"port", cfg, "5432"},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.GetValue(tt.name); got != tt.want {
+			t.Errorf("GetValue(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	var sb strings.Builder
+	if _, err := view.WriteTo(&sb); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if strings.Contains(sb.String(), "env-host") {
+		t.Errorf("the view wrote the overlay value:\n%s", sb.String())
+	}
+}
+
+// TestWithOverlayFollows checks that an overlay view shares the original's
+// sections: changes made through either, and a new read of the original,
+// show through the other.
+func TestWithOverlayFollows(t *testing.T) {
+	cfg := selected(t, "[a]\nhost=file-host\n", "a")
+	view := cfg.WithOverlay(func(section, name string) (string, bool) { return "", false })
+	if view.AppendSection("b", nil, false) == nil {
+		t.Fatal("AppendSection on the view failed")
+	}
+	cfg.AppendSection("c", nil, false)
+	if err := view.SelectSection("a"); err != nil {
+		t.Fatalf("SelectSection: %v", err)
+	}
+	if err := view.SetValue("host", "view-host", 0); err != nil {
+		t.Fatalf("SetValue on the view: %v", err)
+	}
+	names := func(cfg *Configuration) string {
+		var ns []string
+		for s := cfg.GetFirst(); s != nil; s = s.GetNext() {
+			ns = append(ns, s.GetName())
+		}
+		return strings.Join(ns, ",")
+	}
+	for _, tt := range []struct {
+		name string
+		cfg  *Configuration
+	}{
+		{"view", view},
+		{"original", cfg},
+	} {
+		if got := names(tt.cfg); got != "a,b,c" {
+			t.Errorf("%s has sections %s, want a,b,c", tt.name, got)
+		}
+		if got := tt.cfg.GetValueBySection("a", "host"); got != "view-host" {
+			t.Errorf("%s has a.host=%q, want %q", tt.name, got, "view-host")
+		}
+	}
+	if err := cfg.SelectSection("a"); err != nil {
+		t.Fatalf("SelectSection: %v", err)
+	}
+	if err := cfg.SetValue("host", "new-host", 0); err != nil {
+		t.Fatalf("SetValue on the original: %v", err)
+	}
+	if got := view.GetValue("host"); got != "new-host" {
+		t.Errorf("after SetValue on the original, view host = %q, want %q", got, "new-host")
+	}
+	if err := cfg.ReadContext(context.Background(), strings.NewReader("[z]\n[a]\nhost=reloaded\n"), "t.cfg"); err != nil {
+		t.Fatalf("ReadContext: %v", err)
+	}
+	if got := view.GetValue("host"); got != "reloaded" {
+		t.Errorf("after reloading the original, view host = %q, want %q", got, "reloaded")
+	}
+	if got := names(view); got != "z,a" {
+		t.Errorf("after reloading the original, view has sections %s, want z,a", got)
+	}
+}
+
+// TestWithOverlayList checks that an overlay value is split into a list that
+// serves every index, as the file's value would.
+func TestWithOverlayList(t *testing.T) {
+	cfg := selected(t, "[s]\nports=1,2\n", "s")
+	view := cfg.WithOverlay(func(section, name string) (string, bool) {
+		if name == "ports" {
+			return "8080,8443", true
+		}
+		return "", false
+	})
+	if err := view.SelectSection("s"); err != nil {
+		t.Fatalf("SelectSection: %v", err)
+	}
+	for i, want := range []int{8080, 8443} {
+		var got int
+		if err := view.GetValueIntByIndex("ports", uint(i), &got); err != nil || got != want {
+			t.Errorf("GetValueIntByIndex(ports, %d) = %d, %v, want %d", i, got, err, want)
+		}
+	}
+	var n int
+	if err := view.GetValueIntByIndex("ports", 2, &n); err == nil {
+		t.Errorf("GetValueIntByIndex(ports, 2) = %d, want an error", n)
+	}
+	got, err := GetList[int](view, "", "ports")
+	if err != nil || fmt.Sprint(got) != "[8080 8443]" {
+		t.Errorf("GetList(ports) = %v, %v, want [8080 8443]", got, err)
+	}
+	got, err = GetList[int](cfg, "", "ports")
+	if err != nil || fmt.Sprint(got) != "[1 2]" {
+		t.Errorf("GetList(ports) on the original = %v, %v, want [1 2]", got, err)
+	}
+}
+
+func TestNewConfigurationNil(t *testing.T) {
+	tests := []string{"", "cfg", "ini"}
+	for _, ext := range tests {
+		cfg := NewConfiguration(ext)
+		if cfg == nil {
+			t.Fatalf("NewConfiguration(%q) = nil", ext)
+		}
+		if s := cfg.AppendSection("s", nil, false); s == nil {
+			t.Errorf("NewConfiguration(%q): AppendSection failed", ext)
+		}
+	}
+}
+
+func TestGetValueComplex(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    complex128
+		wantErr bool
+	}{
+		{"1+2i", 1 + 2i, false},
+		{"(3.5-1.5i)", 3.5 - 1.5i, false},
+		{" 4i ", 4i, false},
+		{"7", 7, false},
+		{"garbage", 0, true},
+		{"1+", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			cfg := selected(t, "[s]\nc="+tt.value+"\n", "s")
+			var c128 complex128
+			err := cfg.GetValueComplex128("c", &c128)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetValueComplex128: err = %v, want error %v", err, tt.wantErr)
+			}
+			if c128 != tt.want {
+				t.Errorf("GetValueComplex128 = %v, want %v", c128, tt.want)
+			}
+			var c64 complex64
+			err = cfg.GetValueComplex64("c", &c64)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetValueComplex64: err = %v, want error %v", err, tt.wantErr)
+			}
+			if c64 != complex64(tt.want) {
+				t.Errorf("GetValueComplex64 = %v, want %v", c64, tt.want)
+			}
+		})
+	}
+}
+
+// TestParameterGetValueComplex checks that a Parameter decodes its own first
+// value, and that a parse failure still wraps strconv's error.
+func TestParameterGetValueComplex(t *testing.T) {
+	cfg := selected(t, "[s]\nc=1+2i,3i\nbad=1+\n", "s")
+	s := cfg.GetSelectedSection()
+	var c128 complex128
+	if err := s.FindParameter("c", false).GetValueComplex128("c", &c128); err != nil || c128 != 1+2i {
+		t.Errorf("GetValueComplex128 = %v, %v, want %v", c128, err, 1+2i)
+	}
+	var c64 complex64
+	if err := s.FindParameter("c", false).GetValueComplex64("c", &c64); err != nil || c64 != 1+2i {
+		t.Errorf("GetValueComplex64 = %v, %v, want %v", c64, err, 1+2i)
+	}
+	if err := s.FindParameter("bad", false).GetValueComplex128("bad", &c128); !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("GetValueComplex128(bad) = %v, want strconv.ErrSyntax", err)
+	}
+}
+
+func TestValueWithEquals(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		param string
+		want  string
+	}{
+		{"url", "url=http://x/?a=b", "url", "http://x/?a=b"},
+		{"quoted", `query="a=b&c=d"`, "query", `"a=b&c=d"`},
+		{"spaces", "  key  =  a=b  ", "key", "a=b"},
+		{"quoted key", `"k=1"=v`, "k=1", "v"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, "[s]\n"+tt.line+"\n")
+			if got := cfg.FindSection("s").GetValue(tt.param, 0); got != tt.want {
+				t.Fatalf("value %q, want %q", got, tt.want)
+			}
+			var sb strings.Builder
+			if _, err := cfg.Print(&sb); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			back := parse(t, sb.String())
+			if got := back.FindSection("s").GetValue(tt.param, 0); got != tt.want {
+				t.Errorf("after a round trip through\n%s value %q, want %q", sb.String(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteToMatchesPrint(t *testing.T) {
+	var text strings.Builder
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&text, "# section %d\n[s%d]\n", i, i)
+		for j := 0; j < 20; j++ {
+			fmt.Fprintf(&text, "k%d=%d,\"v %d\",x\n", j, j, i*j)
+		}
+		fmt.Fprintf(&text, "flag%d\nempty=\n", i)
+	}
+	cfg := parse(t, text.String())
+	var printed strings.Builder
+	pn, err := cfg.Print(&printed)
+	if err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	var copied bytes.Buffer
+	// io.Copy takes a Reader; it uses the WriterTo first when there is one.
+	src := struct {
+		io.Reader
+		io.WriterTo
+	}{nil, cfg}
+	cn, err := io.Copy(&copied, src)
+	if err != nil {
+		t.Fatalf("io.Copy: %v", err)
+	}
+	if copied.String() != printed.String() {
+		t.Errorf("io.Copy wrote\n%.300s\nPrint wrote\n%.300s", copied.String(), printed.String())
+	}
+	if cn != pn || cn != int64(copied.Len()) {
+		t.Errorf("io.Copy counted %d bytes, Print %d, buffer holds %d", cn, pn, copied.Len())
+	}
+}
+
+// TestPrintFewerQuotes checks that a parameter that knows the quotes of only
+// some of its values prints the same through Print and WriteTo, the others
+// unquoted.
+func TestPrintFewerQuotes(t *testing.T) {
+	cfg := selected(t, "[s]\nk=1\n", "s")
+	p := cfg.FindSection("s").FindParameter("k", false)
+	p.values, p.quotes = []string{"a b", "c", "d"}, []byte{'"'}
+	const want = "[s]\nk=\"a b\",c,d\n"
+	var printed, streamed strings.Builder
+	if _, err := cfg.Print(&printed); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	if _, err := cfg.WriteTo(&streamed); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	for name, got := range map[string]string{"Print": printed.String(), "WriteTo": streamed.String()} {
+		if got != want {
+			t.Errorf("%s wrote %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRequireArity(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		min, max int
+		wantErr  string
+	}{
+		{"exact", "1,2,3", 3, 3, ""},
+		{"exact too few", "1,2", 3, 3, "expected exactly 3"},
+		{"exact too many", "1,2,3,4", 3, 3, "expected exactly 3"},
+		{"at least one", "1", 1, -1, ""},
+		{"unbounded", "1,2,3,4,5,6", 1, -1, ""},
+		{"none", "", 1, -1, "expected at least 1"},
+		{"range too few", "1", 2, 4, "expected at least 2"},
+		{"range too many", "1,2,3,4,5", 2, 4, "expected at most 4"},
+		{"range", "1,2,3", 2, 4, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, "[s]\nrgb="+tt.value+"\n")
+			for _, err := range []error{
+				cfg.FindSection("s").FindParameter("rgb", false).RequireArity(tt.min, tt.max),
+				cfg.RequireArity("s", "rgb", tt.min, tt.max),
+			} {
+				if tt.wantErr == "" {
+					if err != nil {
+						t.Errorf("unexpected error: %v", err)
+					}
+				} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+				}
+			}
+		})
+	}
+	cfg := parse(t, "[s]\nrgb=1\n")
+	if err := cfg.RequireArity("t", "rgb", 1, 1); err == nil {
+		t.Error("missing section: no error")
+	}
+	if err := cfg.RequireArity("s", "hsv", 1, 1); err == nil {
+		t.Error("missing parameter: no error")
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"lf", "[s]\na=1\nb=2\n"},
+		{"crlf", "[s]\r\na=1\r\nb=2\r\n"},
+		{"lone cr", "[s]\ra=1\rb=2\r"},
+		{"no final newline", "[s]\na=1\nb=2"},
+		{"crlf no final newline", "[s]\r\na=1\r\nb=2"},
+		{"lone cr no final newline", "[s]\ra=1\rb=2"},
+		{"mixed", "[s]\ra=1\r\nb=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parse(t, tt.text).FindSection("s")
+			if s == nil {
+				t.Fatal("section s not found")
+			}
+			for name, want := range map[string]string{"a": "1", "b": "2"} {
+				if got := s.GetValue(name, 0); got != want {
+					t.Errorf("%s=%q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetValueByteSlice(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []byte
+		wantErr string
+	}{
+		{"hex", "0xDE,0xAD,0xBE,0xEF", []byte{0xde, 0xad, 0xbe, 0xef}, ""},
+		{"decimal", "1, 2, 255", []byte{1, 2, 255}, ""},
+		{"mixed", "0x10,16,0o20", []byte{16, 16, 16}, ""},
+		{"overflow", "1,256,3", nil, "element 1 \"256\""},
+		{"not a number", "1,two", nil, "element 1 \"two\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nmagic="+tt.value+"\n", "s")
+			var got []byte
+			err := cfg.GetValueByteSlice("magic", &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetValueByteSlice: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got % x, want % x", got, tt.want)
+			}
+		})
+	}
+	cfg := selected(t, "[s]\nmagic=1\n", "s")
+	var got []byte
+	if err := cfg.GetValueByteSlice("nope", &got); !errors.Is(err, ErrParameterNotFound) {
+		t.Errorf("missing parameter: error %v, want ErrParameterNotFound", err)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	const text = "# head\n[a]\nx=1\ny=2,3\n[b]\nz=\"q\"\n# tail\n"
+	tests := []struct {
+		name string
+		edit func(cfg *Configuration) error
+	}{
+		{"set values", func(cfg *Configuration) error {
+			if err := cfg.SetValue("x", "10", 0); err != nil {
+				return err
+			}
+			return cfg.SetValueBySection("b", "z", 0, "r")
+		}},
+		{"add section", func(cfg *Configuration) error {
+			cfg.AppendSection("c", nil, false)
+			return cfg.SetValueBySection("a", "y", 1, "4")
+		}},
+		{"select another section", func(cfg *Configuration) error {
+			return cfg.SelectSection("b")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, text, "a")
+			var before strings.Builder
+			cfg.Print(&before)
+			snap := cfg.Snapshot()
+			if err := tt.edit(cfg); err != nil {
+				t.Fatalf("edit: %v", err)
+			}
+			// Restore twice: the snapshot must survive being restored.
+			for i := 0; i < 2; i++ {
+				cfg.Restore(snap)
+				var after strings.Builder
+				cfg.Print(&after)
+				if after.String() != before.String() {
+					t.Fatalf("restore %d gave\n%s\nwant\n%s", i, after.String(), before.String())
+				}
+				if got := cfg.GetValue("x"); got != "1" {
+					t.Errorf("restore %d: x=%q in the selected section, want \"1\"", i, got)
+				}
+				cfg.SetValue("x", "99", 0)
+			}
+		})
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.cfg", "# nothing but a comment\n")
+	b := writeFile(t, dir, "b.cfg", "[b]\nk=1\nread \""+dir+"/./a.cfg\"\n")
+	secret := writeFile(t, dir, "db_pass", "s3cret\n")
+	root := writeFile(t, dir, "root.cfg", "read \""+a+"\"\nread \""+b+"\"\n[r]\nv=2\npassword=@"+secret+"\n")
+	other := writeFile(t, dir, "other.cfg", "[o]\nv=3\n")
+
+	// The @path secret file is read, but is not a dependency.
+	cfg := NewConfiguration("cfg")
+	cfg.SetFileValueRefs(true)
+	if err := cfg.ReadFile(root, "", false); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if got, want := cfg.Dependencies(), []string{a, b}; strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Dependencies() = %q, want %q", got, want)
+	}
+	if err := cfg.ReadFile(other, "", false); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if got := cfg.Dependencies(); len(got) != 0 {
+		t.Errorf("after reading a file with no statements, Dependencies() = %q", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	const text = "[p]\ninherited=from parent\n[s:p]\nempty=\nquoted=\"\"\nflag\nvalue=v\n"
+	tests := []struct {
+		name      string
+		want      string
+		wantFound bool
+	}{
+		{"empty", "", true},
+		{"quoted", `""`, true},
+		{"flag", "", true},
+		{"value", "v", true},
+		{"inherited", "from parent", true},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, text, "s")
+			for _, look := range []func(string) (string, bool){cfg.Lookup, cfg.FindSection("s").Lookup} {
+				got, found := look(tt.name)
+				if got != tt.want || found != tt.wantFound {
+					t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.name, got, found, tt.want, tt.wantFound)
+				}
+			}
+		})
+	}
+	if _, found := parse(t, "[s]\nv=1\n").Lookup("v"); found {
+		t.Error("Lookup with no section selected found a value")
+	}
+}
+
+func TestRenameSection(t *testing.T) {
+	const text = "# about b\n[b]\nx=1\n[d:b]\ny=2\n[e]\nz=3\n"
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+		wantErr  string
+	}{
+		{"rename", "b", "c", "# about b\n[c]\nx=1\n[d:c]\ny=2\n[e]\nz=3\n", ""},
+		{"change case", "b", "B", "# about b\n[B]\nx=1\n[d:B]\ny=2\n[e]\nz=3\n", ""},
+		{"collision", "b", "e", "", "already exists"},
+		{"collision ignoring case", "b", "E", "", "already exists"},
+		{"missing", "q", "r", "", "not found"},
+		{"empty name", "b", " ", "", "cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, text)
+			err := cfg.RenameSection(tt.old, tt.new)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenameSection: %v", err)
+			}
+			var sb strings.Builder
+			cfg.Print(&sb)
+			if sb.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", sb.String(), tt.want)
+			}
+			if err := cfg.SelectSection("d"); err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.GetValue("x"); got != "1" {
+				t.Errorf("[d] no longer inherits x from the renamed section: x=%q", got)
+			}
+		})
+	}
+}
+
+func TestRenameParameter(t *testing.T) {
+	const text = "[s]\n# about a\na=1,2\nb=3\n"
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+		wantErr  string
+	}{
+		{"rename", "a", "c", "[s]\n# about a\nc=1,2\nb=3\n", ""},
+		{"change case", "a", "A", "[s]\n# about a\nA=1,2\nb=3\n", ""},
+		{"collision", "a", "b", "", "already exists"},
+		{"collision ignoring case", "a", "B", "", "already exists"},
+		{"missing", "q", "r", "", "not found"},
+		{"empty name", "a", "", "", "cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, text)
+			err := cfg.FindSection("s").RenameParameter(tt.old, tt.new)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RenameParameter: %v", err)
+			}
+			var sb strings.Builder
+			cfg.Print(&sb)
+			if sb.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", sb.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNextParameterValuesNil(t *testing.T) {
+	const text = "[s]\na=1,\"2\"\nb=x\nc=\n"
+	wantNames := []string{"a", "b", "c"}
+	wantValues := [][]string{{"1", `"2"`}, {"x"}, nil}
+
+	cfg := selected(t, text, "s")
+	for i, want := range wantNames {
+		name, n, values, quotes, err := cfg.GetNextParameterValues(nil, nil)
+		if err != nil {
+			t.Fatalf("GetNextParameterValues %d: %v", i, err)
+		}
+		if len(name) != 1 || name[0] != want || len(values) != 1 || len(quotes) != 1 {
+			t.Fatalf("GetNextParameterValues %d: name %q, values %q, quotes %q", i, name, values, quotes)
+		}
+		if n != len(wantValues[i]) || strings.Join(values[0], ",") != strings.Join(wantValues[i], ",") {
+			t.Errorf("%s: %d values %q, want %q", want, n, values[0], wantValues[i])
+		}
+	}
+	if _, _, _, _, err := cfg.GetNextParameterValues(nil, nil); err == nil {
+		t.Error("no error after the last parameter")
+	}
+
+	cfg = selected(t, text, "s")
+	for i, want := range wantNames {
+		name, values, err := cfg.GetNextParameterValues2(nil)
+		if err != nil {
+			t.Fatalf("GetNextParameterValues2 %d: %v", i, err)
+		}
+		first := ""
+		if len(wantValues[i]) > 0 {
+			first = wantValues[i][0]
+		}
+		if len(name) != 1 || name[0] != want || len(values) != 1 || values[0] != first {
+			t.Errorf("GetNextParameterValues2 %d: name %q, values %q, want %s=%q", i, name, values, want, first)
+		}
+	}
+	if _, _, err := cfg.GetNextParameterValues2(nil); err == nil {
+		t.Error("no error after the last parameter")
+	}
+
+	if _, _, _, _, err := parse(t, text).GetNextParameterValues(nil, nil); err == nil {
+		t.Error("no error with no section selected")
+	}
+}
+
+func TestPeekParameter(t *testing.T) {
+	cfg := selected(t, "[s]\na=1\nb=2\nc=3\n", "s")
+	for _, want := range []string{"a", "b", "c"} {
+		for i := 0; i < 2; i++ {
+			if p := cfg.PeekParameter(); p == nil || p.GetName() != want {
+				t.Fatalf("PeekParameter() = %v, want %s", p, want)
+			}
+			if p := cfg.FindSection("s").PeekSelectedParameter(); p == nil || p.GetName() != want {
+				t.Fatalf("PeekSelectedParameter() = %v, want %s", p, want)
+			}
+		}
+		if p := cfg.GetNextParameter(); p == nil || p.GetName() != want {
+			t.Fatalf("GetNextParameter() = %v, want %s", p, want)
+		}
+	}
+	if p := cfg.PeekParameter(); p != nil {
+		t.Errorf("PeekParameter() after the last one = %s, want nil", p.GetName())
+	}
+	if p := parse(t, "[s]\na=1\n").PeekParameter(); p != nil {
+		t.Errorf("PeekParameter() with no section selected = %s, want nil", p.GetName())
+	}
+}
+
+func TestSetValueInFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		val     any
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{"int as %d", 42, "%d", "42", false},
+		{"int padded", 7, "%03d", "007", false},
+		{"hex", 255, "%#x", "0xff", false},
+		{"float precision", 3.14159, "%.2f", "3.14", false},
+		{"string as %s", "abc", "%s", "abc", false},
+		{"text around the verb", 8080, "port %d", "port 8080", false},
+		{"empty format", 1.5, "", "1.5", false},
+		{"string as %d", "abc", "%d", "", true},
+		{"int as %s", 42, "%s", "", true},
+		{"bool as %f", true, "%f", "", true},
+		{"missing argument", 1, "%d %d", "", true},
+		{"extra argument", 1, "no verb", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nv=old\n", "s")
+			err := cfg.SetValueInFormat("v", tt.val, tt.format)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("no error, value %q stored", cfg.GetValue("v"))
+				}
+				if got := cfg.GetValue("v"); got != "old" {
+					t.Errorf("failed set changed the value to %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetValueInFormat: %v", err)
+			}
+			if got := cfg.GetValue("v"); got != tt.want {
+				t.Errorf("value %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	cfg := selected(t, "[s]\nv=1\ncolor=#010203\n", "s")
+	view := cfg.ReadOnly()
+	if !view.IsReadOnly() || cfg.IsReadOnly() {
+		t.Fatalf("IsReadOnly: view %v, original %v", view.IsReadOnly(), cfg.IsReadOnly())
+	}
+	sets := []struct {
+		name string
+		set  func(c *Configuration) error
+	}{
+		{"SetValue", func(c *Configuration) error { return c.SetValue("v", "2", 0) }},
+		{"SetValueBySection", func(c *Configuration) error { return c.SetValueBySection("s", "v", 0, "2") }},
+		{"SetValues", func(c *Configuration) error { return c.SetValues("s", map[string]string{"v": "2"}) }},
+		{"SetValueInFormat", func(c *Configuration) error { return c.SetValueInFormat("v", 2, "%d") }},
+		{"SetArrayValue", func(c *Configuration) error { return c.SetArrayValue("v", "2", 0, 0) }},
+		{"SetArrayValueBySection", func(c *Configuration) error { return c.SetArrayValueBySection("s", "v", 0, "2") }},
+		{"SetArrayValueInFormat", func(c *Configuration) error { return c.SetArrayValueInFormat("v", 0, 2, "%d") }},
+		{"SetValueUint8", func(c *Configuration) error { return c.SetValueUint8("v", 2) }},
+		{"SetValueFileMode", func(c *Configuration) error { return c.SetValueFileMode("v", 0o600) }},
+		{"ClearParameters", func(c *Configuration) error { return c.ClearParameters("s") }},
+		{"RenameSection", func(c *Configuration) error { return c.RenameSection("s", "t") }},
+		{"Reconfigure", func(c *Configuration) error { return c.Reconfigure() }},
+		{"ReadFile", func(c *Configuration) error { return c.ReadFile("t.cfg", "", false) }},
+	}
+	for _, tt := range sets {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.set(view); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("error %v, want ErrReadOnly", err)
+			}
+			if got := view.GetValue("v"); got != "1" {
+				t.Errorf("view's v=%q after a refused set, want \"1\"", got)
+			}
+		})
+	}
+	if s := view.AppendSection("new", nil, false); s != nil {
+		t.Error("AppendSection on the view returned a section")
+	}
+
+	// The Sections and Parameters handed out by the view refuse changes too.
+	s := view.FindSection("s")
+	p := s.FindParameter("v", false)
+	refused := []struct {
+		name string
+		set  func() error
+	}{
+		{"Section.SetValue", func() error { return s.SetValue("v", "3", 0) }},
+		{"Section.SetValuePtr", func() error { return s.SetValuePtr("v", "3", 0) }},
+		{"Section.SetValuePtrOnIndex", func() error { return s.SetValuePtrOnIndex("v", "3", 0, 0) }},
+		{"Section.SetValueInFormat", func() error { return s.SetValueInFormat("v", 0, "%d", 3) }},
+		{"Section.RenameParameter", func() error { return s.RenameParameter("v", "w") }},
+		{"Section.ClearParameters", func() error { return s.ClearParameters() }},
+		{"Section.AppendLiteralParameter", func() error { _, err := s.AppendLiteralParameter("w", "3", 0); return err }},
+		{"Parameter.SetValue", func() error { return p.SetValue("3", 0) }},
+		{"Parameter.SetValuePtr", func() error { return p.SetValuePtr("3", 0) }},
+		{"Parameter.SetValuePtrOnIndex", func() error { return p.SetValuePtrOnIndex(0, "3", 0) }},
+	}
+	for _, tt := range refused {
+		if err := tt.set(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s: error %v, want ErrReadOnly", tt.name, err)
+		}
+	}
+	if s.AppendParameter("w", "3", nil, false) != nil || s.Append2(p) != nil {
+		t.Error("appending a parameter to the view's section returned one")
+	}
+	if got := view.GetValue("v"); got != "1" {
+		t.Errorf("view v=%q after refused sets, want \"1\"", got)
+	}
+	if n := s.GetNParameters(); n != 2 {
+		t.Errorf("view's section has %d parameters, want 2", n)
+	}
+
+	// Changing the original doesn't reach the view, and copies taken from
+	// the view may be changed.
+	if err := cfg.SetValue("v", "4", 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.GetValue("v"); got != "4" {
+		t.Errorf("original v=%q, want \"4\"", got)
+	}
+	if got := view.GetValue("v"); got != "1" {
+		t.Errorf("view v=%q, want \"1\"", got)
+	}
+	if err := CopyParameter(p).SetValue("5", 0); err != nil {
+		t.Errorf("CopyParameter of a view's parameter: %v", err)
+	}
+	if c := s.Clone("c"); c.GetNParameters() != 2 || c.GetValue("v", 0) != "1" {
+		t.Errorf("Clone of a view's section has %d parameters, v=%q", c.GetNParameters(), c.GetValue("v", 0))
+	}
+	if o := view.WithOverlay(nil); o.FindSection("s").SetValue("v", "6", 0) != ErrReadOnly || o.GetValue("v") != "1" {
+		t.Error("WithOverlay of a read-only view can be changed")
+	}
+	if got := view.GetValue("color"); got != "#010203" {
+		t.Errorf("view color=%q, want \"#010203\"", got)
+	}
+}
+
+func TestInheritedByIndex(t *testing.T) {
+	// [child] has a v of its own, shadowing its parent's three values, and
+	// inherits w with its three values.
+	const text = "[parent]\nv=10,20,30\nw=1,2,3\n[child:parent]\nv=5\n"
+	tests := []struct {
+		name    string
+		param   string
+		i       uint
+		want    int
+		wantErr bool
+	}{
+		{"own value", "v", 0, 5, false},
+		{"shadowed parent's second value", "v", 1, 0, true},
+		{"inherited first", "w", 0, 1, false},
+		{"inherited last", "w", 2, 3, false},
+		{"inherited out of range", "w", 3, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := parse(t, text).FindSection("child")
+			n := s.GetNValues(tt.param)
+			if tt.wantErr != (tt.i >= n) {
+				t.Errorf("GetNValues(%q) = %d, so index %d is in range %v", tt.param, n, tt.i, !tt.wantErr)
+			}
+			var got int
+			err := s.GetValueIntByIndex(tt.param, tt.i, &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("GetValueIntByIndex: got %d, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetValueIntByIndex: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetValueIntByIndex = %d, want %d", got, tt.want)
+			}
+			if v := s.GetValue(tt.param, tt.i); v != strconv.Itoa(tt.want) {
+				t.Errorf("GetValue = %q, want %d", v, tt.want)
+			}
+		})
+	}
+}
+
+func TestFloatSpecials(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		allow   bool
+		check   func(f float64) bool
+		wantErr error // nil for success.
+	}{
+		{"plain", "3.14", false, func(f float64) bool { return math.Abs(f-3.14) < 1e-6 }, nil},
+		{"plain allowed", "3.14", true, func(f float64) bool { return math.Abs(f-3.14) < 1e-6 }, nil},
+		{"inf refused", "inf", false, nil, ErrFloatSpecial},
+		{"inf allowed", "inf", true, func(f float64) bool { return math.IsInf(f, 1) }, nil},
+		{"-inf allowed", "-Inf", true, func(f float64) bool { return math.IsInf(f, -1) }, nil},
+		{"nan refused", "nan", false, nil, ErrFloatSpecial},
+		{"nan allowed", "NaN", true, func(f float64) bool { return math.IsNaN(f) }, nil},
+		{"overflow", "1e999", true, nil, strconv.ErrRange},
+		{"garbage", "pi", true, nil, strconv.ErrSyntax},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nf=1,"+tt.value+"\n", "s")
+			cfg.SetFloatSpecials(tt.allow)
+			if cfg.GetFloatSpecials() != tt.allow {
+				t.Fatalf("GetFloatSpecials() = %v", cfg.GetFloatSpecials())
+			}
+			var f64 float64
+			var f32 float32
+			errs := []error{
+				cfg.GetValueFloat64ByIndex("f", 1, &f64),
+				cfg.GetValueFloat32ByIndex("f", 1, &f32),
+			}
+			for i, err := range errs {
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Errorf("getter %d: error %v, want %v", i, err, tt.wantErr)
+					}
+					continue
+				}
+				if err != nil {
+					t.Errorf("getter %d: %v", i, err)
+				}
+			}
+			if tt.check != nil && (!tt.check(f64) || !tt.check(float64(f32))) {
+				t.Errorf("got float64 %v, float32 %v", f64, f32)
+			}
+		})
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		section  string
+		kv       map[string]string
+		want     string
+		wantErrs []string
+	}{
+		{"five at once", "s", map[string]string{"a": "1", "e": "5", "c": "3", "d": "x,y", "b": "2"},
+			"[s]\na=1\nold=0\nb=2\nc=3\nd=x,y\ne=5\n[t]\nz=9\n", nil},
+		{"selected section", "", map[string]string{"a": "7", "new": "8"},
+			"[s]\na=7\nold=0\nnew=8\n[t]\nz=9\n", nil},
+		{"new section", "u", map[string]string{"k": "v"},
+			"[s]\na=0\nold=0\n[t]\nz=9\n[u]\nk=v\n", nil},
+		{"bad keys", "t", map[string]string{"good": "1", "bad=key": "2", " ": "3", "also": "4"},
+			"[s]\na=0\nold=0\n[t]\nz=9\nalso=4\ngood=1\n", []string{`"bad=key"`, `" "`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\na=0\nold=0\n[t]\nz=9\n", "s")
+			err := cfg.SetValues(tt.section, tt.kv)
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Fatalf("SetValues: %v", err)
+			}
+			for _, want := range tt.wantErrs {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("error %v, want one naming %s", err, want)
+				}
+			}
+			var sb strings.Builder
+			cfg.Print(&sb)
+			if sb.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", sb.String(), tt.want)
+			}
+			if got := cfg.GetSelectedSectionName(); got != "s" {
+				t.Errorf("SetValues selected section %q", got)
+			}
+		})
+	}
+}
+
+func TestSectionListings(t *testing.T) {
+	const text = "[base]\na=1\n[extra]\nb=2\n[child:base,extra]\nc=3\n[grandchild:child]\nd=4\n"
+	cfg := parse(t, text)
+	if got, want := cfg.GetSectionNames(), []string{"base", "extra", "child", "grandchild"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("GetSectionNames() = %q, want %q", got, want)
+	}
+	if got := NewConfiguration("cfg").GetSectionNames(); len(got) != 0 {
+		t.Errorf("GetSectionNames() of an empty configuration = %q", got)
+	}
+	tests := []struct {
+		section string
+		want    []string
+	}{
+		{"base", nil},
+		{"child", []string{"base", "extra"}},
+		{"grandchild", []string{"child"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section, func(t *testing.T) {
+			s := cfg.FindSection(tt.section)
+			got := s.ParentNames()
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("ParentNames() = %q, want %q", got, tt.want)
+			}
+			if len(got) > 0 {
+				got[0] = "changed"
+				if s.ParentNames()[0] == "changed" {
+					t.Error("ParentNames() returned the section's own slice")
+				}
+			}
+		})
+	}
+}
+
+func TestReadEditWrite(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		prepare func(cfg *Configuration, path string) error
+		wantErr bool
+	}{
+		{"after ReadFile", func(cfg *Configuration, path string) error {
+			return cfg.ReadFile(path, "", false)
+		}, false},
+		{"after importing one section", func(cfg *Configuration, path string) error {
+			return cfg.ReadFile(path, "s", true)
+		}, true},
+		{"MarkWritable after importing", func(cfg *Configuration, path string) error {
+			if err := cfg.ReadFile(path, "s", true); err != nil {
+				return err
+			}
+			cfg.MarkWritable()
+			return nil
+		}, false},
+		{"built by hand", func(cfg *Configuration, path string) error {
+			return cfg.SetValues("s", map[string]string{"v": "1"})
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".cfg")
+			if err := os.WriteFile(path, []byte("# keep me\n[s]\nv=1\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg := NewConfiguration("cfg")
+			if err := tt.prepare(cfg, path); err != nil {
+				t.Fatalf("prepare: %v", err)
+			}
+			if err := cfg.SelectSection("s"); err != nil {
+				t.Fatal(err)
+			}
+			if err := cfg.SetValue("v", "2", 0); err != nil {
+				t.Fatalf("SetValue: %v", err)
+			}
+			err := cfg.WriteFile(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("WriteFile succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			back := NewConfiguration("cfg")
+			if err := back.ReadFile(path, "", false); err != nil {
+				t.Fatal(err)
+			}
+			if got := back.FindSection("s").GetValue("v", 0); got != "2" {
+				t.Errorf("v=%q after writing, want \"2\"", got)
+			}
+		})
+	}
+}
+
+func TestNameValidator(t *testing.T) {
+	errSpace := errors.New("name has a space")
+	noSpaces := func(name string) error {
+		if strings.ContainsAny(name, " \t") {
+			return errSpace
+		}
+		return nil
+	}
+	tests := []struct {
+		name     string
+		text     string
+		validate func(string) error
+		wantLine int // 0 if the read must succeed.
+	}{
+		{"no validator", "[s]\nweird name=1\n", nil, 0},
+		{"good names", "[s]\na=1\nb_c=2\n", noSpaces, 0},
+		{"space", "[s]\na=1\nweird name=1\n", noSpaces, 3},
+		{"quoted space", "# c\n[s]\n\"weird name\"=1\n", noSpaces, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetNameValidator(tt.validate)
+			err := cfg.ReadContext(context.Background(), strings.NewReader(tt.text), "t.cfg")
+			if tt.wantLine == 0 {
+				if err != nil {
+					t.Fatalf("ReadContext: %v", err)
+				}
+				return
+			}
+			var ce *ConfigError
+			if !errors.As(err, &ce) || !errors.Is(err, errSpace) {
+				t.Fatalf("error %v, want a ConfigError wrapping the validator's", err)
+			}
+			if ce.Line != tt.wantLine || ce.File != "t.cfg" {
+				t.Errorf("error at %s:%d, want t.cfg:%d", ce.File, ce.Line, tt.wantLine)
+			}
+		})
+	}
+
+	cfg := selected(t, "[s]\na=1\n", "s")
+	cfg.SetNameValidator(noSpaces)
+	if err := cfg.SetValues("s", map[string]string{"weird name": "1", "ok": "2"}); !errors.Is(err, errSpace) {
+		t.Errorf("SetValues: error %v, want the validator's", err)
+	}
+	if err := cfg.SetValueInFormat("weird name", 1, "%d"); !errors.Is(err, errSpace) {
+		t.Errorf("SetValueInFormat: error %v, want the validator's", err)
+	}
+	if _, found := cfg.Lookup("weird name"); found {
+		t.Error("a rejected name was added")
+	}
+	if got := cfg.GetValue("ok"); got != "2" {
+		t.Errorf("ok=%q, want \"2\"", got)
+	}
+}
+
+func TestEffectiveParameters(t *testing.T) {
+	const text = "[base]\nshared=base\nfrom_base=1\n[mixin]\nshared=mixin\nFROM_BASE=mixin\nfrom_mixin=2\n" +
+		"[child:base,mixin]\nShared=child\nown=3\n[loop1:loop2]\na=1\n[loop2:loop1]\nb=2\n"
+	tests := []struct {
+		section string
+		want    []string // name=value, in order.
+	}{
+		{"base", []string{"shared=base", "from_base=1"}},
+		{"child", []string{"Shared=child", "own=3", "from_base=1", "from_mixin=2"}},
+		{"loop1", []string{"a=1", "b=2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section, func(t *testing.T) {
+			var got []string
+			for _, p := range parse(t, text).FindSection(tt.section).EffectiveParameters() {
+				got = append(got, p.GetName()+"="+p.GetValue(0))
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommentText(t *testing.T) {
+	const text = "## Server settings\n#\n# Where we listen.\n[server]\n" +
+		"# The port to listen on.\n#   Must be above 1024.\nport=8080\n" +
+		"host=localhost\n\n#### Banner\n\n# shown on login\nbanner=hi\n" +
+		"[child:server]\nown=1\n"
+	cfg := parse(t, text)
+	s := cfg.FindSection("server")
+	if got, want := s.CommentText(), "Server settings\nWhere we listen."; got != want {
+		t.Errorf("section CommentText() = %q, want %q", got, want)
+	}
+	tests := []struct {
+		section, param string
+		want           string
+	}{
+		{"server", "port", "The port to listen on.\nMust be above 1024."},
+		{"server", "host", ""},
+		{"server", "banner", "Banner\nshown on login"},
+		{"child", "port", "The port to listen on.\nMust be above 1024."},
+		{"child", "own", ""},
+		{"child", "missing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section+"/"+tt.param, func(t *testing.T) {
+			sect := cfg.FindSection(tt.section)
+			if got := sect.ParameterCommentText(tt.param); got != tt.want {
+				t.Errorf("ParameterCommentText() = %q, want %q", got, tt.want)
+			}
+			if p := sect.FindParameter(tt.param, false); p != nil {
+				if got := p.CommentText(); got != tt.want {
+					t.Errorf("CommentText() = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+
+	cfg = NewConfiguration("cfg")
+	cfg.SetCommentPrefixes(";", "//")
+	if err := cfg.ReadContext(context.Background(), strings.NewReader(";; Section\n[s]\n// Param.\np=1\n"), "t.cfg"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.FindSection("s").CommentText(); got != "Section" {
+		t.Errorf("with ; prefixes, section CommentText() = %q", got)
+	}
+	if got := cfg.FindSection("s").ParameterCommentText("p"); got != "Param." {
+		t.Errorf("with // prefixes, ParameterCommentText() = %q", got)
+	}
+}
+
+func TestDuplicateSectionPolicy(t *testing.T) {
+	const text = "[server]\nport=80\n[other]\nx=1\n# second\n[server]\nhost=h\nport=81\n"
+	tests := []struct {
+		name     string
+		policy   DuplicatePolicy
+		want     string // What Print writes; "" if the read must fail.
+		wantPort string
+		wantHost string
+	}{
+		{"separate", DuplicateSeparate,
+			"[server]\nport=80\n[other]\nx=1\n# second\n[server]\nhost=h\nport=81\n", "80", ""},
+		{"merge into", DuplicateMergeInto,
+			"# second\n[server]\nport=80\nhost=h\nport=81\n[other]\nx=1\n", "80", "h"},
+		{"error", DuplicateError, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetDuplicateSectionPolicy(tt.policy)
+			err := cfg.ReadContext(context.Background(), strings.NewReader(text), "t.cfg")
+			if tt.want == "" {
+				var ce *ConfigError
+				if !errors.As(err, &ce) || ce.Line != 6 || ce.Section != "server" {
+					t.Fatalf("error %v, want one for [server] at line 6", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadContext: %v", err)
+			}
+			var sb strings.Builder
+			cfg.Print(&sb)
+			if sb.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", sb.String(), tt.want)
+			}
+			s := cfg.FindSection("server")
+			if got := s.GetValue("port", 0); got != tt.wantPort {
+				t.Errorf("port=%q, want %q", got, tt.wantPort)
+			}
+			if got := s.GetValue("host", 0); got != tt.wantHost {
+				t.Errorf("host=%q, want %q", got, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestGetList(t *testing.T) {
+	const text = "[s]\nports=80, 443,8080\ncodes=010,0x1F\ntimes=1s,250ms,2h\nflags=TRUE,false\nratios=0.5,1e3\n" +
+		"names=a, \"b c\"\nbad=1,two,3\nbig=1,300\n[child:s]\nown=1\n"
+	cfg := selected(t, text, "s")
+	check := func(t *testing.T, got any, err error, want string) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("GetList: %v", err)
+		}
+		if s := fmt.Sprint(got); s != want {
+			t.Errorf("got %s, want %s", s, want)
+		}
+	}
+	t.Run("int", func(t *testing.T) {
+		got, err := GetList[int](cfg, "", "ports")
+		check(t, got, err, "[80 443 8080]")
+	})
+	t.Run("uint16", func(t *testing.T) {
+		got, err := GetList[uint16](cfg, "s", "ports")
+		check(t, got, err, "[80 443 8080]")
+	})
+	t.Run("duration", func(t *testing.T) {
+		got, err := GetList[time.Duration](cfg, "s", "times")
+		check(t, got, err, "[1s 250ms 2h0m0s]")
+	})
+	t.Run("bool", func(t *testing.T) {
+		got, err := GetList[bool](cfg, "s", "flags")
+		check(t, got, err, "[true false]")
+	})
+	t.Run("float64", func(t *testing.T) {
+		got, err := GetList[float64](cfg, "s", "ratios")
+		check(t, got, err, "[0.5 1000]")
+	})
+	t.Run("string", func(t *testing.T) {
+		got, err := GetList[string](cfg, "s", "names")
+		check(t, got, err, `[a "b c"]`)
+	})
+	t.Run("inherited", func(t *testing.T) {
+		got, err := GetList[int](cfg, "child", "ports")
+		check(t, got, err, "[80 443 8080]")
+	})
+	t.Run("prefixes", func(t *testing.T) {
+		cfg := selected(t, text, "s")
+		cfg.SetIntBase(0)
+		got, err := GetList[int](cfg, "s", "codes")
+		check(t, got, err, "[8 31]")
+	})
+
+	errTests := []struct {
+		name    string
+		get     func() error
+		wantErr string
+	}{
+		{"malformed element", func() error { _, err := GetList[int](cfg, "s", "bad"); return err }, `element 1 "two"`},
+		{"prefix in base 10", func() error { _, err := GetList[int](cfg, "s", "codes"); return err }, `element 1 "0x1F"`},
+		{"out of range", func() error { _, err := GetList[uint8](cfg, "s", "big"); return err }, `element 1 "300"`},
+		{"not a duration", func() error { _, err := GetList[time.Duration](cfg, "s", "ports"); return err }, `element 0 "80"`},
+		{"unsupported type", func() error { _, err := GetList[[]byte](cfg, "s", "ports"); return err }, "unsupported"},
+		{"missing parameter", func() error { _, err := GetList[int](cfg, "s", "nope"); return err }, ErrParameterNotFound.Error()},
+		{"missing section", func() error { _, err := GetList[int](cfg, "nope", "ports"); return err }, "not found"},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.get(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFindConfigFile(t *testing.T) {
+	const (
+		app      = "findconfigfile-test"
+		xdgFile  = "xdg/" + app + "/" + app + ".cfg"
+		homeFile = "home/.config/" + app + "/" + app + ".cfg"
+	)
+	tests := []struct {
+		name     string
+		place    []string // Files to create, relative to the temp dir.
+		dirs     []string // Directories to create with a candidate's name.
+		explicit string
+		noXDG    bool
+		want     string // Relative to the temp dir, "" if none is found.
+	}{
+		{"explicit", []string{"my.cfg", xdgFile}, nil, "my.cfg", false, "my.cfg"},
+		{"explicit missing", []string{xdgFile}, nil, "my.cfg", false, xdgFile},
+		{"xdg before home", []string{xdgFile, homeFile}, nil, "", false, xdgFile},
+		{"home", []string{homeFile}, nil, "", false, homeFile},
+		{"home without xdg", []string{xdgFile, homeFile}, nil, "", true, homeFile},
+		{"directory skipped", []string{homeFile}, []string{xdgFile}, "", false, homeFile},
+		{"none", nil, nil, "my.cfg", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range tt.place {
+				path := filepath.Join(dir, f)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, d := range tt.dirs {
+				if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			t.Setenv("HOME", filepath.Join(dir, "home"))
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+			if tt.noXDG {
+				t.Setenv("XDG_CONFIG_HOME", "")
+			}
+			explicit := tt.explicit
+			if explicit != "" {
+				explicit = filepath.Join(dir, explicit)
+			}
+			got, err := FindConfigFile(app, explicit)
+			if tt.want == "" {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Fatalf("error %v, want os.ErrNotExist", err)
+				}
+				for _, loc := range []string{explicit, filepath.Join(dir, "xdg"), filepath.Join(dir, "home", ".config"), "/etc/" + app} {
+					if !strings.Contains(err.Error(), loc) {
+						t.Errorf("error %q does not list %s", err, loc)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindConfigFile: %v", err)
+			}
+			if want := filepath.Join(dir, tt.want); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestAppendLiteralParameter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		quote byte
+		want  string
+	}{
+		{"double quotes", "a b", '"', "x=\"a b\"\n"},
+		{"single quote", "say \"hi\"", '\'', "x='say \"hi\"'\n"},
+		{"holds the quote", "say \"hi\"", '"', "x='say \"hi\"'\n"},
+		{"holds both quotes", "it's \"hi\"", '"', "x=it's \"hi\"\n"},
+		{"blanks kept", " a ", 0, "x=\" a \"\n"},
+		{"plain", "abc", 0, "x=abc\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, "[s]\n")
+			p, err := cfg.FindSection("s").AppendLiteralParameter("x", tt.value, tt.quote)
+			if err != nil {
+				t.Fatalf("AppendLiteralParameter: %v", err)
+			}
+			if n := p.GetNValues(); n != 1 {
+				t.Fatalf("stored %d values %q, want 1", n, p.GetValueArray())
+			}
+			if got := p.GetValue(0); got != tt.value {
+				t.Errorf("value %q, want %q", got, tt.value)
+			}
+			var buf bytes.Buffer
+			if _, err := cfg.Print(&buf); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.want) {
+				t.Errorf("Print wrote %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestAppendLiteralParameterRereads writes literal values to a file and reads
+// them back, and checks that those that could not come back whole are refused.
+func TestAppendLiteralParameterRereads(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		quote   byte
+		wantErr error
+	}{
+		{"holds the quote", "say \"hi\"", '"', nil},
+		{"holds both quotes", "it's \"hi\"", '\'', nil},
+		{"blanks", "  a  ", 0, nil},
+		{"comma", "a,b", '"', ErrUnwritableValue},
+		{"comma in quotes", "x,\"y\"", '"', ErrUnwritableValue},
+		{"blanks and both quotes", " it's \"hi\" ", '"', ErrUnwritableValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, "[s]\ny=1\n")
+			_, err := cfg.FindSection("s").AppendLiteralParameter("z", tt.value, tt.quote)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AppendLiteralParameter = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if cfg.FindSection("s").FindParameter("z", false) != nil {
+					t.Error("a refused value was appended")
+				}
+				return
+			}
+			path := filepath.Join(t.TempDir(), "t.cfg")
+			cfg.MarkWritable()
+			if err := cfg.WriteFile(path); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			back := NewConfiguration("cfg")
+			if err := back.ReadFile(path, "", false); err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if !back.Equal(cfg) {
+				t.Errorf("read back %q, wrote %q", back.String(), cfg.String())
+			}
+		})
+	}
+}
+
+func TestConfigErrorContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		setup   func(cfg *Configuration)
+		op      func(cfg *Configuration) error
+		want    ConfigError
+		wantErr error
+	}{
+		{
+			"line too long", "[s]\na=1\nlong=123456789\n",
+			func(cfg *Configuration) { cfg.SetMaxLineLength(8) }, nil,
+			ConfigError{File: "t.cfg", Line: 3, Op: "read"}, ErrLineTooLong,
+		},
+		{
+			"trailing comma", "[s]\na=1\nb=1,2,\n",
+			func(cfg *Configuration) { cfg.SetTrailingCommaPolicy(TrailingCommaError) }, nil,
+			ConfigError{File: "t.cfg", Line: 3, Section: "s", Param: "b", Op: "read"}, ErrTrailingComma,
+		},
+		{
+			"get", "[s]\na=1\nb=maybe\n", nil,
+			func(cfg *Configuration) error {
+				_, err := cfg.GetValueBool("b", 0, "true", "false")
+				return err
+			},
+			ConfigError{File: "t.cfg", Line: 3, Section: "s", Param: "b", Op: "get"}, nil,
+		},
+		{
+			"set missing", "[s]\na=1\n", nil,
+			func(cfg *Configuration) error { return cfg.SetValue("nope", "1", 0) },
+			ConfigError{File: "t.cfg", Section: "s", Param: "nope", Op: "set"}, nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			if tt.setup != nil {
+				tt.setup(cfg)
+			}
+			err := cfg.ReadContext(context.Background(), strings.NewReader(tt.text), "t.cfg")
+			if tt.op != nil {
+				if err != nil {
+					t.Fatalf("ReadContext: %v", err)
+				}
+				if err := cfg.SelectSection("s"); err != nil {
+					t.Fatalf("SelectSection: %v", err)
+				}
+				err = tt.op(cfg)
+			}
+			var ce *ConfigError
+			if !errors.As(err, &ce) {
+				t.Fatalf("error %v (%T), want a *ConfigError", err, err)
+			}
+			if ce.File != tt.want.File || ce.Line != tt.want.Line || ce.Section != tt.want.Section || ce.Param != tt.want.Param || ce.Op != tt.want.Op {
+				t.Errorf("got %s:%d [%s] %s op %q, want %s:%d [%s] %s op %q",
+					ce.File, ce.Line, ce.Section, ce.Param, ce.Op,
+					tt.want.File, tt.want.Line, tt.want.Section, tt.want.Param, tt.want.Op)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error %v, want it to wrap %v", err, tt.wantErr)
+			}
+			if ce.Unwrap() != ce.Err {
+				t.Error("Unwrap does not return Err")
+			}
+		})
+	}
+}
+
+func TestSaveComments(t *testing.T) {
+	const commented = "# header\n[s]\n# about a\na=1\n\n/* block */\nb=2\n# footer\n"
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		text      string
+		save      bool
+		allowLoss bool
+		wantErr   error
+	}{
+		{"saved", commented, true, false, nil},
+		{"dropped", commented, false, false, ErrCommentLoss},
+		{"dropped and allowed", commented, false, true, nil},
+		{"dropped but none", "[s]\na=1\nb=2\n", false, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".cfg")
+			if err := os.WriteFile(path, []byte(tt.text), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg := NewConfiguration("cfg")
+			cfg.SaveComments(tt.save)
+			cfg.AllowCommentLoss(tt.allowLoss)
+			if err := cfg.ReadFile(path, "", false); err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			var buf bytes.Buffer
+			if _, err := cfg.Print(&buf); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			hasComments := strings.ContainsAny(buf.String(), "#/")
+			if hasComments != tt.save {
+				t.Errorf("Print wrote %q, want comments %v", buf.String(), tt.save)
+			}
+			if got, _ := cfg.FindSection("s").Lookup("a"); got != "1" {
+				t.Errorf("a = %q, want 1", got)
+			}
+			if err := cfg.WriteFile(path); !errors.Is(err, tt.wantErr) {
+				t.Errorf("WriteFile: %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInline(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.cfg", "# base comment\n[base]\nhost=h\nport=1\n")
+	shared := writeFile(t, dir, "shared.cfg", "[shared]\nx=1\n")
+	root := writeFile(t, dir, "root.cfg", "import \""+base+"\"\nread \""+shared+"\"\n# app comment\n[app:base]\nport=2\nref=[shared]\n")
+
+	cfg := NewConfiguration("cfg")
+	if err := cfg.ReadFile(root, "", false); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	out := filepath.Join(dir, "flat.cfg")
+	if err := cfg.Inline().WriteFile(out); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(b)
+	for _, s := range []string{"import ", "read ", "[app:", "=[shared]", "base comment"} {
+		if strings.Contains(text, s) {
+			t.Errorf("inlined file holds %q:\n%s", s, text)
+		}
+	}
+	if !strings.Contains(text, "# app comment") {
+		t.Errorf("inlined file lost the root's own comment:\n%s", text)
+	}
+
+	flat := NewConfiguration("cfg")
+	if err := flat.ReadFile(out, "", false); err != nil {
+		t.Fatalf("ReadFile of the inlined file: %v", err)
+	}
+	if deps := flat.Dependencies(); len(deps) != 0 {
+		t.Errorf("inlined file depends on %q", deps)
+	}
+	tests := []struct {
+		section, param, want string
+	}{
+		{"base", "host", "h"},
+		{"base", "port", "1"},
+		{"shared", "x", "1"},
+		{"app", "host", "h"},
+		{"app", "port", "2"},
+		{"ref", "x", "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section+"."+tt.param, func(t *testing.T) {
+			s := flat.FindSection(tt.section)
+			if s == nil {
+				t.Fatalf("no section [%s] in:\n%s", tt.section, text)
+			}
+			if s.GetNParents() != 0 {
+				t.Errorf("[%s] still has parents", tt.section)
+			}
+			p := s.FindParameter(tt.param, false)
+			if p == nil {
+				t.Fatalf("[%s] has no %s of its own", tt.section, tt.param)
+			}
+			if got := p.GetValue(0); got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.param, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotedSectionName(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		section     string
+		wantParents []string
+		wantHeader  string
+	}{
+		{"colon in quotes", "[\"weird:name\"]\nv=1\n", "weird:name", nil, "[\"weird:name\"]"},
+		{"bracket in quotes", "[\"a]b\"]\nv=1\n", "a]b", nil, "[\"a]b\"]"},
+		{"quoted child of quoted parent", "[\"p:1\"]\nv=1\n[\"c:2\":\"p:1\"]\n", "c:2", []string{"p:1"}, "[\"c:2\":\"p:1\"]"},
+		{"plain inheritance", "[parent]\nv=1\n[child:parent]\n", "child", []string{"parent"}, "[child:parent]"},
+		{"plain name", "[plain]\nv=1\n", "plain", nil, "[plain]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, tt.text)
+			s := cfg.FindSection(tt.section)
+			if s == nil {
+				t.Fatalf("no section %q in %q", tt.section, cfg.GetSectionNames())
+			}
+			var parents []string
+			for i := uint(0); i < s.GetNParents(); i++ {
+				parents = append(parents, s.GetParentName(i))
+			}
+			if strings.Join(parents, ",") != strings.Join(tt.wantParents, ",") {
+				t.Errorf("parents %q, want %q", parents, tt.wantParents)
+			}
+			if got, _ := s.Lookup("v"); got != "1" {
+				t.Errorf("v = %q, want 1", got)
+			}
+			var buf bytes.Buffer
+			if _, err := cfg.Print(&buf); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.wantHeader+"\n") {
+				t.Errorf("Print wrote %q, want header %s", buf.String(), tt.wantHeader)
+			}
+			if back := parse(t, buf.String()); back.FindSection(tt.section) == nil {
+				t.Errorf("section %q lost on re-read of %q", tt.section, buf.String())
+			}
+		})
+	}
+}
+
+func TestSetPathname(t *testing.T) {
+	abs := filepath.Join(t.TempDir(), "etc", "app.cfg")
+	tests := []struct {
+		name     string
+		full     string
+		wantPath string
+		wantDir  string
+		wantFile string
+	}{
+		{"absolute", abs, abs, filepath.Dir(abs), "app.cfg"},
+		{"relative", filepath.Join("conf", "app.cfg"), filepath.Join("conf", "app.cfg"), "conf", "app.cfg"},
+		{"bare name", "app.cfg", "app.cfg", ".", "app.cfg"},
+		{"untidy", filepath.Join("conf", ".", "x", "..", "app.cfg"), filepath.Join("conf", "app.cfg"), "conf", "app.cfg"},
+		{"empty", "", "", ".", "."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetFilename("old.cfg")
+			cfg.SetPathname(tt.full)
+			if got := cfg.GetPathname(); got != tt.wantPath {
+				t.Errorf("GetPathname() = %q, want %q", got, tt.wantPath)
+			}
+			if got := cfg.GetDirectory(); got != tt.wantDir {
+				t.Errorf("GetDirectory() = %q, want %q", got, tt.wantDir)
+			}
+			if got := cfg.GetFilename(); got != tt.wantFile {
+				t.Errorf("GetFilename() = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestGetValueAny(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantValue   string
+		wantMatched string
+		wantFound   bool
+	}{
+		{"new name", "[s]\nnew_key=n\nold_key=o\n", "n", "new_key", true},
+		{"only old name", "[s]\nold_key=o\n", "o", "old_key", true},
+		{"new name empty", "[s]\nnew_key=\nold_key=o\n", "o", "old_key", true},
+		{"inherited", "[p]\nold_key=o\n[s:p]\n", "o", "old_key", true},
+		{"neither", "[s]\nother=x\n", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, tt.text, "s")
+			v, matched, found := cfg.GetValueAny("new_key", "old_key")
+			if v != tt.wantValue || matched != tt.wantMatched || found != tt.wantFound {
+				t.Errorf("GetValueAny = %q, %q, %v; want %q, %q, %v", v, matched, found, tt.wantValue, tt.wantMatched, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestIndexGap(t *testing.T) {
+	tests := []struct {
+		name    string
+		set     func(s *Section) error
+		wantErr error
+		want    []string
+	}{
+		{"replace", func(s *Section) error { return s.GetParameter("x", false).SetValuePtrOnIndex(0, "b", 0) }, nil, []string{"b"}},
+		{"append", func(s *Section) error { return s.GetParameter("x", false).SetValuePtrOnIndex(1, "b", 0) }, nil, []string{"a", "b"}},
+		{"gap", func(s *Section) error { return s.GetParameter("x", false).SetValuePtrOnIndex(3, "b", 0) }, ErrIndexGap, []string{"a"}},
+		{"format append", func(s *Section) error { return s.SetValueInFormat("x", 1, "%d", 7) }, nil, []string{"a", "7"}},
+		{"format gap", func(s *Section) error { return s.SetValueInFormat("x", 3, "%d", 7) }, ErrIndexGap, []string{"a"}},
+		{"format gap on new parameter", func(s *Section) error { return s.SetValueInFormat("y", 1, "%d", 7) }, ErrIndexGap, []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, "[s]\nx=a\n")
+			s := cfg.FindSection("s")
+			if err := tt.set(s); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			p := s.GetParameter("x", false)
+			if got := p.GetValueArray(); p.GetNValues() != uint(len(got)) || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("values %q (n=%d), want %q", got, p.GetNValues(), tt.want)
+			}
+			if s.GetParameter("y", false) != nil {
+				t.Error("a failed set created parameter y")
+			}
+			var buf bytes.Buffer
+			if _, err := cfg.Print(&buf); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if strings.Contains(buf.String(), ",,") || strings.Contains(buf.String(), ", ,") {
+				t.Errorf("Print wrote empty values: %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	dir := t.TempDir()
+	frags := map[string]string{
+		"20-site.cfg":  "[db]\nhost=b\n",
+		"10-base.cfg":  "[db]\nhost=a\nport=1\n[log]\nlevel=info\n",
+		"30-local.cfg": "[db]\nport=3\n[extra]\nk=v\n",
+		"notes.txt":    "[ignored]\nk=v\n",
+	}
+	for name, text := range frags {
+		writeFile(t, dir, name, text)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "40-dir.cfg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		pattern  string
+		policy   DuplicatePolicy
+		sections []string
+		values   map[string]string
+		wantErr  bool
+	}{
+		{"all fragments", "", DuplicateSeparate, []string{"db", "log", "extra"},
+			map[string]string{"db.host": "b", "db.port": "3", "log.level": "info", "extra.k": "v"}, false},
+		{"pattern", "2*.cfg", DuplicateSeparate, []string{"db"},
+			map[string]string{"db.host": "b"}, false},
+		{"duplicate error", "", DuplicateError, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetDuplicateSectionPolicy(tt.policy)
+			err := cfg.ReadDir(dir, tt.pattern)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ReadDir succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadDir: %v", err)
+			}
+			if got := cfg.GetSectionNames(); strings.Join(got, ",") != strings.Join(tt.sections, ",") {
+				t.Errorf("sections %q, want %q", got, tt.sections)
+			}
+			for key, want := range tt.values {
+				section, param, _ := strings.Cut(key, ".")
+				if got, _ := cfg.FindSection(section).Lookup(param); got != want {
+					t.Errorf("%s = %q, want %q", key, got, want)
+				}
+			}
+			if err := cfg.WriteFile(filepath.Join(t.TempDir(), "out.cfg")); err == nil {
+				t.Error("WriteFile of merged fragments succeeded")
+			}
+		})
+	}
+}
+
+func TestGetValueSubConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		pairSep  string
+		kvSep    string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"quoted", "[s]\ndb=\"host=x;port=5432\"\n", "", "", "x", 5432, false},
+		{"unquoted", "[s]\ndb=host=x;port=5432\n", "", "", "x", 5432, false},
+		{"inner quotes", "[s]\ndb=host='a;b';port=1\n", "", "", "'a", 0, true},
+		{"commas kept", "[s]\ndb=\"host=a,b;port=2\"\n", "", "", "a,b", 2, false},
+		{"other separators", "[s]\ndb=\"host:x&port:80\"\n", "&", ":", "x", 80, false},
+		{"inherited", "[p]\ndb=\"host=x;port=5432\"\n[s:p]\n", "", "", "x", 5432, false},
+		{"not a pair", "[s]\ndb=\"host=x;port\"\n", "", "", "", 0, true},
+		{"missing", "[s]\nother=1\n", "", "", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, tt.text, "s")
+			sub, err := cfg.GetValueSubConfig("db", tt.pairSep, tt.kvSep)
+			if err == nil {
+				var port int
+				if err = sub.GetValueInt("port", &port); err == nil {
+					if host := sub.GetValue("host"); host != tt.wantHost || port != tt.wantPort {
+						t.Errorf("host=%q port=%d, want host=%q port=%d", host, port, tt.wantHost, tt.wantPort)
+					}
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHasFlag(t *testing.T) {
+	const text = "[p]\ninherited\n[s:p]\nverbose\n\"dry run\"\nempty=\nvalue=1\n"
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"verbose", true},
+		{"dry run", true},
+		{"inherited", true},
+		{"empty", false},
+		{"value", false},
+		{"missing", false},
+	}
+	cfg := selected(t, text, "s")
+	var buf bytes.Buffer
+	if _, err := cfg.Print(&buf); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	for _, line := range []string{"\nverbose\n", "\n\"dry run\"\n", "\nempty=\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Print wrote %q, want the line %q", buf.String(), strings.Trim(line, "\n"))
+		}
+	}
+	back := NewConfiguration("cfg")
+	if err := back.ReadContext(context.Background(), &buf, "t.cfg"); err != nil {
+		t.Fatalf("re-read: %v", err)
+	}
+	if err := back.SelectSection("s"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.HasFlag(tt.name); got != tt.want {
+				t.Errorf("HasFlag(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+			if got := back.HasFlag(tt.name); got != tt.want {
+				t.Errorf("after a round trip HasFlag(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteOrder(t *testing.T) {
+	const text = "[b]\n# about z\nz=1\nA=2\n[C]\nx=0\n[a]\ny=3\n"
+	tests := []struct {
+		name  string
+		order WriteOrder
+		want  string
+	}{
+		{"as read", AsRead, text},
+		{"sorted", Sorted, "[a]\ny=3\n[b]\nA=2\n# about z\nz=1\n[C]\nx=0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, text)
+			cfg.SetWriteOrder(tt.order)
+			var buf bytes.Buffer
+			if _, err := cfg.Print(&buf); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("Print wrote\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+			var wt bytes.Buffer
+			if _, err := cfg.WriteTo(&wt); err != nil {
+				t.Fatalf("WriteTo: %v", err)
+			}
+			if wt.String() != buf.String() {
+				t.Errorf("WriteTo wrote\n%s\nPrint wrote\n%s", wt.String(), buf.String())
+			}
+			if got := cfg.GetSectionNames(); strings.Join(got, ",") != "b,C,a" {
+				t.Errorf("writing reordered the sections themselves: %q", got)
+			}
+		})
+	}
+}
+
+func TestIsImported(t *testing.T) {
+	dir := t.TempDir()
+	base := writeFile(t, dir, "base.cfg", "[base]\nhost=h\n")
+	shared := writeFile(t, dir, "shared.cfg", "[shared]\nx=1\n")
+	root := writeFile(t, dir, "root.cfg", "import \""+base+"\"\nread \""+shared+"\"\n[app:base]\nport=2\n")
+	cfg := NewConfiguration("cfg")
+	if err := cfg.ReadFile(root, "", false); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	tests := []struct {
+		section, name string
+		want          bool
+		wantErr       bool
+	}{
+		{"base", "host", true, false},
+		{"app", "port", false, false},
+		{"app", "host", true, false},
+		{"shared", "x", false, false},
+		{"app", "missing", false, true},
+		{"nowhere", "host", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.section+"."+tt.name, func(t *testing.T) {
+			got, err := cfg.IsImported(tt.section, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("IsImported(%q, %q) = %v, want %v", tt.section, tt.name, got, tt.want)
+			}
+		})
+	}
+	if _, err := cfg.IsImported("app", "missing"); !errors.Is(err, ErrParameterNotFound) {
+		t.Errorf("missing parameter gave %v, want ErrParameterNotFound", err)
+	}
+}
+
+func TestTypedGetterEmptyOrMissing(t *testing.T) {
+	const text = "[p]\nbase=7\n[s:p]\nn=42\nempty=\nbad=x\n"
+	tests := []struct {
+		name    string
+		want    int
+		wantErr error
+	}{
+		{"n", 42, nil},
+		{"base", 7, nil},
+		{"empty", 0, ErrEmptyValue},
+		{"missing", 0, ErrParameterNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, text, "s")
+			var got int
+			err := cfg.GetValueInt(tt.name, &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetValueInt(%q): %v, want %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetValueInt(%q) = %d, want %d", tt.name, got, tt.want)
+			}
+			var d time.Duration
+			if err := cfg.GetValueDuration(tt.name, &d); tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetValueDuration(%q): %v, want %v", tt.name, err, tt.wantErr)
+			}
+		})
+	}
+	cfg := selected(t, text, "s")
+	var n int
+	err := cfg.GetValueInt("bad", &n)
+	if err == nil || errors.Is(err, ErrEmptyValue) || errors.Is(err, ErrParameterNotFound) {
+		t.Errorf("GetValueInt(\"bad\"): %v, want a decoding error", err)
+	}
+}
+
+func TestFileValueRefs(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_pass")
+	if err := os.WriteFile(secret, []byte("  s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "nope")
+	tests := []struct {
+		name      string
+		line      string
+		refs      bool
+		want      string
+		wantPrint string
+		wantErr   error
+	}{
+		{"present", "password=@" + secret, true, "s3cret", "password=@" + secret, nil},
+		{"missing", "password=@" + missing, true, "", "", os.ErrNotExist},
+		{"off", "password=@" + secret, false, "@" + secret, "password=@" + secret, nil},
+		{"quoted", "password=\"@" + secret + "\"", true, "\"@" + secret + "\"", "password=\"@" + secret + "\"", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetFileValueRefs(tt.refs)
+			err := cfg.ReadContext(context.Background(), strings.NewReader("[s]\n"+tt.line+"\n"), "t.cfg")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadContext: %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got, _ := cfg.FindSection("s").Lookup("password"); got != tt.want {
+				t.Errorf("password = %q, want %q", got, tt.want)
+			}
+			var buf bytes.Buffer
+			if _, err := cfg.Print(&buf); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if !strings.Contains(buf.String(), tt.wantPrint+"\n") || strings.Contains(buf.String(), "s3cret") {
+				t.Errorf("Print wrote %q, want %q and no secret", buf.String(), tt.wantPrint)
+			}
+		})
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	dir := t.TempDir()
+	typo := writeFile(t, dir, "typo.cfg", "[i]\nk=1\nport 80\n")
+	tests := []struct {
+		name string
+		text string
+		want []Warning
+	}{
+		{"clean", "[s]\nhost=h\n", nil},
+		{"missing equals", "[s]\nhost=h\nport 80\nuser=u\n", []Warning{
+			{Line: 3, Text: "port 80", Reason: "not a comment, section header or parameter"},
+		}},
+		{"broken header", "[s]\nhost=h\n[t] junk\nuser=u\n", []Warning{
+			{Line: 3, Text: "[t] junk", Reason: "invalid section header"},
+		}},
+		{"in a read file", "read \"" + typo + "\"\n[s]\nhost=h\n", []Warning{
+			{File: typo, Line: 3, Text: "port 80", Reason: "not a comment, section header or parameter"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, dir, strings.ReplaceAll(tt.name, " ", "_")+".cfg", tt.text)
+			cfg := NewConfiguration("cfg")
+			if err := cfg.ReadFile(path, "", false); err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			got := cfg.Warnings()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Warnings() = %v, want %v", got, tt.want)
+			}
+			for i, w := range tt.want {
+				if w.File == "" {
+					w.File = path
+				}
+				if got[i] != w {
+					t.Errorf("warning %d = %+v, want %+v", i, got[i], w)
+				}
+			}
+			if cfg.FindSection("s") == nil {
+				t.Error("the read did not go on after the warning")
+			}
+			if err := cfg.ReadFile(writeFile(t, dir, "clean.cfg", "[s]\n"), "", false); err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.Warnings(); len(got) != 0 {
+				t.Errorf("a clean read kept the old warnings: %v", got)
+			}
+		})
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	const text = "[db]\nhost=h\nports=1,2,3\n[web-front:db]\nlog.level=debug\n"
+	tests := []struct {
+		name   string
+		prefix string
+		sep    *string
+		order  WriteOrder
+		want   []string
+	}{
+		{"prefixed", "app", nil, AsRead, []string{
+			"APP_DB_HOST=h", "APP_DB_PORTS=1,2,3",
+			"APP_WEB_FRONT_LOG_LEVEL=debug", "APP_WEB_FRONT_HOST=h", "APP_WEB_FRONT_PORTS=1,2,3",
+		}},
+		{"no prefix", "", nil, AsRead, []string{
+			"DB_HOST=h", "DB_PORTS=1,2,3",
+			"WEB_FRONT_LOG_LEVEL=debug", "WEB_FRONT_HOST=h", "WEB_FRONT_PORTS=1,2,3",
+		}},
+		{"separator", "x", func() *string { s := ":"; return &s }(), AsRead, []string{
+			"X_DB_HOST=h", "X_DB_PORTS=1:2:3",
+			"X_WEB_FRONT_LOG_LEVEL=debug", "X_WEB_FRONT_HOST=h", "X_WEB_FRONT_PORTS=1:2:3",
+		}},
+		{"sorted", "", nil, Sorted, []string{
+			"DB_HOST=h", "DB_PORTS=1,2,3",
+			"WEB_FRONT_HOST=h", "WEB_FRONT_LOG_LEVEL=debug", "WEB_FRONT_PORTS=1,2,3",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, text)
+			if tt.sep != nil {
+				cfg.SetEnvironSeparator(*tt.sep)
+			}
+			cfg.SetWriteOrder(tt.order)
+			if got := cfg.Environ(tt.prefix); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Environ(%q) =\n%s\nwant\n%s", tt.prefix, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestMarkSecret(t *testing.T) {
+	const text = "[db]\npassword=hunter2\nuser=u\n[other]\npassword=open\n"
+	tests := []struct {
+		name   string
+		show   bool
+		output func(cfg *Configuration) string
+		want   string
+	}{
+		{"String", false, func(cfg *Configuration) string { return cfg.String() }, "password=***"},
+		{"%v", false, func(cfg *Configuration) string { return fmt.Sprintf("%v", cfg) }, "password=***"},
+		{"WriteTo", false, func(cfg *Configuration) string {
+			var buf bytes.Buffer
+			cfg.WriteTo(&buf)
+			return buf.String()
+		}, "password=***"},
+		{"PrintSecrets", true, func(cfg *Configuration) string { return cfg.String() }, "password=hunter2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.MarkSecret("db", "password")
+			if err := cfg.ReadContext(context.Background(), strings.NewReader(text), "t.cfg"); err != nil {
+				t.Fatalf("ReadContext: %v", err)
+			}
+			cfg.PrintSecrets(tt.show)
+			out := tt.output(cfg)
+			if !strings.Contains(out, "[db]\n"+tt.want+"\n") {
+				t.Errorf("wrote %q, want %q in [db]", out, tt.want)
+			}
+			if !tt.show && strings.Contains(out, "hunter2") {
+				t.Errorf("the secret leaked: %q", out)
+			}
+			if !strings.Contains(out, "user=u\n") || !strings.Contains(out, "password=open\n") {
+				t.Errorf("redacted more than the secret: %q", out)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "db.cfg")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfiguration("cfg")
+	cfg.MarkSecret("db", "password")
+	if err := cfg.ReadFile(path, "", false); err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if err := cfg.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "password=hunter2\n") {
+		t.Errorf("WriteFile did not persist the secret:\n%s", b)
+	}
+}
+
+func TestIntBase(t *testing.T) {
+	tests := []struct {
+		value   string
+		base    int
+		want    int64
+		wantErr bool
+	}{
+		{"0x1F", 10, 0, true},
+		{"0o17", 10, 0, true},
+		{"010", 10, 10, false},
+		{"-42", 10, -42, false},
+		{"0x1F", 0, 31, false},
+		{"0o17", 0, 15, false},
+		{"010", 0, 8, false},
+		{"0b101", 0, 5, false},
+		{"1_000", 0, 1000, false},
+		{"08", 0, 0, true},
+		{"010", 16, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s base %d", tt.value, tt.base), func(t *testing.T) {
+			cfg := selected(t, "[s]\nn="+tt.value+"\n", "s")
+			cfg.SetIntBase(tt.base)
+			var got int64
+			err := cfg.GetValueInt64("n", &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetValueInt64(%q): %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetValueInt64(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+			var small int8
+			if err := cfg.GetValueInt8("n", &small); err == nil && int64(small) != tt.want {
+				t.Errorf("GetValueInt8(%q) = %d, want %d", tt.value, small, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetValueTimeLayout(t *testing.T) {
+	east := time.FixedZone("EAST", 3*3600)
+	tests := []struct {
+		name    string
+		value   string
+		layout  string
+		loc     *time.Location
+		want    time.Time
+		wantErr bool
+	}{
+		{"datetime", "2024-03-05 10:20:30", "2006-01-02 15:04:05", nil, time.Date(2024, 3, 5, 10, 20, 30, 0, time.UTC), false},
+		{"date only", "2024-03-05", "2006-01-02", nil, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), false},
+		{"in a location", "2024-03-05 10:20:30", "2006-01-02 15:04:05", east, time.Date(2024, 3, 5, 10, 20, 30, 0, east), false},
+		{"zone in the value wins", "2024-03-05 10:20:30 +0100", "2006-01-02 15:04:05 -0700", east, time.Date(2024, 3, 5, 9, 20, 30, 0, time.UTC), false},
+		{"wrong layout", "2024-03-05", "2006-01-02 15:04:05", nil, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nwhen="+tt.value+"\n", "s")
+			var got time.Time
+			var err error
+			if tt.loc == nil {
+				err = cfg.GetValueTimeLayout("when", tt.layout, &got)
+			} else {
+				err = cfg.GetValueTimeLayoutIn("when", tt.layout, tt.loc, &got)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+	cfg := selected(t, "[s]\nwhen=2024-03-05\n", "s")
+	var rfc time.Time
+	if err := cfg.GetValueTime("when", &rfc); err == nil {
+		t.Error("GetValueTime read a date-only value; it must stay RFC3339")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		literal string // Appended as parameter z of [s] when set.
+		quote   byte
+		wantErr string
+	}{
+		{"commas and quotes", "[s]\nx=\"a,b\", 'c \"d\"'\ny=1\n", "", 0, ""},
+		{"inherited and secret", "[p]\npw=hunter2\n[s:p]\ny=1\n", "", 0, ""},
+		{"literal with a quote", "[s]\ny=1\n", "say \"hi\"", '"', ""},
+		{"literal with a comma", "[s]\ny=1\n", "a,b", '"', "parameter z of [s]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, tt.text)
+			cfg.MarkSecret("p", "pw")
+			if tt.literal != "" {
+				// Not AppendLiteralParameter, which refuses what would not round-trip.
+				cfg.FindSection("s").AppendParameter("z", "", nil, false).SetValuePtr(tt.literal, tt.quote)
+			}
+			err := cfg.Verify()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Verify() = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() = %v, want an error naming %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeaderFooterComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		headers []string
+		footers []string
+		want    string
+	}{
+		{"header first", "# old\n[s]\nx=1\n", []string{"generated"}, nil, "# generated\n# old\n[s]\nx=1\n"},
+		{"lines split and prefixed", "[s]\nx=1\n", []string{"a\n\n# b\n  c  "}, nil, "# a\n# b\n# c\n[s]\nx=1\n"},
+		{"later header goes above", "[s]\nx=1\n", []string{"second", "first"}, nil, "# first\n# second\n[s]\nx=1\n"},
+		{"footer last", "# old\n[s]\nx=1\n", nil, []string{"end", "really"}, "# old\n[s]\nx=1\n# end\n# really\n"},
+		{"both on empty", "", []string{"h"}, []string{"f"}, "# h\n# f\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, tt.text)
+			for _, h := range tt.headers {
+				cfg.AddHeaderComment(h)
+			}
+			for _, f := range tt.footers {
+				cfg.AddFooterComment(f)
+			}
+			var sb strings.Builder
+			if _, err := cfg.Print(&sb); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if sb.String() != tt.want {
+				t.Errorf("Print wrote %q, want %q", sb.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetValueSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		overlay string
+		param   string
+		want    []string
+		wantErr bool
+	}{
+		{"duplicates dropped", "[s]\nfeatures=a,b,a,c\n", "", "features", []string{"a", "b", "c"}, false},
+		{"blanks and empties", "[s]\nfeatures= a , ,b\n", "", "features", []string{"a", "b"}, false},
+		{"single", "[s]\nfeatures=a\n", "", "features", []string{"a"}, false},
+		{"inherited", "[p]\nfeatures=x,x\n[s:p]\ny=1\n", "", "features", []string{"x"}, false},
+		{"from the overlay", "[s]\nfeatures=a\n", "b,c,b", "features", []string{"b", "c"}, false},
+		{"missing", "[s]\nfeatures=a\n", "", "nope", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, tt.text, "s")
+			if tt.overlay != "" {
+				cfg = cfg.WithOverlay(func(section, name string) (string, bool) {
+					return tt.overlay, section == "s" && name == "features"
+				})
+			}
+			set, err := cfg.GetValueSet(tt.param)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if len(set) != len(tt.want) {
+				t.Errorf("got %v, want %v", set, tt.want)
+			}
+			for _, v := range tt.want {
+				if _, ok := set[v]; !ok {
+					t.Errorf("%q missing from %v", v, set)
+				}
+				if !cfg.ValueContains(tt.param, v) {
+					t.Errorf("ValueContains(%q, %q) = false", tt.param, v)
+				}
+			}
+			if cfg.ValueContains(tt.param, "z") {
+				t.Errorf("ValueContains(%q, \"z\") = true", tt.param)
+			}
+		})
+	}
+}
+
+func TestGetValuePrecisionFloat64(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		precision string
+		want      float64
+		wantErr   bool
+	}{
+		{"two digits", "3.14159", "2", 3.14, false},
+		{"rounds up", "2.71828", "2", 2.72, false},
+		{"no digits", "2.71828", "0", 3, false},
+		{"every digit", "3.14159", "", 3.14159, false},
+		{"bad precision", "3.14159", "two", 0, true},
+		{"not a number", "pi", "2", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nx="+tt.value+"\n", "s")
+			var got, gotSection float64
+			err := cfg.GetValuePrecisionFloat64("x", tt.precision, &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			err = cfg.FindSection("s").GetValuePrecisionFloat64("x", tt.precision, &gotSection)
+			if (err != nil) != tt.wantErr || gotSection != tt.want {
+				t.Errorf("Section got %v, %v; want %v", gotSection, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigWriter(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(w *ConfigWriter) *ConfigWriter
+		want    string
+		wantErr string
+	}{
+		{"two sections", func(w *ConfigWriter) *ConfigWriter {
+			return w.Comment("generated").Section("db").Set("host", "x").Comment("the port").Set("port", "5432").
+				Section("app").Set("tags", "a,\"b,c\"").Comment("end")
+		}, "# generated\n[db]\nhost=x\n# the port\nport=5432\n[app]\ntags=a,\"b,c\"\n# end\n", ""},
+		{"back to a section", func(w *ConfigWriter) *ConfigWriter {
+			return w.Section("a").Set("x", "1").Section("b").Set("y", "2").Section("a").Set("z", "3")
+		}, "[a]\nx=1\nz=3\n[b]\ny=2\n", ""},
+		{"no section", func(w *ConfigWriter) *ConfigWriter { return w.Set("a", "b") }, "", "no section started"},
+		{"set twice", func(w *ConfigWriter) *ConfigWriter {
+			return w.Section("s").Set("a", "b").Set("a", "c").Section("t")
+		}, "", "parameter already set"},
+		{"empty section name", func(w *ConfigWriter) *ConfigWriter { return w.Section(" ") }, "", "section name cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "out.cfg")
+			err := tt.build(NewWriter()).WriteFile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("WriteFile: %v, want an error with %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("WriteFile wrote %s despite the error", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("wrote %q, want %q", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetValuesAfterSet(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		set   func(s *Section) error
+		want  string
+		wantN uint
+	}{
+		{"as read", "[s]\nx=a,\"b c\",'d'\n", func(s *Section) error { return nil }, "a,\"b c\",'d'", 3},
+		{"multi-valued set", "[s]\nx=old\n", func(s *Section) error { return s.SetValue("x", "a,b,c", 0) }, "a,b,c", 3},
+		{"fewer values", "[s]\nx=a,b,c\n", func(s *Section) error { return s.SetValue("x", "z", 0) }, "z", 1},
+		{"one index", "[s]\nx=a,b\n", func(s *Section) error { return s.SetValuePtrOnIndex("x", "w", 1, '"') }, "a,\"w\"", 2},
+		{"empty", "[s]\nx=a,b\n", func(s *Section) error { return s.SetValue("x", "", 0) }, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, tt.text, "s")
+			if err := tt.set(cfg.FindSection("s")); err != nil {
+				t.Fatalf("set: %v", err)
+			}
+			if got := cfg.GetValues("x"); got != tt.want {
+				t.Errorf("GetValues = %q, want %q", got, tt.want)
+			}
+			if n := cfg.FindSection("s").GetNValues("x"); n != tt.wantN {
+				t.Errorf("GetNValues = %d, want %d", n, tt.wantN)
+			}
+		})
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(cfg *Configuration) error
+		want []string
+	}{
+		{"set a value", func(cfg *Configuration) error { return cfg.SetValue("x", "2", 0) }, []string{"s x 1 2"}},
+		{"same value", func(cfg *Configuration) error { return cfg.SetValue("x", "1", 0) }, nil},
+		{"typed setter", func(cfg *Configuration) error { return cfg.SetValueInt("x", 7) }, []string{"s x 1 7"}},
+		{"by index", func(cfg *Configuration) error { return cfg.SetValueIntByIndex("y", 1, 9) }, []string{"s y a,b a,9"}},
+		{"section level", func(cfg *Configuration) error { return cfg.FindSection("s").SetValue("y", "c", 0) }, []string{"s y a,b c"}},
+		{"another section", func(cfg *Configuration) error { return cfg.SetValues("t", map[string]string{"z": "new"}) }, []string{"t z old new"}},
+		{"defaults", func(cfg *Configuration) error {
+			cfg.ApplyDefaults(parse(t, "[s]\nx=0\nw=1\n"))
+			return nil
+		}, nil},
+		{"no callback", func(cfg *Configuration) error {
+			cfg.OnChange(nil)
+			return cfg.SetValue("x", "2", 0)
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nx=1\ny=a,b\n[t]\nz=old\n", "s")
+			var got []string
+			cfg.OnChange(func(section, name, oldValue, newValue string) {
+				got = append(got, section+" "+name+" "+oldValue+" "+newValue)
+			})
+			if err := tt.set(cfg); err != nil {
+				t.Fatalf("set: %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("OnChange saw %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetValueColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    color.RGBA
+		wantErr bool
+	}{
+		{"3 digits", "#f80", color.RGBA{0xff, 0x88, 0x00, 0xff}, false},
+		{"6 digits", "#FF8800", color.RGBA{0xff, 0x88, 0x00, 0xff}, false},
+		{"8 digits", "#ff880080", color.RGBA{0xff, 0x88, 0x00, 0x80}, false},
+		{"no hash", "ff8800", color.RGBA{}, true},
+		{"bad hex", "#ff88zz", color.RGBA{}, true},
+		{"wrong length", "#ff88", color.RGBA{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nfg="+tt.value+"\n", "s")
+			var got color.RGBA
+			err := cfg.GetValueColor("fg", &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := cfg.SetValueColor("bg", got); err == nil {
+				t.Error("SetValueColor made a parameter that was not there")
+			}
+			if err := cfg.SetValueColor("fg", got); err != nil {
+				t.Fatalf("SetValueColor: %v", err)
+			}
+			var back color.RGBA
+			if err := cfg.GetValueColor("fg", &back); err != nil || back != tt.want {
+				t.Errorf("after SetValueColor (%q) got %v, %v; want %v", cfg.GetValue("fg"), back, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrailingCommaPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  TrailingCommaPolicy
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"drop", DropEmpty, "a,b,", []string{"a", "b"}, false},
+		{"keep", KeepEmpty, "a,b,", []string{"a", "b", ""}, false},
+		{"error", TrailingCommaError, "a,b,", nil, true},
+		// Not a trailing comma, though the read splits the quoted one as usual.
+		{"quoted comma", TrailingCommaError, "a,\"b,\"", []string{"a", "\"b", "\""}, false},
+		{"no comma", TrailingCommaError, "a,b", []string{"a", "b"}, false},
+	}
+	values := func(cfg *Configuration, name string) []string {
+		p := cfg.FindSection("s").FindParameter(name, false)
+		var v []string
+		for i := uint(0); i < p.GetNValues(); i++ {
+			v = append(v, p.GetValue(i))
+		}
+		return v
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetTrailingCommaPolicy(tt.policy)
+			err := cfg.ReadContext(context.Background(), strings.NewReader("[s]\nhosts="+tt.value+"\nother=x\n"), "t.cfg")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("read: %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrTrailingComma) {
+					t.Errorf("read: %v, want ErrTrailingComma", err)
+				}
+			} else if got := values(cfg, "hosts"); strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+
+			cfg = NewConfiguration("cfg")
+			cfg.SetTrailingCommaPolicy(tt.policy)
+			if err := cfg.ReadContext(context.Background(), strings.NewReader("[s]\nother=x\n"), "t.cfg"); err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if err := cfg.SelectSection("s"); err != nil {
+				t.Fatalf("SelectSection: %v", err)
+			}
+			err = cfg.SetValue("other", tt.value, 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetValue: %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrTrailingComma) {
+					t.Errorf("SetValue: %v, want ErrTrailingComma", err)
+				}
+				if got := cfg.GetValues("other"); got != "x" {
+					t.Errorf("failed SetValue left %q, want \"x\"", got)
+				}
+			} else if got := values(cfg, "other"); strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+				t.Errorf("set %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionDelimiters(t *testing.T) {
+	tests := []struct {
+		name        string
+		open, close string
+		text        string
+	}{
+		{"angle brackets", "<", ">", "# top\n<db>\nhost=x\n<app:db>\nport=1\n"},
+		{"double braces", "{{", "}}", "# top\n{{db}}\nhost=x\n{{app:db}}\nport=1\n"},
+		{"empty restores defaults", "", ">", "# top\n[db]\nhost=x\n[app:db]\nport=1\n"},
+		{"comment restores defaults", "#", "#", "# top\n[db]\nhost=x\n[app:db]\nport=1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := func(text string) *Configuration {
+				cfg := NewConfiguration("cfg")
+				cfg.SetSectionDelimiters(tt.open, tt.close)
+				if err := cfg.ReadContext(context.Background(), strings.NewReader(text), "t.cfg"); err != nil {
+					t.Fatalf("ReadContext: %v", err)
+				}
+				return cfg
+			}
+			cfg := read(tt.text)
+			if got := strings.Join(cfg.GetSectionNames(), ","); got != "db,app" {
+				t.Fatalf("sections %q, want \"db,app\"", got)
+			}
+			if got := cfg.FindSection("app").GetValue("host", 0); got != "x" {
+				t.Errorf("app inherits host %q, want \"x\"", got)
+			}
+			var sb strings.Builder
+			if _, err := cfg.Print(&sb); err != nil {
+				t.Fatalf("Print: %v", err)
+			}
+			if sb.String() != tt.text {
+				t.Errorf("Print wrote %q, want %q", sb.String(), tt.text)
+			}
+			if !cfg.Equal(read(sb.String())) {
+				t.Error("what Print wrote does not read back the same")
+			}
+		})
+	}
+	cfg := NewConfiguration("cfg")
+	cfg.SetSectionDelimiters("<", ">")
+	cfg.AppendSection("a>b", nil, false)
+	var sb strings.Builder
+	if _, err := cfg.Print(&sb); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	back := NewConfiguration("cfg")
+	back.SetSectionDelimiters("<", ">")
+	if err := back.ReadContext(context.Background(), strings.NewReader(sb.String()), "t.cfg"); err != nil {
+		t.Fatalf("ReadContext(%q): %v", sb.String(), err)
+	}
+	if back.FindSection("a>b") == nil {
+		t.Errorf("%q read back as sections %q, want \"a>b\"", sb.String(), back.GetSectionNames())
+	}
+}
+
+func TestDump(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		prepare func(cfg *Configuration) *Configuration
+		want    []string
+	}{
+		{"sorted with inheritance", "[b]\nz=1\na=x,y\n[a]\nk=\n[c:b]\nw=2\n", nil,
+			[]string{"a.k=", "b.a[0]=x", "b.a[1]=y", "b.z=1", "c.a[0]=x", "c.a[1]=y", "c.w=2", "c.z=1"}},
+		{"indexes stay numeric", "[s]\nv=0,1,2,3,4,5,6,7,8,9,10\n", nil,
+			[]string{"s.v[0]=0", "s.v[1]=1", "s.v[2]=2", "s.v[3]=3", "s.v[4]=4", "s.v[5]=5", "s.v[6]=6", "s.v[7]=7", "s.v[8]=8", "s.v[9]=9", "s.v[10]=10"}},
+		{"secret hidden", "[s]\npw=hunter2\nuser=me\n", func(cfg *Configuration) *Configuration {
+			cfg.MarkSecret("s", "pw")
+			return cfg
+		}, []string{"s.pw=***", "s.user=me"}},
+		{"secret shown", "[s]\npw=hunter2\n", func(cfg *Configuration) *Configuration {
+			cfg.MarkSecret("s", "pw")
+			cfg.PrintSecrets(true)
+			return cfg
+		}, []string{"s.pw=hunter2"}},
+		{"overlay wins", "[s]\nhost=file\n", func(cfg *Configuration) *Configuration {
+			return cfg.WithOverlay(func(section, name string) (string, bool) { return "a,b", name == "host" })
+		}, []string{"s.host[0]=a", "s.host[1]=b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parse(t, tt.text)
+			if tt.prepare != nil {
+				cfg = tt.prepare(cfg)
+			}
+			if got := cfg.Dump(); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Dump() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestAllowCommentLoss(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string // What WriteFile writes once loss is allowed.
+	}{
+		{"line comments", "# header\n[s]\n# about a\na=1\n", "[s]\na=1\n"},
+		{"block comment", "[s]\n/* about a */\na=1\n", "[s]\na=1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "t.cfg")
+			if err := os.WriteFile(path, []byte(tt.text), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			cfg := NewConfiguration("cfg")
+			cfg.SaveComments(false)
+			if err := cfg.ReadFile(path, "", false); err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if err := cfg.WriteFile(path); !errors.Is(err, ErrCommentLoss) {
+				t.Fatalf("WriteFile: %v, want ErrCommentLoss", err)
+			}
+			if b, _ := os.ReadFile(path); string(b) != tt.text {
+				t.Errorf("refused WriteFile left %q, want %q", b, tt.text)
+			}
+			cfg.AllowCommentLoss(true)
+			if err := cfg.WriteFile(path); err != nil {
+				t.Fatalf("WriteFile with loss allowed: %v", err)
+			}
+			if b, _ := os.ReadFile(path); string(b) != tt.want {
+				t.Errorf("WriteFile wrote %q, want %q", b, tt.want)
+			}
+			cfg.AllowCommentLoss(false)
+			if err := cfg.WriteFile(path); !errors.Is(err, ErrCommentLoss) {
+				t.Errorf("WriteFile after disallowing again: %v, want ErrCommentLoss", err)
+			}
+		})
+	}
+}
+
+func TestValidateUTF8(t *testing.T) {
+	const text = "[s]\na=ok\nb=x\xffy,z\n"
+	tests := []struct {
+		name     string
+		validate bool
+		wantLine int // 0 if the read succeeds.
+		wantA    string
+		wantB    string
+	}{
+		{"validate", true, 3, "", ""},
+		{"sanitize", false, 0, "q\uFFFD", "x\uFFFDy,z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewConfiguration("cfg")
+			cfg.SetValidateUTF8(tt.validate)
+			err := cfg.ReadContext(context.Background(), strings.NewReader(text), "t.cfg")
+			if tt.wantLine != 0 {
+				var ce *ConfigError
+				if !errors.As(err, &ce) || !errors.Is(err, ErrInvalidUTF8) {
+					t.Fatalf("ReadContext: %v, want a *ConfigError wrapping ErrInvalidUTF8", err)
+				}
+				if ce.Line != tt.wantLine || ce.Section != "s" || ce.Param != "b" {
+					t.Errorf("error at line %d [%s] %s, want line %d [s] b", ce.Line, ce.Section, ce.Param, tt.wantLine)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadContext: %v", err)
+			}
+			s := cfg.FindSection("s")
+			if err := s.SetValuePtr("a", "q\xfe", 0); err != nil {
+				t.Fatalf("SetValuePtr: %v", err)
+			}
+			var changed []string
+			cfg.OnChange(func(section, name, oldValue, newValue string) { changed = append(changed, name) })
+			if err := cfg.SanitizeUTF8(); err != nil {
+				t.Fatalf("SanitizeUTF8: %v", err)
+			}
+			if got := s.GetValues("a"); got != tt.wantA {
+				t.Errorf("a = %q, want %q", got, tt.wantA)
+			}
+			if got := s.GetValues("b"); got != tt.wantB {
+				t.Errorf("b = %q, want %q", got, tt.wantB)
+			}
+			if strings.Join(changed, ",") != "a" {
+				t.Errorf("OnChange told of %q, want only a", changed)
+			}
+			if err := cfg.ReadOnly().SanitizeUTF8(); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("SanitizeUTF8 on a read-only view: %v, want ErrReadOnly", err)
+			}
+		})
+	}
+}
+
+func TestGetValueFileMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		want      os.FileMode
+		wantWrite string
+		wantErr   bool
+	}{
+		{"leading zero", "0640", 0o640, "0640", false},
+		{"no leading zero", "640", 0o640, "0640", false},
+		{"few digits", "7", 0o007, "0007", false},
+		{"setuid", "4755", 0o755 | os.ModeSetuid, "04755", false},
+		{"setgid and sticky", "03770", 0o770 | os.ModeSetgid | os.ModeSticky, "03770", false},
+		{"not octal", "999", 0, "", true},
+		{"out of range", "017777", 0, "", true},
+		{"negative", "-640", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := selected(t, "[s]\nmode="+tt.value+"\n", "s")
+			var got os.FileMode
+			err := cfg.GetValueFileMode("mode", &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := cfg.SetValueFileMode("mode", got|os.ModeDir); err != nil {
+				t.Fatalf("SetValueFileMode: %v", err)
+			}
+			if w := cfg.GetValue("mode"); w != tt.wantWrite {
+				t.Errorf("SetValueFileMode wrote %q, want %q", w, tt.wantWrite)
+			}
+			var back os.FileMode
+			if err := cfg.GetValueFileMode("mode", &back); err != nil || back != tt.want {
+				t.Errorf("read back %v, %v; want %v", back, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !unix
+// **************************************************************************
+// Filename:
+//  platform_other.go
+//
+// Description:
+//  Stand-ins for what platform_unix.go takes from a Unix-like system, so the
+//  configuration package builds on Windows and the like.
+//
+// ***************************************************************************
+package configuration
+import (
+		"os"
+)
+
+// Timespec is what GetValueTimespec() decodes a time into, shaped like
+// unix.Timespec, which this platform lacks.
+type Timespec struct{
+  Sec          int64                    // Seconds since the epoch.
+	Nsec         int64                    // And nanoseconds, 0 to 999999999.
+}
+// Unix returns the seconds and nanoseconds, as unix.Timespec's does.
+func (ts *Timespec) Unix() (sec int64, nsec int64) { return ts.Sec,ts.Nsec }
+// Nano returns the time in nanoseconds since the epoch.
+func (ts *Timespec) Nano() int64 { return ts.Sec*1e9+ts.Nsec }
+
+// -------------------------- // nsecToTimespec // -------------------------- //
+// Convert nanoseconds since the epoch to a Timespec, rounding down as        //
+// unix.NsecToTimespec() does, so Nsec is never negative.                     //
+// -------------------------------------------------------------------------- //
+func nsecToTimespec(ns int64) Timespec{
+  sec,nsec:=ns/1e9,ns%1e9               // Split the seconds off.
+	if nsec<0{                            // Before the epoch?
+	  nsec+=1e9                           // Yes, borrow a second...
+		sec--                               // ...from the seconds.
+	}                                     // Done checking for negative time.
+	return Timespec{Sec: sec,Nsec: nsec}  // Return the Timespec.
+}                                       // --------- nsecToTimespec --------- //
+// ----------------------------- // fileOwner // ---------------------------- //
+// There are no Unix owners to keep here.                                     //
+// -------------------------------------------------------------------------- //
+func fileOwner(fi os.FileInfo) (uid,gid int,ok bool){
+  return -1,-1,false                    // We can't say.
+}                                       // ----------- fileOwner ------------ //
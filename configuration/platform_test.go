@@ -0,0 +1,32 @@
+package configuration
+
+import "testing"
+
+// TestNsecToTimespec runs against platform_unix.go or platform_other.go,
+// whichever this platform builds, so both must agree.
+func TestNsecToTimespec(t *testing.T) {
+	tests := []struct {
+		name string
+		ns   int64
+		sec  int64
+		nsec int64
+	}{
+		{"epoch", 0, 0, 0},
+		{"after", 1500000000, 1, 500000000},
+		{"whole seconds", 3e9, 3, 0},
+		{"before", -1, -1, 999999999},
+		{"before by more", -1500000000, -2, 500000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := nsecToTimespec(tt.ns)
+			sec, nsec := ts.Unix()
+			if sec != tt.sec || nsec != tt.nsec {
+				t.Errorf("nsecToTimespec(%d) = %d s %d ns, want %d s %d ns", tt.ns, sec, nsec, tt.sec, tt.nsec)
+			}
+			if ts.Nano() != tt.ns {
+				t.Errorf("Nano() = %d, want %d", ts.Nano(), tt.ns)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+//go:build unix
+// **************************************************************************
+// Filename:
+//  platform_unix.go
+//
+// Description:
+//  What the configuration package takes from a Unix-like system. Elsewhere
+//  platform_other.go stands in for it.
+//
+// ***************************************************************************
+package configuration
+import (
+		"os"
+		"syscall"
+		"golang.org/x/sys/unix"
+)
+
+// Timespec is what GetValueTimespec() decodes a time into. On Unix-like
+// systems it is unix.Timespec itself.
+type Timespec=unix.Timespec
+
+// -------------------------- // nsecToTimespec // -------------------------- //
+// Convert nanoseconds since the epoch to a Timespec.                         //
+// -------------------------------------------------------------------------- //
+func nsecToTimespec(ns int64) Timespec{
+  return unix.NsecToTimespec(ns)        // Let unix do it.
+}                                       // --------- nsecToTimespec --------- //
+// ----------------------------- // fileOwner // ---------------------------- //
+// Return the owner and group of a file, so WriteFile() can keep them.        //
+// -------------------------------------------------------------------------- //
+func fileOwner(fi os.FileInfo) (uid,gid int,ok bool){
+  st,ok:=fi.Sys().(*syscall.Stat_t)     // Do we know its owner?
+	if !ok{                               // No?
+	  return -1,-1,false                  // Then we can't say.
+	}                                     // Done checking for owner.
+	return int(st.Uid),int(st.Gid),true   // Return the owner.
+}                                       // ----------- fileOwner ------------ //
@@ -0,0 +1,57 @@
+//go:build unix
+
+package configuration
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestWriteFilePermissions(t *testing.T) {
+	old := syscall.Umask(0o022)
+	defer syscall.Umask(old)
+	tests := []struct {
+		name     string
+		existing os.FileMode // 0 for no file yet.
+		want     os.FileMode
+	}{
+		{"new file is private", 0, 0o600},
+		{"keeps 0600", 0o600, 0o600},
+		{"keeps 0640", 0o640, 0o640},
+		// Kept even though the umask would take the group and other write bits.
+		{"keeps 0666", 0o666, 0o666},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "t.cfg")
+			if tt.existing != 0 {
+				if err := os.WriteFile(path, []byte("[s]\nold=1\n"), tt.existing); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chmod(path, tt.existing); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfg := parse(t, "[s]\nsecret=hunter2\n")
+			if err := cfg.WriteFile(path); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fi.Mode().Perm(); got != tt.want {
+				t.Errorf("mode %v, want %v", got, tt.want)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != "[s]\nsecret=hunter2\n" {
+				t.Errorf("file holds %q", b)
+			}
+		})
+	}
+}
@@ -9,3 +9,8 @@ type Log interface {
 	ExitLog(msg string, args ...interface{})  // Exit log
 	Shutdown() error                          // Shutdown the logger
 }
+
+// Flusher is a Log that holds lines back and can be told to write them out.
+type Flusher interface {
+	Flush() error // Block until every line logged so far is written.
+}
@@ -0,0 +1,163 @@
+/****************************************************************
+* filename:
+*  async.go
+* Description:
+*  An asynchronous front for the Logger: lines are queued on a
+*  channel and written by a goroutine of their own, so the caller
+*  never waits for the semaphore, the disk or the extra sinks.
+* Author:
+*  JEP  J.Enrique Peraza
+***************************************************************/
+
+package logger
+
+import (
+	"fmt"
+	"sync"
+)
+
+// asyncLine is one log call waiting on the queue of an AsyncLogger.
+type asyncLine struct {
+	level    LogLevel // The level it was logged at.
+	funcname string   // Who logged it, taken when it was logged.
+	msg      string   // The formatted message.
+}
+
+// ------------------------------------ //
+// AsyncLogger queues log lines on a channel and writes them to a Logger from
+// a goroutine of its own. When the queue is full the caller waits for room,
+// so lines are never dropped. Lines still on the queue are lost if the
+// process exits before Flush or Shutdown; utils.SetLogger takes care of that
+// on SIGINT/SIGTERM.
+// ------------------------------------ //
+type AsyncLogger struct {
+	l       *Logger        // The logger the lines are written to.
+	lines   chan asyncLine // The queue of lines to write.
+	quit    chan struct{}  // Closed to stop the writer.
+	mu      sync.Mutex     // Protects pending and closed.
+	drained *sync.Cond     // Signalled when pending drops to zero.
+	pending int            // Lines queued but not yet written.
+	closed  bool           // Shutdown was called, log synchronously.
+}
+
+// ------------------------------------- //
+// NewAsyncLogger starts an AsyncLogger that writes to l, with room for n
+// lines on its queue.
+// -------------------------------------- //
+func NewAsyncLogger(l *Logger, n int) *AsyncLogger { // -------- NewAsyncLogger -------- //
+	a := &AsyncLogger{ // Our new async logger.
+		l:     l,                         // Where the lines go.
+		lines: make(chan asyncLine, n),   // The queue.
+		quit:  make(chan struct{}),       // Not stopped yet.
+	} // Done making the async logger.
+	a.drained = sync.NewCond(&a.mu) // Flush waits on it.
+	go a.write()                    // Start the writer.
+	return a                        // Return the async logger.
+} // -------- NewAsyncLogger -------- //
+
+// ------------------------------------ //
+// write takes lines off the queue and logs them, until Shutdown stops it.
+// ------------------------------------ //
+func (a *AsyncLogger) write() { // ------------- write -------------- //
+	for { // Until we are told to stop...
+		select { // Wait for a line or for the stop.
+		case line := <-a.lines: // A line to write.
+			a.l.logFrom(line.level, line.funcname, line.msg) // Write it.
+			a.mu.Lock()     // Lock the mutex to protect the count.
+			a.pending--     // One less line to wait for.
+			if a.pending == 0 { // Is the queue empty?
+				a.drained.Broadcast() // Yes, wake up Flush.
+			} // Done checking for an empty queue.
+			a.mu.Unlock() // Unlock the mutex when done.
+		case <-a.quit: // Shutdown has drained the queue.
+			return // So we are done.
+		} // Done waiting.
+	} // Done writing lines.
+} // ------------- write -------------- //
+
+// ------------------------------------ //
+// enqueue puts a line on the queue, after Shutdown it is written right away.
+// It must be called straight from Deb, Inf... so getFuncName finds their
+// caller.
+// ------------------------------------ //
+func (a *AsyncLogger) enqueue(level LogLevel, msg string) { // ------------ enqueue ------------ //
+	funcname := getFuncName() // Who is logging, before we lose them.
+	a.mu.Lock()               // Lock the mutex to protect the count.
+	if a.closed {             // Has the writer stopped?
+		a.mu.Unlock()                    // Yes, unlock the mutex,
+		a.l.logFrom(level, funcname, msg) // and write the line ourselves.
+		return                           // Done.
+	} // Done checking for a stopped writer.
+	a.pending++   // One more line to wait for.
+	a.mu.Unlock() // Unlock the mutex, the writer needs it.
+	a.lines <- asyncLine{level, funcname, msg} // Queue it, maybe waiting for room.
+} // ------------ enqueue ------------ //
+
+// ------------------------------------ //
+// Flush blocks until every line queued so far is written and the channel is
+// empty, then syncs the log file with Logger.Flush. Lines logged while it
+// waits are waited for too.
+// ------------------------------------ //
+func (a *AsyncLogger) Flush() error { // ------------- Flush -------------- //
+	a.mu.Lock()           // Lock the mutex to protect the count.
+	for a.pending > 0 {   // Are lines still queued?
+		a.drained.Wait() // Yes, wait for the writer.
+	} // Done waiting for the queue.
+	a.mu.Unlock()     // Unlock the mutex when done.
+	return a.l.Flush() // Sync what was written.
+} // ------------- Flush -------------- //
+
+// ------------------------------------ //
+// Shutdown flushes the queue, stops the writer and shuts down the Logger.
+// Lines logged afterwards are written synchronously.
+// ------------------------------------ //
+func (a *AsyncLogger) Shutdown() error { // ----------- Shutdown ------------- //
+	a.mu.Lock()     // Lock the mutex to protect the flag.
+	if a.closed {   // Did we already stop?
+		a.mu.Unlock()        // Yes, unlock the mutex,
+		return a.l.Shutdown() // and just pass it on.
+	} // Done checking for a stopped writer.
+	a.closed = true     // No more lines go on the queue.
+	for a.pending > 0 { // Are lines still queued?
+		a.drained.Wait() // Yes, wait for the writer.
+	} // Done waiting for the queue.
+	a.mu.Unlock()         // Unlock the mutex when done.
+	close(a.quit)         // Stop the writer.
+	return a.l.Shutdown() // Shut down the logger.
+} // ----------- Shutdown ------------- //
+
+// ExitLog writes out the queue, then closes the log files like Logger.ExitLog.
+func (a *AsyncLogger) ExitLog(format string, args ...interface{}) {
+	a.Flush()                       // Queued lines go to the old file.
+	a.l.ExitLog(format, args...)    // Then close it.
+} // ------------- ExitLog ------------ //
+
+// Deb queues a debug message
+func (a *AsyncLogger) Deb(format string, args ...interface{}) bool {
+	a.enqueue(Debug, fmt.Sprintf(format, args...))
+	return true
+}
+
+// Inf queues an info message
+func (a *AsyncLogger) Inf(format string, args ...interface{}) bool {
+	a.enqueue(Info, fmt.Sprintf(format, args...))
+	return true
+}
+
+// War queues a warning message
+func (a *AsyncLogger) War(format string, args ...interface{}) bool {
+	a.enqueue(Warning, fmt.Sprintf(format, args...))
+	return true
+}
+
+// Err queues an error message
+func (a *AsyncLogger) Err(format string, args ...interface{}) bool {
+	a.enqueue(Error, fmt.Sprintf(format, args...))
+	return false
+}
+
+// Fat queues a fatal message
+func (a *AsyncLogger) Fat(format string, args ...interface{}) bool {
+	a.enqueue(Fatal, fmt.Sprintf(format, args...))
+	return false
+}
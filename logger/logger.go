@@ -12,7 +12,9 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -117,6 +119,13 @@ type Logger struct {
 	Level  LogLevel   // Log level
 	Symbol string     // Annunciatior to indicate level.
 	init   bool       // Flag to indicate if logger was init.
+	syncOn       bool          // Sync the log file to disk after writing?
+	syncEvery    int           // Sync after this many writes (<=1: every write).
+	syncInterval time.Duration // ... or once this much time has passed.
+	unsynced     int           // Writes since the last sync.
+	lastSync     time.Time     // When we last synced.
+	outputs      []io.Writer   // Extra sinks that get every log line.
+	metrics      func(level LogLevel) // Called for every line logged, nil if none.
 }
 
 // ------------------------------------- //
@@ -130,6 +139,7 @@ func NewLogger() (*Logger, error) { // ------------ NewLogger ----------- //
 		key:    0x7003,       // Set the semaphore key
 		mu:     sync.Mutex{}, // Initialize the mutex
 		init:   false,        // Set the init flag to false.
+		syncOn: true,         // Sync every line unless told otherwise.
 	} // Return the logger instance
 	if err := l.Initialize(); err != nil { // Error initializing the logger?
 		return nil, err // Yes, return nil object and the error.
@@ -300,6 +310,7 @@ func (l *Logger) ExitLog(format string, args ...interface{}) {
 			fmt.Fprintf(fpl, "%s %s Closing all log files.\n",
 				time.Now().Format(time.RFC3339Nano), getAppname())
 		} // Done with no reason why.
+		fpl.Sync() // Don't leave lines the sync policy held back.
 		fpl.Close()
 		fpl = nil // Close the log file.
 	} // Done closing the log file.
@@ -337,25 +348,162 @@ func (l *Logger) writeToFile(file, msg string) error {
 		if err != nil {                // Error writing to the file?
 			return fmt.Errorf("writetofile(%q): %w", file, err)
 		} // Otherwise, continue.
-		fpl.Sync() // Sync the file to ensure all data is written
+		l.syncLog() // Sync the file if our policy says so.
 	} else { // Open the error file in append mode, create it if it doesn't exist
 		openErrorfile()                // Open the error file.
 		_, err := fpe.WriteString(msg) // Write the log message to the file
 		if err != nil {                // Error writing to the file?
 			return fmt.Errorf("writetofile(%q): %w", file, err)
 		} // Else, continue
-		fpe.Sync() // Sync the file to ensure all data is written
+		if l.syncOn { // Are we syncing at all?
+			fpe.Sync() // Yes, errors are synced right away, whatever the policy.
+		} // Done syncing the error file.
 	} // Done checking which file to write to.
 	return nil // Return nil error if successfull.
 } // ---------writeToFile-------- //
 
+// ------------------------------------ //
+// SetSync turns syncing the log and error files to disk on or off. It is
+// on by default, so that a crash can't lose the last lines still sitting in
+// the OS page cache, which matters for audit logs. The price is an fsync(2)
+// per line, which can cut throughput by orders of magnitude on slow disks;
+// SetSyncPolicy trades some of that durability back for speed.
+// ------------------------------------ //
+func (l *Logger) SetSync(on bool) { // ------------ SetSync ------------- //
+	l.mu.Lock()         // Lock the mutex to protect the policy.
+	defer l.mu.Unlock() // Unlock the mutex when done.
+	l.syncOn = on       // Remember whether to sync.
+	l.unsynced = 0      // Start counting again.
+	l.lastSync = time.Now()
+} // ------------ SetSync ------------- //
+// ------------------------------------ //
+// SetSyncPolicy batches syncs of the log file: it is synced once every n
+// writes, or once d has passed since the last sync, whichever comes first.
+// n<=1 and d==0 syncs every write. Lines written since the last sync can be
+// lost in a crash. Lines that go to the error file are not batched: they are
+// synced on every write, unless SetSync(false) turned syncing off.
+// ------------------------------------ //
+func (l *Logger) SetSyncPolicy(n int, d time.Duration) { // ----- SetSyncPolicy ----- //
+	l.mu.Lock()         // Lock the mutex to protect the policy.
+	defer l.mu.Unlock() // Unlock the mutex when done.
+	l.syncEvery = n     // Sync every n writes,
+	l.syncInterval = d  // or every d.
+	l.unsynced = 0      // Start counting again.
+	l.lastSync = time.Now()
+} // ----- SetSyncPolicy ----- //
+// ------------------------------------ //
+// syncLog syncs the log file if the sync policy says it is time to. It is
+// called with the mutex held.
+// ------------------------------------ //
+func (l *Logger) syncLog() { // ------------ syncLog ------------- //
+	if !l.syncOn || fpl == nil { // Are we syncing, and is there a file?
+		return // No, nothing to do.
+	} // Done checking if we sync.
+	l.unsynced++ // One more write not on disk.
+	due := l.syncEvery <= 1 && l.syncInterval == 0 // Every write?
+	if l.syncEvery > 1 && l.unsynced >= l.syncEvery { // Enough writes?
+		due = true // Yes, time to sync.
+	} // Done checking the write count.
+	if l.syncInterval > 0 && time.Since(l.lastSync) >= l.syncInterval { // Long enough?
+		due = true // Yes, time to sync.
+	} // Done checking the interval.
+	if due { // Is it time to sync?
+		fpl.Sync()              // Yes, flush the page cache to disk.
+		l.unsynced = 0          // Nothing is pending now.
+		l.lastSync = time.Now() // Remember when we synced.
+	} // Done syncing.
+} // ------------ syncLog ------------- //
+
+// ------------------------------------ //
+// Flush syncs the log file to disk now, whatever the sync policy says, so
+// lines held back by SetSync(false) or SetSyncPolicy are not lost. It blocks
+// until the sync is done. utils.SetLogger arranges for it to be called when
+// the shutdown callbacks have run. AsyncLogger.Flush calls it once its queue
+// is empty.
+// ------------------------------------ //
+func (l *Logger) Flush() error { // ------------- Flush -------------- //
+	l.mu.Lock()         // Lock the mutex to protect the policy.
+	defer l.mu.Unlock() // Unlock the mutex when done.
+	if fpl == nil {     // Is the log file open?
+		return nil // No, nothing to flush.
+	} // Done checking for the log file.
+	l.unsynced = 0          // Nothing will be pending.
+	l.lastSync = time.Now() // Remember when we synced.
+	return fpl.Sync()       // Flush the page cache to disk.
+} // ------------- Flush -------------- //
+
+// ------------------------------------ //
+// AddOutput adds a sink, e.g. os.Stdout or a connection to a remote collector,
+// that receives every line logged, in the order it goes to the log file. It
+// gets the lines even when the log file can't be written, as when the
+// semaphore could not be made and the messages only go to stderr.
+// ------------------------------------ //
+func (l *Logger) AddOutput(w io.Writer) { // ----------- AddOutput ------------ //
+	l.mu.Lock()                        // Lock the mutex to protect the sinks.
+	defer l.mu.Unlock()                // Unlock the mutex when done.
+	l.outputs = append(l.outputs, w)   // Add the sink.
+} // ----------- AddOutput ------------ //
+// ------------------------------------ //
+// SetOutputs replaces all of the extra sinks; with no arguments, log lines
+// only go to the log file again.
+// ------------------------------------ //
+func (l *Logger) SetOutputs(ws ...io.Writer) { // ---------- SetOutputs ----------- //
+	l.mu.Lock()                             // Lock the mutex to protect the sinks.
+	defer l.mu.Unlock()                     // Unlock the mutex when done.
+	l.outputs = append([]io.Writer(nil), ws...) // Replace the sinks.
+} // ---------- SetOutputs ----------- //
+// ------------------------------------ //
+// SetMetricsHook sets a function that is called once for every line that is
+// logged, i.e. not filtered out by the log level, with the line's level, so
+// the application can count lines by level, e.g. for Prometheus. It counts
+// the lines the sinks of AddOutput get, one call per line of a multiline
+// message, whether or not the log file could be written. The hook is
+// called with the logger's mutex held, so it must be cheap and must not log;
+// incrementing a counter is fine. nil removes the hook.
+// ------------------------------------ //
+func (l *Logger) SetMetricsHook(hook func(level LogLevel)) { // -- SetMetricsHook -- //
+	l.mu.Lock()         // Lock the mutex to protect the hook.
+	defer l.mu.Unlock() // Unlock the mutex when done.
+	l.metrics = hook    // Remember the hook.
+} // -- SetMetricsHook -- //
+// ------------------------------------ //
+// fanOut writes a log line to the extra sinks, and records in the error file
+// any sink that failed to take it.
+// ------------------------------------ //
+func (l *Logger) fanOut(msg string) { // ------------ fanOut ------------- //
+	if err := l.writeOutputs(msg); err != nil { // Did any sink fail?
+		l.writeToFile(errpathname, fmt.Sprintf("%s: %s: %v\n",
+			time.Now().Format(time.RFC3339), getAppname(), err)) // Yes, say so.
+	} // Done checking for failed sinks.
+} // ------------ fanOut ------------- //
+// ------------------------------------ //
+// writeOutputs writes a log line to every extra sink. A sink that fails does
+// not keep the others from getting the line; the failures are collected and
+// returned together. It is called with the mutex held, so lines from
+// different goroutines never interleave within a sink.
+// ------------------------------------ //
+func (l *Logger) writeOutputs(msg string) error { // --------- writeOutputs --------- //
+	var errs []error               // The errors from failing sinks.
+	for i, w := range l.outputs {  // For each sink...
+		if _, err := io.WriteString(w, msg); err != nil { // Could we write to it?
+			errs = append(errs, fmt.Errorf("log output %d: %w", i, err)) // No, remember why.
+		} // Done checking for error.
+	} // Done writing to the sinks.
+	return errors.Join(errs...) // Return the failures, if any.
+} // --------- writeOutputs --------- //
+
 // logMessage is the internal log function that facilitates writing logs
 // to the specified text file.
 func (l *Logger) logMessage(level LogLevel, msg string) {
+  l.logFrom(level,getFuncName(),msg)    // Get the function name, from our caller's caller.
+}                                       // ---------logMessage-------- //
+
+// logFrom logs msg as said by funcname. AsyncLogger calls it from its own
+// goroutine, with the name it took when the line was logged.
+func (l *Logger) logFrom(level LogLevel, funcname, msg string) {
   if sem==nil{                          // Is the semaphore initialized?
     fmt.Fprintf(os.Stderr,"%s\n",msg)   // No, write the message to stderr.
-    return                              // Return if semaphore is not initialized.
-  }                                     // Otherwise, continue.
+  }                                     // The sinks still get the message.
   if level < l.Level {                  // Log level less than current level?
 	  return                              // If so, return without logging.
 	}                                     // Otherwise, continue.
@@ -372,26 +520,84 @@ func (l *Logger) logMessage(level LogLevel, msg string) {
       l.Symbol = "@ "                   // Set symbol to !!
   }                                     // Done setting the symbol
   // ---------------------------------- //
-  // If the message in the buffer is a multiline message we will purge that
-  // buffer and set a recursive entrypoint so that it enters the log
-  // with a clean and nice buffer. 
+  // If the message in the buffer is a multiline message we log it line by
+  // line, so that each enters the log with a clean and nice buffer.
   // ---------------------------------- //
-  if strings.Contains(msg,"\n"){        // Does the buffer container a newline?
-    for _,line:=range strings.Split(msg,"\n"){ // Yes split them by line & purge.
-      if line!=""{                      // Is the purged message not empty?
-        l.logMessage(level,line)        // Log that message without the newline.
-      } else {                          // Otherwise...
-        continue                        // Skip the empty line.
-      }                                 // Done checking the line.
-    }                                   // Done splitting the message.
-    return                              // Return if we had to purge a message.
-  }                                     // Otherwise no newline so just fall through.
+  for _,line:=range strings.Split(msg,"\n"){ // Split them by line & purge.
+    if line!=""{                        // Is the purged message not empty?
+      l.logLine(level,funcname,line)    // Log that message without the newline.
+    }                                   // Done checking the line.
+  }                                     // Done splitting the message.
+}                                       // ----------logFrom---------- //
+
+// logLine writes one line of a log message to the log file, and to the error
+// file too if it is an Error or Fatal one, then to the extra sinks, and counts
+// it with the metrics hook. The sinks and the hook get the line even when the
+// files can't be written, because the semaphore could not be made or the
+// files are not there.
+func (l *Logger) logLine(level LogLevel, funcname, msg string) {
   // ---------------------------------- //
   // Lock the mutex so that you have a goroutine-local lock
   // and unlock it when done.
   // ---------------------------------- //
   l.mu.Lock()                           // Lock the mutex to protect the log file
   defer l.mu.Unlock()                   // Unlock the mutex after writing
+  outs:=l.formatLine(funcname,msg)      // The line, chopped to fit the log.
+  if sem!=nil{                          // Can we write to the log files?
+    l.writeLogFiles(level,outs)         // Yes, write it there.
+  }                                     // Done writing the files.
+  for _,out:=range outs{                // For each piece of the line...
+    l.fanOut(out)                       // ...send it to any extra sinks.
+  }                                     // Done with the sinks.
+  if len(outs)>0&&l.metrics!=nil{       // Is anyone counting lines?
+    l.metrics(level)                    // Yes, count this one.
+  }                                     // Done counting.
+}                                       // ---------- logLine ---------- //
+
+// formatLine puts the header on a line of a log message and chops it into
+// pieces of at most maxcol runes, the pieces after the first indented to
+// line up with the first one's body.
+func (l *Logger) formatLine(funcname, msg string) []string {
+  maxcol:=168                           // Maximum column size of the log message.
+  timestamp:=time.Now().Format(time.RFC3339) // Get the current timestamp
+  filename:=getAppname()               // Get the file name
+  hdr:=fmt.Sprintf("%s: %s: %s: %s", timestamp, filename, funcname, l.Symbol) // Create the header
+  hRunes:=[]rune(hdr)                   // Convert header to slice of runes.
+  // ---------------------------------- //
+  // Calculate the space for one separator and the body start.
+  // ---------------------------------- //
+  bWidth:=maxcol-len(hRunes)-1          // Calculate the message body's width
+  indent:=strings.Repeat(" ",len(hRunes)+1)// Create the indent
+  // ---------------------------------- //
+  // Chop the message into chunks of bodyWidth characters (runes).
+  // ---------------------------------- //
+  var outs []string                     // The pieces of the line.
+  bRunes:=[]rune(msg)                   // Convert message to slice of runes.
+  line:=""                              // Line to chup up rune slice.
+  for len(bRunes)>0{                    // While there are things to write!
+    if len(bRunes)>bWidth{              // Is the msg larger than bodyWidth?
+      line=string(bRunes[:bWidth])      // Yes get the first bodyWidth runes.
+      bRunes=bRunes[bWidth:]            // Remember we removed the first bodyWidth runes.
+    } else {                            // Else the msg is smaller than bodyWidth.
+      line=string(bRunes)               // Get the runes without chopping them.
+      bRunes=nil                        // Remember we removed the remaining runes.
+    }                                   // Done checking the length of the runes.
+    if outs==nil{                       // Is this the first line of the msg?
+      outs=append(outs,hdr+" "+line+"\n")// The prefix with the header.
+    } else {                            // Else this is not the first line.
+    // -------------------------------- //
+    // So now we have a line that must have a prefix and a body and is greater
+    // than 168 runes (characters) long so it might have to be indented.
+    // -------------------------------- //
+      outs=append(outs,indent+line+"\n")// The prefix with the body.
+    }                                   // Done with long line.
+  }                                     // Done with while we have to write.
+  return outs                           // Return the pieces.
+}                                       // -------- formatLine --------- //
+
+// writeLogFiles writes the pieces of a log line to the log file, and to the
+// error file for Error and Fatal lines. It is called with the mutex held.
+func (l *Logger) writeLogFiles(level LogLevel, outs []string) {
   // ---------------------------------- //
   // Lock the semaphore to ensure only one process can write to the file at a time
   // and unlock it when done.
@@ -423,52 +629,13 @@ func (l *Logger) logMessage(level LogLevel, msg string) {
   // ---------------------------------- //
 	// Write the log message to the file
 	// ---------------------------------- //
-  maxcol:=168                           // Maximum column size of the log message.
-  timestamp:=time.Now().Format(time.RFC3339) // Get the current timestamp
-  filename:=getAppname()               // Get the file name
-  funcname:=getFuncName()               // Get the function name
-  hdr:=fmt.Sprintf("%s: %s: %s: %s", timestamp, filename, funcname, l.Symbol) // Create the header
-  hRunes:=[]rune(hdr)                   // Convert header to slice of runes.
-  // ---------------------------------- //
-  // Calculate the space for one separator and the body start.
-  // ---------------------------------- //
-  bWidth:=maxcol-len(hRunes)-1          // Calculate the message body's width
-  indent:=strings.Repeat(" ",len(hRunes)+1)// Create the indent
-  // ---------------------------------- //
-  // Chop the message into chunks of bodyWidth characters (runes)
-  // then write them to the logfile.
-  // ---------------------------------- //
-  bRunes:=[]rune(msg)                   // Convert message to slice of runes.
-  first:=true                           // Flag for first line of message.
-  line:=""                              // Line to chup up rune slice.
-  for len(bRunes)>0{                    // While there are things to write!
-    if len(bRunes)>bWidth{              // Is the msg larger than bodyWidth?
-      line=string(bRunes[:bWidth])      // Yes get the first bodyWidth runes.
-      bRunes=bRunes[bWidth:]            // Remember we removed the first bodyWidth runes.
-    } else {                            // Else the msg is smaller than bodyWidth.
-      line=string(bRunes)               // Get the runes without chopping them.
-      bRunes=nil                        // Remember we removed the remaining runes.
-    }                                   // Done checking the length of the runes.
-    if first{                           // Is this the first line of the msg?
-      out:=hdr+" "+line+"\n"            // The prefix with the header.
-      l.writeToFile(logpathname,out)    // Write the log message to the file.
-      if level >= Error{                // If the log level is Error or Fatal.
-        l.writeToFile(errpathname,out)  // Write the log message to the error file.
-      }                                 // Done checking which file(s) to write to.
-      first=false                       // Remember we wrote the first line.
-    } else {                            // Else this is not the first line.
-    // -------------------------------- //
-    // So now we have a line that must have a prefix and a body and is greater
-    // than 168 runes (characters) long so it might have to be indented.
-    // -------------------------------- //
-      out:=indent+line+"\n"             // The prefix with the body.
-      l.writeToFile(logpathname,out)    // Write the log message to the file.
-      if level >= Error{                // If the log level is Error or Fatal.
-        l.writeToFile(errpathname,out)  // Write the log message to the error file.
-      }                                 // Done writing to the file(s).
-    }                                   // Done with long line. 
-  }                                     // Done with while we have to write.
-}                                       // ---------logMessage-------- //
+  for _,out:=range outs{                // For each piece of the line...
+    l.writeToFile(logpathname,out)      // Write the log message to the file.
+    if level >= Error{                  // If the log level is Error or Fatal.
+      l.writeToFile(errpathname,out)    // Write the log message to the error file.
+    }                                   // Done checking which file(s) to write to.
+  }                                     // Done writing the pieces.
+}                                       // ------- writeLogFiles ------- //
 
 // Deb logs a debug message
 func (l *Logger) Deb(format string, args ...interface{}) bool {
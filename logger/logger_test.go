@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	semaphore "github.com/perazaharmonics/project_name/internal/semaphore"
+)
+
+// testLogger points the log and error files at a temporary directory and
+// makes a semaphore of its own for them, so the test leaves nothing behind.
+func testLogger(t *testing.T) *Logger {
+	t.Helper()
+	dir := t.TempDir()
+	logdirname = dir
+	logpathname = filepath.Join(dir, logFilename)
+	errpathname = filepath.Join(dir, errFilename)
+	for _, path := range []string{logpathname, errpathname} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := 0x7100 + os.Getpid()%0x1000
+	s, err := semaphore.NewSemaphore("logger_test", "log", "", key)
+	if err != nil {
+		t.Skipf("no System V semaphores here: %v", err)
+	}
+	sem = s
+	t.Cleanup(func() {
+		sem.Remove()
+		sem = nil
+		for _, f := range []**os.File{&fpl, &fpe} {
+			if *f != nil {
+				(*f).Close()
+				*f = nil
+			}
+		}
+	})
+	return &Logger{key: key, syncOn: true}
+}
+
+// readLog reads the log file through a file of its own, as another process
+// would.
+func readLog(t *testing.T) string {
+	t.Helper()
+	b, err := os.ReadFile(logpathname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestSyncPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		setup        func(l *Logger)
+		writes       int
+		wantUnsynced int
+	}{
+		{"every write by default", func(l *Logger) {}, 3, 0},
+		{"every third write, 2 written", func(l *Logger) { l.SetSyncPolicy(3, 0) }, 2, 2},
+		{"every third write, 3 written", func(l *Logger) { l.SetSyncPolicy(3, 0) }, 3, 0},
+		{"every third write, 4 written", func(l *Logger) { l.SetSyncPolicy(3, 0) }, 4, 1},
+		{"every hour", func(l *Logger) { l.SetSyncPolicy(0, time.Hour) }, 5, 5},
+		{"interval passed", func(l *Logger) { l.SetSyncPolicy(100, time.Nanosecond) }, 2, 0},
+		{"off", func(l *Logger) { l.SetSync(false) }, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testLogger(t)
+			tt.setup(l)
+			for i := 0; i < tt.writes; i++ {
+				l.Inf("line %d", i)
+			}
+			if l.unsynced != tt.wantUnsynced {
+				t.Errorf("%d writes not synced, want %d", l.unsynced, tt.wantUnsynced)
+			}
+			// Synced or not, the lines are there for anybody reading the file.
+			log := readLog(t)
+			for i := 0; i < tt.writes; i++ {
+				if !strings.Contains(log, fmt.Sprintf("line %d\n", i)) {
+					t.Errorf("line %d not in the log file:\n%s", i, log)
+				}
+			}
+			if err := l.Flush(); err != nil {
+				t.Errorf("Flush: %v", err)
+			}
+			if l.unsynced != 0 {
+				t.Errorf("%d writes not synced after Flush", l.unsynced)
+			}
+		})
+	}
+}
+
+// failWriter is a sink that refuses every line.
+type failWriter struct{}
+
+func (failWriter) Write(b []byte) (int, error) { return 0, errors.New("sink is down") }
+
+func TestOutputs(t *testing.T) {
+	l := testLogger(t)
+	var a, b bytes.Buffer
+	l.SetOutputs(&a, failWriter{})
+	l.AddOutput(&b)
+	l.Inf("first")
+	l.Err("second\nthird")
+	l.Level = Info
+	l.Deb("filtered out")
+
+	want := readLog(t)
+	if n := strings.Count(want, "\n"); n != 3 {
+		t.Fatalf("log file holds %d lines, want 3:\n%s", n, want)
+	}
+	for name, got := range map[string]string{"first sink": a.String(), "second sink": b.String()} {
+		if got != want {
+			t.Errorf("%s got\n%s\nthe log file\n%s", name, got, want)
+		}
+	}
+	errs, err := os.ReadFile(errpathname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(errs), "log output 1: sink is down"); n != 3 {
+		t.Errorf("error file reports the failing sink %d times, want 3:\n%s", n, errs)
+	}
+
+	l.SetOutputs()
+	l.Inf("only the file")
+	if strings.Contains(a.String(), "only the file") || strings.Contains(b.String(), "only the file") {
+		t.Error("SetOutputs() with no sinks still wrote to the old ones")
+	}
+}
+
+func TestMetricsHook(t *testing.T) {
+	tests := []struct {
+		name  string
+		level LogLevel
+		log   func(l *Logger)
+		want  map[LogLevel]int
+	}{
+		{"by level", Debug, func(l *Logger) {
+			l.Inf("a")
+			l.Inf("b")
+			l.Err("c")
+			l.War("d")
+			l.Deb("e")
+		}, map[LogLevel]int{Info: 2, Error: 1, Warning: 1, Debug: 1}},
+		{"filtered lines not counted", Warning, func(l *Logger) {
+			l.Inf("a")
+			l.Deb("b")
+			l.Err("c")
+		}, map[LogLevel]int{Error: 1}},
+		{"one per line of a message", Debug, func(l *Logger) {
+			l.Err("a\nb")
+		}, map[LogLevel]int{Error: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testLogger(t)
+			l.Level = tt.level
+			got := map[LogLevel]int{}
+			l.SetMetricsHook(func(level LogLevel) { got[level]++ })
+			tt.log(l)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("hook counted %v, want %v", got, tt.want)
+			}
+			l.SetMetricsHook(nil)
+			l.Inf("after removing the hook")
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("removed hook still counted: %v", got)
+			}
+		})
+	}
+}
+
+// slowWriter is a sink that takes its time over every line, so lines pile up
+// on an AsyncLogger's queue.
+type slowWriter struct {
+	bytes.Buffer
+}
+
+func (w *slowWriter) Write(b []byte) (int, error) {
+	time.Sleep(2 * time.Millisecond)
+	return w.Buffer.Write(b)
+}
+
+func TestAsyncLogger(t *testing.T) {
+	l := testLogger(t)
+	var sink slowWriter
+	l.AddOutput(&sink)
+	a := NewAsyncLogger(l, 4)
+	for i := 0; i < 20; i++ {
+		a.Inf("line %d", i)
+	}
+	a.Err("last")
+	if err := a.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	log := readLog(t)
+	if log != sink.String() {
+		t.Errorf("sink got\n%s\nthe log file\n%s", sink.String(), log)
+	}
+	lines := strings.Split(strings.TrimSuffix(log, "\n"), "\n")
+	if len(lines) != 21 {
+		t.Fatalf("log file holds %d lines after Flush, want 21:\n%s", len(lines), log)
+	}
+	for i, line := range lines[:20] {
+		if !strings.HasSuffix(line, fmt.Sprintf("line %d", i)) {
+			t.Errorf("line %d is %q", i, line)
+		}
+	}
+	if !strings.Contains(lines[20], ": TestAsyncLogger: ! ") {
+		t.Errorf("last line %q does not name its caller and level", lines[20])
+	}
+}
@@ -7,15 +7,37 @@
 package pipe
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
 )
 
+// POPENGRACE is how long CloseTimeout waits after SIGTERM before it SIGKILLs.
+const POPENGRACE=500*time.Millisecond
+// PROGRESSINTERVAL is how often ReaderWithProgress reports unless told otherwise.
+const PROGRESSINTERVAL=100*time.Millisecond
+
 type Pipes struct {
-  rf   *os.File // Read end of the pipe
-  wf   *os.File // Write end of the pipe
-  rfd  int      // Read file descriptor
-  wfd  int      // Write file descriptor
-  flgs int      // Flags for pipe2
+  rf       *os.File     // Read end of the pipe
+  wf       *os.File     // Write end of the pipe
+  rfd      int          // Read file descriptor
+  wfd      int          // Write file descriptor
+  flgs     int          // Flags for pipe2
+  nread    atomic.Int64 // Bytes read through Read
+  nwritten atomic.Int64 // Bytes written through Write
+  br       *bufio.Reader // Read-ahead buffer, created by Peek
+  every    time.Duration // Least time between progress callbacks
 }
 
 // NewAnonymousPipe is like os.Pipe(), but uses our shim under the hood.
@@ -124,7 +146,14 @@ func (p *Pipes) Read(b []byte) (int, error) {
   if p.rf == nil {                      // Is the read end of the pipe nil?
     return 0, os.ErrInvalid             // Yes, return 0 and error
   }	                                // Done checking if the read end of the pipe is nil.
-  n, err := p.rf.Read(b)                // Read from the pipe
+  var n int                             // The number of bytes read.
+  var err error                         // Any error reading.
+  if p.br != nil {                      // Did Peek buffer some input?
+    n, err = p.br.Read(b)               // Yes, drain the buffer first.
+  } else {                              // Else nothing is buffered.
+    n, err = p.rf.Read(b)               // Read from the pipe
+  }                                     // Done choosing where to read from.
+  p.nread.Add(int64(n))                 // Count what we got, even on error.
   return n, err                         // No error, return the number of bytes read and nil.
 }                                       // ------------ Read ----------------- //
 // Write() writes to the pipe and returns the number of bytes written.
@@ -133,30 +162,63 @@ func (p *Pipes) Write(b []byte) (int, error) {
 	return 0,os.ErrInvalid          // Yes, return 0 and error
   }                                     // Done checking if the write end of the pipe is nil.
   n,err:=p.wf.Write(b)                  // Write to the pipe
+  p.nwritten.Add(int64(n))              // Count what went in, even on error.
   return n,err                          // No error, return the number of bytes written and nil.
 }                                       // ------------ Write ---------------- //
+// WriteString writes all of s to the pipe, or fails trying. A string bigger
+// than the pipe's capacity takes several writes while the reader drains it;
+// WriteString keeps writing until it is all in. A non-blocking write end
+// (NewPipe2 with O_NONBLOCK) needs no special care: its os.File waits in the
+// runtime poller for the pipe to become writable, so EAGAIN never gets here.
+// It returns len(s) and nil, or how much was written before the error. This
+// makes Pipes an io.StringWriter.
+func (p *Pipes) WriteString(s string) (int, error) {
+  if p.wf == nil {                      // Is the write end of the pipe nil?
+    return 0, os.ErrInvalid             // Yes, return 0 and error.
+  }                                     // Done checking the write end.
+  b := []byte(s)                        // What we have to write.
+  total := 0                            // How much of it is in the pipe.
+  for total < len(b) {                  // Until it is all written...
+    n, err := p.Write(b[total:])        // Write what is left.
+    total += n                          // Count what went in.
+    switch {                            // Act according to how it went.
+      case err != nil:                  // Did it fail?
+        return total, err               // Yes, return why.
+      case n == 0:                      // No progress and no reason why?
+        return total, io.ErrShortWrite  // Give up rather than spin.
+    }                                   // Done acting on the write.
+  }                                     // Done writing.
+  return total, nil                     // It is all in the pipe.
+}                                       // ------------ WriteString ---------- //
+// Stats returns how many bytes have gone through the pipe's Read and Write
+// methods, including LineScanner's reads. It is safe to call from any
+// goroutine. Bytes moved through GetReadEnd, GetWriteEnd or the raw fds
+// bypass these methods and are not counted.
+func (p *Pipes) Stats() (bytesWritten, bytesRead int64) {
+  return p.nwritten.Load(), p.nread.Load() // Snapshot both counters.
+}                                       // ------------ Stats ---------------- //
 
-// Close closes the read and write files associated with the pipe by being given
-// the read or write file descriptor.
+// Close closes whichever ends of the pipe are still open. Ends already closed
+// by CloseRead or CloseWrite are skipped, so Close is safe to defer and to call
+// twice; it never touches a descriptor number the OS may since have reused.
 func (p *Pipes) Close() error {
-  if err:=p.rf.Close();err!=nil{            // Did we error closing the read end of the pipe?
-    _=p.wf.Close()                          // Yes, close the write end of the pipe.
-    return err                              // Return the error closing the read end of the pipe.
-    }                                       // Done closing the read end of the pipe.
-  return p.wf.Close()                       // Close the write end of the pipe.
-}                                           // ------------ Close --------------- //
+  rerr:=p.CloseRead()                   // Close the read end, if still open.
+  werr:=p.CloseWrite()                  // Close the write end, if still open.
+  return errors.Join(rerr,werr)         // Nil unless one of them failed.
+}                                       // ------------ Close --------------- //
 
-// CloseRead closes the read end of the pipe.
+// CloseRead closes the read end of the pipe. Closing it again is a no-op.
 func (p *Pipes) CloseRead() error {
   if p.rf==nil{                         // Is the read end of the pipe nil?
 	return nil                      // Nothing to do, return nil.
   }                                     // Done checking if the read end of the pipe is nil.
   err:=p.rf.Close()                     // Close the read end of the pipe.
   p.rf=nil                              // Set the read end of the pipe to nil.
+  p.br=nil                              // Drop anything Peek buffered.
   p.rfd=-1                              // Set read fd to -1.
   return err                            // Return the error closing the read end of the pipe.
 }                                       // ------------ CloseRead ----------- //
-// CloseWrite closes the write end of the pipe.
+// CloseWrite closes the write end of the pipe. Closing it again is a no-op.
 func (p *Pipes) CloseWrite() error {
   if p.wf==nil{                         // Is the write end of the pipe nil?
 	return nil                      // Nothing to do, return nil.
@@ -166,6 +228,181 @@ func (p *Pipes) CloseWrite() error {
   p.wfd=-1                              // Set write end fd to -1.
   return err                            // Return the error closing the write end of the pipe.
 }                                       // ------------ CloseWrite ---------- //
+// Peek returns the next n bytes of the pipe without consuming them, so
+// dispatching on a header does not lose it. The bytes are kept in a buffer
+// owned by the Pipes, and the next Read (or LineScanner) returns them first.
+// Peek blocks until n bytes have arrived or the write end is closed, in which
+// case it returns what there is and io.EOF. Once Peek has been called, read
+// only through the Pipes itself: GetReadEnd, GetReadEndFD and Available see
+// the raw pipe and know nothing about the peeked bytes.
+func (p *Pipes) Peek(n int) ([]byte, error) {
+  if p.rf == nil {                      // Is the read end of the pipe nil?
+    return nil, os.ErrInvalid           // Yes, return nil and error.
+  }                                     // Done checking the read end.
+  if n < 0 {                            // Is the count valid?
+    return nil, fmt.Errorf("pipe: negative peek count %d", n)
+  }                                     // Done checking the count.
+  if p.br == nil {                      // First Peek on this pipe?
+    p.br = bufio.NewReaderSize(p.rf, n) // Yes, buffer the read end (4 KiB at least).
+  } else if n > p.br.Size() {           // Else is the buffer too small?
+    p.br = bufio.NewReaderSize(p.br, n) // Yes, stack a bigger one, keeping what we have.
+  }                                     // Done sizing the buffer.
+  return p.br.Peek(n)                   // Look without consuming.
+}                                       // ------------ Peek ----------------- //
+// LineScanner returns a bufio.Scanner over the read end of the pipe that
+// accepts lines of up to maxTokenBytes bytes, instead of bufio's default of
+// 64 KiB. A maxTokenBytes of 0 or less keeps the default. The scanner reads
+// ahead into its own buffer, so the caller must not also read the pipe, its
+// read end or its fd directly, or it will miss or split lines.
+func (p *Pipes) LineScanner(maxTokenBytes int) *bufio.Scanner {
+  if maxTokenBytes <= 0 {               // Were we given a maximum?
+    maxTokenBytes = bufio.MaxScanTokenSize // No, use bufio's default.
+  }                                     // Done checking the maximum.
+  initial := 4096                       // Start small, like bufio does...
+  if initial > maxTokenBytes {          // ...but never over the maximum.
+    initial = maxTokenBytes             // Start at the maximum then.
+  }                                     // Done sizing the initial buffer.
+  sc := bufio.NewScanner(p)             // Scan through our Read().
+  sc.Buffer(make([]byte, 0, initial), maxTokenBytes) // Grow up to the maximum.
+  return sc                             // Return the scanner.
+}                                       // ------------ LineScanner --------- //
+// SetProgressInterval sets the least time between two callbacks of the
+// readers ReaderWithProgress returns afterwards. Zero means PROGRESSINTERVAL;
+// a negative interval reports after every Read.
+func (p *Pipes) SetProgressInterval(d time.Duration) {
+  p.every = d                           // Remember it for the next reader.
+}                                       // ------------ SetProgressInterval - //
+// ReaderWithProgress returns a reader over the pipe that calls cb with the
+// total number of bytes it has read so far, at most once per progress
+// interval (see SetProgressInterval), so a CLI can draw a progress bar while
+// draining the pipe. cb is always called once more with the final total when
+// the pipe reports an error or io.EOF, or when the reader is closed, if that
+// total has not been reported yet. Closing the reader closes the read end.
+// cb runs on the goroutine calling Read and should return quickly.
+func (p *Pipes) ReaderWithProgress(cb func(total int64)) io.ReadCloser {
+  every := p.every                      // The interval between callbacks.
+  if every == 0 {                       // Were we given one?
+    every = PROGRESSINTERVAL            // No, use the default.
+  }                                     // Done choosing the interval.
+  return &progressReader{p: p, cb: cb, every: every, last: time.Now()}
+}                                       // ------------ ReaderWithProgress -- //
+// progressReader is what ReaderWithProgress returns.
+type progressReader struct {
+  p        *Pipes                       // The pipe we read from.
+  cb       func(total int64)            // Who we report to.
+  every    time.Duration                // Least time between reports.
+  last     time.Time                    // When we last reported.
+  total    int64                        // Bytes read so far.
+  reported int64                        // Total at the last report.
+}
+// Read reads from the pipe and reports progress when it is due.
+func (r *progressReader) Read(b []byte) (int, error) {
+  n, err := r.p.Read(b)                 // Read through the pipe.
+  r.total += int64(n)                   // Count what we got.
+  if err != nil || time.Since(r.last) >= r.every { // Report due?
+    r.report()                          // Yes, tell the caller.
+  }                                     // Done checking for a report.
+  return n, err                         // Return what the pipe gave us.
+}                                       // ------------ Read ----------------- //
+// Close reports the final total, if still unreported, and closes the read end.
+func (r *progressReader) Close() error {
+  r.report()                            // Make sure the last total is seen.
+  return r.p.CloseRead()                // Close the read end.
+}                                       // ------------ Close --------------- //
+// report calls the callback if the total has changed since the last call.
+func (r *progressReader) report() {
+  r.last = time.Now()                   // Restart the interval.
+  if r.cb == nil || r.total == r.reported { // Anything to tell?
+    return                              // No, stay quiet.
+  }                                     // Done checking for news.
+  r.reported = r.total                  // Remember what we told them.
+  r.cb(r.total)                         // Tell them.
+}                                       // ------------ report -------------- //
+// TailReader drains a pipe in the background, keeping only its last lines,
+// so a monitor that falls behind a fast producer never blocks it and never
+// holds more than it asked for. See Pipes.TailReader.
+type TailReader struct {
+  mu    sync.Mutex                      // Guards everything below.
+  lines []string                        // Ring of the last lines.
+  next  int                             // Where the next line goes.
+  full  bool                            // True once the ring has wrapped.
+  err   error                           // Why draining stopped, nil at EOF.
+  done  chan struct{}                   // Closed when draining stops.
+}
+
+// TailReader starts draining the read end of the pipe into a ring of the last
+// maxLines lines, at least one, and returns right away. Draining goes on until
+// the write end is closed or a read fails; the caller must not read the pipe
+// itself meanwhile. Lines are split on '\n', without it; a line longer than
+// bufio.MaxScanTokenSize is cut short, as the rest of it is not worth keeping.
+func (p *Pipes) TailReader(maxLines int) *TailReader {
+  if maxLines < 1 {                     // Were we asked for any lines?
+    maxLines = 1                        // No, keep at least one.
+  }                                     // Done checking the size.
+  t := &TailReader{lines: make([]string, maxLines), done: make(chan struct{})}
+  go t.drain(bufio.NewReader(p))        // Drain through our Read().
+  return t                              // Return the tail.
+}                                       // ------------ TailReader ---------- //
+// drain reads lines until the pipe runs dry, keeping the last ones.
+func (t *TailReader) drain(r *bufio.Reader) {
+  defer close(t.done)                   // Say when we are done.
+  var line []byte                       // The line we are reading.
+  for {                                 // Until the pipe runs dry...
+    frag, more, err := r.ReadLine()     // Read a line, or part of one.
+    if err != nil {                     // Anything left?
+      if len(line) > 0 {                // No, was a line unterminated?
+        t.add(string(line))             // Yes, keep it anyway.
+      }                                 // Done checking the last line.
+      if err != io.EOF {                // Did the read fail?
+        t.mu.Lock()                     // Yes, say why...
+        t.err = err                     // ...for Err().
+        t.mu.Unlock()                   // Done saying why.
+      }                                 // Done checking the error.
+      return                            // No more lines.
+    }                                   // Done checking for the end.
+    if room := bufio.MaxScanTokenSize - len(line); room > 0 {// Any room left?
+      line = append(line, frag[:min(len(frag), room)]...)// Yes, keep what fits.
+    }                                   // Done keeping the fragment.
+    if !more {                          // Is the line complete?
+      t.add(string(line))               // Yes, keep it.
+      line = line[:0]                   // And start the next one.
+    }                                   // Done checking for a whole line.
+  }                                     // Done draining.
+}                                       // ------------ drain --------------- //
+// add puts a line in the ring, over the oldest one once it is full.
+func (t *TailReader) add(line string) {
+  t.mu.Lock()                           // Keep Snapshot out.
+  defer t.mu.Unlock()                   // Let it in when done.
+  t.lines[t.next] = line                // Keep the line.
+  t.next++                              // Move on...
+  if t.next == len(t.lines) {           // ...past the end?
+    t.next, t.full = 0, true            // Yes, wrap around.
+  }                                     // Done moving on.
+}                                       // ------------ add ----------------- //
+// Snapshot returns a copy of the lines kept so far, oldest first. It is safe
+// to call while draining goes on.
+func (t *TailReader) Snapshot() []string {
+  t.mu.Lock()                           // Keep drain out.
+  defer t.mu.Unlock()                   // Let it in when done.
+  if !t.full {                          // Has the ring wrapped yet?
+    return append([]string(nil), t.lines[:t.next]...) // No, it is in order.
+  }                                     // Done checking for wrap.
+  out := make([]string, 0, len(t.lines)) // The lines in order...
+  out = append(out, t.lines[t.next:]...) // ...the oldest first...
+  return append(out, t.lines[:t.next]...) // ...then the newest.
+}                                       // ------------ Snapshot ------------ //
+// Done returns a channel that is closed once draining has stopped, after
+// which Snapshot holds the last lines the pipe ever carried.
+func (t *TailReader) Done() <-chan struct{} {
+  return t.done                         // Closed when draining stops.
+}                                       // ------------ Done ---------------- //
+// Err returns why draining stopped: nil while it goes on or after a clean
+// EOF, or the read error.
+func (t *TailReader) Err() error {
+  t.mu.Lock()                           // Keep drain out.
+  defer t.mu.Unlock()                   // Let it in when done.
+  return t.err                          // Why we stopped, if we did.
+}                                       // ------------ Err ----------------- //
 // DupFile duplicates fs descriptor (using SYS_DUP) and returns a new *os.File.
 func DupFile(f *os.File) (*os.File,error) {
   // ---------------------------------- //
@@ -259,6 +496,11 @@ func Dup3FD(oldfd, newfd, flags int) (int,error) {
 // stdout (in r mode) os stdin (in w mode), plus the Go *os.Process you can Wait()
 // on
 func POpen(cmd,mode string) (f *os.File,proc *os.Process,err error) {
+  return popen(cmd,mode,0,nil)          // Start it without limits.
+}                                       // ------------ POpen --------------- //
+// popen is POpen, with extra Popen flags such as POPENSETPGID, and the
+// child's resource limits set as PopenLimits does.
+func popen(cmd,mode string, flags int, rlimits map[int]unix.Rlimit) (f *os.File,proc *os.Process,err error) {
   if cmd==""||mode==""{                 // Did they give us a command or mode?
     return nil,nil,os.ErrInvalid        // No, return nil and error.
   }                                     // Done checking if the command and mode are empty.
@@ -274,7 +516,7 @@ func POpen(cmd,mode string) (f *os.File,proc *os.Process,err error) {
   // ---------------------------------- //
   // Create a pipe
   // ---------------------------------- //
-  fd,pid,err:=Popen(cmd,modes)          // Call the low-level popen syscall
+  fd,pid,err:=PopenLimits(cmd,modes|flags,rlimits)// Call the low-level popen syscall
   if err!=nil{                          // Did we error getting the pipe's fd?
     return nil,nil,err                  // Yes, return nil object and error.
   }                                     // Done with error creating pipe.
@@ -288,7 +530,7 @@ func POpen(cmd,mode string) (f *os.File,proc *os.Process,err error) {
     return nil,nil,err                  // return nil object and error.
   }                                     // Done with error finding the process.
   return file,proc,nil                  // Return the file and process.
-}                                       // ------------ POpen --------------- //
+}                                       // ------------ popen --------------- //
 // PClose closes the *os.File and then waits for the process to exit, returning
 // its exit code or error.
 func PClose(f *os.File, proc *os.Process) (int, error) {
@@ -308,6 +550,647 @@ func PClose(f *os.File, proc *os.Process) (int, error) {
   }                                     // Done with error waiting for the process.
   return code,nil                       // No error, return the exit code and nil.
 }                                       // ------------ PClose -------------- //
+// Error describes how the child ended, so a PCloseResult can be returned, or
+// wrapped, as an error. Use Err to get nil for a child that succeeded.
+func (r PCloseResult) Error() string {
+  if r.Signal!=0{                       // Was it killed by a signal?
+    return fmt.Sprintf("killed by signal %v",r.Signal)// Yes, say which one.
+  }                                     // Done checking for signal.
+  return fmt.Sprintf("exit status %d",r.Code)// Say how it exited.
+}                                       // ------------- Error -------------- //
+// Err returns nil if the child exited with status 0, and the result itself as
+// an error otherwise.
+func (r PCloseResult) Err() error {
+  if r.Code==0&&r.Signal==0{            // Did the child succeed?
+    return nil                          // Yes, no error.
+  }                                     // Done checking for success.
+  return r                              // No, it is its own error.
+}                                       // -------------- Err --------------- //
+
+// PopenHandle is a popen'ed child together with our end of its pipe.
+type PopenHandle struct {
+  f      *os.File                       // Our end of the pipe to the child.
+  proc   *os.Process                    // The child process.
+  group  bool                           // True if the child leads its own process group.
+  mu     sync.Mutex                     // Protects reaped, res and werr.
+  reaped bool                           // True once the child has been waited for.
+  res    PCloseResult                   // How it ended, once reaped.
+  werr   error                          // Any error waiting for it.
+}
+
+// POpenHandle is like POpen, but wraps the file and process in a PopenHandle
+// so the caller can close it with a deadline.
+func POpenHandle(cmd,mode string) (*PopenHandle,error) {
+  f,proc,err:=POpen(cmd,mode)           // Start the child.
+  if err!=nil{                          // Could we start it?
+    return nil,err                      // No, return nil object and error.
+  }                                     // Done checking for error.
+  return &PopenHandle{f:f,proc:proc},nil// Return the new handle.
+}                                       // --------- POpenHandle ------------ //
+// POpenHandleGroup is POpenHandle with the child leading its own process
+// group, so KillGroup, CloseTimeout and friends reach everything it starts,
+// not only the shell. Being in a group of its own, the child no longer gets
+// the signals the terminal sends ours, like SIGINT for Ctrl-C, and if it
+// reads the terminal it is stopped by SIGTTIN.
+func POpenHandleGroup(cmd,mode string) (*PopenHandle,error) {
+  f,proc,err:=popen(cmd,mode,POPENSETPGID,nil)// Start the child in its own group.
+  if err!=nil{                          // Could we start it?
+    return nil,err                      // No, return nil object and error.
+  }                                     // Done checking for error.
+  return &PopenHandle{f:f,proc:proc,group:true},nil// Return the new handle.
+}                                       // ------- POpenHandleGroup --------- //
+// POpenHandleLimits is POpenHandle for a command that must not use more than
+// its share: the child sets each resource limit in rlimits, e.g.
+// {unix.RLIMIT_CPU: {Cur: 1, Max: 2}}, before it runs cmd. See PopenArgvLimits.
+func POpenHandleLimits(cmd,mode string, rlimits map[int]unix.Rlimit) (*PopenHandle,error) {
+  f,proc,err:=popen(cmd,mode,0,rlimits) // Start the child.
+  if err!=nil{                          // Could we start it?
+    return nil,err                      // No, return nil object and error.
+  }                                     // Done checking for error.
+  return &PopenHandle{f:f,proc:proc},nil// Return the new handle.
+}                                       // ------- POpenHandleLimits -------- //
+// SafePopen runs argv[0] with the arguments in argv[1:], with our end of the
+// pipe connected as POpen's mode would (POPENREAD reads the child's stdout,
+// POPENWRITE writes its stdin). Unlike POpen there is no shell: arguments reach
+// the child exactly as given, so metacharacters like ; | $() are just text.
+// argv[0] is looked up in PATH unless it contains a slash, and the absolute
+// path it resolves to must be a key in allowed, e.g. "/bin/ls", or nothing is
+// run at all. Or POPENSETPGID into mode for the child to lead its own process
+// group, as POpenHandleGroup does.
+func SafePopen(argv []string, allowed map[string]bool, mode int) (*PopenHandle,error) {
+  group:=mode&POPENSETPGID!=0           // Must the child lead its own group?
+  dir:=mode&^POPENSETPGID               // Which way the pipe goes.
+  if len(argv)==0||(dir!=POPENREAD&&dir!=POPENWRITE){// Command and mode given?
+    return nil,os.ErrInvalid            // No, return nil and error.
+  }                                     // Done checking the arguments.
+  path,err:=exec.LookPath(argv[0])      // Find the program.
+  if err!=nil{                          // Could we find it?
+    return nil,err                      // No, return nil and error.
+  }                                     // Done looking up the program.
+  if path,err=filepath.Abs(path);err!=nil{// Where does it really live?
+    return nil,err                      // Can't tell, return nil and error.
+  }                                     // Done making the path absolute.
+  if !allowed[path]{                    // Are we allowed to run it?
+    return nil,fmt.Errorf("%s (%s) is not an allowed command: %w",argv[0],path,os.ErrPermission)
+  }                                     // Done checking the allowlist.
+  fd,pid,err:=PopenArgv(path,argv,mode) // Start the child without a shell.
+  if err!=nil{                          // Could we start it?
+    return nil,err                      // No, return nil and error.
+  }                                     // Done checking for error.
+  name:="popen-r"                       // Name our end of the pipe...
+  if dir==POPENWRITE{                   // ...after the way it goes.
+    name="popen-w"                      // We write to the child.
+  }                                     // Done naming the pipe.
+  f:=os.NewFile(uintptr(fd),name)       // Wrap our end of the pipe.
+  proc,err:=os.FindProcess(pid)         // Wrap the child's pid.
+  if err!=nil{                          // Could we?
+    f.Close()                           // No, close the pipe.
+    return nil,err                      // Return nil and error.
+  }                                     // Done wrapping the pid.
+  return &PopenHandle{f:f,proc:proc,group:group},nil// Return the new handle.
+}                                       // ----------- SafePopen ------------ //
+// File returns our end of the pipe to the child.
+func (h *PopenHandle) File() *os.File {
+  return h.f                            // Return the pipe end.
+}                                       // ------------- File --------------- //
+// Pid returns the process id of the child.
+func (h *PopenHandle) Pid() int {
+  return h.proc.Pid                     // Return the child's pid.
+}                                       // ------------- Pid ---------------- //
+// KillGroup sends sig to the child and, if it leads its own process group
+// (see POpenHandleGroup), everything it started; see KillGroup. A child that
+// does not lead a group is signalled alone. It does not reap the child: Close
+// or CloseTimeout still must. Once they have, it returns os.ErrProcessDone
+// rather than signal a pid that may belong to another process by now.
+func (h *PopenHandle) KillGroup(sig unix.Signal) error {
+  if h==nil||h.proc==nil{               // Do we have a child to signal?
+    return os.ErrInvalid                // No, return error.
+  }                                     // Done checking the handle.
+  h.mu.Lock()                           // Check and signal as one step...
+  defer h.mu.Unlock()                   // ...so nobody reaps it in between.
+  if h.reaped{                          // Is the child gone already?
+    return os.ErrProcessDone            // Yes, its pid is not ours to signal.
+  }                                     // Done checking if reaped.
+  return h.killGroup(sig)               // No, signal it.
+}                                       // ----------- KillGroup ------------ //
+// killGroup is KillGroup for a caller that holds h.mu and knows the child is
+// not reaped yet.
+func (h *PopenHandle) killGroup(sig unix.Signal) error {
+  if !h.group{                          // Does it lead its own group?
+    return unix.Kill(h.proc.Pid,sig)    // No, signal only the child.
+  }                                     // Done checking for a group.
+  return KillGroup(h.proc.Pid,sig)      // Signal its process group.
+}                                       // ----------- killGroup ------------ //
+// signal sends sig by way of killGroup unless the child has exited already,
+// and tells if it did. Holding h.mu makes the check and the kill one step, so
+// a child that exits on its own is never signalled, nor counted as killed.
+func (h *PopenHandle) signal(sig unix.Signal) bool {
+  h.mu.Lock()                           // Nobody reaps it meanwhile.
+  defer h.mu.Unlock()                   // Let go of the lock when done.
+  if h.reaped||h.hasExited(){           // Has it exited on its own?
+    return false                        // Yes, leave it alone.
+  }                                     // Done checking if it exited.
+  return h.killGroup(sig)==nil          // No, signal it.
+}                                       // ------------ signal -------------- //
+// Lines returns a line scanner over the child's output. When the output ends
+// the child is reaped, and if it failed, exiting non-zero or killed by a
+// signal, the scanner's Err reports an error wrapping its PCloseResult after
+// the last line, so 'for sc.Scan(){}; if err:=sc.Err()' catches a failed
+// command. A child that closes its stdout but keeps running holds up the
+// last Scan until it exits. Close must still be called; it returns the same
+// result.
+func (h *PopenHandle) Lines() *bufio.Scanner {
+  return bufio.NewScanner(&exitReader{h:h})// Reap the child at EOF.
+}                                       // ------------- Lines -------------- //
+// exitReader reads a PopenHandle's pipe, turning EOF into the child's failure.
+type exitReader struct {
+  h *PopenHandle                        // The child we read.
+}
+
+// Read reads the pipe and, at EOF, reaps the child and reports how it ended.
+func (r *exitReader) Read(b []byte) (int,error) {
+  n,err:=r.h.f.Read(b)                  // Read the child's output.
+  if err!=io.EOF{                       // Is the output over?
+    return n,err                        // No, return what we read.
+  }                                     // Done checking for EOF.
+  res,err:=r.h.wait()                   // Yes, so the child is done; reap it.
+  if err!=nil{                          // Could we?
+    return n,err                        // No, return the wait error.
+  }                                     // Done checking for wait error.
+  if err=res.Err();err!=nil{            // Did the child fail?
+    return n,fmt.Errorf("child %d: %w",r.h.proc.Pid,err)// Yes, say how.
+  }                                     // Done checking for failure.
+  return n,io.EOF                       // No, it is a plain EOF.
+}                                       // ------------- Read --------------- //
+// wait waits for the child to exit, reaps it the first time it is called, and
+// returns how it ended.
+func (h *PopenHandle) wait() (PCloseResult,error) {
+  h.exited()                            // Wait for the child to exit...
+  return h.reap(false)                  // ...then reap it.
+}                                       // ------------- wait --------------- //
+// exited blocks until the child has exited, without reaping it, so its pid
+// and process group stay ours, and can't be reused, until reap takes them
+// under h.mu. It returns at once if the child is reaped already.
+func (h *PopenHandle) exited() {
+  var info unix.Siginfo                 // Where waitid says how it ended.
+  for {                                 // Until it exits...
+    err:=unix.Waitid(unix.P_PID,h.proc.Pid,&info,unix.WEXITED|unix.WNOWAIT,nil)
+    if err!=unix.EINTR{                 // Interrupted by a signal?
+      return                            // No, it exited, or is reaped already.
+    }                                   // Done checking for EINTR.
+  }                                     // Done waiting.
+}                                       // ------------ exited -------------- //
+// hasExited tells if the child has exited, without reaping it or waiting.
+// The caller holds h.mu.
+func (h *PopenHandle) hasExited() bool {
+  var info unix.Siginfo                 // Zero unless it exited.
+  err:=unix.Waitid(unix.P_PID,h.proc.Pid,&info,unix.WEXITED|unix.WNOWAIT|unix.WNOHANG,nil)
+  return err!=nil||info.Signo!=0        // Gone to the auto-reaper, or exited.
+}                                       // ----------- hasExited ------------ //
+// reap reaps the exited child the first time it is called, recording whether
+// we had to kill it, and returns how it ended. h.mu is held throughout, so
+// KillGroup never signals a pid we have given back.
+func (h *PopenHandle) reap(killed bool) (PCloseResult,error) {
+  h.mu.Lock()                           // Reap and record as one step.
+  defer h.mu.Unlock()                   // Let go of the lock when done.
+  if !h.reaped{                         // Have we reaped it yet?
+    h.res,h.werr=waitResult(h.proc.Pid) // No, it has exited, reap it.
+    h.res.Killed=killed                 // Did we have to signal it?
+    h.reaped=true                       // Only once.
+  }                                     // Done checking if reaped.
+  return h.res,h.werr                   // Return how it ended.
+}                                       // ------------- reap --------------- //
+// Close closes the pipe and waits for the child to exit, however long it takes.
+func (h *PopenHandle) Close() (PCloseResult,error) {
+  if h==nil||h.f==nil{                  // Do we have a child to close?
+    return PCloseResult{Code:-1},os.ErrInvalid// No, return -1 and error.
+  }                                     // Done checking the handle.
+  h.f.Close()                           // Close the pipe so the child sees EOF.
+  return h.wait()                       // Wait for the child to exit.
+}                                       // ------------- Close -------------- //
+// CloseTimeout closes the pipe and waits up to d for the child to exit. If it
+// is still running it is sent SIGTERM, and if it has not exited POPENGRACE
+// later it is sent SIGKILL, by way of KillGroup. The result's Killed field
+// tells if we had to signal the child. The child is reaped either way, so a
+// later Close returns the same result and KillGroup refuses to signal it. A
+// child that exits on its own as the deadline passes is not signalled, and
+// Killed stays false.
+// Only a handle from POpenHandleGroup, or SafePopen with POPENSETPGID, has
+// the signals reach everything the command started. Otherwise they reach the
+// shell alone, and what it started, e.g. the first commands of a pipeline or
+// a job put in the background, is orphaned and goes on running, holding the
+// pipe open if it inherited it.
+func (h *PopenHandle) CloseTimeout(d time.Duration) (PCloseResult,error) {
+  if h==nil||h.f==nil{                  // Do we have a child to close?
+    return PCloseResult{Code:-1},os.ErrInvalid// No, return -1 and error.
+  }                                     // Done checking the handle.
+  h.f.Close()                           // Close the pipe so the child sees EOF.
+  done:=make(chan struct{})             // Closed once the child has exited.
+  go func(){                            // Wait for the child in the background,
+    h.exited()                          // without reaping it, so we may
+    close(done)                         // still signal it safely.
+  }()                                   // Done starting the waiter.
+  timer:=time.NewTimer(d)               // Our deadline.
+  defer timer.Stop()                    // Release the timer when done.
+  select{                               // Wait for the child or the deadline.
+  case <-done:                          // The child exited in time?
+    return h.reap(false)                // Yes, reap it, or say how Lines did.
+  case <-timer.C:                       // The deadline passed?
+  }                                     // Done waiting for the deadline.
+  killed:=h.signal(unix.SIGTERM)        // Politely ask the child to exit.
+  timer.Reset(POPENGRACE)               // Give it some grace.
+  select{                               // Wait for the child or the grace period.
+  case <-done:                          // The child exited after SIGTERM?
+    return h.reap(killed)               // Yes, but maybe we had to ask.
+  case <-timer.C:                       // The grace period passed?
+  }                                     // Done waiting for the grace period.
+  if h.signal(unix.SIGKILL){            // Force the child to exit.
+    killed=true                         // We had to.
+  }                                     // Done killing the child.
+  <-done                                // It can't refuse, wait for it.
+  return h.reap(killed)                 // Reap it.
+}                                       // --------- CloseTimeout ----------- //
+// WaitTimeout waits up to d for child pid to exit, polling it with WNOHANG
+// every few milliseconds, and returns its wait status. If the child is still
+// running when d is up it returns an error wrapping os.ErrDeadlineExceeded and
+// leaves the child alone, so the caller can decide whether to kill it. A d of
+// 0 or less checks once without waiting.
+func WaitTimeout(pid int, d time.Duration) (unix.WaitStatus, error) {
+  var ws unix.WaitStatus                // The child's wait status.
+  deadline:=time.Now().Add(d)           // When we give up.
+  pause:=time.Millisecond               // How long to sleep between polls.
+  for {                                 // Until it exits or we give up...
+    wpid,err:=wait4(pid,&ws,unix.WNOHANG)// Has it exited?
+    switch {                            // Act according to the answer.
+    case errors.Is(err,unix.EINTR):     // Interrupted by a signal?
+      continue                          // Yes, ask again.
+    case err!=nil:                      // Could we ask at all?
+      return ws,err                     // No, return the error.
+    case wpid==pid:                     // Did it exit?
+      return ws,nil                     // Yes, return how.
+    }                                   // Done acting on the answer.
+    left:=time.Until(deadline)          // How long we may still wait.
+    if left<=0 {                        // Is the time up?
+      return ws,fmt.Errorf("pipe: child %d still running after %v: %w",pid,d,os.ErrDeadlineExceeded)
+    }                                   // Done checking the deadline.
+    if pause>left {                     // Would we sleep past the deadline?
+      pause=left                        // Yes, only sleep until then.
+    }                                   // Done clamping the pause.
+    time.Sleep(pause)                   // Give the child some time.
+    if pause<50*time.Millisecond {      // Still polling fast?
+      pause*=2                          // Yes, back off a little.
+    }                                   // Done backing off.
+  }                                     // Done polling.
+}                                       // --------- WaitTimeout ------------ //
+// RunFilter runs 'sh -c cmd' as a line filter: each input line is written to
+// the child's stdin followed by a newline, and every line it writes to its
+// stdout is returned in output. The input is fed from a goroutine so a child
+// that writes before it has read everything can't deadlock us.
+func RunFilter(cmd string, input []string) (output []string, status PCloseResult, err error) {
+  if cmd==""{                           // Did they give us a command?
+    return nil,PCloseResult{Code:-1},os.ErrInvalid// No, return nil and error.
+  }                                     // Done checking the command.
+  rfd,wfd,pid,err:=Popen2(cmd)          // Start the child.
+  if err!=nil{                          // Could we start it?
+    return nil,PCloseResult{Code:-1},err// No, return nil and error.
+  }                                     // Done checking for error.
+  r:=os.NewFile(uintptr(rfd),"popen-r") // The child's stdout.
+  w:=os.NewFile(uintptr(wfd),"popen-w") // The child's stdin.
+  werr:=make(chan error,1)              // Where the writer reports.
+  go func(){                            // Feed the child in the background.
+    bw:=bufio.NewWriter(w)              // Buffer our writes.
+    var err error                       // The first write error, if any.
+    for _,line:=range input{            // For each input line...
+      if _,err=bw.WriteString(line+"\n");err!=nil{// Could we write it?
+        break                           // No, the child stopped reading.
+      }                                 // Done checking for write error.
+    }                                   // Done writing input lines.
+    if err==nil{                        // Did we write everything?
+      err=bw.Flush()                    // Yes, flush what is left.
+    }                                   // Done checking for write error.
+    w.Close()                           // Close stdin so the child sees EOF.
+    werr<-err                           // Report how it went.
+  }()                                   // Done starting the writer.
+  sc:=bufio.NewScanner(r)               // Scan the child's output by lines.
+  for sc.Scan(){                        // For each output line...
+    output=append(output,sc.Text())     // Collect it.
+  }                                     // Done reading output lines.
+  err=sc.Err()                          // Did we have trouble reading?
+  r.Close()                             // Done with the child's stdout.
+  if e:=<-werr;err==nil&&e!=nil&&!errors.Is(e,unix.EPIPE){// Trouble writing?
+    err=e                               // Yes, and not because the child quit.
+  }                                     // Done checking for write error.
+  status,e:=waitResult(pid)             // Wait for the child to exit.
+  if err==nil{                          // Any error so far?
+    err=e                               // No, so report the wait error.
+  }                                     // Done checking for wait error.
+  return output,status,err              // Return what the filter produced.
+}                                       // ----------- RunFilter ------------ //
+// PopenLines runs 'sh -c cmd' with its stdout connected to a line scanner,
+// collapsing the usual fd, os.File, bufio and FindProcess steps into one call.
+// As with PopenHandle.Lines, a child that exits non-zero makes the scanner's
+// Err return an error wrapping its PCloseResult once the lines run out.
+// Call close when done scanning, even after an error: it closes the pipe and
+// reaps the child, returning how it ended. Closing before the scanner reaches
+// EOF may leave the child to die of SIGPIPE.
+func PopenLines(cmd string) (scanner *bufio.Scanner, close func() (PCloseResult, error), err error) {
+  h,err:=POpenHandle(cmd,"r")           // Start the child, reading its stdout.
+  if err!=nil{                          // Could we start it?
+    return nil,nil,err                  // No, return nil and error.
+  }                                     // Done checking for error.
+  return h.Lines(),h.Close,nil          // Scan its output, reap it on close.
+}                                       // ----------- PopenLines ----------- //
+// PopenLinesContext runs 'sh -c cmd' and sends each line of its stdout on
+// lines, for a caller that may stop reading before the output ends. The
+// child leads its own process group, as with POpenHandleGroup, so when ctx is
+// cancelled the child and everything it started are sent SIGTERM, and
+// SIGKILL if they are still there POPENGRACE later; what they write meanwhile
+// is drained and dropped, so a child blocked writing to the pipe still exits.
+// Once the child is reaped, lines is closed and errs delivers one error before
+// it is closed too: nil if the child exited 0, an error wrapping its
+// PCloseResult if it failed, and one wrapping ctx.Err() as well if we were
+// cancelled. The caller must keep receiving from lines until it is closed, or
+// cancel ctx.
+func PopenLinesContext(ctx context.Context, cmd string) (<-chan string, <-chan error) {
+  lines:=make(chan string)              // Where the lines go.
+  errs:=make(chan error,1)              // Where how it ended goes.
+  h,err:=POpenHandleGroup(cmd,"r")      // Start the child, reading its stdout.
+  if err!=nil{                          // Could we start it?
+    close(lines)                        // No, there are no lines...
+    errs<-err                           // ...only the error...
+    close(errs)                         // ...and nothing more.
+    return lines,errs                   // Return the closed channels.
+  }                                     // Done checking for error.
+  done:=make(chan struct{})             // Closed once the output ends.
+  // KillGroup checks and signals under the handle's lock, and Lines reaps
+  // under it, so a callback still running after the child is reaped gets
+  // os.ErrProcessDone rather than signal a pgid that may be reused by now.
+  stop:=context.AfterFunc(ctx,func(){   // When the context is done...
+    h.KillGroup(unix.SIGTERM)           // ...politely ask the child to exit...
+    select{                             // ...and give it some grace.
+    case <-done:                        // Did its output end?
+    case <-time.After(POPENGRACE):      // Or did the grace period pass?
+      h.KillGroup(unix.SIGKILL)         // Yes, force the child to exit.
+    }                                   // Done waiting for the grace period.
+  })                                    // Done arranging the cancellation.
+  go func(){                            // Scan the output in the background.
+    defer close(errs)                   // Last, say there is nothing more.
+    sc:=h.Lines()                       // Reap the child at EOF.
+    for sc.Scan(){                      // For each output line...
+      if ctx.Err()!=nil{                // Were we cancelled?
+        continue                        // Yes, drain the line.
+      }                                 // Done checking for cancellation.
+      select{                           // Hand the line over...
+      case lines<-sc.Text():            // ...if the caller takes it...
+      case <-ctx.Done():                // ...unless we are cancelled first.
+      }                                 // Done handing the line over.
+    }                                   // Done scanning the output.
+    close(done)                         // The output is over.
+    stop()                              // No need to cancel any more.
+    err:=sc.Err()                       // Did the child fail, or the read?
+    if _,cerr:=h.Close();err==nil&&cerr!=nil{// Reap it, if Lines didn't.
+      err=cerr                          // Could not, report that.
+    }                                   // Done closing the handle.
+    if cerr:=ctx.Err();cerr!=nil{       // Were we cancelled?
+      if err==nil{                      // Yes, did the child fail too?
+        err=cerr                        // No, report the cancellation.
+      } else {                          // Yes, it was probably killed.
+        err=fmt.Errorf("%w: %w",cerr,err)// Report both.
+      }                                 // Done checking for failure.
+    }                                   // Done checking for cancellation.
+    close(lines)                        // No more lines.
+    errs<-err                           // Report how it ended.
+  }()                                   // Done starting the scanner.
+  return lines,errs                     // Return the channels.
+}                                       // ------- PopenLinesContext -------- //
+// PopenCapture runs 'sh -c cmd' like POpenHandle(cmd, "r"), but instead of
+// sharing our stderr the child writes its stderr to a pipe of its own, whose
+// read end is returned as stderr, so a failing command's diagnostics can be
+// read apart from its output. The handle's Close does not close stderr; the
+// caller must.
+//
+// Drain both pipes concurrently, for instance reading stderr in a goroutine.
+// A pipe holds only so much (64 KiB by default): if the child fills the one
+// nobody is reading it blocks, and a caller waiting for EOF on the other one
+// deadlocks with it.
+func PopenCapture(cmd string) (handle *PopenHandle, stderr *os.File, err error) {
+  if cmd==""{                           // Did they give us a command?
+    return nil,nil,os.ErrInvalid        // No, return nil and error.
+  }                                     // Done checking the command.
+  outfd,errfd,pid,err:=PopenStderr(cmd) // Start the child.
+  if err!=nil{                          // Could we start it?
+    return nil,nil,err                  // No, return nil and error.
+  }                                     // Done checking for error.
+  f:=os.NewFile(uintptr(outfd),"popen-r")// The child's stdout.
+  stderr=os.NewFile(uintptr(errfd),"popen-stderr")// The child's stderr.
+  proc,err:=os.FindProcess(pid)         // Wrap the child's pid.
+  if err!=nil{                          // Could we?
+    f.Close()                           // No, close the pipes.
+    stderr.Close()                      // Both of them.
+    return nil,nil,err                  // Return nil and error.
+  }                                     // Done wrapping the pid.
+  return &PopenHandle{f:f,proc:proc},stderr,nil// Return the handle and stderr.
+}                                       // ---------- PopenCapture ---------- //
+
+// WorkerPool hands work items to n copies of a filter command and collects
+// what they answer, the fan-out of the pipe demonstration programs made
+// reusable. Each worker is 'sh -c cmd' with a pipe to its stdin and one from
+// its stdout. Items and results are lines: a worker reads one item per line
+// and is expected to write one line per item, as cat, sed or awk do. Each
+// item goes to the worker with the fewest items still unanswered, taking
+// them round-robin on a tie. Results arrive in the order the workers write
+// them, not the order the items were submitted.
+//
+// Read Results while submitting: a worker whose results nobody reads fills
+// its pipe and stops reading items, and in time Submit blocks on it.
+type WorkerPool struct {
+  mu      sync.Mutex                    // Serializes Submit and Close.
+  workers []*poolWorker                 // The workers, in the order started.
+  next    int                           // Where the next round-robin starts.
+  results chan Result                   // What the workers write back.
+  readers sync.WaitGroup                // The goroutines reading the workers.
+  closed  bool                          // True once Close was called.
+}
+
+// poolWorker is one worker of a WorkerPool.
+type poolWorker struct {
+  pid     int                           // The child process.
+  in      *os.File                      // Our end of its stdin.
+  out     *os.File                      // Our end of its stdout.
+  pending atomic.Int64                  // Items submitted but not answered.
+}
+
+// Result is a line a WorkerPool worker wrote, or why reading it failed.
+type Result struct {
+  Worker int                            // The worker, 0 to n-1.
+  Data   []byte                         // The line, without its newline.
+  Err    error                          // Why reading the worker failed, if it did.
+}
+
+// NewWorkerPool starts n workers, each running 'sh -c cmd'.
+func NewWorkerPool(n int, cmd string) (*WorkerPool,error) {
+  if n<=0||cmd==""{                     // Did they give us workers and a command?
+    return nil,os.ErrInvalid            // No, return nil and error.
+  }                                     // Done checking the arguments.
+  wp:=&WorkerPool{results:make(chan Result,n)}// Room for a result per worker.
+  for i:=0;i<n;i++{                     // For each worker...
+    rfd,wfd,pid,err:=Popen2(cmd)        // Start it.
+    if err!=nil{                        // Could we start it?
+      go func(){ for range wp.results{} }()// No, nobody will read the others...
+      wp.Close()                        // ...as we stop them.
+      return nil,err                    // Return nil and error.
+    }                                   // Done checking for error.
+    w:=&poolWorker{pid:pid,             // Wrap the worker and its pipes.
+      in:os.NewFile(uintptr(wfd),"worker-w"),
+      out:os.NewFile(uintptr(rfd),"worker-r")}
+    wp.workers=append(wp.workers,w)     // Add it to the pool.
+    wp.readers.Add(1)                   // One more reader to wait for.
+    go wp.read(i,w)                     // Collect what it writes.
+  }                                     // Done starting workers.
+  return wp,nil                         // Return the pool.
+}                                       // --------- NewWorkerPool ---------- //
+// read sends each line worker i writes to Results until it closes its stdout.
+func (wp *WorkerPool) read(i int, w *poolWorker) {
+  defer wp.readers.Done()               // Tell Close when we are done.
+  sc:=bufio.NewScanner(w.out)           // Scan the worker's output by lines.
+  for sc.Scan(){                        // For each line...
+    w.pending.Add(-1)                   // One item less outstanding.
+    wp.results<-Result{Worker:i,Data:append([]byte(nil),sc.Bytes()...)}
+  }                                     // Done reading lines.
+  if err:=sc.Err();err!=nil{            // Did we have trouble reading?
+    wp.results<-Result{Worker:i,Err:err}// Yes, say so.
+  }                                     // Done checking for read error.
+}                                       // ------------- read --------------- //
+// Submit sends item, followed by a newline, to the least busy worker. The
+// item must not contain a newline of its own.
+func (wp *WorkerPool) Submit(item []byte) error {
+  if bytes.IndexByte(item,'\n')>=0{     // Is it more than one line?
+    return fmt.Errorf("work item contains a newline: %w",os.ErrInvalid)
+  }                                     // Done checking the item.
+  wp.mu.Lock()                          // One Submit at a time.
+  defer wp.mu.Unlock()                  // Let the next one in when done.
+  if wp.closed{                         // Is the pool still open?
+    return os.ErrClosed                 // No, return error.
+  }                                     // Done checking for closed pool.
+  n:=len(wp.workers)                    // How many workers we have.
+  best:=wp.next%n                       // Start where round-robin left off.
+  for k:=1;k<n;k++{                     // For each of the others...
+    j:=(wp.next+k)%n                    // ...in round-robin order...
+    if wp.workers[j].pending.Load()<wp.workers[best].pending.Load(){
+      best=j                            // ...take it if it is less busy.
+    }                                   // Done comparing workers.
+  }                                     // Done picking a worker.
+  wp.next=best+1                        // Start after it next time.
+  w:=wp.workers[best]                   // The worker we picked.
+  w.pending.Add(1)                      // It has one more item to answer.
+  line:=make([]byte,len(item)+1)        // The item as a line.
+  copy(line,item)                       // The item...
+  line[len(item)]='\n'                  // ...and its newline.
+  if _,err:=w.in.Write(line);err!=nil{  // Could we hand it over?
+    w.pending.Add(-1)                   // No, it won't be answered.
+    return fmt.Errorf("worker %d: %w",best,err)// Return error.
+  }                                     // Done writing the item.
+  return nil                            // We are good if we got here.
+}                                       // ------------ Submit -------------- //
+// Results returns the channel the workers' answers arrive on. Close closes
+// it once every worker has finished and everything they wrote was delivered.
+func (wp *WorkerPool) Results() <-chan Result {
+  return wp.results                     // Return the results channel.
+}                                       // ------------ Results ------------- //
+// Close closes the workers' stdin so they finish their items and exit, waits
+// until all they wrote has been sent to Results, reaps them and closes
+// Results. Keep reading Results until then. It returns an error for every
+// worker that did not exit with status 0.
+func (wp *WorkerPool) Close() error {
+  wp.mu.Lock()                          // Wait for any Submit to finish.
+  if wp.closed{                         // Did we close already?
+    wp.mu.Unlock()                      // Yes, let go of the lock.
+    return os.ErrClosed                 // Return error.
+  }                                     // Done checking for closed pool.
+  wp.closed=true                        // No more Submits.
+  wp.mu.Unlock()                        // Let go of the lock.
+  for _,w:=range wp.workers{            // For each worker...
+    w.in.Close()                        // ...close its stdin so it sees EOF.
+  }                                     // Done closing stdins.
+  wp.readers.Wait()                     // Wait for all they wrote.
+  var errs []error                      // How the workers failed, if any did.
+  for i,w:=range wp.workers{            // For each worker...
+    w.out.Close()                       // ...close its stdout...
+    res,err:=waitResult(w.pid)          // ...and reap it.
+    switch{                             // How did it end?
+    case err!=nil:                      // Could not wait for it?
+      errs=append(errs,fmt.Errorf("worker %d: %w",i,err))
+    case res.Signal!=0:                 // Killed by a signal?
+      errs=append(errs,fmt.Errorf("worker %d killed by %v",i,res.Signal))
+    case res.Code!=0:                   // Exited with an error?
+      errs=append(errs,fmt.Errorf("worker %d exited with status %d",i,res.Code))
+    }                                   // Done checking how it ended.
+  }                                     // Done reaping workers.
+  close(wp.results)                     // No more results.
+  return errors.Join(errs...)           // Return the failures, if any.
+}                                       // ------------- Close -------------- //
+
+// Latch is a countdown latch built on a pipe, after the synchronization idiom
+// in Kerrisk's TLPI 44.3. Every child forked after NewLatch inherits a copy of
+// the write end, and calls Arrive (or simply exits) when it is done. The pipe
+// is close-on-exec, so an exec'ed child only holds it if it is passed on, e.g.
+// in exec.Cmd.ExtraFiles. Wait closes the parent's own copy and returns once
+// the last copy is closed and the read end sees EOF.
+type Latch struct {
+  p *Pipes                              // The pipe the children hold open.
+}
+
+// NewLatch creates the latch's pipe. Create it before forking the children.
+func NewLatch() (*Latch,error) {
+  p,err:=NewPipe2(O_NONBLOCK|O_CLOEXEC) // Non-blocking so Wait can be cancelled.
+  if err!=nil{                          // Did we error creating the pipe?
+    return nil,err                      // Yes, return nil object and error.
+  }                                     // Done with error creating pipe.
+  return &Latch{p:p},nil                // Return our latch.
+}                                       // ------------ NewLatch ------------ //
+// WriteEnd returns the write end, e.g. to pass to exec'ed children in
+// exec.Cmd.ExtraFiles. It is nil once this process has arrived.
+func (l *Latch) WriteEnd() *os.File {
+  return l.p.wf                         // Return the write end of the pipe.
+}                                       // ------------ WriteEnd ------------ //
+// Arrive is called by a child when it is done: it closes its copy of the
+// write end of the pipe.
+func (l *Latch) Arrive() error {
+  return l.p.CloseWrite()               // Close our copy of the write end.
+}                                       // ------------- Arrive ------------- //
+// Wait closes the caller's copy of the write end, which it must not hold
+// open or it would wait forever, then blocks until every child has arrived
+// or ctx is done.
+func (l *Latch) Wait(ctx context.Context) error {
+  if err:=l.p.CloseWrite();err!=nil{    // Close our copy of the write end.
+    return err                          // Could not close it.
+  }                                     // Done closing the write end.
+  if l.p.rf==nil{                       // Do we still have the read end?
+    return os.ErrInvalid                // No, we have waited already.
+  }                                     // Done checking the read end.
+  stop:=context.AfterFunc(ctx,func(){   // When the context is done...
+    l.p.rf.SetReadDeadline(time.Now())  // ...wake up our Read().
+  })                                    // Done arranging for cancellation.
+  defer stop()                          // Don't wake anybody once we return.
+  buf:=make([]byte,64)                  // Children are not supposed to write.
+  for{                                  // Until EOF...
+    _,err:=l.p.Read(buf)                // Read (and discard) from the pipe.
+    if errors.Is(err,io.EOF){           // Have all the children arrived?
+      return nil                        // Yes, we are done waiting.
+    }                                   // Done checking for EOF.
+    if err!=nil{                        // Any other error?
+      if ctx.Err()!=nil{                // Was it because we were cancelled?
+        return ctx.Err()                // Yes, say so.
+      }                                 // Done checking for cancellation.
+      return err                        // No, return the read error.
+    }                                   // Done checking for read error.
+  }                                     // Done reading until EOF.
+}                                       // ------------- Wait --------------- //
+// Close releases the latch's pipe.
+func (l *Latch) Close() error {
+  l.p.CloseWrite()                      // Close the write end, if still open.
+  return l.p.CloseRead()                // Close the read end.
+}                                       // ------------- Close -------------- //
 
 // CreateFIFO makes a named pipe (FIFO) at path with the given permissions.
 func CreateFIFO(path string, perm os.FileMode) error {
@@ -0,0 +1,1099 @@
+//go:build linux && amd64
+// +build linux,amd64
+
+package pipe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// readAll reads fd to EOF and closes it.
+func readAll(t *testing.T, fd int, name string) string {
+	t.Helper()
+	f := os.NewFile(uintptr(fd), name)
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(b)
+}
+
+func TestPopenStderr(t *testing.T) {
+	tests := []struct {
+		name    string
+		cmd     string
+		wantOut string
+		wantErr string
+	}{
+		{"stdout only", "echo out", "out\n", ""},
+		{"stderr only", "echo err >&2", "", "err\n"},
+		{"both", "echo out; echo err >&2", "out\n", "err\n"},
+		// Another popen's pipes are open while this one runs, but they are
+		// close-on-exec, so only stdin, stdout and stderr are open in the shell.
+		{"no leaked fds", "ls -1 /proc/$$/fd", "0\n1\n2\n", ""},
+	}
+	bgout, bgerr, bgpid, err := PopenStderr("sleep 0.2")
+	if err != nil {
+		t.Fatalf("PopenStderr: %v", err)
+	}
+	defer func() {
+		unix.Close(bgout)
+		unix.Close(bgerr)
+		Pclose(bgpid)
+	}()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outfd, errfd, pid, err := PopenStderr(tt.cmd)
+			if err != nil {
+				t.Fatalf("PopenStderr: %v", err)
+			}
+			errc := make(chan string, 1)
+			go func() { errc <- readAll(t, errfd, "stderr") }()
+			out := readAll(t, outfd, "stdout")
+			stderr := <-errc
+			if _, err := Pclose(pid); err != nil {
+				t.Fatalf("Pclose: %v", err)
+			}
+			if out != tt.wantOut {
+				t.Errorf("stdout %q, want %q", out, tt.wantOut)
+			}
+			if !strings.HasPrefix(stderr, tt.wantErr) || (tt.wantErr == "" && stderr != "") {
+				t.Errorf("stderr %q, want %q", stderr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteString(t *testing.T) {
+	big := strings.Repeat("0123456789abcdef", 1<<14) + "tail" // 256 KiB, past a pipe's 64 KiB.
+	tests := []struct {
+		name      string
+		flags     int
+		stopAfter int // Close the read end after this many bytes, 0 to read it all.
+		wantErr   error
+	}{
+		{"blocking", 0, 0, nil},
+		{"non-blocking", O_NONBLOCK, 0, nil},
+		{"blocking reader gone", 0, 4096, unix.EPIPE},
+		{"non-blocking reader gone", O_NONBLOCK, 4096, unix.EPIPE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipe2(tt.flags)
+			if err != nil {
+				t.Fatalf("NewPipe2: %v", err)
+			}
+			defer p.wf.Close()
+			got := make(chan string, 1)
+			go func() { // A slow reader.
+				var sb strings.Builder
+				buf := make([]byte, 4096)
+				for {
+					n, err := p.rf.Read(buf)
+					sb.Write(buf[:n])
+					if err != nil || (tt.stopAfter > 0 && sb.Len() >= tt.stopAfter) {
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+				p.rf.Close()
+				got <- sb.String()
+			}()
+			n, err := p.WriteString(big)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || n >= len(big) {
+					t.Fatalf("WriteString = %d, %v, want a short write and %v", n, err, tt.wantErr)
+				}
+				<-got
+				return
+			}
+			if err != nil || n != len(big) {
+				t.Fatalf("WriteString = %d, %v, want %d, nil", n, err, len(big))
+			}
+			p.wf.Close()
+			if s := <-got; s != big {
+				t.Errorf("reader got %d bytes, want %d", len(s), len(big))
+			}
+		})
+	}
+}
+
+func TestCloseTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        string
+		wantCode   int
+		wantSignal unix.Signal
+		wantKilled bool
+	}{
+		{"exits in time", "exit 3", 3, 0, false},
+		{"hangs", "sleep 60", -1, unix.SIGTERM, true},
+		{"ignores SIGTERM", "trap '' TERM; sleep 60", -1, unix.SIGKILL, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := POpenHandleGroup(tt.cmd, "r")
+			if err != nil {
+				t.Fatalf("POpenHandleGroup: %v", err)
+			}
+			start := time.Now()
+			res, err := h.CloseTimeout(100 * time.Millisecond)
+			if err != nil {
+				t.Fatalf("CloseTimeout: %v", err)
+			}
+			if took := time.Since(start); took > 100*time.Millisecond+POPENGRACE+2*time.Second {
+				t.Errorf("CloseTimeout took %v", took)
+			}
+			if res.Code != tt.wantCode || res.Signal != tt.wantSignal || res.Killed != tt.wantKilled {
+				t.Errorf("got %+v, want {Code:%d Signal:%v Killed:%v}", res, tt.wantCode, tt.wantSignal, tt.wantKilled)
+			}
+			// The child is reaped: its pid must not be waited for or signalled again.
+			if again, err := h.Close(); again != res || err != nil {
+				t.Errorf("Close after CloseTimeout = %+v, %v, want %+v, nil", again, err, res)
+			}
+			if err := h.KillGroup(unix.SIGKILL); !errors.Is(err, os.ErrProcessDone) {
+				t.Errorf("KillGroup after CloseTimeout: %v, want %v", err, os.ErrProcessDone)
+			}
+		})
+	}
+}
+
+// TestCloseTimeoutNoGroup shows the limit of CloseTimeout on a handle whose
+// child does not lead its own process group: only the shell is signalled, and
+// a job it put in the background goes on running.
+func TestCloseTimeoutNoGroup(t *testing.T) {
+	h, err := POpenHandle("sleep 60 & echo $!; wait", "r")
+	if err != nil {
+		t.Fatalf("POpenHandle: %v", err)
+	}
+	sc := bufio.NewScanner(h.File())
+	if !sc.Scan() {
+		t.Fatalf("no pid from the shell: %v", sc.Err())
+	}
+	pid, err := strconv.Atoi(sc.Text())
+	if err != nil {
+		t.Fatalf("pid %q: %v", sc.Text(), err)
+	}
+	defer unix.Kill(pid, unix.SIGKILL)
+	res, err := h.CloseTimeout(100 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("CloseTimeout: %v", err)
+	}
+	if !res.Killed {
+		t.Errorf("got %+v, want the shell killed", res)
+	}
+	if err := unix.Kill(pid, 0); err != nil {
+		t.Errorf("background sleep %d is gone (%v), want it orphaned", pid, err)
+	}
+}
+
+func TestRunFilter(t *testing.T) {
+	many := make([]string, 20000)
+	for i := range many {
+		many[i] = "foo line"
+	}
+	tests := []struct {
+		name     string
+		cmd      string
+		input    []string
+		want     []string
+		wantCode int
+	}{
+		{"grep", "grep foo", []string{"foo", "bar", "a food", "baz"}, []string{"foo", "a food"}, 0},
+		{"no match", "grep foo", []string{"bar", "baz"}, nil, 1},
+		{"no input", "cat", nil, nil, 0},
+		// More than a pipe buffer each way: feeding the child must not
+		// wait for us to read its output.
+		{"large", "cat", many, many, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, res, err := RunFilter(tt.cmd, tt.input)
+			if err != nil {
+				t.Fatalf("RunFilter: %v", err)
+			}
+			if res.Code != tt.wantCode {
+				t.Errorf("exit code %d, want %d", res.Code, tt.wantCode)
+			}
+			if strings.Join(out, "\n") != strings.Join(tt.want, "\n") || len(out) != len(tt.want) {
+				t.Errorf("got %d lines %.80q, want %d lines %.80q", len(out), out, len(tt.want), tt.want)
+			}
+		})
+	}
+	if _, _, err := RunFilter("", nil); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("empty command: error %v, want os.ErrInvalid", err)
+	}
+}
+
+func TestLatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		children []string // Each child gets the write end as fd 3.
+		timeout  time.Duration
+		wantErr  error
+		minWait  time.Duration
+	}{
+		{"no children", nil, time.Second, nil, 0},
+		{"children exit", []string{"true", "sleep 0.1", "sleep 0.2"}, 5 * time.Second, nil, 200 * time.Millisecond},
+		// The last child arrives by closing fd 3, long before it exits.
+		{"child arrives early", []string{"sleep 0.1", "exec 3>&-; sleep 60"}, 5 * time.Second, nil, 100 * time.Millisecond},
+		{"cancelled", []string{"sleep 60"}, 100 * time.Millisecond, context.DeadlineExceeded, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := NewLatch()
+			if err != nil {
+				t.Fatalf("NewLatch: %v", err)
+			}
+			defer l.Close()
+			start := time.Now()
+			for _, script := range tt.children {
+				cmd := exec.Command("sh", "-c", script)
+				cmd.ExtraFiles = []*os.File{l.WriteEnd()}
+				if err := cmd.Start(); err != nil {
+					t.Fatal(err)
+				}
+				defer func() {
+					cmd.Process.Kill()
+					cmd.Wait()
+				}()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			err = l.Wait(ctx)
+			took := time.Since(start)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Wait: error %v, want %v", err, tt.wantErr)
+			}
+			if took < tt.minWait {
+				t.Errorf("Wait returned after %v, want at least %v", took, tt.minWait)
+			}
+		})
+	}
+}
+
+func TestLineScanner(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	tests := []struct {
+		name    string
+		max     int
+		text    string
+		want    []string
+		wantErr error
+	}{
+		{"short lines", 0, "a\nb\n", []string{"a", "b"}, nil},
+		{"line over 64 KiB", 1 << 20, "a\n" + long + "\nb", []string{"a", long, "b"}, nil},
+		{"default maximum", 0, "a\n" + long + "\n", []string{"a"}, bufio.ErrTooLong},
+		{"small maximum", 4, "abc\nabcdef\n", []string{"abc"}, bufio.ErrTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipe()
+			if err != nil {
+				t.Fatalf("NewPipe: %v", err)
+			}
+			defer p.Close()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				io.WriteString(p.wf, tt.text)
+				p.CloseWrite()
+			}()
+			sc := p.LineScanner(tt.max)
+			var got []string
+			for sc.Scan() {
+				got = append(got, sc.Text())
+			}
+			if !errors.Is(sc.Err(), tt.wantErr) {
+				t.Errorf("Err() = %v, want %v", sc.Err(), tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d lines, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d has %d bytes, want %d", i, len(got[i]), len(tt.want[i]))
+				}
+			}
+			io.Copy(io.Discard, p.rf) // Let the writer finish.
+			<-done
+		})
+	}
+}
+
+func TestDoubleClose(t *testing.T) {
+	tests := []struct {
+		name  string
+		close func(p *Pipes) []error
+	}{
+		{"read twice", func(p *Pipes) []error { return []error{p.CloseRead(), p.CloseRead()} }},
+		{"write twice", func(p *Pipes) []error { return []error{p.CloseWrite(), p.CloseWrite()} }},
+		{"close twice", func(p *Pipes) []error { return []error{p.Close(), p.Close()} }},
+		{"ends then close", func(p *Pipes) []error { return []error{p.CloseRead(), p.CloseWrite(), p.Close()} }},
+		{"close then ends", func(p *Pipes) []error { return []error{p.Close(), p.CloseRead(), p.CloseWrite()} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipe()
+			if err != nil {
+				t.Fatalf("NewPipe: %v", err)
+			}
+			rfd, wfd := p.rfd, p.wfd
+			p.Close()
+			// Another pipe takes the numbers the first one just gave back.
+			q, err := NewPipe()
+			if err != nil {
+				t.Fatalf("NewPipe: %v", err)
+			}
+			defer q.Close()
+			if q.rfd != rfd || q.wfd != wfd {
+				t.Skipf("fds %d,%d not reused (got %d,%d)", rfd, wfd, q.rfd, q.wfd)
+			}
+			// Every close now is a second one, of numbers q owns.
+			for i, err := range tt.close(p) {
+				if err != nil {
+					t.Errorf("close %d: %v", i, err)
+				}
+			}
+			for _, fd := range []int{q.rfd, q.wfd} {
+				if _, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0); err != nil {
+					t.Errorf("fd %d of the other pipe was closed: %v", fd, err)
+				}
+			}
+		})
+	}
+}
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name        string
+		write       func(p *Pipes) error
+		peek        int
+		wantWritten int64
+		wantRead    int64
+	}{
+		{"Write", func(p *Pipes) error { _, err := p.Write([]byte("hello")); return err }, 0, 5, 5},
+		{"WriteString over capacity", func(p *Pipes) error {
+			_, err := p.WriteString(strings.Repeat("x", 200000))
+			return err
+		}, 0, 200000, 200000},
+		// Bytes written to the raw fd are read, but never written, through p.
+		{"raw write end", func(p *Pipes) error { _, err := p.wf.Write([]byte("raw")); return err }, 0, 0, 3},
+		// Peeked bytes are counted once, when Read returns them.
+		{"peeked", func(p *Pipes) error { _, err := p.Write([]byte("header+body")); return err }, 6, 11, 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipe()
+			if err != nil {
+				t.Fatalf("NewPipe: %v", err)
+			}
+			defer p.Close()
+			errc := make(chan error, 1)
+			go func() {
+				err := tt.write(p)
+				p.CloseWrite()
+				errc <- err
+			}()
+			if tt.peek > 0 {
+				if _, err := p.Peek(tt.peek); err != nil {
+					t.Fatalf("Peek: %v", err)
+				}
+			}
+			if _, err := io.Copy(io.Discard, p); err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			if w, r := p.Stats(); w != tt.wantWritten || r != tt.wantRead {
+				t.Errorf("Stats() = %d written, %d read, want %d, %d", w, r, tt.wantWritten, tt.wantRead)
+			}
+		})
+	}
+}
+
+func TestSafePopen(t *testing.T) {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("no echo in PATH")
+	}
+	if echo, err = filepath.Abs(echo); err != nil {
+		t.Fatal(err)
+	}
+	allowed := map[string]bool{echo: true}
+	tests := []struct {
+		name    string
+		argv    []string
+		mode    int
+		want    string
+		wantErr error
+	}{
+		{"allowed", []string{"echo", "hi"}, POPENREAD, "hi\n", nil},
+		{"allowed by path", []string{echo, "hi"}, POPENREAD, "hi\n", nil},
+		{"metacharacters are text", []string{"echo", "a; rm -rf x", "$(id)", "`id`", "|", ">f"}, POPENREAD,
+			"a; rm -rf x $(id) `id` | >f\n", nil},
+		{"own group", []string{"echo", "g"}, POPENREAD | POPENSETPGID, "g\n", nil},
+		{"not allowed", []string{"sh", "-c", "echo hi"}, POPENREAD, "", os.ErrPermission},
+		{"not found", []string{"no-such-command-here"}, POPENREAD, "", exec.ErrNotFound},
+		{"no command", nil, POPENREAD, "", os.ErrInvalid},
+		{"bad mode", []string{"echo"}, 7, "", os.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := SafePopen(tt.argv, allowed, tt.mode)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SafePopen: %v", err)
+			}
+			out, err := io.ReadAll(h.File())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res, err := h.Close(); err != nil || res.Code != 0 {
+				t.Errorf("Close: %+v, %v", res, err)
+			}
+			if string(out) != tt.want {
+				t.Errorf("output %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeek(t *testing.T) {
+	big := strings.Repeat("y", 8192)
+	tests := []struct {
+		name    string
+		text    string
+		peeks   []int
+		want    string
+		wantErr error
+	}{
+		{"header then message", "HDR1payload", []int{4}, "HDR1", nil},
+		{"peek twice", "HDR1payload", []int{4, 4}, "HDR1", nil},
+		{"grow the buffer", big, []int{4, len(big)}, big, nil},
+		{"short input", "ab", []int{4}, "ab", io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipe()
+			if err != nil {
+				t.Fatalf("NewPipe: %v", err)
+			}
+			defer p.Close()
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				io.WriteString(p.wf, tt.text)
+				p.CloseWrite()
+			}()
+			var got []byte
+			for _, n := range tt.peeks {
+				got, err = p.Peek(n)
+			}
+			if string(got) != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("Peek = %d bytes, %v; want %d bytes, %v", len(got), err, len(tt.want), tt.wantErr)
+			}
+			all, err := io.ReadAll(p)
+			if err != nil {
+				t.Fatalf("ReadAll: %v", err)
+			}
+			if string(all) != tt.text {
+				t.Errorf("read %d bytes after Peek, want the whole %d", len(all), len(tt.text))
+			}
+			if n := p.nread.Load(); n != int64(len(tt.text)) {
+				t.Errorf("counted %d bytes read, want %d", n, len(tt.text))
+			}
+			<-done
+		})
+	}
+	p, err := NewPipe()
+	if err != nil {
+		t.Fatalf("NewPipe: %v", err)
+	}
+	defer p.Close()
+	if _, err := p.Peek(-1); err == nil {
+		t.Error("Peek(-1) succeeded, want an error")
+	}
+}
+
+func TestPopenLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		want     []string
+		wantCode int
+		wantErr  bool
+	}{
+		{"three lines", `printf 'a\nb\nc\n'`, []string{"a", "b", "c"}, 0, false},
+		{"no final newline", "printf 'a\\nb'", []string{"a", "b"}, 0, false},
+		{"no output", "true", nil, 0, false},
+		{"fails after output", "echo a; exit 2", []string{"a"}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, closeFn, err := PopenLines(tt.cmd)
+			if err != nil {
+				t.Fatalf("PopenLines: %v", err)
+			}
+			var got []string
+			for sc.Scan() {
+				got = append(got, sc.Text())
+			}
+			if (sc.Err() != nil) != tt.wantErr {
+				t.Errorf("Err() = %v, want error %v", sc.Err(), tt.wantErr)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("lines %q, want %q", got, tt.want)
+			}
+			res, err := closeFn()
+			if err != nil {
+				t.Fatalf("close: %v", err)
+			}
+			if res.Code != tt.wantCode || res.Killed {
+				t.Errorf("close gave %+v, want code %d", res, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestReaderWithProgress(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		every     time.Duration
+		wantCalls func(n int) bool
+	}{
+		{"once at EOF", 100000, time.Hour, func(n int) bool { return n == 1 }},
+		{"on every read", 100000, time.Nanosecond, func(n int) bool { return n > 1 }},
+		{"empty payload", 0, time.Nanosecond, func(n int) bool { return n == 0 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipe()
+			if err != nil {
+				t.Fatalf("NewPipe: %v", err)
+			}
+			defer p.Close()
+			p.SetProgressInterval(tt.every)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				p.WriteString(strings.Repeat("z", tt.size))
+				p.CloseWrite()
+			}()
+			var totals []int64
+			r := p.ReaderWithProgress(func(total int64) { totals = append(totals, total) })
+			n, err := io.Copy(io.Discard, r)
+			if err != nil || n != int64(tt.size) {
+				t.Fatalf("read %d bytes, %v; want %d", n, err, tt.size)
+			}
+			<-done
+			if err := r.Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+			if !tt.wantCalls(len(totals)) {
+				t.Errorf("callback called %d times", len(totals))
+			}
+			for i := 1; i < len(totals); i++ {
+				if totals[i] <= totals[i-1] {
+					t.Errorf("totals not increasing: %v", totals)
+					break
+				}
+			}
+			if len(totals) > 0 && totals[len(totals)-1] != int64(tt.size) {
+				t.Errorf("final total %d, want %d", totals[len(totals)-1], tt.size)
+			}
+		})
+	}
+}
+
+func TestPopenCapture(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		wantOut  string
+		wantErr  string
+		wantCode int
+	}{
+		{"both streams", "echo out; echo err >&2", "out\n", "err\n", 0},
+		{"failing command", "echo oops >&2; exit 4", "", "oops\n", 4},
+		{"stderr past a pipe's capacity", "head -c 100000 /dev/zero | tr '\\0' e >&2; echo done",
+			"done\n", strings.Repeat("e", 100000), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, stderr, err := PopenCapture(tt.cmd)
+			if err != nil {
+				t.Fatalf("PopenCapture: %v", err)
+			}
+			defer stderr.Close()
+			errc := make(chan string, 1)
+			go func() {
+				b, _ := io.ReadAll(stderr)
+				errc <- string(b)
+			}()
+			out, err := io.ReadAll(h.f)
+			if err != nil {
+				t.Fatalf("read stdout: %v", err)
+			}
+			if got := <-errc; got != tt.wantErr {
+				t.Errorf("stderr has %d bytes %.20q, want %d bytes %.20q", len(got), got, len(tt.wantErr), tt.wantErr)
+			}
+			if string(out) != tt.wantOut {
+				t.Errorf("stdout %q, want %q", out, tt.wantOut)
+			}
+			res, err := h.Close()
+			if err != nil || res.Code != tt.wantCode {
+				t.Errorf("Close = %+v, %v; want code %d", res, err, tt.wantCode)
+			}
+		})
+	}
+	if _, _, err := PopenCapture(""); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("PopenCapture(\"\"): %v, want os.ErrInvalid", err)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		kill       bool
+		wantErr    error
+		wantExit   int
+		wantSignal unix.Signal
+	}{
+		{"exits", []string{"sh", "-c", "exit 3"}, false, nil, 3, 0},
+		{"still running", []string{"sleep", "5"}, false, os.ErrDeadlineExceeded, 0, 0},
+		{"killed", []string{"sleep", "5"}, true, nil, -1, unix.SIGKILL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(tt.args[0], tt.args[1:]...)
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			pid := cmd.Process.Pid
+			defer func() {
+				cmd.Process.Kill()
+				WaitTimeout(pid, time.Second)
+			}()
+			d := 2 * time.Second
+			if tt.wantErr != nil {
+				d = 100 * time.Millisecond
+			}
+			if tt.kill {
+				cmd.Process.Kill()
+			}
+			start := time.Now()
+			ws, err := WaitTimeout(pid, d)
+			if took := time.Since(start); took > d+time.Second {
+				t.Errorf("WaitTimeout took %v", took)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("WaitTimeout: %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if ws.ExitStatus() != tt.wantExit || ws.Signaled() != (tt.wantSignal != 0) || ws.Signaled() && ws.Signal() != tt.wantSignal {
+				t.Errorf("status exit %d signal %v, want exit %d signal %v", ws.ExitStatus(), ws.Signal(), tt.wantExit, tt.wantSignal)
+			}
+		})
+	}
+	if _, err := WaitTimeout(os.Getpid(), 0); !errors.Is(err, unix.ECHILD) {
+		t.Errorf("WaitTimeout on a non-child: %v, want ECHILD", err)
+	}
+}
+
+// alive reports whether pid is running, counting a zombie as gone.
+func alive(pid int) bool {
+	b, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return false
+	}
+	s := string(b)
+	i := strings.LastIndexByte(s, ')')
+	return i < 0 || i+2 >= len(s) || s[i+2] != 'Z'
+}
+
+func TestKillGroup(t *testing.T) {
+	tests := []struct {
+		name           string
+		group          bool
+		wantGrandchild bool
+		wantFuncErr    error
+	}{
+		{"group", true, false, nil},
+		{"no group", false, true, unix.ESRCH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			open := POpenHandle
+			if tt.group {
+				open = POpenHandleGroup
+			}
+			h, err := open("sleep 60 & echo $!; wait", "r")
+			if err != nil {
+				t.Fatalf("popen: %v", err)
+			}
+			sc := bufio.NewScanner(h.f)
+			if !sc.Scan() {
+				t.Fatalf("no grandchild pid: %v", sc.Err())
+			}
+			gpid, err := strconv.Atoi(sc.Text())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer unix.Kill(gpid, unix.SIGKILL)
+			if err := KillGroup(h.proc.Pid, unix.SIGTERM); !errors.Is(err, tt.wantFuncErr) {
+				t.Errorf("KillGroup: %v, want %v", err, tt.wantFuncErr)
+			}
+			if err := h.KillGroup(unix.SIGTERM); err != nil {
+				t.Errorf("PopenHandle.KillGroup: %v", err)
+			}
+			res, err := h.Close()
+			if err != nil || res.Signal != unix.SIGTERM {
+				t.Errorf("Close = %+v, %v; want killed by SIGTERM", res, err)
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for alive(gpid) && !tt.wantGrandchild && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+			if got := alive(gpid); got != tt.wantGrandchild {
+				t.Errorf("grandchild alive %v, want %v", got, tt.wantGrandchild)
+			}
+		})
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		n         int
+		cmd       string
+		items     int
+		prefix    string
+		wantClose bool // Close reports a failed worker.
+	}{
+		{"two cats", 2, "cat", 200, "", false},
+		{"sed", 3, "sed -u 's/^/x/'", 50, "x", false},
+		{"workers fail", 2, "read line; echo \"$line\"; exit 3", 2, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wp, err := NewWorkerPool(tt.n, tt.cmd)
+			if err != nil {
+				t.Fatalf("NewWorkerPool: %v", err)
+			}
+			closed := make(chan error, 1)
+			go func() {
+				for i := 0; i < tt.items; i++ {
+					if err := wp.Submit([]byte(strconv.Itoa(i))); err != nil {
+						t.Errorf("Submit(%d): %v", i, err)
+					}
+				}
+				closed <- wp.Close()
+			}()
+			got := map[string]bool{}
+			workers := map[int]bool{}
+			for r := range wp.Results() {
+				if r.Err != nil {
+					t.Errorf("worker %d: %v", r.Worker, r.Err)
+					continue
+				}
+				got[string(r.Data)] = true
+				workers[r.Worker] = true
+			}
+			if err := <-closed; (err != nil) != tt.wantClose {
+				t.Errorf("Close: %v, want error %v", err, tt.wantClose)
+			}
+			for i := 0; i < tt.items; i++ {
+				if want := tt.prefix + strconv.Itoa(i); !got[want] {
+					t.Errorf("result %q missing", want)
+				}
+			}
+			if len(got) != tt.items {
+				t.Errorf("got %d results, want %d", len(got), tt.items)
+			}
+			if len(workers) != tt.n {
+				t.Errorf("%d of %d workers answered", len(workers), tt.n)
+			}
+			if err := wp.Submit([]byte("late")); !errors.Is(err, os.ErrClosed) {
+				t.Errorf("Submit after Close: %v, want os.ErrClosed", err)
+			}
+		})
+	}
+	if _, err := NewWorkerPool(0, "cat"); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("NewWorkerPool(0): %v, want os.ErrInvalid", err)
+	}
+	wp, err := NewWorkerPool(1, "cat")
+	if err != nil {
+		t.Fatalf("NewWorkerPool: %v", err)
+	}
+	if err := wp.Submit([]byte("a\nb")); !errors.Is(err, os.ErrInvalid) {
+		t.Errorf("Submit of two lines: %v, want os.ErrInvalid", err)
+	}
+	if err := wp.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
+
+func TestLinesExitStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        string
+		wantLines  int
+		wantCode   int
+		wantSignal unix.Signal
+	}{
+		{"success", "printf 'a\\nb\\n'", 2, 0, 0},
+		{"exit 1 after two lines", "printf 'a\\nb\\n'; exit 1", 2, 1, 0},
+		{"killed", "echo a; kill -KILL $$", 1, -1, unix.SIGKILL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := POpenHandle(tt.cmd, "r")
+			if err != nil {
+				t.Fatalf("POpenHandle: %v", err)
+			}
+			sc := h.Lines()
+			n := 0
+			for sc.Scan() {
+				n++
+			}
+			if n != tt.wantLines {
+				t.Errorf("scanned %d lines, want %d", n, tt.wantLines)
+			}
+			var res PCloseResult
+			failed := errors.As(sc.Err(), &res)
+			if failed != (tt.wantCode != 0) {
+				t.Fatalf("Err() = %v, want a PCloseResult: %v", sc.Err(), tt.wantCode != 0)
+			}
+			if failed && (res.Code != tt.wantCode || res.Signal != tt.wantSignal) {
+				t.Errorf("Err() carries %+v, want code %d signal %v", res, tt.wantCode, tt.wantSignal)
+			}
+			closed, err := h.Close()
+			if err != nil || closed.Code != tt.wantCode || closed.Signal != tt.wantSignal {
+				t.Errorf("Close = %+v, %v; want the same result as Err", closed, err)
+			}
+		})
+	}
+}
+
+// TestAutoReap runs itself again in a child process, as the reaper cannot be
+// turned off and would take the children other tests wait for with os/exec.
+func TestAutoReap(t *testing.T) {
+	if os.Getenv("PIPE_TEST_AUTOREAP") != "1" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestAutoReap$", "-test.v")
+		cmd.Env = append(os.Environ(), "PIPE_TEST_AUTOREAP=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("auto-reap child: %v\n%s", err, out)
+		}
+		return
+	}
+	EnableAutoReap()
+	EnableAutoReap()
+	tests := []struct {
+		name     string
+		cmd      string
+		wantCode int
+	}{
+		{"success", "exit 0", 0},
+		{"failure", "exit 3", 3},
+		{"after output", "echo hi; exit 7", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, proc, err := POpen(tt.cmd, "r")
+			if err != nil {
+				t.Fatalf("POpen: %v", err)
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				reaper.mu.Lock()
+				_, ok := reaper.statuses[proc.Pid]
+				reaper.mu.Unlock()
+				if ok {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("reaper did not collect pid %d", proc.Pid)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			code, err := PClose(f, proc)
+			if err != nil || code != tt.wantCode {
+				t.Errorf("PClose = %d, %v; want %d", code, err, tt.wantCode)
+			}
+			if _, ok := reaped(proc.Pid); ok {
+				t.Errorf("status of pid %d still recorded after PClose", proc.Pid)
+			}
+		})
+	}
+}
+
+func TestPopenLimits(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	tests := []struct {
+		name    string
+		cmd     string
+		rlimits map[int]unix.Rlimit
+		want    PCloseResult
+	}{
+		{"no limits", "exit 0", nil, PCloseResult{}},
+		{"cpu time", "while :; do :; done", map[int]unix.Rlimit{unix.RLIMIT_CPU: {Cur: 1, Max: 2}}, PCloseResult{Code: -1, Signal: unix.SIGXCPU}},
+		{"file size", "exec head -c 4096 /dev/zero >" + out, map[int]unix.Rlimit{unix.RLIMIT_FSIZE: {Cur: 0, Max: 0}}, PCloseResult{Code: -1, Signal: unix.SIGXFSZ}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := POpenHandleLimits(tt.cmd, "r", tt.rlimits)
+			if err != nil {
+				t.Fatalf("POpenHandleLimits: %v", err)
+			}
+			res, err := h.CloseTimeout(10 * time.Second)
+			if err != nil {
+				t.Fatalf("CloseTimeout: %v", err)
+			}
+			if res.Code != tt.want.Code || res.Signal != tt.want.Signal || res.Killed {
+				t.Errorf("CloseTimeout = %#v, want %#v", res, tt.want)
+			}
+		})
+	}
+}
+
+func TestPopenLinesContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		cmd        string
+		cancel     bool // Cancel on the first line, the child's pid.
+		want       []string
+		wantCode   int
+		wantSignal unix.Signal
+	}{
+		{"to the end", "echo a; echo b", false, []string{"a", "b"}, 0, 0},
+		{"failing", "echo a; exit 3", false, []string{"a"}, 3, 0},
+		{"cancelled", "echo $$; while :; do echo tick; sleep 0.01; done", true, nil, -1, unix.SIGTERM},
+		{"ignores SIGTERM", "trap '' TERM; echo $$; while :; do sleep 0.01; done", true, nil, -1, unix.SIGKILL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			lines, errs := PopenLinesContext(ctx, tt.cmd)
+			var got []string
+			pid := 0
+			for line := range lines {
+				if tt.cancel && pid == 0 {
+					pid, _ = strconv.Atoi(line)
+					cancel()
+					continue
+				}
+				if !tt.cancel {
+					got = append(got, line)
+				}
+			}
+			err := <-errs
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("lines %q, want %q", got, tt.want)
+			}
+			if tt.wantCode == 0 && tt.wantSignal == 0 {
+				if err != nil {
+					t.Errorf("errs: %v, want nil", err)
+				}
+				return
+			}
+			var res PCloseResult
+			if !errors.As(err, &res) || res.Code != tt.wantCode || res.Signal != tt.wantSignal {
+				t.Errorf("errs: %v, want code %d signal %v", err, tt.wantCode, tt.wantSignal)
+			}
+			if errors.Is(err, context.Canceled) != tt.cancel {
+				t.Errorf("errs: %v, cancellation reported %v, want %v", err, !tt.cancel, tt.cancel)
+			}
+			if tt.cancel && (pid == 0 || alive(pid)) {
+				t.Errorf("child %d still running after cancel", pid)
+			}
+			if _, ok := <-errs; ok {
+				t.Error("errs not closed after its error")
+			}
+		})
+	}
+}
+
+func TestTailReader(t *testing.T) {
+	numbered := func(from, to int) []string {
+		var s []string
+		for i := from; i < to; i++ {
+			s = append(s, strconv.Itoa(i))
+		}
+		return s
+	}
+	long := strings.Repeat("x", bufio.MaxScanTokenSize+10)
+	tests := []struct {
+		name string
+		max  int
+		text string
+		want []string
+	}{
+		{"last 100 of 1000", 100, strings.Join(numbered(0, 1000), "\n") + "\n", numbered(900, 1000)},
+		{"fewer than kept", 100, "a\nb\n", []string{"a", "b"}},
+		{"exactly full", 3, "a\nb\nc\n", []string{"a", "b", "c"}},
+		{"keeps at least one", 0, "a\nb\n", []string{"b"}},
+		{"unterminated last line", 2, "a\nb\nc", []string{"b", "c"}},
+		{"long line cut short", 2, long + "\nb\n", []string{long[:bufio.MaxScanTokenSize], "b"}},
+		{"nothing", 5, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewPipe()
+			if err != nil {
+				t.Fatalf("NewPipe: %v", err)
+			}
+			defer p.Close()
+			written := make(chan struct{})
+			go func() {
+				defer close(written)
+				io.WriteString(p.wf, tt.text)
+				p.CloseWrite()
+			}()
+			tail := p.TailReader(tt.max)
+			select {
+			case <-tail.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("TailReader never saw EOF")
+			}
+			<-written
+			if err := tail.Err(); err != nil {
+				t.Errorf("Err() = %v", err)
+			}
+			got := tail.Snapshot()
+			if len(got) != len(tt.want) {
+				t.Fatalf("kept %d lines, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d is %.20q (%d bytes), want %.20q (%d bytes)", i, got[i], len(got[i]), tt.want[i], len(tt.want[i]))
+				}
+			}
+		})
+	}
+}
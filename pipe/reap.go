@@ -0,0 +1,94 @@
+//go:build linux && amd64
+// +build linux,amd64
+
+// Filename: reap.go
+// An opt-in SIGCHLD handler that reaps exited children as soon as they exit,
+// so a program that is slow to PClose its popen'ed children does not collect
+// zombies, and keeps their statuses for PClose to pick up later.
+package pipe
+
+import (
+  "os"
+  "os/signal"
+  "sync"
+
+  "golang.org/x/sys/unix"
+)
+
+// reaper holds what the SIGCHLD handler has reaped. mu is held across each
+// Wait4 and the recording of its status, so a waiter that gets ECHILD can take
+// mu and be sure the status is in statuses.
+var reaper struct {
+  mu       sync.Mutex                   // Protects the rest.
+  once     sync.Once                    // Install the handler only once.
+  statuses map[int]unix.WaitStatus      // Statuses reaped and not yet claimed.
+}
+
+// EnableAutoReap installs a SIGCHLD handler that reaps every child that
+// exits with Wait4(-1, WNOHANG), in a loop as signals coalesce, and records
+// its status by pid. PClose, PopenHandle's Close and CloseTimeout, WaitTimeout
+// and the rest of this package find a status there when Wait4 reports ECHILD,
+// so they still return how the child ended, without blocking if it already
+// has. Calling it again does nothing; it cannot be undone.
+//
+// It reaps every child of the process, not only the ones started here. Do not
+// enable it in a program that waits for children some other way, such as
+// os/exec's Cmd.Wait or os.Process.Wait: the reaper may get to the child
+// first and those waits fail with ECHILD. A status nobody claims is kept for
+// the life of the process. The handler only listens for SIGCHLD, which the
+// utils package's SignalHandler does not, so the two can be used together.
+func EnableAutoReap() {
+  reaper.once.Do(func() {               // Only the first call does anything.
+    reaper.mu.Lock()                    // Protect the map.
+    reaper.statuses=make(map[int]unix.WaitStatus)// Where statuses go.
+    reaper.mu.Unlock()                  // Done creating the map.
+    ch:=make(chan os.Signal,1)          // One pending SIGCHLD is enough.
+    signal.Notify(ch,unix.SIGCHLD)      // Tell us when a child exits.
+    go func(){                          // Reap in the background.
+      for range ch{                     // Each time children have exited...
+        reapAll()                       // ...reap all of them.
+      }                                 // Done waiting for signals.
+    }()                                 // Done starting the reaper.
+    reapAll()                           // Some may have exited already.
+  })                                    // Done installing the reaper.
+}                                       // -------- EnableAutoReap ---------- //
+// reapAll reaps every child that has exited and records its status.
+func reapAll() {
+  for {                                 // Until no child is left to reap...
+    reaper.mu.Lock()                    // Reap and record as one step.
+    var ws unix.WaitStatus              // The child's status.
+    pid,err:=unix.Wait4(-1,&ws,unix.WNOHANG,nil)// Has a child exited?
+    if err==unix.EINTR{                 // Interrupted by a signal?
+      reaper.mu.Unlock()                // Yes, let go of the lock...
+      continue                          // ...and ask again.
+    }                                   // Done checking for EINTR.
+    if err!=nil||pid<=0{                // No children, or none exited?
+      reaper.mu.Unlock()                // Yes, let go of the lock.
+      return                            // We are done for now.
+    }                                   // Done checking for a child.
+    reaper.statuses[pid]=ws             // Record how it ended.
+    reaper.mu.Unlock()                  // Let go of the lock.
+  }                                     // Done reaping.
+}                                       // ------------ reapAll ------------- //
+// reaped returns, and forgets, the status the reaper recorded for pid.
+func reaped(pid int) (unix.WaitStatus,bool) {
+  reaper.mu.Lock()                      // Wait for any reap in progress.
+  defer reaper.mu.Unlock()              // Let go of the lock when done.
+  ws,ok:=reaper.statuses[pid]           // Did the reaper get it?
+  if ok{                                // Yes?
+    delete(reaper.statuses,pid)         // It is claimed now.
+  }                                     // Done checking for status.
+  return ws,ok                          // Return what we found.
+}                                       // ------------ reaped -------------- //
+// wait4 is unix.Wait4 for a single child, that also finds the child's status
+// when the auto-reaper got to it first.
+func wait4(pid int, ws *unix.WaitStatus, options int) (int,error) {
+  wpid,err:=unix.Wait4(pid,ws,options,nil)// Wait for the child.
+  if err==unix.ECHILD{                  // Has somebody else reaped it?
+    if st,ok:=reaped(pid);ok{           // Was it the auto-reaper?
+      *ws=st                            // Yes, here is how it ended.
+      return pid,nil                    // As if we had reaped it.
+    }                                   // Done checking the reaper.
+  }                                     // Done checking for ECHILD.
+  return wpid,err                       // Return what Wait4 said.
+}                                       // ------------- wait4 -------------- //
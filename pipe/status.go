@@ -0,0 +1,77 @@
+// Filename: status.go
+// Status codes the pipe demonstration programs report, shared so each
+// command does not have to define its own. Unlike the rest of the package,
+// this file builds on every platform.
+package pipe
+
+import "errors"
+
+// ErrUnsupportedPlatform is what the pipe functions return on a platform
+// other than Linux on amd64, where they are only stubs.
+var ErrUnsupportedPlatform=errors.New("pipe: not supported on this platform")
+
+// Status says how a pipe operation ended. The failures are errors, so a
+// function can return a Status where an error is expected; use Err to get
+// nil for the statuses that are not failures.
+type Status int
+
+const (
+  Success Status=iota                   // No errors
+  ForkError                             // Fork error
+  PipeError                             // Pipe error
+  PipeCreated                           // Pipe created successfully
+  PipeReadEndClosed                     // Read end of pipe closed
+  PipeWriteEndClosed                    // Write end of pipe closed
+  PipeReadError                         // Read error
+  PipeWriteError                        // Write error
+  GotEOF                                // EOF encountered
+  ExecError                             // Exec error
+  UnknownError                          // Unknown error
+)
+
+// StatusToString returns a short description of a status code.
+func StatusToString(status Status) string {
+  switch status {                       // Check the status code
+  case Success:                         // No errors
+    return "Success"                    // Return the string
+  case ForkError:                       // Fork error
+    return "Fork error"                 // Return the string
+  case PipeError:                       // Pipe error
+    return "Pipe error"                 // Return the string
+  case PipeCreated:                     // Pipe created successfully
+    return "Pipe created successfully"  // Return the string
+  case PipeReadEndClosed:               // Read end of pipe closed
+    return "Read end of pipe closed"    // Return the string
+  case PipeWriteEndClosed:              // Write end of pipe closed
+    return "Write end of pipe closed"   // Return the string
+  case PipeReadError:                   // Read error
+    return "Read error"                 // Return the string
+  case PipeWriteError:                  // Write error
+    return "Write error"                // Return the string
+  case GotEOF:                          // EOF encountered
+    return "EOF encountered"            // Return the string
+  case ExecError:                       // Exec error
+    return "Exec error"                 // Return the string
+  case UnknownError:                    // Unknown error
+    return "Unknown error"              // Return the string
+  default:                              // Unknown status code
+    return "Unknown status code"        // Return the string
+  }                                     // Done stringing the status code.
+}                                       // ------------ StatusToString ------- //
+// String returns the same description as StatusToString.
+func (s Status) String() string {
+  return StatusToString(s)              // Describe the status.
+}                                       // ------------ String --------------- //
+// Error makes Status an error, described as by StatusToString.
+func (s Status) Error() string {
+  return StatusToString(s)              // Describe the status.
+}                                       // ------------ Error ---------------- //
+// Err returns nil for the statuses that are not failures, Success,
+// PipeCreated and GotEOF, and the status itself as an error otherwise.
+func (s Status) Err() error {
+  switch s {                            // Is it a failure?
+  case Success, PipeCreated, GotEOF:    // No, these are fine.
+    return nil                          // So no error.
+  }                                     // Done checking for failure.
+  return s                              // Yes, it is its own error.
+}                                       // ------------ Err ------------------ //
@@ -0,0 +1,49 @@
+package pipe
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		status  Status
+		want    string
+		wantErr bool
+	}{
+		{Success, "Success", false},
+		{PipeCreated, "Pipe created successfully", false},
+		{GotEOF, "EOF encountered", false},
+		{ForkError, "Fork error", true},
+		{PipeError, "Pipe error", true},
+		{ExecError, "Exec error", true},
+		{UnknownError, "Unknown error", true},
+		{Status(99), "Unknown status code", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.status.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+			if got := fmt.Sprint(tt.status); got != tt.want {
+				t.Errorf("Sprint = %q, want %q", got, tt.want)
+			}
+			err := tt.status.Err()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Err() = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			wrapped := fmt.Errorf("running the demo: %w", err)
+			var s Status
+			if !errors.As(wrapped, &s) || s != tt.status {
+				t.Errorf("errors.As recovered %v, want %v", s, tt.status)
+			}
+			if !errors.Is(wrapped, tt.status) {
+				t.Errorf("errors.Is(%v, %v) is false", wrapped, tt.status)
+			}
+		})
+	}
+}
@@ -24,6 +24,9 @@ const (
 	// Popen read and write flags:
 	POPENREAD=0      
 	POPENWRITE=1
+	// Popen flag, or'ed with POPENREAD or POPENWRITE: the child leads its own
+	// process group, so KillGroup reaches everything it starts.
+	POPENSETPGID=2
 )
 
 // Pipe is a wrapper around the pipe(2) syscall.
@@ -142,8 +145,62 @@ func Dup3(oldfd, newfd, flags int) (int, error) {
 // Popen is similar to C's popen("cmd",mode). It creates a pipe
 // then forks. In the child it hooks up pipe -> stdin/stdout, then 
 // execve("/bin/sh","-c",cmd). In the parent it closes the unused end
-// of the pipe. Flags must be 0, POPENREAD or POPENWRITE.
+// of the pipe. Flags must be 0, POPENREAD or POPENWRITE, or'ed with
+// POPENSETPGID if the child is to lead its own process group, for KillGroup.
 func Popen(cmd string, flags int) (fd, pid int, err error) {
+  return PopenArgv("/bin/sh",[]string{"sh","-c",cmd},flags)// Let the shell run it.
+}                                       // ------------ Popen ------------
+
+// PopenLimits is Popen with resource limits: before it execve's the shell,
+// the child sets each limit in rlimits, keyed by resource (unix.RLIMIT_CPU,
+// unix.RLIMIT_AS, unix.RLIMIT_FSIZE...), as PopenArgvLimits does.
+func PopenLimits(cmd string, flags int, rlimits map[int]unix.Rlimit) (fd, pid int, err error) {
+  return PopenArgvLimits("/bin/sh",[]string{"sh","-c",cmd},flags,rlimits)// Let the shell run it.
+}                                       // ---------- PopenLimits ----------
+
+// PopenArgv is Popen without the shell: the child execve's path with argv
+// exactly as given, so nothing in argv is ever interpreted by a shell. If the
+// execve fails the child exits with status 127, as a shell would.
+func PopenArgv(path string, argv []string, flags int) (fd, pid int, err error) {
+  return PopenArgvLimits(path,argv,flags,nil)// No limits to set.
+}                                       // ---------- PopenArgv ----------
+
+// PopenArgvLimits is PopenArgv with resource limits, to cap the CPU time,
+// memory or file size of a command we do not trust. Between fork and execve
+// the child calls setrlimit(2) for each resource in rlimits, so a limit
+// applies to the command and everything it starts, and the soft limit's
+// signal, say SIGXCPU for RLIMIT_CPU, shows in the Signal of its PCloseResult.
+// If a limit cannot be set the child exits with status 127 without running
+// anything. The child runs in the window between fork and execve, where the
+// Go runtime is not usable: it may only make raw, async-signal-safe system
+// calls, so everything it needs is built before the fork.
+func PopenArgvLimits(path string, argv []string, flags int, rlimits map[int]unix.Rlimit) (fd, pid int, err error) {
+  // ---------------------------------- //
+  // Build everything the child needs before we fork, so the child does not
+  // have to allocate, nor walk a map.
+  // ---------------------------------- //
+  type limit struct {                   // One setrlimit(2) for the child.
+    resource int                        // What to limit.
+    rlim     unix.Rlimit                // And how much.
+  }                                     // Done declaring a limit.
+  setpgid:=flags&POPENSETPGID!=0        // Must the child lead its own group?
+  flags&^=POPENSETPGID                  // The rest says which way the pipe goes.
+  limits:=make([]limit,0,len(rlimits))  // The limits, as a slice.
+  for r,l:=range rlimits{               // For each limit asked for...
+    limits=append(limits,limit{r,l})    // ...the child sets it.
+  }                                     // Done flattening the limits.
+  argv0,err:=unix.BytePtrFromString(path)// The program to run.
+  if err!=nil{                          // Could we convert it?
+    return 0,0,err                      // No, return 0 and error.
+  }                                     // Done converting the path.
+  argvp,err:=cstrings(argv)             // Its arguments.
+  if err!=nil{                          // Could we convert them?
+    return 0,0,err                      // No, return 0 and error.
+  }                                     // Done converting the arguments.
+  envp,err:=cstrings(os.Environ())      // And our environment.
+  if err!=nil{                          // Could we convert it?
+    return 0,0,err                      // No, return 0 and error.
+  }                                     // Done converting the environment.
   // ---------------------------------- //
   // First create a pipe
   // ---------------------------------- //
@@ -154,7 +211,7 @@ func Popen(cmd string, flags int) (fd, pid int, err error) {
   // ---------------------------------- //
   // Fork the process
   // ---------------------------------- //
-  pidraw,_,errno:=unix.Syscall(unix.SYS_FORK,0,0,0)
+  pidraw,_,errno:=unix.RawSyscall(unix.SYS_FORK,0,0,0)// No runtime hooks in the child.
   if errno!=0{                          // Fork failed?
 	unix.Close(int(fds[0]))             // Yes, close the pipe.
 	unix.Close(int(fds[1]))             // Close the other end.
@@ -162,46 +219,233 @@ func Popen(cmd string, flags int) (fd, pid int, err error) {
   }                                     // Done checking error
   pid=int(pidraw)                       // Get the pid
   if pid==0{                            // Are we the child process.
+    // -------------------------------- //
+    // Only raw syscalls from here on: the runtime is not ours in the child.
+    // -------------------------------- //
+    if setpgid{                         // Must we lead our own group?
+      unix.RawSyscall(unix.SYS_SETPGID,0,0,0)// Yes, so it can be killed whole.
+    }                                   // Done checking for a group.
     if flags==POPENREAD{                // Yes, we are the child and we writing.
 	  // ------------------------------ //
 	  // Child writes into pipe -> Dup2(fds[1],STDOUT_FILENO)
 	  // ------------------------------ //
-      unix.Close(int(fds[0]))           // Close the read end of the pipe.
-	  unix.Dup2(int(fds[1]),int(unix.Stdout)) // Redirect stdout to pipe.
+      unix.RawSyscall(unix.SYS_CLOSE,uintptr(fds[0]),0,0)// Close the read end of the pipe.
+	  unix.RawSyscall(unix.SYS_DUP2,uintptr(fds[1]),uintptr(unix.Stdout),0)// Redirect stdout to pipe.
 	} else{                             // We are the child and we reading.
       // ------------------------------ //
 	  // Child reads from pipe -> Dup2(fds[0],STDIN_FILENO)
 	  // ------------------------------ //
-	  unix.Close(int(fds[1]))           // Close the write end of the pipe.
-	  unix.Dup2(int(fds[0]),int(unix.Stdin)) // Redirect stdin to pipe.
+	  unix.RawSyscall(unix.SYS_CLOSE,uintptr(fds[1]),0,0)// Close the write end of the pipe.
+	  unix.RawSyscall(unix.SYS_DUP2,uintptr(fds[0]),uintptr(unix.Stdin),0)// Redirect stdin to pipe.
 	}                                   // Done acting according to pid.
-	arg0 := "sh"                        // Get the first argument.
-	arg1 := "-c"                        // Get the second argument.
-	argv := []string{arg0, arg1, cmd}   // Create the argument list as []string.
-	env := os.Environ()                 // Get the environment as []string.
+	for i:=range limits{                // For each limit to set...
+	  _,_,e:=unix.RawSyscall(unix.SYS_SETRLIMIT,uintptr(limits[i].resource),
+	    uintptr(unsafe.Pointer(&limits[i].rlim)),0)
+	  if e!=0{                          // Could we set it?
+	    unix.RawSyscall(unix.SYS_EXIT_GROUP,127,0,0)// No, run nothing.
+	  }                                 // Done checking for error.
+	}                                   // Done setting limits.
 	// -------------------------------- //
-	// Now execve the command.
+	// Now execve the command. It only returns if it failed.
 	// -------------------------------- //
-	unix.Exec("/bin/sh", argv, env)     // Execute the command with []string env.
-	return -1,pid,unix.EINVAL              // Execve failed, return 0 and error.
+	unix.RawSyscall(unix.SYS_EXECVE,uintptr(unsafe.Pointer(argv0)),
+	  uintptr(unsafe.Pointer(&argvp[0])),uintptr(unsafe.Pointer(&envp[0])))
+	unix.RawSyscall(unix.SYS_EXIT_GROUP,127,0,0)// Execve failed, like sh does.
   }                                     // Done checking pid.
   // ---------------------------------- //
   // Parent process
   // ---------------------------------- //
+  if setpgid{                           // Does the child lead its own group?
+    unix.Setpgid(pid,pid)               // Yes, in case it has not got there yet.
+  }                                     // Done checking for a group.
   if flags==POPENREAD{                  // We are the parent and we reading.
 	unix.Close(int(fds[1]))             // Close the write end of the pipe.
 	return int(fds[0]),pid,nil          // Return the read end of the pipe.
   }                                     // Done checking if reading.
   unix.Close(int(fds[0]))               // Close the read end of the pipe.
   return int(fds[1]),pid,nil            // Return the write end of the pipe.
-}                                       // ------------ Popen ------------
+}                                       // -------- PopenArgvLimits --------
+
+// cstrings converts ss to the NULL-terminated array of C strings execve wants.
+func cstrings(ss []string) ([]*byte, error) {
+  ptrs:=make([]*byte,len(ss)+1)         // One more for the NULL at the end.
+  for i,str:=range ss{                  // For each string...
+    p,err:=unix.BytePtrFromString(str)  // Convert it to a C string.
+    if err!=nil{                        // Did it have a NUL in it?
+      return nil,err                    // Yes, it can't be passed to execve.
+    }                                   // Done checking for error.
+    ptrs[i]=p                           // Store the C string.
+  }                                     // Done converting strings.
+  return ptrs,nil                       // Return the array.
+}                                       // ----------- cstrings ------------
+
+// Popen2 is a bidirectional Popen. It forks and execve's "/bin/sh -c cmd"
+// with the child's stdin and stdout both hooked to pipes. In the parent it
+// returns rfd, reading the child's stdout, and wfd, writing its stdin. Our
+// ends are close-on-exec, so a child started later, say another worker of a
+// WorkerPool, does not hold this child's stdin open.
+func Popen2(cmd string) (rfd, wfd, pid int, err error) {
+  // ---------------------------------- //
+  // Build everything the child needs before we fork, so the child does not
+  // have to allocate.
+  // ---------------------------------- //
+  argv0,err:=unix.BytePtrFromString("/bin/sh")// The program to run.
+  if err!=nil{                          // Could we convert it?
+    return 0,0,0,err                    // No, return 0 and error.
+  }                                     // Done converting the path.
+  argvp,err:=cstrings([]string{"sh","-c",cmd})// Its arguments.
+  if err!=nil{                          // Could we convert them?
+    return 0,0,0,err                    // No, return 0 and error.
+  }                                     // Done converting the arguments.
+  envp,err:=cstrings(os.Environ())      // And our environment.
+  if err!=nil{                          // Could we convert it?
+    return 0,0,0,err                    // No, return 0 and error.
+  }                                     // Done converting the environment.
+  // ---------------------------------- //
+  // Create the pipes: in feeds the child, out is fed by the child.
+  // ---------------------------------- //
+  var in,out [2]int32                   // Our file descriptor sets.
+  if _,_,e:=unix.Syscall(unix.SYS_PIPE2,uintptr(unsafe.Pointer(&in)),unix.O_CLOEXEC,0);e!=0{
+    return 0,0,0,e                      // Pipe creation failed.
+  }                                     // Input pipe created.
+  if _,_,e:=unix.Syscall(unix.SYS_PIPE2,uintptr(unsafe.Pointer(&out)),unix.O_CLOEXEC,0);e!=0{
+    unix.Close(int(in[0]))              // Close the input pipe.
+    unix.Close(int(in[1]))              // Close the other end.
+    return 0,0,0,e                      // Pipe creation failed.
+  }                                     // Output pipe created.
+  // ---------------------------------- //
+  // Fork the process
+  // ---------------------------------- //
+  pidraw,_,errno:=unix.RawSyscall(unix.SYS_FORK,0,0,0)// No runtime hooks in the child.
+  if errno!=0{                          // Fork failed?
+    unix.Close(int(in[0]))              // Yes, close the pipes.
+    unix.Close(int(in[1]))              // Both ends,
+    unix.Close(int(out[0]))             // of both of them.
+    unix.Close(int(out[1]))             // Done closing the pipes.
+    return 0,0,0,errno                  // Return 0 and error.
+  }                                     // Done checking error
+  pid=int(pidraw)                       // Get the pid
+  if pid==0{                            // Are we the child process.
+    // -------------------------------- //
+    // Only raw syscalls from here on: the runtime is not ours in the child.
+    // dup2 clears close-on-exec on stdin and stdout, the rest close on exec.
+    // -------------------------------- //
+    unix.RawSyscall(unix.SYS_DUP2,uintptr(in[0]),uintptr(unix.Stdin),0)// Read stdin from the input pipe.
+    unix.RawSyscall(unix.SYS_DUP2,uintptr(out[1]),uintptr(unix.Stdout),0)// Write stdout to the output pipe.
+    unix.RawSyscall(unix.SYS_EXECVE,uintptr(unsafe.Pointer(argv0)),
+      uintptr(unsafe.Pointer(&argvp[0])),uintptr(unsafe.Pointer(&envp[0])))
+    unix.RawSyscall(unix.SYS_EXIT_GROUP,127,0,0)// Execve failed, like sh does.
+  }                                     // Done checking pid.
+  // ---------------------------------- //
+  // Parent process
+  // ---------------------------------- //
+  unix.Close(int(in[0]))                // Close the child's stdin end.
+  unix.Close(int(out[1]))               // Close the child's stdout end.
+  return int(out[0]),int(in[1]),pid,nil // Return our ends of the pipes.
+}                                       // ------------ Popen2 ------------
+
+// PopenStderr is a read-mode Popen that keeps the child's stderr apart. It
+// forks and execve's "/bin/sh -c cmd" with the child's stdout and stderr
+// hooked to two different pipes, and returns outfd, reading its stdout, and
+// errfd, reading its stderr. The child's stdin is ours.
+func PopenStderr(cmd string) (outfd, errfd, pid int, err error) {
+  // ---------------------------------- //
+  // Build everything the child needs before we fork, so the child does not
+  // have to allocate.
+  // ---------------------------------- //
+  argv0,err:=unix.BytePtrFromString("/bin/sh")// The program to run.
+  if err!=nil{                          // Could we convert it?
+    return 0,0,0,err                    // No, return 0 and error.
+  }                                     // Done converting the path.
+  argvp,err:=cstrings([]string{"sh","-c",cmd})// Its arguments.
+  if err!=nil{                          // Could we convert them?
+    return 0,0,0,err                    // No, return 0 and error.
+  }                                     // Done converting the arguments.
+  envp,err:=cstrings(os.Environ())      // And our environment.
+  if err!=nil{                          // Could we convert it?
+    return 0,0,0,err                    // No, return 0 and error.
+  }                                     // Done converting the environment.
+  // ---------------------------------- //
+  // Create the pipes: out gets the child's stdout, errp its stderr.
+  // ---------------------------------- //
+  var out,errp [2]int32                 // Our file descriptor sets.
+  if _,_,e:=unix.Syscall(unix.SYS_PIPE2,uintptr(unsafe.Pointer(&out)),unix.O_CLOEXEC,0);e!=0{
+    return 0,0,0,e                      // Pipe creation failed.
+  }                                     // Output pipe created.
+  if _,_,e:=unix.Syscall(unix.SYS_PIPE2,uintptr(unsafe.Pointer(&errp)),unix.O_CLOEXEC,0);e!=0{
+    unix.Close(int(out[0]))             // Close the output pipe.
+    unix.Close(int(out[1]))             // Close the other end.
+    return 0,0,0,e                      // Pipe creation failed.
+  }                                     // Error pipe created.
+  // ---------------------------------- //
+  // Fork the process
+  // ---------------------------------- //
+  pidraw,_,errno:=unix.RawSyscall(unix.SYS_FORK,0,0,0)// No runtime hooks in the child.
+  if errno!=0{                          // Fork failed?
+    unix.Close(int(out[0]))             // Yes, close the pipes.
+    unix.Close(int(out[1]))             // Both ends,
+    unix.Close(int(errp[0]))            // of both of them.
+    unix.Close(int(errp[1]))            // Done closing the pipes.
+    return 0,0,0,errno                  // Return 0 and error.
+  }                                     // Done checking error
+  pid=int(pidraw)                       // Get the pid
+  if pid==0{                            // Are we the child process.
+    // -------------------------------- //
+    // Only raw syscalls from here on: the runtime is not ours in the child.
+    // dup2 clears close-on-exec on stdout and stderr, the rest close on exec.
+    // -------------------------------- //
+    unix.RawSyscall(unix.SYS_DUP2,uintptr(out[1]),uintptr(unix.Stdout),0)// Write stdout to the output pipe.
+    unix.RawSyscall(unix.SYS_DUP2,uintptr(errp[1]),uintptr(unix.Stderr),0)// Write stderr to the error pipe.
+    unix.RawSyscall(unix.SYS_EXECVE,uintptr(unsafe.Pointer(argv0)),
+      uintptr(unsafe.Pointer(&argvp[0])),uintptr(unsafe.Pointer(&envp[0])))
+    unix.RawSyscall(unix.SYS_EXIT_GROUP,127,0,0)// Execve failed, like sh does.
+  }                                     // Done checking pid.
+  // ---------------------------------- //
+  // Parent process
+  // ---------------------------------- //
+  unix.Close(int(out[1]))               // Close the child's stdout end.
+  unix.Close(int(errp[1]))              // Close the child's stderr end.
+  return int(out[0]),int(errp[0]),pid,nil// Return our ends of the pipes.
+}                                       // ---------- PopenStderr ----------
 
 // PClose waits for child pid to exit and returns its exit status.
 func Pclose(pid int) (int,error){
   var ws unix.WaitStatus                // Create a wait status variable.
-  _,err:=unix.Wait4(pid,&ws,0,nil)      // Wait for the child to exit.
+  _,err:=wait4(pid,&ws,0)               // Wait for the child to exit.
   if err!=nil{                          // Wait failed?
     return -1,err                       // Yes, return -1 and error.
   }                                     // Done checking error.
   return ws.ExitStatus(),nil            // Return the exit status and nil.
 }                                       // ----------- Pclose -----------
+
+// PCloseResult describes how a popen'ed child ended.
+type PCloseResult struct {
+  Code   int                            // Exit status, -1 if killed by a signal.
+  Signal unix.Signal                    // The signal that killed the child, if any.
+  Killed bool                           // True if we had to kill the child ourselves.
+}
+
+// waitResult waits for child pid to exit and describes how it ended.
+func waitResult(pid int) (PCloseResult,error){
+  var ws unix.WaitStatus                // Create a wait status variable.
+  _,err:=wait4(pid,&ws,0)               // Wait for the child to exit.
+  if err!=nil{                          // Wait failed?
+    return PCloseResult{Code:-1},err    // Yes, return -1 and error.
+  }                                     // Done checking error.
+  if ws.Signaled(){                     // Was the child killed by a signal?
+    return PCloseResult{Code:-1,Signal:ws.Signal()},nil// Yes, say which one.
+  }                                     // Done checking for signal.
+  return PCloseResult{Code:ws.ExitStatus()},nil// Return the exit status.
+}                                       // ----------- waitResult -----------
+
+// KillGroup sends signal sig to the process group led by pid: the child and
+// everything it started, since "sh -c" may fork the command rather than exec
+// it, and the command may start children of its own. Signalling the child
+// alone would leave those orphaned. Only a child started with the
+// POPENSETPGID flag leads a group: it calls setpgid(0,0) before it execs, and
+// the parent calls setpgid(pid,pid) before Popen returns, so its pid is the
+// pgid from the start. Any other child has no such group, and KillGroup fails
+// with ESRCH.
+func KillGroup(pid int, sig unix.Signal) error{
+  return unix.Kill(-pid,sig)            // Signal the child's process group.
+}                                       // ----------- KillGroup -----------
@@ -0,0 +1,75 @@
+//go:build !(linux && amd64)
+// +build !linux !amd64
+
+// Filename: sys_pipe_other.go
+// Stubs for the pipe(2), fork and exec wrappers of sys_pipe_linux_amd64.go,
+// for the platforms it does not build on. They all fail with
+// ErrUnsupportedPlatform, so a program can build everywhere and find out at
+// run time. The rest of the package, Pipes, PopenHandle and WorkerPool, is
+// only there on Linux.
+package pipe
+
+const (
+	// Popen read and write flags:
+	POPENREAD=0
+	POPENWRITE=1
+	// Popen flag: the child leads its own process group.
+	POPENSETPGID=2
+)
+
+// Pipe is not supported on this platform.
+func Pipe() (r, w int, err error) {
+  return -1,-1,ErrUnsupportedPlatform   // Nothing to create.
+}                                       // ------------ Pipe ------------
+// Pipe2 is not supported on this platform.
+func Pipe2(flags int) (r, w int, err error) {
+  return -1,-1,ErrUnsupportedPlatform   // Nothing to create.
+}                                       // ------------ Pipe2 ------------
+// Mkfifo is not supported on this platform.
+func Mkfifo(path string, mode uint32) error {
+  return ErrUnsupportedPlatform         // Nothing to create.
+}                                       // ------------ Mkfifo ------------
+// GetPipeSize is not supported on this platform.
+func GetPipeSize(fd int) (int, error) {
+  return 0,ErrUnsupportedPlatform       // Nothing to ask.
+}                                       // --------- GetPipeSize ---------
+// SetPipeSize is not supported on this platform.
+func SetPipeSize(fd int, sz int) (int, error) {
+  return 0,ErrUnsupportedPlatform       // Nothing to change.
+}                                       // --------- SetPipeSize ---------
+// GetAvailableBytes is not supported on this platform.
+func GetAvailableBytes(fd int) (int, error) {
+  return 0,ErrUnsupportedPlatform       // Nothing to ask.
+}                                       // ------ GetAvailableBytes ------
+// Dup is not supported on this platform.
+func Dup(oldfd int) (int, error) {
+  return -1,ErrUnsupportedPlatform      // Nothing to duplicate.
+}                                       // ------------ Dup ------------
+// Dup2 is not supported on this platform.
+func Dup2(oldfd, newfd int) (int, error) {
+  return -1,ErrUnsupportedPlatform      // Nothing to duplicate.
+}                                       // ------------ Dup2 ------------
+// Dup3 is not supported on this platform.
+func Dup3(oldfd, newfd, flags int) (int, error) {
+  return -1,ErrUnsupportedPlatform      // Nothing to duplicate.
+}                                       // ------------ Dup3 ------------
+// Popen is not supported on this platform.
+func Popen(cmd string, flags int) (fd, pid int, err error) {
+  return -1,0,ErrUnsupportedPlatform    // Nothing to start.
+}                                       // ------------ Popen ------------
+// PopenArgv is not supported on this platform.
+func PopenArgv(path string, argv []string, flags int) (fd, pid int, err error) {
+  return -1,0,ErrUnsupportedPlatform    // Nothing to start.
+}                                       // ---------- PopenArgv ----------
+// Popen2 is not supported on this platform.
+func Popen2(cmd string) (rfd, wfd, pid int, err error) {
+  return -1,-1,0,ErrUnsupportedPlatform // Nothing to start.
+}                                       // ------------ Popen2 ------------
+// PopenStderr is not supported on this platform.
+func PopenStderr(cmd string) (outfd, errfd, pid int, err error) {
+  return -1,-1,0,ErrUnsupportedPlatform // Nothing to start.
+}                                       // ---------- PopenStderr ----------
+// Pclose is not supported on this platform.
+func Pclose(pid int) (int,error){
+  return -1,ErrUnsupportedPlatform      // Nothing to wait for.
+}                                       // ------------ Pclose ------------
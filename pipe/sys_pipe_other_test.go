@@ -0,0 +1,38 @@
+//go:build !(linux && amd64)
+// +build !linux !amd64
+
+package pipe
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnsupportedPlatform(t *testing.T) {
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"Pipe", func() error { _, _, err := Pipe(); return err }},
+		{"Pipe2", func() error { _, _, err := Pipe2(0); return err }},
+		{"Mkfifo", func() error { return Mkfifo("fifo", 0o600) }},
+		{"GetPipeSize", func() error { _, err := GetPipeSize(0); return err }},
+		{"SetPipeSize", func() error { _, err := SetPipeSize(0, 4096); return err }},
+		{"GetAvailableBytes", func() error { _, err := GetAvailableBytes(0); return err }},
+		{"Dup", func() error { _, err := Dup(0); return err }},
+		{"Dup2", func() error { _, err := Dup2(0, 1); return err }},
+		{"Dup3", func() error { _, err := Dup3(0, 1, 0); return err }},
+		{"Popen", func() error { _, _, err := Popen("true", POPENREAD); return err }},
+		{"PopenArgv", func() error { _, _, err := PopenArgv("/bin/true", []string{"true"}, POPENREAD); return err }},
+		{"Popen2", func() error { _, _, _, err := Popen2("true"); return err }},
+		{"PopenStderr", func() error { _, _, _, err := PopenStderr("true"); return err }},
+		{"Pclose", func() error { _, err := Pclose(1); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrUnsupportedPlatform) {
+				t.Errorf("%s: %v, want ErrUnsupportedPlatform", tt.name, err)
+			}
+		})
+	}
+}
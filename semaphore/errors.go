@@ -0,0 +1,17 @@
+// ============================================================================
+// Filename: errors.go
+// Description: Errors the semaphore package returns on every platform.
+//
+// Author:
+//  JEP J. Enrique Peraza
+//
+// ============================================================================
+package semaphore
+import (
+  "errors"                              // For errors.New.
+)
+// ------------------------------------ //
+// ErrUnsupportedPlatform is what the semaphore package returns where there
+// are no System V semaphores to wrap, that is anywhere but Linux on amd64.
+// ------------------------------------ //
+var ErrUnsupportedPlatform=errors.New("semaphore: System V semaphores are not supported on this platform")
@@ -0,0 +1,56 @@
+//go:build !(linux && amd64)
+//+build !linux !amd64
+
+// ============================================================================
+// Filename: semaphore_other.go
+// Description: Stand-ins for the System V semaphores of semaphore.go, for the
+// platforms that lack them, so the packages that use a Semaphore, like the
+// logger, still build there. Every call fails with ErrUnsupportedPlatform.
+//
+// Author:
+//  JEP J. Enrique Peraza
+//
+// ============================================================================
+package semaphore
+// ------------------------------------ //
+// Semaphore is never made here; NewSemaphore always fails.
+// ------------------------------------ //
+type Semaphore struct {
+  key        int                        // The semaphore key.
+	id         int                        // The semaphore set ID.
+}                                       // Our semaphore structure.
+// ------------------------------------ //
+// NewSemaphore fails with ErrUnsupportedPlatform.
+// ------------------------------------ //
+func NewSemaphore(who,name,username string, key int) (*Semaphore, error){
+  return nil,ErrUnsupportedPlatform     // No semaphores here.
+}                                       // ---------- NewSemaphore ---------- //
+// ------------------------------------ //
+// Getters for the semaphore key
+// ------------------------------------ //
+func (s *Semaphore) GetKey() int{       // Get the semaphore key.
+  return s.key                          // Return the semaphore key.
+}                                       // ------------ GetKey -------------- //
+func (s *Semaphore) GetID() int{        // Get the semaphore ID.
+	return s.id                           // Return the semaphore ID.
+}                                       // ------------ GetID --------------- //
+// ------------------------------------ //
+// The rest fail with ErrUnsupportedPlatform.
+// ------------------------------------ //
+func (s *Semaphore) Lock(why ...string) error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) Unlock(why ...string) error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) IncrementUserCount() error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) DecrementUserCount() error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) GetUserCount() (int,error){ return 0,ErrUnsupportedPlatform }
+func (s *Semaphore) IsLocked() (bool,error){ return false,ErrUnsupportedPlatform }
+func (s *Semaphore) ClearUserCount() error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) ForceRemove() error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) Remove() error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) Close() error{ return ErrUnsupportedPlatform }
+func (s *Semaphore) ForceUnlock() error{ return ErrUnsupportedPlatform }
+// ------------------------------------ //
+// ErrSym returns the error's string; there are no errno symbols here.
+// ------------------------------------ //
+func ErrSym(err error) string{          // ------------- ErrSym ------------- //
+  return err.Error()                    // Return go err string.
+}                                       // ------------- ErrSym ------------- //
@@ -0,0 +1,39 @@
+//go:build !(linux && amd64)
+// +build !linux !amd64
+
+package semaphore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUnsupportedPlatform(t *testing.T) {
+	if s, err := NewSemaphore("test", "sem", "nobody", 0x5e11); s != nil || !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Fatalf("NewSemaphore = %v, %v; want nil, ErrUnsupportedPlatform", s, err)
+	}
+	s := &Semaphore{}
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"Lock", func() error { return s.Lock() }},
+		{"Unlock", func() error { return s.Unlock() }},
+		{"IncrementUserCount", s.IncrementUserCount},
+		{"DecrementUserCount", s.DecrementUserCount},
+		{"GetUserCount", func() error { _, err := s.GetUserCount(); return err }},
+		{"IsLocked", func() error { _, err := s.IsLocked(); return err }},
+		{"ClearUserCount", s.ClearUserCount},
+		{"ForceRemove", s.ForceRemove},
+		{"Remove", s.Remove},
+		{"Close", s.Close},
+		{"ForceUnlock", s.ForceUnlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrUnsupportedPlatform) {
+				t.Errorf("%s: %v, want ErrUnsupportedPlatform", tt.name, err)
+			}
+		})
+	}
+}
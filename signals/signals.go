@@ -20,6 +20,7 @@ import (
   "os/signal"                           // For signal handling
   "sync"                                // For mutexes and locks
   "syscall"                             // For syscall handling
+  "time"                                // For the flush deadline
 
   logger "github.com/perazaharmonics/gosys/internal/logger" // Our custom log package.
 )
@@ -28,12 +29,21 @@ var (
   log         logger.Log               // Our log object.
   shutdownCBs []func()                 // Slice of shutdown callbacks
   mtx         sync.Mutex               // Protect shutdownCBs slice.
+  shutdownOnce sync.Once               // Run the shutdown callbacks only once.
+  flushWait   = 2 * time.Second        // Longest we wait for the log to flush.
+  exit        = os.Exit                // How we end the process on a signal.
 )
 
+const sigQueueLen = 16                 // Signals queued while the handler is busy.
 // const debug = true                  // Enables debug logging.
 // ----------------------------------- //
 // SetLogger pernits our main package to hand over the log object to the
-// signal.go package.
+// signal.go package. If the log object is a logger.Flusher, such as a
+// logger.AsyncLogger, SetLogger registers its Flush for shutdown on its own:
+// it runs after every callback of RegisterShutdownCB, so the lines still on
+// the AsyncLogger's queue, the ones the callbacks log, and the ones a batched
+// sync policy held back are not lost on SIGINT/SIGTERM. The flush is given
+// flushWait to finish, so a stuck logger can't keep the process alive.
 // ----------------------------------- //
 func SetLogger(l logger.Log) {         // ----------- SetLogger ------------ //
   mtx.Lock()                            // Lock the mtx to protect the log object.
@@ -65,57 +75,154 @@ func RegisterShutdownCB(cb func()) {    // ------ RegisterShutdownCB -------- //
 // ------------------------------------ //
 // SignalHandler sets up a signal listener that handles SIGHUP for log rotation
 // SIGINT/SIGTERM for graceful shutdown, and SIGQUIT for immediate exit.
+// The work is split in three: relaySignals only moves each signal from the
+// runtime's channel onto a channel of ours and never blocks, logs or takes a
+// lock, so signals keep being drained even while the logger is busy.
+// shutdownOnSignal waits for the first SIGINT, SIGTERM or SIGQUIT on a
+// channel of its own and shuts down without ever waiting for the logger.
+// handleSignals is an ordinary goroutine that drains the queue of the other
+// signals and does their logging, which may block.
 // ------------------------------------ //
 func SignalHandler(cancel context.CancelFunc) { // ------- SignalHandler ---------- //
   sigCh := make(chan os.Signal, 1)      // A channel to receive OS signals.
+  queue := make(chan os.Signal, sigQueueLen) // Signals waiting to be handled.
+  stop := make(chan os.Signal, 1)       // The signal that shuts us down.
 	// ----------------------------------- //
 	// Notify the channel when we receive these signals.
 	// ----------------------------------- //
   signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT,syscall.SIGPIPE)
 	// ----------------------------------- //
-	// Spawn a gouroutine that listens for signals and handles them on a separate
-	// thread.
+	// Spawn the relay that only passes signals on, and the goroutines that
+	// handle them on a separate thread.
 	// ----------------------------------- //
-  go func() {                           // On a separate thread.
-    for {                               // Until we receive a signal..
-    sig := <-sigCh                      // Wait for a signal on the channel.
+  go relaySignals(sigCh, queue, stop)   // Just move signals along.
+  go shutdownOnSignal(stop, cancel)     // Shut down on the first stop signal.
+  go handleSignals(queue, cancel)       // Do the rest of the work elsewhere.
+}                                       // ---------- SignalHandler --------- //
+// ------------------------------------ //
+// relaySignals moves signals from the runtime's channel onto ours. It does
+// nothing else: no logging, no locks, no allocation. SIGINT, SIGTERM and
+// SIGQUIT go to stop, which holds one: if it is full a shutdown is already
+// pending, so another one adds nothing. The rest go to the queue, and if it
+// is full the signal is dropped rather than blocking the relay.
+// ------------------------------------ //
+func relaySignals(in <-chan os.Signal, queue, stop chan<- os.Signal) { // -- relaySignals -- //
+  for sig := range in {                 // For each signal we receive...
+    switch sig {                        // Does it shut us down?
+    case syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT: // Yes.
+      select {                          // Hand it to shutdownOnSignal.
+      case stop <- sig:                 // The shutdown is on its way.
+      default:                          // A shutdown is already pending.
+      }                                 // Done passing the stop signal.
+    default:                            // No, it is for handleSignals.
+      select {                          // Try to queue it.
+      case queue <- sig:                // Queued for the handler.
+      default:                          // Queue full, the handler is stuck.
+      }                                 // Done queueing the signal.
+    }                                   // Done checking the signal.
+  }                                     // Done relaying signals.
+}                                       // ---------- relaySignals ---------- //
+// ------------------------------------ //
+// shutdownOnSignal waits for the first SIGINT, SIGTERM or SIGQUIT, then
+// cancels the context, runs the shutdown callbacks and exits. It never waits
+// long for the logger: the line saying why we stop is logged by logWait, and
+// the flush after the callbacks gives up after flushWait, so a logger that is
+// stuck holding its lock cannot hold up the shutdown.
+// ------------------------------------ //
+func shutdownOnSignal(stop <-chan os.Signal, cancel context.CancelFunc) { // - shutdownOnSignal - //
+  sig := <-stop                         // Wait for the signal to stop.
+  cancel()                              // Cancel the context.
+  if sig == syscall.SIGQUIT {           // Is it a SIGQUIT signal?
+    logWait(func(l logger.Log) { l.War("Received SIGQUIT: Forcing shutdown.") })
+  } else {                              // It was a SIGINT/SIGTERM signal.
+    logWait(func(l logger.Log) { l.Inf("Received %v: Starting graceful shutdown.", sig) })
+  }                                     // Done saying why we stop.
+  runShutdownCBs()                      // Run the shutdown callbacks.
+  exit(0)                               // Most often good.
+}                                       // -------- shutdownOnSignal -------- //
+// ------------------------------------ //
+// handleSignals drains the queue filled by relaySignals and acts on each
+// signal. It runs on an ordinary goroutine, so it is free to log and to take
+// locks.
+// ------------------------------------ //
+func handleSignals(queue <-chan os.Signal, cancel context.CancelFunc) { // - handleSignals - //
+  for sig := range queue {              // Until we receive a signal..
+    log := GetLogger()                  // The logger as of this signal.
     switch sig {                        // Yes, what signal is it?
     case syscall.SIGHUP:                // It was a SIGHUP signal.
       log.Inf("Closing the log file.")  // Log rotation.
       log.ExitLog("Because we received a SIGHUP signal.")
       log.Inf("Re-opened log file.")    // Done handling SIGHUP.
-    case syscall.SIGINT, syscall.SIGTERM: // It was a SIGINT/SIGTERM signal?
-      log.Inf("Received %v: Starting graceful shutdown.", sig)
-      cancel()                          // Cancel the context.
-      runShutdownCBs()                  // Run the shutdown callbacks.
-      os.Exit(0)                        // Most often good.
-    case syscall.SIGQUIT:               // Is it a SIGQUIT signal?
-     log.War("Received SIGQUIT: Forcing shutdown.")
-     cancel()                           // Cancel the context.
-     runShutdownCBs()                   // Run the shutdown callbacks.
-     os.Exit(0)                         // Not so bad
     case syscall.SIGPIPE:               // Is it a SIGPIPE signal?
       log.War("Received SIGPIPE: Ignoring.")
     default:                            // It was something else.
-      log.Err("Received unknown signal: %v", sig)
       cancel()                          // Cancel the context.
+      logWait(func(l logger.Log) { l.Err("Received unknown signal: %v", sig) })
       runShutdownCBs()                  // Run the shutdown CBs
-      os.Exit(1)                        // Bad stuff maybe
+      exit(1)                           // Bad stuff maybe
     }                                   // Done checking the signal.
   }                                     // Done waiting for signals.
-	}()                                    // Done spawning the goroutine.
-}                                       // ---------- SignalHandler --------- //
+}                                       // ---------- handleSignals --------- //
+// ------------------------------------ //
+// logWait makes a log call on a goroutine of its own and waits at most
+// flushWait for it, the way flushLog waits for the flush, so that shutting
+// down hands the line to the logger before the flush and the exit, but never
+// waits long for a logger that is stuck holding its lock. Such a logger writes
+// the line once it is free, or loses it if the process exits first. It goes to
+// the logger of the moment of the call, not to one handed to SetLogger later.
+// ------------------------------------ //
+func logWait(fn func(l logger.Log)) {   // ------------ logWait ------------- //
+  l := GetLogger()                      // The logger as of now.
+  done := make(chan struct{})           // Closed once the line is logged.
+  go func() {                           // Leave the waiting to someone else.
+    fn(l)                               // Log it when the logger lets us.
+    close(done)                         // Say it is done.
+  }()                                   // Done starting the logger call.
+  select {                              // Wait for it, but not forever.
+  case <-done:                          // Did it log?
+  case <-time.After(flushWait):         // The logger is stuck.
+  }                                     // Done waiting for the line.
+}                                       // ------------ logWait ------------- //
 // ------------------------------------ //
 // runShutdownCBs runs all of the registered shutdown callback functions in the
-// order they were registered.
+// order they were registered. The signal handler and main may both call it,
+// even at the same time, but the callbacks only ever run once; late callers
+// wait until the first one is done. The callbacks run on a copy of the slice,
+// so they are free to call RegisterShutdownCB or SetLogger themselves.
 // -- ---------------------------------- //
 func runShutdownCBs() {                  // -------- runShutdownCBs --------- //
-  mtx.Lock()                             // Lock the mutex to protect the slice.
-  defer mtx.Unlock()                     // Unlock the mutex when done.
-  for _, cb := range shutdownCBs {       // For each callback in the slice.
-    safeCall(cb)                         // Call the callback function.
-  }                                      // Done calling the callbacks.
+  shutdownOnce.Do(func() {               // Only the first caller runs them.
+    mtx.Lock()                           // Lock the mutex to protect the slice.
+    cbs := append([]func(){}, shutdownCBs...) // Copy the callbacks.
+    mtx.Unlock()                         // Unlock the mutex, we have our copy.
+    for _, cb := range cbs {             // For each callback in the slice.
+      safeCall(cb)                       // Call the callback function.
+    }                                    // Done calling the callbacks.
+    flushLog()                           // Write out what they logged.
+  })                                     // Done running callbacks once.
 }                                        // -------- runShutdownCBs --------- //
+// ------------------------------------ //
+// flushLog flushes the log object handed to SetLogger, if it knows how to,
+// waiting at most flushWait for it.
+// ------------------------------------ //
+func flushLog() {                        // ----------- flushLog ----------- //
+  mtx.Lock()                             // Lock the mutex to protect the log object.
+  l := log                               // The logger as of now.
+  mtx.Unlock()                           // Unlock the mutex, we have our copy.
+  f, ok := l.(logger.Flusher)            // Can it be flushed?
+  if !ok {                               // No?
+    return                               // Then there is nothing to do.
+  }                                      // Done checking for a Flusher.
+  done := make(chan error, 1)            // Where the flush says how it went.
+  go func() { done <- f.Flush() }()      // Flush it, maybe waiting for its lock.
+  select {                               // Wait for the flush, but not forever.
+  case err := <-done:                    // Did it flush?
+    if err != nil {                      // Yes, but did it fail?
+      logWait(func(l logger.Log) { l.Err("Flushing the log on shutdown: %v", err) })
+    }                                    // Done checking for error.
+  case <-time.After(flushWait):          // The logger is stuck.
+  }                                      // Done waiting for the flush.
+}                                        // ----------- flushLog ----------- //
 // ------------------------------------- //
 // InvokeShutdownCBs is a helper function that runs all registered shutdown
 // callback functions in the order they were registered and just wraps around
@@ -127,14 +234,14 @@ func InvokeShutdownCBs() {              // ----- InvokeShutdownCBs -------- //
 }                                       // ------- InvokeShutdownCBs -------- //
 // ------------------------------------ //
 // safeCall is a helper function that executes a shutdown callback function
-// with panic recovery.
+// with panic recovery. A panic is logged by logWait, so the line reaches the
+// logger before flushLog runs. The callback itself is not logged.
 // ----------------------------------- //
 func safeCall(cb func()) {             // ---------- safeCall ------------- //
   defer func() {                       // Defer the recovery function to handle panics.
     if r := recover(); r != nil {      // Did we panic?
-     log.Err("Recovered from panic in shutdown callback: %v", r) // Yes, log it.
+     logWait(func(l logger.Log) { l.Err("Recovered from panic in shutdown callback: %v", r) }) // Yes, log it.
     }                                  // Done checking for panic.
   }()                                  // Done deferring the recovery function.
-  log.Inf("Running shutdown callback: %p", cb) // Log the callback.
   cb()                                 // Call the callback function.
 }                                      // ---------- safeCall ------------- //
@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	logger "github.com/perazaharmonics/gosys/internal/logger"
+)
+
+// stuckLog is a logger.Log and logger.Flusher whose every call waits for mu,
+// so a test can hold mu to play a logger stuck holding its lock.
+type stuckLog struct {
+	mu sync.Mutex
+}
+
+func (l *stuckLog) wait() bool {
+	l.mu.Lock()
+	l.mu.Unlock()
+	return true
+}
+
+func (l *stuckLog) Inf(msg string, args ...interface{}) bool { return l.wait() }
+func (l *stuckLog) Deb(msg string, args ...interface{}) bool { return l.wait() }
+func (l *stuckLog) War(msg string, args ...interface{}) bool { return l.wait() }
+func (l *stuckLog) Err(msg string, args ...interface{}) bool { return l.wait() }
+func (l *stuckLog) Fat(msg string, args ...interface{}) bool { return l.wait() }
+func (l *stuckLog) ExitLog(msg string, args ...interface{})  { l.wait() }
+func (l *stuckLog) Shutdown() error                          { l.wait(); return nil }
+func (l *stuckLog) Flush() error                             { l.wait(); return nil }
+
+func TestRelaySignals(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        []os.Signal
+		wantStop  os.Signal
+		wantQueue int
+	}{
+		{"term", []os.Signal{syscall.SIGTERM}, syscall.SIGTERM, 0},
+		{"hup", []os.Signal{syscall.SIGHUP}, nil, 1},
+		{"term after full queue", append(repeat(syscall.SIGHUP, sigQueueLen+4), syscall.SIGTERM), syscall.SIGTERM, sigQueueLen},
+		{"int then quit", []os.Signal{syscall.SIGINT, syscall.SIGQUIT}, syscall.SIGINT, 0},
+		{"pipe then quit", []os.Signal{syscall.SIGPIPE, syscall.SIGQUIT}, syscall.SIGQUIT, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := make(chan os.Signal, len(tt.in))
+			queue := make(chan os.Signal, sigQueueLen)
+			stop := make(chan os.Signal, 1)
+			for _, sig := range tt.in {
+				in <- sig
+			}
+			close(in)
+			relaySignals(in, queue, stop)
+			var got os.Signal
+			select {
+			case got = <-stop:
+			default:
+			}
+			if got != tt.wantStop {
+				t.Errorf("stop got %v, want %v", got, tt.wantStop)
+			}
+			if len(queue) != tt.wantQueue {
+				t.Errorf("queue holds %d signals, want %d", len(queue), tt.wantQueue)
+			}
+		})
+	}
+}
+
+func repeat(sig os.Signal, n int) []os.Signal {
+	s := make([]os.Signal, n)
+	for i := range s {
+		s[i] = sig
+	}
+	return s
+}
+
+// TestShutdownWithStuckLogger delivers SIGTERM while the logger's lock is
+// held, and checks that the context is cancelled, the callbacks run and the
+// process exits anyway.
+func TestShutdownWithStuckLogger(t *testing.T) {
+	l := &stuckLog{}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	SetLogger(l)
+	mtx.Lock()
+	shutdownCBs, shutdownOnce = nil, sync.Once{}
+	mtx.Unlock()
+	oldExit, oldWait := exit, flushWait
+	defer func() { exit, flushWait = oldExit, oldWait }()
+	exited := make(chan int, 1)
+	exit = func(code int) { exited <- code }
+	flushWait = 50 * time.Millisecond
+	ran := make(chan struct{}, 1)
+	RegisterShutdownCB(func() { ran <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	SignalHandler(cancel)
+	defer signal.Reset(syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT, syscall.SIGPIPE)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+
+	timeout := time.After(5 * time.Second)
+	select {
+	case <-ctx.Done():
+	case <-timeout:
+		t.Fatal("context not cancelled: shutdown waited for the logger")
+	}
+	select {
+	case <-ran:
+	case <-timeout:
+		t.Fatal("shutdown callback not run: shutdown waited for the logger")
+	}
+	select {
+	case code := <-exited:
+		if code != 0 {
+			t.Errorf("exit code %d, want 0", code)
+		}
+	case <-timeout:
+		t.Fatal("no exit: shutdown waited for the logger")
+	}
+}
+
+// TestInvokeShutdownCBsOnce races InvokeShutdownCBs against itself and
+// RegisterShutdownCB; run it with -race.
+func TestInvokeShutdownCBsOnce(t *testing.T) {
+	SetLogger(&stuckLog{})
+	mtx.Lock()
+	shutdownCBs, shutdownOnce = nil, sync.Once{}
+	mtx.Unlock()
+	var counts [3]int32
+	RegisterShutdownCB(func() { atomic.AddInt32(&counts[0], 1) })
+	RegisterShutdownCB(func() {
+		atomic.AddInt32(&counts[1], 1)
+		RegisterShutdownCB(func() {}) // Must not deadlock.
+		panic("a callback panics")
+	})
+	RegisterShutdownCB(func() { atomic.AddInt32(&counts[2], 1) })
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			InvokeShutdownCBs()
+		}()
+		go func() {
+			defer wg.Done()
+			RegisterShutdownCB(func() {})
+		}()
+	}
+	wg.Wait()
+	for i := range counts {
+		if n := atomic.LoadInt32(&counts[i]); n != 1 {
+			t.Errorf("callback %d ran %d times, want 1", i, n)
+		}
+	}
+}
+
+// recordLog is a logger.Log and logger.Flusher that records what it was told.
+type recordLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *recordLog) add(e string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, e)
+	return true
+}
+
+func (l *recordLog) Inf(msg string, args ...interface{}) bool { return l.add(msg) }
+func (l *recordLog) Deb(msg string, args ...interface{}) bool { return l.add(msg) }
+func (l *recordLog) War(msg string, args ...interface{}) bool { return l.add(msg) }
+func (l *recordLog) Err(msg string, args ...interface{}) bool { return l.add(msg) }
+func (l *recordLog) Fat(msg string, args ...interface{}) bool { return l.add(msg) }
+func (l *recordLog) ExitLog(msg string, args ...interface{})  { l.add(msg) }
+func (l *recordLog) Shutdown() error                          { l.add("shutdown"); return nil }
+func (l *recordLog) Flush() error                             { l.add("flush"); return nil }
+
+// TestFlushOnShutdown checks that the logger is flushed once, after the
+// shutdown callbacks have logged.
+func TestFlushOnShutdown(t *testing.T) {
+	tests := []struct {
+		name string
+		cbs  []string
+		want []string
+	}{
+		{"no callbacks", nil, []string{"flush"}},
+		{"callbacks log", []string{"a", "b"}, []string{"a", "b", "flush"}},
+		{"callback panics", []string{"a", "panic"}, []string{"a", "Recovered from panic in shutdown callback: %v", "flush"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &recordLog{}
+			SetLogger(l)
+			mtx.Lock()
+			shutdownCBs, shutdownOnce = nil, sync.Once{}
+			mtx.Unlock()
+			for _, msg := range tt.cbs {
+				msg := msg
+				RegisterShutdownCB(func() {
+					if msg == "panic" {
+						panic(msg)
+					}
+					l.Inf(msg)
+				})
+			}
+			InvokeShutdownCBs()
+			InvokeShutdownCBs()
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if strings.Join(l.events, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("logger saw %q, want %q", l.events, tt.want)
+			}
+		})
+	}
+}
+
+// slowWriter is a log sink that takes its time over every line, so lines pile
+// up on an AsyncLogger's queue.
+type slowWriter struct {
+	strings.Builder
+}
+
+func (w *slowWriter) Write(b []byte) (int, error) {
+	time.Sleep(2 * time.Millisecond)
+	return w.Builder.Write(b)
+}
+
+// TestAsyncFlushOnShutdown checks that the lines still queued on an
+// AsyncLogger handed to SetLogger are written when the shutdown callbacks run.
+func TestAsyncFlushOnShutdown(t *testing.T) {
+	l := &logger.Logger{Level: logger.Info}
+	var sink slowWriter
+	l.AddOutput(&sink)
+	a := logger.NewAsyncLogger(l, 4)
+	SetLogger(a)
+	mtx.Lock()
+	shutdownCBs, shutdownOnce = nil, sync.Once{}
+	mtx.Unlock()
+	RegisterShutdownCB(func() { a.Inf("from the callback") })
+	for i := 0; i < 20; i++ {
+		a.Inf("line %d", i)
+	}
+	InvokeShutdownCBs()
+	got := sink.String()
+	if n := strings.Count(got, "\n"); n != 21 {
+		t.Fatalf("sink holds %d lines after shutdown, want 21:\n%s", n, got)
+	}
+	if !strings.HasSuffix(got, "from the callback\n") {
+		t.Errorf("the callback's line is not the last one:\n%s", got)
+	}
+}